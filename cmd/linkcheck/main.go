@@ -0,0 +1,179 @@
+// Command linkcheck checks links from the command line without running the
+// server. It exits with status 1 when any link is not available, which makes
+// it usable as a CI step.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/service"
+	"github.com/olgkv/linkchecker/internal/storage"
+)
+
+const (
+	exitOK     = 0
+	exitBroken = 1
+	exitUsage  = 2
+)
+
+type linkResult struct {
+	Link   string            `json:"link"`
+	Status domain.LinkStatus `json:"status"`
+}
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("linkcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: linkcheck [flags] [url ...]")
+		fmt.Fprintln(stderr, "URLs are read from arguments, -f FILE, or stdin (one per line).")
+		fs.PrintDefaults()
+	}
+	file := fs.String("f", "", "read URLs from `file` (\"-\" for stdin)")
+	format := fs.String("o", "table", "output format: table, json or csv")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for the whole check")
+	workers := fs.Int("workers", 100, "concurrent checks")
+	robots := fs.Bool("robots", false, "honor robots.txt and skip disallowed links")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *format != "table" && *format != "json" && *format != "csv" {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *format)
+		return exitUsage
+	}
+
+	links, err := collectLinks(fs.Args(), *file, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if len(links) == 0 {
+		fmt.Fprintln(stderr, "no URLs to check")
+		return exitUsage
+	}
+
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), &http.Client{}, *workers, *timeout, 1)
+	defer svc.Close()
+	if *robots {
+		svc.EnableRobots("linkchecker", 0)
+	}
+
+	_, checked, err := svc.CheckLinks(ctx, links, service.CheckOptions{})
+	if err != nil {
+		fmt.Fprintf(stderr, "check links: %v\n", err)
+		return exitBroken
+	}
+
+	results := make([]linkResult, 0, len(links))
+	code := exitOK
+	for _, link := range links {
+		st := checked[link].Status
+		if st != domain.StatusAvailable {
+			code = exitBroken
+		}
+		results = append(results, linkResult{Link: link, Status: st})
+	}
+
+	if err := writeResults(stdout, *format, results); err != nil {
+		fmt.Fprintf(stderr, "write results: %v\n", err)
+		return exitBroken
+	}
+	return code
+}
+
+// collectLinks gathers URLs from args and file; with neither it reads stdin.
+// Blank lines, lines starting with # and duplicates are skipped.
+func collectLinks(args []string, file string, stdin io.Reader) ([]string, error) {
+	links := append([]string(nil), args...)
+
+	var r io.Reader
+	switch {
+	case file == "-":
+		r = stdin
+	case file != "":
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("open URL list: %w", err)
+		}
+		defer f.Close()
+		r = f
+	case len(args) == 0:
+		r = stdin
+	}
+
+	if r != nil {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			links = append(links, line)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("read URL list: %w", err)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(links))
+	unique := links[:0]
+	for _, l := range links {
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		unique = append(unique, l)
+	}
+	return unique, nil
+}
+
+func writeResults(w io.Writer, format string, results []linkResult) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"link", "status"})
+		for _, r := range results {
+			_ = cw.Write([]string{r.Link, string(r.Status)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "LINK\tSTATUS")
+		for _, r := range results {
+			fmt.Fprintf(tw, "%s\t%s\n", r.Link, r.Status)
+		}
+		return tw.Flush()
+	}
+}
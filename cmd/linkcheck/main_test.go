@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCollectLinks(t *testing.T) {
+	stdin := strings.NewReader("# список\nexample.com\n\ngo.dev\nexample.com\n")
+	links, err := collectLinks(nil, "-", stdin)
+	if err != nil {
+		t.Fatalf("collectLinks: %v", err)
+	}
+	if strings.Join(links, ",") != "example.com,go.dev" {
+		t.Fatalf("links = %v", links)
+	}
+
+	// аргументы без -f не должны ждать stdin
+	links, err = collectLinks([]string{"a.com"}, "", strings.NewReader("b.com\n"))
+	if err != nil || len(links) != 1 || links[0] != "a.com" {
+		t.Fatalf("links = %v, err = %v", links, err)
+	}
+}
+
+func TestRun_BrokenLinkExitCode(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-o", "json", "not a url"}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitBroken {
+		t.Fatalf("exit code = %d, want %d (stderr: %s)", code, exitBroken, stderr.String())
+	}
+
+	var results []linkResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(results) != 1 || results[0].Status != "not available" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"-o", "xml", "a.com"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Fatalf("exit code = %d, want %d", code, exitUsage)
+	}
+	if code := run(context.Background(), nil, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Fatalf("exit code without URLs = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	results := []linkResult{{Link: "example.com", Status: "available"}}
+
+	var csvOut bytes.Buffer
+	if err := writeResults(&csvOut, "csv", results); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if csvOut.String() != "link,status\nexample.com,available\n" {
+		t.Fatalf("csv = %q", csvOut.String())
+	}
+
+	var table bytes.Buffer
+	if err := writeResults(&table, "table", results); err != nil {
+		t.Fatalf("table: %v", err)
+	}
+	if !strings.Contains(table.String(), "example.com  available") {
+		t.Fatalf("table = %q", table.String())
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/olgkv/linkchecker/internal/httpapi"
+)
+
+// There is no SIGUSR1 outside unix; GET /admin/state serves the same
+// snapshot.
+func dumpStateOnSIGUSR1(context.Context, *slog.Logger, func() httpapi.StateResponse) {}
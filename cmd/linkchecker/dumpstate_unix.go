@@ -0,0 +1,30 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/olgkv/linkchecker/internal/httpapi"
+)
+
+// dumpStateOnSIGUSR1 logs the snapshot returned by state for every SIGUSR1
+// until ctx is done.
+func dumpStateOnSIGUSR1(ctx context.Context, log *slog.Logger, state func() httpapi.StateResponse) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	defer signal.Stop(usr1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-usr1:
+			log.Info("state dump", "state", state())
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+type options struct {
+	overrides   map[string]string
+	showVersion bool
+	dumpConfig  bool
+}
+
+// flagName maps an env name to its flag: MAX_WORKERS becomes max-workers.
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// parseFlags defines one flag per config setting plus --config, --version and
+// --dump-config. Only flags given explicitly end up in overrides, so unset
+// flags never shadow env or config file values.
+func parseFlags(args []string, output io.Writer) (options, error) {
+	fs := flag.NewFlagSet("linkchecker", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	opts := options{overrides: map[string]string{}}
+	names := map[string]string{"config": "CONFIG_FILE"}
+	fs.String("config", "", "path to a YAML or TOML config file (CONFIG_FILE)")
+	for _, s := range config.Settings() {
+		name := flagName(s.Name)
+		names[name] = s.Name
+		fs.String(name, s.Default, fmt.Sprintf("overrides %s", s.Name))
+	}
+	fs.BoolVar(&opts.showVersion, "version", false, "print version and exit")
+	fs.BoolVar(&opts.dumpConfig, "dump-config", false, "print effective configuration as YAML and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	if fs.NArg() > 0 {
+		return options{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if env, ok := names[f.Name]; ok {
+			opts.overrides[env] = f.Value.String()
+		}
+	})
+	return opts, nil
+}
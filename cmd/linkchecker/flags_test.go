@@ -0,0 +1,29 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	opts, err := parseFlags([]string{"--port", "9090", "--max-workers=8", "--config", "app.yaml", "--dump-config"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	want := map[string]string{"PORT": "9090", "MAX_WORKERS": "8", "CONFIG_FILE": "app.yaml"}
+	if len(opts.overrides) != len(want) {
+		t.Fatalf("overrides = %v, want %v", opts.overrides, want)
+	}
+	for k, v := range want {
+		if opts.overrides[k] != v {
+			t.Fatalf("overrides[%s] = %q, want %q", k, opts.overrides[k], v)
+		}
+	}
+	if !opts.dumpConfig || opts.showVersion {
+		t.Fatalf("unexpected switches: %+v", opts)
+	}
+
+	if _, err := parseFlags([]string{"--no-such-flag"}, io.Discard); err == nil {
+		t.Fatalf("expected error for unknown flag")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation.
+const listenFDsStart = 3
+
+// listenerServer serves an *http.Server on a listener opened in advance,
+// e.g. a unix socket or a socket inherited from systemd.
+type listenerServer struct {
+	*http.Server
+	ln net.Listener
+}
+
+func (s listenerServer) ListenAndServe() error {
+	if s.TLSConfig != nil {
+		return s.ServeTLS(s.ln, "", "")
+	}
+	return s.Serve(s.ln)
+}
+
+// publicListener returns the listener for the public server: a systemd
+// activated socket when LISTEN_FDS is set for this process, otherwise the one
+// described by spec ("unix:/path" or "tcp:host:port"). It returns nil when the
+// server should listen on its own Addr.
+func publicListener(spec string) (net.Listener, error) {
+	lns, err := activationListeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(lns) > 0 {
+		for _, ln := range lns[1:] {
+			_ = ln.Close()
+		}
+		return lns[0], nil
+	}
+
+	network, addr, ok := strings.Cut(spec, ":")
+	switch {
+	case spec == "":
+		return nil, nil
+	case ok && network == "unix":
+		// Сокет от прошлого запуска мешает bind, а сам по себе ничего не значит.
+		if err := os.Remove(addr); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+		return net.Listen("unix", addr)
+	case ok && network == "tcp":
+		return net.Listen("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unsupported LISTEN %q, want unix:/path or tcp:host:port", spec)
+	}
+}
+
+// activationListeners implements the systemd socket activation protocol
+// (sd_listen_fds): descriptors start at 3 and are meant for us only when
+// LISTEN_PID matches our pid.
+func activationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	lns := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("inherit socket fd %d: %w", fd, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestPublicListener_UnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "linkchecker.sock")
+	// оставшийся от прошлого запуска файл не должен мешать
+	if err := os.WriteFile(sock, nil, 0o600); err != nil {
+		t.Fatalf("write stale socket: %v", err)
+	}
+
+	ln, err := publicListener("unix:" + sock)
+	if err != nil {
+		t.Fatalf("publicListener: %v", err)
+	}
+	srv := listenerServer{
+		Server: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		})},
+		ln: ln,
+	}
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	resp, err := client.Get("http://unix/health")
+	if err != nil {
+		t.Fatalf("GET over unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Fatalf("body = %q, want ok", body)
+	}
+}
+
+func TestPublicListener_Defaults(t *testing.T) {
+	ln, err := publicListener("")
+	if err != nil || ln != nil {
+		t.Fatalf("expected no listener for empty spec, got %v, %v", ln, err)
+	}
+	if _, err := publicListener("udp:1.2.3.4:53"); err == nil {
+		t.Fatalf("expected error for unsupported network")
+	}
+
+	// LISTEN_FDS другого процесса игнорируется.
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+	lns, err := activationListeners()
+	if err != nil || lns != nil {
+		t.Fatalf("expected activation to be ignored, got %v, %v", lns, err)
+	}
+}
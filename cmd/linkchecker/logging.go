@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+// newLogger builds the process logger from LOG_LEVEL, LOG_FORMAT and
+// LOG_OUTPUT. The returned closer releases the log file, if any.
+func newLogger(cfg *config.Config) (*slog.Logger, io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, nil, fmt.Errorf("parse LOG_LEVEL: %w", err)
+	}
+
+	var out io.Writer
+	var closer io.Closer = io.NopCloser(nil)
+	switch strings.ToLower(cfg.LogOutput) {
+	case "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	case "file":
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open LOG_FILE: %w", err)
+		}
+		out, closer = f, f
+	default:
+		return nil, nil, fmt.Errorf("unknown LOG_OUTPUT %q, want stdout, stderr or file", cfg.LogOutput)
+	}
+
+	opts := &slog.HandlerOptions{AddSource: true, Level: level}
+	if loc := cfg.Location(); loc != time.UTC {
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.TimeValue(a.Value.Time().In(loc))
+			}
+			return a
+		}
+	}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.LogFormat) {
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	case "text":
+		handler = slog.NewTextHandler(out, opts)
+	default:
+		_ = closer.Close()
+		return nil, nil, fmt.Errorf("unknown LOG_FORMAT %q, want json or text", cfg.LogFormat)
+	}
+	return slog.New(handler), closer, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+func TestNewLoggerWritesTextToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := &config.Config{LogLevel: "warn", LogFormat: "text", LogOutput: "file", LogFile: path}
+
+	logger, closer, err := newLogger(cfg)
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", "k", "v")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	got := string(data)
+	if strings.Contains(got, "hidden") {
+		t.Fatalf("info record written below warn level: %s", got)
+	}
+	if !strings.Contains(got, "level=WARN") || !strings.Contains(got, "msg=shown") || !strings.Contains(got, "k=v") {
+		t.Fatalf("unexpected log output: %s", got)
+	}
+}
+
+func TestNewLoggerRejectsUnknownFormat(t *testing.T) {
+	cfg := &config.Config{LogLevel: "info", LogFormat: "xml", LogOutput: "stderr"}
+	if _, _, err := newLogger(cfg); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestNewLoggerUsesTimezone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := &config.Config{LogLevel: "info", LogFormat: "json", LogOutput: "file", LogFile: path, Timezone: "Europe/Moscow"}
+
+	logger, closer, err := newLogger(cfg)
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	logger.Info("hello")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if !strings.Contains(string(data), `+03:00"`) {
+		t.Fatalf("time not in Europe/Moscow: %s", data)
+	}
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -23,22 +26,26 @@ type serviceWaiter interface {
 	Wait()
 }
 
-func runHTTPServer(ctx context.Context, srv httpServer, svc serviceWaiter) {
-	go func() {
-		slog.Info("server listening", "addr", getAddr(srv))
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("http server exited", "err", err)
-			os.Exit(1)
-		}
-	}()
+func runHTTPServer(ctx context.Context, svc serviceWaiter, srvs ...httpServer) {
+	for _, srv := range srvs {
+		go func() {
+			slog.Info("server listening", "addr", getAddr(srv), "tls", usesTLS(srv))
+			if err := listen(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("http server exited", "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
 
 	<-ctx.Done()
 	slog.Info("shutting down")
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		slog.Error("server shutdown error", "err", err)
+	for _, srv := range srvs {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("server shutdown error", "err", err)
+		}
 	}
 
 	if svc != nil {
@@ -46,34 +53,160 @@ func runHTTPServer(ctx context.Context, srv httpServer, svc serviceWaiter) {
 	}
 }
 
+// listen serves HTTPS when the server carries a TLS config and plain HTTP otherwise.
+func listen(srv httpServer) error {
+	if hs, ok := srv.(*http.Server); ok && hs.TLSConfig != nil {
+		// Сертификаты уже лежат в TLSConfig, поэтому пути не нужны.
+		return srv.(*http.Server).ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+func usesTLS(srv httpServer) bool {
+	switch s := srv.(type) {
+	case *http.Server:
+		return s.TLSConfig != nil
+	case listenerServer:
+		return s.TLSConfig != nil
+	}
+	return false
+}
+
 func getAddr(srv httpServer) string {
-	if hs, ok := srv.(*http.Server); ok {
-		return hs.Addr
+	switch s := srv.(type) {
+	case *http.Server:
+		return s.Addr
+	case listenerServer:
+		return s.ln.Addr().String()
 	}
 	return ""
 }
 
+// runWorker consumes link-check jobs from the queue until ctx is done or
+// the queue connection is lost.
+func runWorker(ctx context.Context, cfg *config.Config, logger *slog.Logger) {
+	worker, svc, statsFn, err := app.NewWorker(cfg, logger)
+	if err != nil {
+		logger.Error("init consumer", "err", err)
+		os.Exit(1)
+	}
+	logger.Info("consuming jobs", "subject", cfg.NATSSubject, "group", cfg.NATSQueueGroup)
+	runErr := worker.Consumer.Run(ctx)
+	svc.Wait()
+	svc.Close()
+	if err := worker.Queue.Close(); err != nil {
+		logger.Error("close queue", "err", err)
+	}
+
+	total, completed := statsFn()
+	logger.Info("shutdown summary", "total_tasks", total, "completed_tasks", completed)
+	if runErr != nil {
+		logger.Error("consumer stopped", "err", runErr)
+		os.Exit(1)
+	}
+}
+
+// reloadOnSIGHUP calls reload for every SIGHUP until ctx is done.
+func reloadOnSIGHUP(ctx context.Context, reload func() error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := reload(); err != nil {
+				slog.Error("reload config", "err", err)
+			}
+		}
+	}
+}
+
 func main() {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{AddSource: true})
 	slog.SetDefault(slog.New(handler))
 
-	cfg, err := config.Load()
+	opts, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if opts.showVersion {
+		fmt.Println(version)
+		return
+	}
+
+	cfg, err := config.LoadWith(opts.overrides)
 	if err != nil {
 		slog.Error("load config", "err", err)
 		os.Exit(1)
 	}
+	if opts.dumpConfig {
+		if err := cfg.Dump(os.Stdout); err != nil {
+			slog.Error("dump config", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	srv, svc, statsFn, err := app.NewServer(cfg)
+	logger, logCloser, err := newLogger(cfg)
 	if err != nil {
-		slog.Error("init server", "err", err)
+		slog.Error("init logger", "err", err)
 		os.Exit(1)
 	}
+	defer logCloser.Close()
+	slog.SetDefault(logger)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
+	if cfg.Mode == "consumer" {
+		runWorker(ctx, cfg, logger)
+		return
+	}
+
+	servers, svc, statsFn, err := app.NewServer(cfg, logger)
+	if err != nil {
+		logger.Error("init server", "err", err)
+		os.Exit(1)
+	}
 
-	runHTTPServer(ctx, srv, svc)
+	ln, err := publicListener(cfg.Listen)
+	if err != nil {
+		logger.Error("open listener", "err", err)
+		os.Exit(1)
+	}
+	srvs := []httpServer{servers.Public}
+	if ln != nil {
+		srvs[0] = listenerServer{Server: servers.Public, ln: ln}
+	}
+	if servers.Admin != nil {
+		srvs = append(srvs, servers.Admin)
+	}
+	go reloadOnSIGHUP(ctx, servers.Reload)
+	go dumpStateOnSIGUSR1(ctx, logger, servers.State)
+	var loadFailed atomic.Bool
+	go func() {
+		if err := <-servers.Loaded; err != nil {
+			logger.Error("init storage", "err", err)
+			loadFailed.Store(true)
+			stop()
+		}
+	}()
+	runHTTPServer(ctx, svc, srvs...)
+	svc.Close()
+	if servers.Events != nil {
+		if err := servers.Events.Close(); err != nil {
+			logger.Error("close event bus", "err", err)
+		}
+	}
 
 	total, completed := statsFn()
-	slog.Info("shutdown summary", "total_tasks", total, "completed_tasks", completed)
+	logger.Info("shutdown summary", "total_tasks", total, "completed_tasks", completed)
+	if loadFailed.Load() {
+		os.Exit(1)
+	}
 }
@@ -45,7 +45,7 @@ func TestRunHTTPServer_ShutdownCalledOnContextCancel(t *testing.T) {
 		cancel()
 	}()
 
-	runHTTPServer(ctx, f, svc)
+	runHTTPServer(ctx, svc, f)
 
 	if atomic.LoadInt32(&f.shutdownCalled) == 0 {
 		t.Fatalf("expected Shutdown to be called")
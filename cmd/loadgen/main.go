@@ -0,0 +1,264 @@
+// Command loadgen sends synthetic POST /v1/links traffic to a running
+// linkchecker instance at a fixed rate and prints a latency summary, so that
+// changes to the worker pool or storage can be compared under load.
+//
+// By default the batches consist of hosts under the reserved .invalid
+// domain, which never resolve: the server rejects them without outbound
+// requests, and the measurement covers the server itself rather than the
+// network. Use -f to check real hosts instead.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type options struct {
+	target      string
+	rps         float64
+	duration    time.Duration
+	batch       int
+	batchMax    int
+	concurrency int
+	key         string
+	hosts       []string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: loadgen [flags]")
+		fs.PrintDefaults()
+	}
+	var opts options
+	fs.StringVar(&opts.target, "target", "http://localhost:8080", "base `URL` of the linkchecker instance")
+	fs.Float64Var(&opts.rps, "rps", 10, "requests per second")
+	fs.DurationVar(&opts.duration, "duration", 30*time.Second, "how long to send traffic")
+	fs.IntVar(&opts.batch, "batch", 10, "links per request")
+	fs.IntVar(&opts.batchMax, "batch-max", 0, "if greater than -batch, pick the batch size uniformly up to this value")
+	fs.IntVar(&opts.concurrency, "concurrency", 64, "maximum requests in flight")
+	fs.StringVar(&opts.key, "key", "", "API key sent as a bearer token")
+	file := fs.String("f", "", "pick links from this `file`, one host per line, instead of synthetic ones")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if opts.rps <= 0 || opts.batch <= 0 || opts.concurrency <= 0 || opts.duration <= 0 {
+		fmt.Fprintln(stderr, "-rps, -batch, -concurrency and -duration must be positive")
+		return exitUsage
+	}
+	if opts.batchMax < opts.batch {
+		opts.batchMax = opts.batch
+	}
+
+	if *file != "" {
+		hosts, err := readHosts(*file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		opts.hosts = hosts
+	}
+
+	st := generate(ctx, &http.Client{Timeout: time.Minute}, opts)
+	st.write(stdout)
+	if st.sent == 0 || st.failed == st.sent {
+		return exitError
+	}
+	return exitOK
+}
+
+func readHosts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open host list: %w", err)
+	}
+	defer f.Close()
+
+	var hosts []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			hosts = append(hosts, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read host list: %w", err)
+	}
+	if len(hosts) == 0 {
+		return nil, errors.New("host list is empty")
+	}
+	return hosts, nil
+}
+
+// generate sends requests at opts.rps until opts.duration passes or ctx is
+// done and waits for the requests in flight.
+func generate(ctx context.Context, client *http.Client, opts options) *stats {
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	st := &stats{codes: map[int]int{}}
+	limiter := rate.NewLimiter(rate.Limit(opts.rps), 1)
+	sem := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for seq := 0; ; seq++ {
+		if err := limiter.Wait(ctx); err != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			// все слоты заняты — сервер не успевает, запрос не отправляем
+			st.add(0, 0, errDropped)
+			continue
+		}
+		wg.Add(1)
+		go func(body []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			code, latency, err := send(client, opts, body)
+			st.add(code, latency, err)
+		}(batchBody(opts, seq))
+	}
+	wg.Wait()
+	st.elapsed = time.Since(start)
+	return st
+}
+
+var errDropped = errors.New("concurrency limit reached")
+
+func batchBody(opts options, seq int) []byte {
+	n := opts.batch
+	if opts.batchMax > opts.batch {
+		n += rand.IntN(opts.batchMax - opts.batch + 1)
+	}
+	links := make([]string, n)
+	for i := range links {
+		if len(opts.hosts) > 0 {
+			links[i] = opts.hosts[rand.IntN(len(opts.hosts))]
+		} else {
+			links[i] = fmt.Sprintf("loadgen-%d-%d.invalid", seq, i)
+		}
+	}
+	body, _ := json.Marshal(map[string]any{"links": links, "tags": []string{"loadgen"}})
+	return body
+}
+
+func send(client *http.Client, opts options, body []byte) (int, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(opts.target, "/")+"/v1/links", bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if opts.key != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.key)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, time.Since(start), nil
+}
+
+type stats struct {
+	mu        sync.Mutex
+	sent      int
+	failed    int
+	dropped   int
+	codes     map[int]int
+	latencies []time.Duration
+	elapsed   time.Duration
+}
+
+// add records one request; responses other than 200 and 202 count as failed.
+func (s *stats) add(code int, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errors.Is(err, errDropped) {
+		s.dropped++
+		return
+	}
+	s.sent++
+	if err != nil {
+		s.failed++
+		return
+	}
+	s.codes[code]++
+	s.latencies = append(s.latencies, latency)
+	if code != http.StatusOK && code != http.StatusAccepted {
+		s.failed++
+	}
+}
+
+func (s *stats) write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slices.Sort(s.latencies)
+
+	fmt.Fprintf(w, "requests:  %d sent, %d failed, %d dropped in %s (%.1f req/s)\n",
+		s.sent, s.failed, s.dropped, s.elapsed.Round(time.Millisecond), float64(s.sent)/s.elapsed.Seconds())
+	codes := make([]int, 0, len(s.codes))
+	for code := range s.codes {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "status %d: %d\n", code, s.codes[code])
+	}
+	if len(s.latencies) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "latency:   p50 %s  p90 %s  p99 %s  max %s\n",
+		percentile(s.latencies, 50), percentile(s.latencies, 90), percentile(s.latencies, 99), s.latencies[len(s.latencies)-1])
+}
+
+// percentile returns the p-th percentile of sorted using the nearest-rank
+// method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Microsecond)
+}
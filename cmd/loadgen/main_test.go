@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	if got := percentile(sorted, 50); got != 50*time.Millisecond {
+		t.Fatalf("p50 = %s", got)
+	}
+	if got := percentile(sorted, 99); got != 99*time.Millisecond {
+		t.Fatalf("p99 = %s", got)
+	}
+	if got := percentile(sorted[:1], 90); got != time.Millisecond {
+		t.Fatalf("p90 of one sample = %s", got)
+	}
+}
+
+func TestRun_SendsBatches(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Links []string `json:"links"`
+		}
+		if r.URL.Path != "/v1/links" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Links) < 2 || len(req.Links) > 4 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-target", srv.URL, "-rps", "50", "-duration", "200ms", "-batch", "2", "-batch-max", "4", "-key", "secret"}
+	if code := run(context.Background(), args, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code = %d, stderr: %s", code, stderr.String())
+	}
+	if requests.Load() == 0 {
+		t.Fatal("no requests reached the server")
+	}
+	if out := stdout.String(); !strings.Contains(out, "status 200:") || !strings.Contains(out, "0 failed") {
+		t.Fatalf("unexpected summary:\n%s", out)
+	}
+}
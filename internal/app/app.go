@@ -1,25 +1,46 @@
 package app
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
+	"os"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/olgkv/linkchecker/internal/auth"
+	"github.com/olgkv/linkchecker/internal/blob"
 	"github.com/olgkv/linkchecker/internal/config"
+	"github.com/olgkv/linkchecker/internal/domain"
 	"github.com/olgkv/linkchecker/internal/httpapi"
+	"github.com/olgkv/linkchecker/internal/maintenance"
+	"github.com/olgkv/linkchecker/internal/monitor"
+	"github.com/olgkv/linkchecker/internal/notify"
+	pdfgen "github.com/olgkv/linkchecker/internal/pdf"
+	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/internal/queue"
+	"github.com/olgkv/linkchecker/internal/quota"
 	"github.com/olgkv/linkchecker/internal/service"
 	"github.com/olgkv/linkchecker/internal/storage"
+	"github.com/olgkv/linkchecker/pkg/linkchecker"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"golang.org/x/time/rate"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 var httpRequestsTotal = promauto.NewCounterVec(
@@ -27,141 +48,684 @@ var httpRequestsTotal = promauto.NewCounterVec(
 		Name: "webserver_http_requests_total",
 		Help: "Total HTTP requests processed by route",
 	},
-	[]string{"method", "path", "status"},
+	[]string{"method", "route", "status"},
 )
 
-// NewServer wires application dependencies and returns configured HTTP server,
-// service instance, and a stats function for graceful shutdown logging.
-func NewServer(cfg *config.Config) (*http.Server, *service.Service, func() (int, int), error) {
-	repo := storage.NewJSONRepository(cfg.TasksFile)
-	st := storage.NewFileStorage(repo)
-	if err := st.Load(); err != nil {
-		return nil, nil, nil, fmt.Errorf("load storage: %w", err)
+// httpRequestDuration and httpRequestsInFlight are labelled with the route
+// pattern rather than the path so that IDs in paths do not add series.
+var (
+	httpRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "webserver_http_request_duration_seconds",
+			Help:    "HTTP request latency by route and status class",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"method", "route", "class"},
+	)
+	httpRequestsInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "webserver_http_requests_in_flight",
+			Help: "HTTP requests being served by route",
+		},
+		[]string{"route"},
+	)
+)
+
+// metricsService and metricsLimiters are the service and rate limiters
+// whose state the metrics below report; the last ones wired by NewServer win.
+var (
+	metricsService  atomic.Pointer[service.Service]
+	metricsLimiters atomic.Pointer[routeLimiters]
+)
+
+func breakerStats() service.BreakerStats {
+	if svc := metricsService.Load(); svc != nil {
+		return svc.BreakerStats()
 	}
+	return service.BreakerStats{}
+}
 
-	client := newHTTPClient(cfg.HTTPTimeout)
-	svc := service.New(st, client, cfg.MaxWorkers, cfg.HTTPTimeout, cfg.ReportWorkers)
+var (
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "linkchecker_breaker_hosts",
+		Help: "Hosts with failed checks tracked by the circuit breaker",
+	}, func() float64 { return float64(breakerStats().Hosts) })
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "linkchecker_breaker_open_hosts",
+		Help: "Hosts currently skipped by the circuit breaker",
+	}, func() float64 { return float64(breakerStats().Open) })
+	_ = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "linkchecker_breaker_trips_total",
+		Help: "Times a host reached the circuit breaker failure threshold",
+	}, func() float64 { return float64(breakerStats().Trips) })
+)
+
+var (
+	storageOpDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "linkchecker_storage_op_duration_seconds",
+			Help:    "Duration of tasks log operations",
+			Buckets: []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 30},
+		},
+		[]string{"op"},
+	)
+	storageRotations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "linkchecker_storage_rotations_total",
+		Help: "Rotations of the tasks log into compressed segments",
+	})
+	storageDeleted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "linkchecker_storage_cleanup_deleted_total",
+			Help: "Log segments and tasks removed by cleanup",
+		},
+		[]string{"kind"},
+	)
+)
+
+var checkPhaseDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "linkchecker_check_phase_duration_seconds",
+		Help:    "Duration of the DNS, connect, TLS and time to first byte phases of check requests",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	},
+	[]string{"phase"},
+)
+
+// checkMetrics reports the phases of check requests to Prometheus.
+type checkMetrics struct{}
+
+func (checkMetrics) ObservePhase(phase string, d time.Duration) {
+	checkPhaseDuration.WithLabelValues(phase).Observe(d.Seconds())
+}
+
+// storageMetrics reports storage measurements to Prometheus.
+type storageMetrics struct{}
+
+func (storageMetrics) ObserveOp(op string, d time.Duration) {
+	storageOpDuration.WithLabelValues(op).Observe(d.Seconds())
+}
+
+func (storageMetrics) Rotated() { storageRotations.Inc() }
+
+func (storageMetrics) Deleted(kind string, n int) {
+	storageDeleted.WithLabelValues(kind).Add(float64(n))
+}
+
+func rateLimiterStats() limiterStats {
+	if l := metricsLimiters.Load(); l != nil {
+		return l.stats()
+	}
+	return limiterStats{}
+}
+
+var (
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "linkchecker_ratelimit_clients",
+		Help: "Clients tracked by the in-memory rate limiters",
+	}, func() float64 { return float64(rateLimiterStats().clients) })
+	_ = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name:        "linkchecker_ratelimit_evictions_total",
+		Help:        "Clients dropped by the in-memory rate limiters",
+		ConstLabels: prometheus.Labels{"reason": "expired"},
+	}, func() float64 { return float64(rateLimiterStats().expired) })
+	_ = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name:        "linkchecker_ratelimit_evictions_total",
+		Help:        "Clients dropped by the in-memory rate limiters",
+		ConstLabels: prometheus.Labels{"reason": "capacity"},
+	}, func() float64 { return float64(rateLimiterStats().evicted) })
+)
+
+// Servers holds the HTTP servers of the application. Admin is nil unless a
+// separate admin listener is configured via ADMIN_PORT. Reload re-reads the
+// configuration and applies runtime-tunable settings. State returns the
+// diagnostic snapshot of /admin/state. The task log is loaded in the
+// background while the servers already listen; Loaded delivers the outcome.
+// Events is the connection task events are published on, nil unless
+// EVENTS_SUBJECT is set; close it after the service.
+type Servers struct {
+	Public *http.Server
+	Admin  *http.Server
+	Reload func() error
+	State  func() httpapi.StateResponse
+	Loaded <-chan error
+	Events *queue.NATS
+}
+
+// NewServer wires application dependencies and returns configured HTTP servers,
+// service instance, and a stats function for graceful shutdown logging.
+func NewServer(cfg *config.Config, log *slog.Logger) (*Servers, *service.Service, func() (int, int), error) {
+	svc, st, err := newService(cfg, log)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// пока сервер не собран, любая ошибка закрывает сервис и шину событий
+	var events *queue.NATS
+	ready := false
+	defer func() {
+		if ready {
+			return
+		}
+		if events != nil {
+			_ = events.Close()
+		}
+		svc.Close()
+	}()
+	rd := &readiness{storage: st}
+	schedule, err := useMaintenance(svc, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := useReportStore(svc, cfg); err != nil {
+		return nil, nil, nil, err
+	}
+	monitors, err := monitor.Open(cfg.MonitorsFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("load monitors: %w", err)
+	}
+	svc.UseMonitors(monitors)
+	if cfg.ReportCompany != "" || cfg.ReportHeader != "" || cfg.ReportFooter != "" || cfg.ReportAccentColor != "" || cfg.ReportLogo != "" {
+		branding, err := pdfgen.NewBranding(cfg.ReportCompany, cfg.ReportHeader, cfg.ReportFooter, cfg.ReportAccentColor, cfg.ReportLogo)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("report branding: %w", err)
+		}
+		svc.SetReportBranding(branding)
+	}
+	svc.SetReportPasswords(cfg.ReportPDFPassword, cfg.ReportPDFOwnerPassword)
+	svc.SetLocation(cfg.Location())
+	metricsService.Store(svc)
+	if cfg.EventsSubject != "" {
+		if events, err = dialNATS(cfg, log); err != nil {
+			return nil, nil, nil, fmt.Errorf("connect event bus: %w", err)
+		}
+		svc.UseEventPublisher(queue.NewEventPublisher(events, cfg.EventsSubject))
+	}
 	h := httpapi.NewHandler(svc, cfg.MaxLinks)
+	h.UseMaintenance(schedule)
+	h.SetMaxBatchLinks(cfg.MaxBatchLinks)
+	h.SetMaxUploadBytes(cfg.MaxUploadBytes)
+	h.SetLocation(cfg.Location())
+	if cfg.QuotaDaily > 0 || cfg.QuotaMonthly > 0 || cfg.QuotaOverrides != "" {
+		overrides, err := quota.ParseOverrides(cfg.QuotaOverrides)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("parse QUOTA_OVERRIDES: %w", err)
+		}
+		tracker, err := quota.NewTracker(cfg.QuotaFile, quota.Limits{Daily: cfg.QuotaDaily, Monthly: cfg.QuotaMonthly}, overrides)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("load quota usage: %w", err)
+		}
+		h.UseQuota(tracker)
+		svc.UseQuota(tracker)
+	}
+
+	authn, err := newAuthenticator(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	protect := func(policy auth.Policy, fn http.HandlerFunc) http.Handler {
+		return auth.Middleware(authn, auth.Require(policy, fn))
+	}
+	readers := auth.Policy{"*": auth.RoleReader}
+	submitters := auth.Policy{"*": auth.RoleSubmitter}
+	admins := auth.Policy{"*": auth.RoleAdmin}
+	taskPolicy := auth.Policy{http.MethodGet: auth.RoleReader, "*": auth.RoleSubmitter}
 
-	var ipLimiter *ipRateLimiter
-	if cfg.RateLimitRPS > 0 && cfg.RateLimitBurst > 0 {
-		ipLimiter = newIPRateLimiter(rate.Limit(cfg.RateLimitRPS), cfg.RateLimitBurst, 10*time.Minute)
+	limits, err := newRouteLimiters(cfg, authn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	metricsLimiters.Store(limits)
+	if cfg.RateLimitBackend == "" || cfg.RateLimitBackend == "memory" {
+		h.UseRateLimiterStats(func() httpapi.RateLimiterState {
+			st := limits.stats()
+			return httpapi.RateLimiterState{Clients: st.clients, Expired: st.expired, Evicted: st.evicted}
+		})
 	}
+	bounds, err := newRequestLimits(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rl := &reloader{
+		load:    func() (*config.Config, error) { return config.LoadWith(cfg.Overrides) },
+		svc:     svc,
+		handler: h,
+		limits:  limits,
+		log:     log,
+	}
+	h.UseReloader(rl.Reload)
 
 	mux := http.NewServeMux()
-	mux.Handle("/links", rateLimitMiddleware(ipLimiter, loggingMiddleware(http.HandlerFunc(h.Links))))
-	mux.Handle("/report", rateLimitMiddleware(ipLimiter, loggingMiddleware(http.HandlerFunc(h.Report))))
+	// публичные маршруты: лимит запросов, журнал, готовность, лимиты тела и времени, авторизация
+	public := func(route string, policy auth.Policy, fn http.HandlerFunc) {
+		handleAPI(mux, route, limits.wrap(log, route, loggingMiddleware(log, rd.wrap(bounds.wrap(route, protect(policy, fn))))))
+	}
+	public("/links", submitters, h.Links)
+	public("/report", readers, h.Report)
+	public("/report/sla", readers, h.SLAReport)
+	public("/reports/{id}", readers, h.StoredReport)
+	public("/reports/{id}/share", readers, h.ShareReport)
+	// подписанные ссылки открываются без токена, подпись проверяет сам обработчик
+	const download = "/reports/{id}/download"
+	handleAPI(mux, download, limits.wrap(log, download, loggingMiddleware(log, bounds.wrap(download, http.HandlerFunc(h.SharedReport)))))
+	public("/tasks", readers, h.Tasks)
+	public("/batches", readers, h.Batches)
+	public("/tasks/search", readers, h.SearchTasks)
+	public("/tasks/{id}/progress", readers, h.TaskProgress)
+	public("/tasks/{id}", taskPolicy, h.Task)
+	public("/domains/{host}/summary", readers, h.DomainSummary)
+	public("/monitors", readers, h.Monitors)
+	public("/monitors/{id}", readers, h.Monitor)
+	public("/monitors/{id}/{action}", submitters, h.MonitorAction)
+	handleAPI(mux, "/me/usage", loggingMiddleware(log, bounds.wrap("/me/usage", protect(readers, h.Usage))))
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/readyz", rd)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	})
 
-	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: mux,
+	tlsCfg, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	servers := &Servers{
+		Public: &http.Server{
+			Addr:      ":" + cfg.Port,
+			Handler:   mux,
+			TLSConfig: tlsCfg,
+		},
+		Reload: rl.Reload,
+		State:  h.Snapshot,
+		Events: events,
+	}
+
+	if cfg.AdminPort == "" {
+		// без аутентификации на публичном порту админских маршрутов нет
+		if authn == nil {
+			log.Warn("admin routes disabled: set ADMIN_PORT, API_KEYS or OIDC_JWKS_URL to serve them")
+		} else {
+			registerAdminRoutes(mux, log, h, func(fn http.HandlerFunc) http.Handler {
+				return rd.wrap(protect(admins, fn))
+			})
+		}
+	} else {
+		adminTLS, err := newAdminTLSConfig(cfg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		adminMux := http.NewServeMux()
+		registerAdminRoutes(adminMux, log, h, func(fn http.HandlerFunc) http.Handler {
+			return rd.wrap(auth.ClientCertMiddleware(auth.RoleAdmin, fn))
+		})
+		servers.Admin = &http.Server{
+			Addr:      ":" + cfg.AdminPort,
+			Handler:   adminMux,
+			TLSConfig: adminTLS,
+		}
 	}
 
 	statsFn := func() (int, int) {
 		return st.Stats()
 	}
 
-	return srv, svc, statsFn, nil
+	servers.Loaded = rd.load(svc, log)
+	ready = true
+	return servers, svc, statsFn, nil
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+// newService opens the task storage and builds the link-checking service
+// shared by the HTTP server and the queue consumer. The storage is loaded
+// by loadStorage.
+func newService(cfg *config.Config, log *slog.Logger) (*service.Service, *storage.FileStorage, error) {
+	syncPolicy, err := storage.ParseSyncPolicy(cfg.FsyncPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse FSYNC_POLICY: %w", err)
+	}
+	repo := storage.NewJSONRepository(cfg.TasksFile, storage.WithSyncPolicy(syncPolicy), storage.WithLogger(log),
+		storage.WithMetrics(storageMetrics{}), storage.WithSlowAppend(cfg.StorageSlowAppend))
+	if err := repo.Lock(); err != nil {
+		return nil, nil, fmt.Errorf("lock storage: %w", err)
+	}
+	st := storage.NewFileStorage(repo)
+	st.UseMetrics(storageMetrics{})
 
-		lw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-		next.ServeHTTP(lw, r)
-		if v := r.Context().Value(httpapi.LinksNumContextKey); v != nil {
-			if id, ok := v.(int); ok {
-				lw.linksNum = id
+	client := newHTTPClient(newHTTPTransport(cfg))
+	svc := service.New(st, client, cfg.MaxWorkers, cfg.HTTPTimeout, cfg.ReportWorkers)
+	svc.UseLogger(log)
+	// Close сервиса закрывает и хранилище, снимая блокировку файла задач
+	ready := false
+	defer func() {
+		if !ready {
+			svc.Close()
+		}
+	}()
+	svc.SetRetryPolicy(retryPolicy(cfg))
+	svc.SetReportPool(cfg.ReportWorkersMin, cfg.ReportWorkers, cfg.ReportQueue)
+	if cfg.GlobalWorkers > 0 {
+		weights, err := service.ParseTenantWeights(cfg.TenantWeights)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse TENANT_WEIGHTS: %w", err)
+		}
+		svc.EnableWorkerPool(cfg.GlobalWorkers)
+		svc.SetTenantWeights(weights)
+	}
+	if cfg.HTTP3Probe {
+		client3, err := newHTTP3Client(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		svc.EnableHTTP3Probe(client3)
+	}
+	if cfg.RobotsTxt {
+		svc.EnableRobots(cfg.RobotsUserAgent, cfg.RobotsCacheTTL)
+	}
+	if cfg.ConditionalChecks {
+		svc.EnableConditionalChecks(cfg.ConditionalCacheSize)
+	}
+	if cfg.SMTPProbe {
+		svc.EnableSMTPProbe(cfg.SMTPProbeHelo, cfg.SMTPProbeFrom)
+	}
+	if cfg.SFTPKnownHosts != "" || cfg.SFTPPrivateKey != "" {
+		hostKey, signers, err := sftpKeys(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		svc.UseSFTPKeys(hostKey, signers...)
+	}
+	if cfg.StatusRules != "" {
+		rules, err := linkchecker.ParseStatusRules(cfg.StatusRules)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse STATUS_RULES: %w", err)
+		}
+		svc.SetStatusRules(rules)
+	}
+	httpsPolicy, err := linkchecker.ParseHTTPSPolicy(cfg.HTTPSPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse HTTPS_POLICY: %w", err)
+	}
+	if cfg.AllowedSchemes != "" || httpsPolicy != linkchecker.HTTPSAsWritten {
+		var schemes []string
+		for _, s := range strings.Split(cfg.AllowedSchemes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				schemes = append(schemes, s)
 			}
 		}
+		svc.RestrictSchemes(schemes, httpsPolicy)
+	}
+	if ip := net.ParseIP(cfg.OutboundSourceIP); ip != nil {
+		svc.UseSourceIP(ip)
+	}
+	if cfg.OutboundBandwidth > 0 {
+		svc.SetBandwidth(cfg.OutboundBandwidth)
+	}
+	svc.UseCheckMetrics(checkMetrics{})
+	svc.EnableOutboundLog(cfg.OutboundLogSampleRate)
+	if err := useBlocklist(svc, cfg, log); err != nil {
+		return nil, nil, err
+	}
+	if err := useNotifiers(svc, cfg); err != nil {
+		return nil, nil, err
+	}
+	svc.EnableRetention(cfg.TaskRetention)
+	svc.EnableBreakerJanitor(cfg.BreakerMaxHosts)
+	svc.EnableDeduplication(cfg.DedupWindow)
+	spool, err := storage.OpenFileSpool(cfg.TasksFile + ".spool")
+	if err != nil {
+		return nil, nil, err
+	}
+	svc.UseSpool(spool)
+	ready = true
+	return svc, st, nil
+}
 
-		latency := time.Since(start)
-		slog.Info("request completed",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"links_num", lw.linksNum,
-			"latency_ms", latency.Milliseconds(),
-			"status", lw.statusCode,
-		)
-		httpRequestsTotal.WithLabelValues(r.Method, r.URL.Path, strconv.Itoa(lw.statusCode)).Inc()
-	})
+// loadStorage replays the task log of st and retries persisting the results
+// deferred before a restart.
+func loadStorage(svc *service.Service, st *storage.FileStorage, log *slog.Logger) error {
+	if err := st.Load(context.Background()); err != nil {
+		return fmt.Errorf("load storage: %w", err)
+	}
+	if n, err := svc.DrainSpool(); err != nil {
+		return fmt.Errorf("drain result spool: %w", err)
+	} else if n > 0 {
+		log.Info("persisting results deferred before restart", "tasks", n)
+	}
+	return nil
 }
 
-type ipRateLimiter struct {
-	mu      sync.Mutex
-	limit   rate.Limit
-	burst   int
-	ttl     time.Duration
-	clients map[string]*ipLimiterEntry
+// useBlocklist makes checks refuse the links listed in BLOCKLIST_FILE or
+// flagged by Google Safe Browsing when SAFE_BROWSING_API_KEY is set.
+func useBlocklist(svc *service.Service, cfg *config.Config, log *slog.Logger) error {
+	var lists linkchecker.Blocklists
+	if cfg.BlocklistFile != "" {
+		bl, err := linkchecker.LoadHostBlocklist(cfg.BlocklistFile)
+		if err != nil {
+			return fmt.Errorf("load BLOCKLIST_FILE: %w", err)
+		}
+		log.Info("blocklist loaded", "file", cfg.BlocklistFile, "entries", bl.Len())
+		lists = append(lists, bl)
+	}
+	if cfg.SafeBrowsingKey != "" {
+		lists = append(lists, linkchecker.NewSafeBrowsing(cfg.SafeBrowsingKey, &http.Client{Timeout: cfg.HTTPTimeout}))
+	}
+	if len(lists) > 0 {
+		svc.UseBlocklist(lists)
+	}
+	return nil
 }
 
-type ipLimiterEntry struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
+// useMaintenance loads the maintenance windows from MAINTENANCE_FILE and
+// makes checks skip the hosts they cover.
+func useMaintenance(svc *service.Service, cfg *config.Config) (*maintenance.Schedule, error) {
+	schedule, err := maintenance.Open(cfg.MaintenanceFile)
+	if err != nil {
+		return nil, fmt.Errorf("load maintenance windows: %w", err)
+	}
+	svc.UseMaintenance(schedule)
+	return schedule, nil
 }
 
-func newIPRateLimiter(limit rate.Limit, burst int, ttl time.Duration) *ipRateLimiter {
-	if ttl <= 0 {
-		ttl = 10 * time.Minute
+// useReportStore keeps generated reports in the store selected by
+// REPORT_STORE, if any.
+func useReportStore(svc *service.Service, cfg *config.Config) error {
+	var store ports.BlobStore
+	switch cfg.ReportStore {
+	case "":
+		return nil
+	case "disk":
+		disk, err := blob.NewDiskStore(cfg.ReportDir)
+		if err != nil {
+			return fmt.Errorf("open report store: %w", err)
+		}
+		store = disk
+	case "s3":
+		s3, err := blob.NewS3Store(blob.S3Config{
+			Endpoint:  cfg.ReportS3Endpoint,
+			Bucket:    cfg.ReportS3Bucket,
+			Region:    cfg.ReportS3Region,
+			AccessKey: cfg.ReportS3AccessKey,
+			SecretKey: cfg.ReportS3SecretKey,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("open report store: %w", err)
+		}
+		store = s3
 	}
-	return &ipRateLimiter{
-		limit:   limit,
-		burst:   burst,
-		ttl:     ttl,
-		clients: make(map[string]*ipLimiterEntry),
+	svc.UseReportStore(store, cfg.ReportRetention)
+	if cfg.ReportLinkSecret != "" {
+		svc.EnableReportLinks([]byte(cfg.ReportLinkSecret), cfg.ReportLinkTTL)
 	}
+	return nil
 }
 
-func (l *ipRateLimiter) allow(ip string) bool {
-	if ip == "" {
-		ip = "unknown"
+// useNotifiers enables the notification channels with webhooks configured.
+func useNotifiers(svc *service.Service, cfg *config.Config) error {
+	svc.SetPublicURL(cfg.PublicURL)
+	if cfg.SlackWebhooks != "" {
+		hooks, err := notify.ParseWebhooks(cfg.SlackWebhooks)
+		if err != nil {
+			return fmt.Errorf("parse SLACK_WEBHOOKS: %w", err)
+		}
+		svc.UseNotifier("slack", notify.NewSlack(hooks, nil))
 	}
-	now := time.Now()
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if entry, ok := l.clients[ip]; ok {
-		if now.Sub(entry.lastSeen) > l.ttl {
-			delete(l.clients, ip)
-		} else {
-			entry.lastSeen = now
-			return entry.limiter.Allow()
+	if cfg.TeamsWebhooks != "" {
+		hooks, err := notify.ParseWebhooks(cfg.TeamsWebhooks)
+		if err != nil {
+			return fmt.Errorf("parse TEAMS_WEBHOOKS: %w", err)
 		}
+		svc.UseNotifier("teams", notify.NewTeams(hooks, nil))
 	}
+	return nil
+}
 
-	limiter := rate.NewLimiter(l.limit, l.burst)
-	l.clients[ip] = &ipLimiterEntry{limiter: limiter, lastSeen: now}
+// apiVersionPrefix is the prefix of the current API version.
+const apiVersionPrefix = "/v1"
 
-	for key, entry := range l.clients {
-		if now.Sub(entry.lastSeen) > l.ttl {
-			delete(l.clients, key)
-		}
+// handleAPI registers h under the versioned path and keeps the unversioned
+// path as a deprecated alias.
+func handleAPI(mux *http.ServeMux, pattern string, h http.Handler) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	} else {
+		method += " "
 	}
+	mux.Handle(method+apiVersionPrefix+path, h)
+	mux.Handle(method+path, deprecatedAlias(apiVersionPrefix+path, h))
+}
+
+// deprecatedAlias marks responses of an unversioned route as deprecated and
+// points clients to its successor.
+func deprecatedAlias(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
 
-	return limiter.Allow()
+// registerAdminRoutes mounts maintenance endpoints, bulk deletion with
+// DELETE /tasks included; guard authenticates them either with tokens on
+// the public listener or client certificates on the admin one. The public
+// listener serves them only when an authenticator is configured.
+func registerAdminRoutes(mux *http.ServeMux, log *slog.Logger, h *httpapi.Handler, guard func(http.HandlerFunc) http.Handler) {
+	handleAPI(mux, "DELETE /tasks", loggingMiddleware(log, guard(h.Cleanup)))
+	handleAPI(mux, "/admin/export", loggingMiddleware(log, guard(h.Export)))
+	handleAPI(mux, "/admin/import", loggingMiddleware(log, guard(h.Import)))
+	handleAPI(mux, "/admin/breaker", loggingMiddleware(log, guard(h.Breaker)))
+	handleAPI(mux, "/admin/maintenance", loggingMiddleware(log, guard(h.Maintenance)))
+	handleAPI(mux, "/admin/cleanup", loggingMiddleware(log, guard(h.Cleanup)))
+	handleAPI(mux, "/admin/reload", loggingMiddleware(log, guard(h.Reload)))
+	handleAPI(mux, "/admin/state", loggingMiddleware(log, guard(h.State)))
 }
 
-func rateLimitMiddleware(limiter *ipRateLimiter, next http.Handler) http.Handler {
-	if limiter == nil {
-		return next
+// newAuthenticator combines static API keys and OIDC token validation. It
+// returns nil when neither is configured, which leaves the API open.
+func newAuthenticator(cfg *config.Config) (auth.Authenticator, error) {
+	var chain auth.Chain
+	keys, err := auth.ParseKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("parse API_KEYS: %w", err)
+	}
+	if keys != nil {
+		chain = append(chain, keys)
+	}
+	if cfg.OIDCJWKSURL != "" {
+		if cfg.OIDCIssuer == "" || cfg.OIDCAudience == "" {
+			return nil, errors.New("OIDC_ISSUER and OIDC_AUDIENCE are required with OIDC_JWKS_URL")
+		}
+		chain = append(chain, auth.NewJWTVerifier(auth.JWTConfig{
+			Issuer:    cfg.OIDCIssuer,
+			Audience:  cfg.OIDCAudience,
+			JWKSURL:   cfg.OIDCJWKSURL,
+			RoleClaim: cfg.OIDCRoleClaim,
+		}, nil))
 	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+func loggingMiddleware(log *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := clientIP(r)
-		if !limiter.allow(ip) {
-			http.Error(w, "too many requests", http.StatusTooManyRequests)
-			return
+		start := time.Now()
+		route := routeLabel(r)
+		inFlight := httpRequestsInFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		requestID := requestID(r)
+		w.Header().Set("X-Request-ID", requestID)
+		r = r.WithContext(context.WithValue(r.Context(), httpapi.RequestIDContextKey, requestID))
+
+		lw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(lw, r)
+		if v := r.Context().Value(httpapi.TaskIDContextKey); v != nil {
+			if id, ok := v.(int); ok {
+				lw.taskID = id
+			}
 		}
-		next.ServeHTTP(w, r)
+
+		latency := time.Since(start)
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"request_id", requestID,
+			"principal", lw.principal,
+			"task_id", lw.taskID,
+			"latency_ms", latency.Milliseconds(),
+			"status", lw.statusCode,
+		}
+		if s := lw.links; s != nil {
+			attrs = append(attrs, slog.Group("links",
+				"total", s.Links,
+				"available", s.Available,
+				"not_available", s.Broken,
+				"by_error_kind", s.ByErrorKind,
+			))
+		}
+		log.Info("request completed", attrs...)
+		httpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(lw.statusCode)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route, strconv.Itoa(lw.statusCode/100)+"xx").Observe(latency.Seconds())
 	})
 }
 
+// maxRequestIDLen bounds the X-Request-ID accepted from clients.
+const maxRequestIDLen = 64
+
+// requestID returns the X-Request-ID sent with r if it is short and plain
+// enough to log, or a new random one.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get("X-Request-ID"); id != "" && len(id) <= maxRequestIDLen && plainID(id) {
+		return id
+	}
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func plainID(id string) bool {
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// routeLabel returns the pattern r was routed by, with /v1 and its
+// deprecated alias reported as one route.
+func routeLabel(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	if method, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return method + " " + strings.TrimPrefix(path, apiVersionPrefix)
+	}
+	return strings.TrimPrefix(r.Pattern, apiVersionPrefix)
+}
+
 func clientIP(r *http.Request) string {
 	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
 		parts := strings.Split(fwd, ",")
@@ -179,14 +743,148 @@ func clientIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
-func newHTTPClient(timeout time.Duration) *http.Client {
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
+// newHTTPTransport builds the transport shared by all link checks so that
+// connections to the same hosts are reused across requests and tasks.
+func newHTTPTransport(cfg *config.Config) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.HTTPDialTimeout,
+		KeepAlive: 30 * time.Second,
+		// хосты с IPv6 и IPv4 — happy eyeballs, IPv4 стартует с задержкой
+		FallbackDelay: cfg.HTTPFallbackDelay,
+	}
+	if ip := net.ParseIP(cfg.OutboundSourceIP); ip != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	if !usesProxy() {
+		// адрес проверяем при соединении, а не только при разборе ссылки
+		dialer.ControlContext = linkchecker.RefusePrivate
+	}
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        cfg.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.HTTPIdleConnTimeout,
+		TLSHandshakeTimeout: cfg.HTTPTLSHandshakeTimeout,
+	}
+}
+
+// usesProxy reports whether the environment sets a proxy for link checks.
+// Connections then go to the proxy, which resolves the checked hosts itself.
+func usesProxy() bool {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// newHTTP3Client returns the client for HTTP/3 probes. QUIC runs over UDP, so
+// it shares no connections with the TCP transport.
+func newHTTP3Client(cfg *config.Config) (*http.Client, error) {
+	udp, err := listenQUIC(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("open HTTP/3 socket: %w", err)
+	}
+	network := "ip"
+	if ip := udp.LocalAddr().(*net.UDPAddr).IP; !ip.IsUnspecified() {
+		// сокет привязан к адресу одного семейства — резолвим только его
+		network = "ip6"
+		if ip.To4() != nil {
+			network = "ip4"
+		}
 	}
 	return &http.Client{
-		Timeout:   timeout,
+		Transport: &http3.Transport{
+			QUICConfig: &quic.Config{
+				HandshakeIdleTimeout: cfg.HTTPTLSHandshakeTimeout,
+				MaxIdleTimeout:       cfg.HTTPIdleConnTimeout,
+			},
+			Dial: dialQUIC(&quic.Transport{Conn: udp}, network),
+		},
+	}, nil
+}
+
+// listenQUIC opens the UDP socket HTTP/3 probes are sent from, bound to
+// OUTBOUND_SOURCE_IP when that is set.
+func listenQUIC(cfg *config.Config) (*net.UDPConn, error) {
+	var local *net.UDPAddr
+	if ip := net.ParseIP(cfg.OutboundSourceIP); ip != nil {
+		local = &net.UDPAddr{IP: ip}
+	}
+	return net.ListenUDP("udp", local)
+}
+
+// dialQUIC returns an http3.Transport Dial function connecting through tr
+// to the first address of the host in network ("ip", "ip4" or "ip6"). It
+// resolves the host itself and passes the address to
+// linkchecker.RefusePrivate before dialing, as the TCP dialer does, since
+// HTTP/3 never goes through a proxy.
+func dialQUIC(tr *quic.Transport, network string) func(context.Context, string, *tls.Config, *quic.Config) (*quic.Conn, error) {
+	return func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		portNum, err := net.DefaultResolver.LookupPort(ctx, "udp", port)
+		if err != nil {
+			return nil, err
+		}
+		remote := netip.AddrPortFrom(ips[0].Unmap(), uint16(portNum))
+		if err := linkchecker.RefusePrivate(ctx, "udp", remote.String(), nil); err != nil {
+			return nil, err
+		}
+		return tr.DialEarly(ctx, net.UDPAddrFromAddrPort(remote), tlsCfg, cfg)
+	}
+}
+
+// sftpKeys loads the known_hosts file and the unencrypted private key used
+// by sftp:// checks; either may be unset.
+func sftpKeys(cfg *config.Config) (ssh.HostKeyCallback, []ssh.Signer, error) {
+	var hostKey ssh.HostKeyCallback
+	if cfg.SFTPKnownHosts != "" {
+		cb, err := knownhosts.New(cfg.SFTPKnownHosts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load SFTP_KNOWN_HOSTS: %w", err)
+		}
+		hostKey = cb
+	}
+	var signers []ssh.Signer
+	if cfg.SFTPPrivateKey != "" {
+		pem, err := os.ReadFile(cfg.SFTPPrivateKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read SFTP_PRIVATE_KEY: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse SFTP_PRIVATE_KEY: %w", err)
+		}
+		signers = append(signers, signer)
+	}
+	return hostKey, signers, nil
+}
+
+// retryPolicy returns the retry settings of link checks.
+func retryPolicy(cfg *config.Config) service.RetryPolicy {
+	return service.RetryPolicy{
+		Retries:     cfg.CheckRetries,
+		BackoffBase: cfg.CheckBackoffBase,
+		BackoffMax:  cfg.CheckBackoffMax,
+		Jitter:      cfg.CheckBackoffJitter,
+	}
+}
+
+// newHTTPClient returns the client for link checks. It has no timeout of its
+// own: the service bounds each check with HTTP_TIMEOUT through the request
+// context, which keeps the timeout reloadable.
+func newHTTPClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
 		Transport: transport,
 	}
 }
@@ -194,7 +892,17 @@ func newHTTPClient(timeout time.Duration) *http.Client {
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
-	linksNum   int
+	taskID     int
+	links      *domain.ResultCounts
+	principal  string
+}
+
+func (lw *loggingResponseWriter) RecordPrincipal(p auth.Principal) {
+	lw.principal = p.Name
+}
+
+func (lw *loggingResponseWriter) RecordLinks(taskID int, summary domain.ResultCounts) {
+	lw.taskID, lw.links = taskID, &summary
 }
 
 func (lw *loggingResponseWriter) WriteHeader(code int) {
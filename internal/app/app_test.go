@@ -1,20 +1,40 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/httpapi"
+	"github.com/olgkv/linkchecker/pkg/linkchecker"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quic-go/quic-go"
 )
 
 func TestRateLimitMiddleware_PerIP(t *testing.T) {
-	limiter := newIPRateLimiter(1, 1, time.Minute)
+	limiter := newIPRateLimiter(1, 1, time.Minute, 0)
 	var hits int
 	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		hits++
 		w.WriteHeader(http.StatusOK)
 	})
-	h := rateLimitMiddleware(limiter, inner)
+	h := rateLimitMiddleware(slog.Default(), limiter, clientIP, inner)
 
 	req := httptest.NewRequest(http.MethodGet, "/links", nil)
 	req.RemoteAddr = "1.1.1.1:1234"
@@ -36,11 +56,11 @@ func TestRateLimitMiddleware_PerIP(t *testing.T) {
 }
 
 func TestRateLimitMiddleware_DifferentIPs(t *testing.T) {
-	limiter := newIPRateLimiter(1, 1, time.Minute)
+	limiter := newIPRateLimiter(1, 1, time.Minute, 0)
 	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
-	h := rateLimitMiddleware(limiter, inner)
+	h := rateLimitMiddleware(slog.Default(), limiter, clientIP, inner)
 
 	req1 := httptest.NewRequest(http.MethodGet, "/links", nil)
 	req1.RemoteAddr = "2.2.2.2:1000"
@@ -78,3 +98,294 @@ func TestClientIPExtraction(t *testing.T) {
 		t.Fatalf("expected RemoteAddr host, got %s", ip)
 	}
 }
+
+func TestHandleAPI_VersionedAndDeprecatedAlias(t *testing.T) {
+	mux := http.NewServeMux()
+	handleAPI(mux, "/links", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/links", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "" {
+		t.Fatalf("/v1/links: status %d, Deprecation %q", rec.Code, rec.Header().Get("Deprecation"))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/links", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/links: status %d", rec.Code)
+	}
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Fatalf("expected Deprecation header on unversioned alias")
+	}
+	if link := rec.Header().Get("Link"); link != `</v1/links>; rel="successor-version"` {
+		t.Fatalf("unexpected Link header %q", link)
+	}
+}
+
+func TestHandleAPI_MethodPattern(t *testing.T) {
+	mux := http.NewServeMux()
+	// DELETE /tasks админский, GET /tasks публичный — маршруты не конфликтуют
+	handleAPI(mux, "/tasks", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handleAPI(mux, "DELETE /tasks", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if routeLabel(r) != "DELETE /tasks" {
+			t.Errorf("route label %q", routeLabel(r))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for path, method := range map[string]string{"/v1/tasks": http.MethodDelete, "/tasks": http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("%s %s: status %d", method, path, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/tasks: status %d", rec.Code)
+	}
+}
+
+func TestNewHTTPTransport_ReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	cfg := &config.Config{
+		HTTPMaxIdleConns:        4,
+		HTTPMaxIdleConnsPerHost: 2,
+		HTTPIdleConnTimeout:     time.Minute,
+		HTTPTLSHandshakeTimeout: time.Second,
+		HTTPDialTimeout:         time.Second,
+	}
+	transport := newHTTPTransport(cfg)
+	defer transport.CloseIdleConnections()
+	if transport.MaxIdleConnsPerHost != 2 || transport.TLSHandshakeTimeout != time.Second {
+		t.Fatalf("config not applied: %+v", transport)
+	}
+
+	client := newHTTPClient(transport)
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("expected one reused connection, got %d", n)
+	}
+}
+
+func TestLoggingMiddleware_RouteMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	handleAPI(mux, "/test/{id}", loggingMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(scrapeMetrics(t), `webserver_http_requests_in_flight{route="/test/{id}"} 1`) {
+			t.Error("request not counted as in flight")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})))
+
+	for _, path := range []string{"/v1/test/a", "/test/b"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	got := scrapeMetrics(t)
+	for _, want := range []string{
+		`webserver_http_request_duration_seconds_count{class="4xx",method="GET",route="/test/{id}"} 2`,
+		`webserver_http_requests_total{method="GET",route="/test/{id}",status="404"} 2`,
+		`webserver_http_requests_in_flight{route="/test/{id}"} 0`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("metrics lack %s", want)
+		}
+	}
+}
+
+func TestLoggingMiddleware_RequestID(t *testing.T) {
+	var seen string
+	h := loggingMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = r.Context().Value(httpapi.RequestIDContextKey).(string)
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/links", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	h.ServeHTTP(rec, req)
+	if seen != "abc-123" || rec.Header().Get("X-Request-ID") != "abc-123" {
+		t.Fatalf("request ID %q, header %q; want the client's", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/links", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	h.ServeHTTP(rec, req)
+	if len(seen) != 16 || seen == "bad id\n" || rec.Header().Get("X-Request-ID") != seen {
+		t.Fatalf("expected a generated request ID, got %q", seen)
+	}
+}
+
+func TestLoggingMiddleware_LinksSummary(t *testing.T) {
+	var buf bytes.Buffer
+	h := loggingMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(httpapi.LinksRecorder).RecordLinks(7, domain.ResultCounts{Links: 3, Checked: 3, Available: 1, Broken: 2, ByErrorKind: map[string]int{"dns": 2}})
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/links", nil))
+
+	var entry struct {
+		TaskID int `json:"task_id"`
+		Links  struct {
+			Total        int            `json:"total"`
+			Available    int            `json:"available"`
+			NotAvailable int            `json:"not_available"`
+			ByErrorKind  map[string]int `json:"by_error_kind"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode %s: %v", buf.String(), err)
+	}
+	if entry.TaskID != 7 || entry.Links.Total != 3 || entry.Links.Available != 1 || entry.Links.NotAvailable != 2 || entry.Links.ByErrorKind["dns"] != 2 {
+		t.Fatalf("unexpected log entry %s", buf.String())
+	}
+}
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+// testConfig is the default configuration with every file in a temporary
+// directory.
+func testConfig(t *testing.T, overrides map[string]string) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	values := map[string]string{
+		"TASKS_FILE":       filepath.Join(dir, "tasks.json"),
+		"REPORT_DIR":       filepath.Join(dir, "reports"),
+		"QUOTA_FILE":       filepath.Join(dir, "usage.json"),
+		"MAINTENANCE_FILE": filepath.Join(dir, "maintenance.json"),
+		"MONITORS_FILE":    filepath.Join(dir, "monitors.json"),
+	}
+	maps.Copy(values, overrides)
+	cfg, err := config.LoadWith(values)
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	return cfg
+}
+
+func TestNewServer_AdminRoutesNeedAuth(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tc := range []struct {
+		name      string
+		overrides map[string]string
+		want      int
+	}{
+		{"open API", nil, http.StatusNotFound},
+		{"API keys", map[string]string{"API_KEYS": "ops:secret:admin"}, http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			servers, svc, _, err := NewServer(testConfig(t, tc.overrides), log)
+			if err != nil {
+				t.Fatalf("NewServer: %v", err)
+			}
+			defer svc.Close()
+			<-servers.Loaded
+
+			for _, path := range []string{"/v1/admin/export", "/v1/admin/state", "/v1/tasks?before=2030-01-01T00:00:00Z"} {
+				method := http.MethodGet
+				if strings.HasPrefix(path, "/v1/tasks") {
+					method = http.MethodDelete
+				}
+				rec := httptest.NewRecorder()
+				servers.Public.Handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+				want := tc.want
+				if want == http.StatusNotFound && method == http.MethodDelete {
+					// GET /tasks остается, DELETE для него не разрешен
+					want = http.StatusMethodNotAllowed
+				}
+				if rec.Code != want {
+					t.Fatalf("%s %s: status %d, want %d", method, path, rec.Code, want)
+				}
+			}
+		})
+	}
+}
+
+type clientFunc func(*http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestDialQUIC_RefusesPrivate(t *testing.T) {
+	// контекст проверки ссылки берём у HTTP/3-клиента, которому его передает Checker
+	var checkCtx context.Context
+	c := linkchecker.New(linkchecker.Options{
+		Client: clientFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/2.0", Body: http.NoBody}, nil
+		}),
+		HTTP3Client: clientFunc(func(r *http.Request) (*http.Response, error) {
+			checkCtx = r.Context()
+			return nil, errors.New("no HTTP/3")
+		}),
+		// при проверке ссылки хост публичный, при соединении — уже нет
+		Resolver: func(string) ([]net.IP, error) { return []net.IP{net.ParseIP("93.184.216.34")}, nil },
+	})
+	if r := c.CheckLink(context.Background(), "https://localhost/"); r.HTTP3 == nil || checkCtx == nil {
+		t.Fatalf("HTTP/3 probe not made: %+v", r)
+	}
+
+	udp, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	tr := &quic.Transport{Conn: udp}
+	defer tr.Close()
+	dial := dialQUIC(tr, "ip")
+	_, err = dial(checkCtx, "localhost:443", &tls.Config{}, &quic.Config{})
+	if err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Fatalf("dial = %v, want the private address refused", err)
+	}
+}
+
+func TestListenQUIC_SourceIP(t *testing.T) {
+	udp, err := listenQUIC(&config.Config{OutboundSourceIP: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("listenQUIC: %v", err)
+	}
+	defer udp.Close()
+	if ip := udp.LocalAddr().(*net.UDPAddr).IP; !ip.Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("HTTP/3 socket bound to %s, want 127.0.0.1", ip)
+	}
+}
+
+func TestNewServer_ClosesServiceOnError(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := testConfig(t, map[string]string{"QUOTA_DAILY": "10", "QUOTA_OVERRIDES": "broken"})
+	if _, _, _, err := NewServer(cfg, log); err == nil {
+		t.Fatal("expected QUOTA_OVERRIDES to be rejected")
+	}
+
+	// блокировка файла задач снята, сервер с исправленной настройкой стартует
+	cfg.QuotaOverrides = ""
+	servers, svc, _, err := NewServer(cfg, log)
+	if err != nil {
+		t.Fatalf("NewServer after a failed start: %v", err)
+	}
+	defer svc.Close()
+	<-servers.Loaded
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+	"github.com/olgkv/linkchecker/internal/consumer"
+	"github.com/olgkv/linkchecker/internal/queue"
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+// natsDialTimeout bounds connecting to the queue on startup.
+const natsDialTimeout = 10 * time.Second
+
+// Worker holds the queue consumer run with MODE=consumer and its queue
+// connection, to be closed once the consumer has stopped.
+type Worker struct {
+	Consumer *consumer.Consumer
+	Queue    *queue.NATS
+}
+
+// NewWorker wires the same service and storage as NewServer to a NATS
+// subscription instead of HTTP handlers.
+func NewWorker(cfg *config.Config, log *slog.Logger) (*Worker, *service.Service, func() (int, int), error) {
+	svc, st, err := newService(cfg, log)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := loadStorage(svc, st, log); err != nil {
+		svc.Close()
+		return nil, nil, nil, err
+	}
+	if _, err := useMaintenance(svc, cfg); err != nil {
+		svc.Close()
+		return nil, nil, nil, err
+	}
+	nc, err := dialNATS(cfg, log)
+	if err != nil {
+		svc.Close()
+		return nil, nil, nil, err
+	}
+	if err := nc.QueueSubscribe(cfg.NATSSubject, cfg.NATSQueueGroup); err != nil {
+		_ = nc.Close()
+		svc.Close()
+		return nil, nil, nil, fmt.Errorf("subscribe to %s: %w", cfg.NATSSubject, err)
+	}
+	if cfg.EventsSubject != "" {
+		svc.UseEventPublisher(queue.NewEventPublisher(nc, cfg.EventsSubject))
+	}
+	c := consumer.New(svc, nc, cfg.NATSResultsSubject, cfg.MaxLinks, cfg.ConsumerConcurrency)
+	c.UseLogger(log)
+	return &Worker{Consumer: c, Queue: nc}, svc, st.Stats, nil
+}
+
+// dialNATS connects to NATS_URL.
+func dialNATS(cfg *config.Config, log *slog.Logger) (*queue.NATS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsDialTimeout)
+	defer cancel()
+	nc, err := queue.Dial(ctx, cfg.NATSURL)
+	if err != nil {
+		return nil, err
+	}
+	nc.UseLogger(log)
+	return nc, nil
+}
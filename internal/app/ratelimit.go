@@ -0,0 +1,434 @@
+package app
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/auth"
+	"github.com/olgkv/linkchecker/internal/config"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter decides whether a request identified by key may proceed.
+// SetLimit changes the rate on the fly; rps <= 0 lets every request through.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+	SetLimit(rps float64, burst int)
+}
+
+// newRateLimiter builds the limiter selected by RATE_LIMIT_BACKEND. It returns
+// nil when rate limiting is disabled.
+func newRateLimiter(cfg *config.Config) (RateLimiter, error) {
+	return newBackendLimiter(cfg, cfg.RateLimitRPS, cfg.RateLimitBurst, "")
+}
+
+// newBackendLimiter builds a limiter of RATE_LIMIT_BACKEND allowing rps with
+// burst; scope separates its Redis buckets from those of other limiters.
+func newBackendLimiter(cfg *config.Config, rps float64, burst int, scope string) (RateLimiter, error) {
+	if rps <= 0 || burst <= 0 {
+		return nil, nil
+	}
+	switch cfg.RateLimitBackend {
+	case "", "memory":
+		l := newIPRateLimiter(rate.Limit(rps), burst, 10*time.Minute, cfg.RateLimitMaxClients)
+		l.startJanitor(time.Minute)
+		return l, nil
+	case "redis":
+		if cfg.RedisURL == "" {
+			return nil, fmt.Errorf("REDIS_URL is required with RATE_LIMIT_BACKEND=redis")
+		}
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		l := newRedisRateLimiter(redis.NewClient(opts), rps, burst)
+		if scope != "" {
+			l.prefix += scope + ":"
+		}
+		return l, nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q", cfg.RateLimitBackend)
+	}
+}
+
+// ipRateLimiter keeps a token bucket per key in memory. Buckets idle for ttl
+// are dropped by the janitor; above maxEntries the least recently used ones
+// are dropped right away, so spoofed addresses cannot exhaust memory.
+type ipRateLimiter struct {
+	mu         sync.Mutex
+	limit      rate.Limit
+	burst      int
+	ttl        time.Duration
+	maxEntries int
+	clients    map[string]*list.Element
+	lru        *list.List // *ipLimiterEntry, most recently seen first
+	expired    uint64
+	evicted    uint64
+}
+
+type ipLimiterEntry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newIPRateLimiter(limit rate.Limit, burst int, ttl time.Duration, maxEntries int) *ipRateLimiter {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	if maxEntries <= 0 {
+		maxEntries = 100000
+	}
+	return &ipRateLimiter{
+		limit:      limit,
+		burst:      burst,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		clients:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (l *ipRateLimiter) Allow(_ context.Context, ip string) (bool, error) {
+	return l.allow(ip), nil
+}
+
+func (l *ipRateLimiter) SetLimit(rps float64, burst int) {
+	limit := rate.Limit(rps)
+	if rps <= 0 {
+		limit = rate.Inf
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.burst = burst
+	for el := l.lru.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*ipLimiterEntry)
+		entry.limiter.SetLimit(limit)
+		entry.limiter.SetBurst(burst)
+	}
+}
+
+func (l *ipRateLimiter) allow(ip string) bool {
+	if ip == "" {
+		ip = "unknown"
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.clients[ip]; ok {
+		entry := el.Value.(*ipLimiterEntry)
+		if now.Sub(entry.lastSeen) <= l.ttl {
+			entry.lastSeen = now
+			l.lru.MoveToFront(el)
+			return entry.limiter.Allow()
+		}
+		// janitor ещё не дошёл: бакет устарел, начинаем заново
+		l.remove(el)
+		l.expired++
+	}
+
+	limiter := rate.NewLimiter(l.limit, l.burst)
+	l.clients[ip] = l.lru.PushFront(&ipLimiterEntry{key: ip, limiter: limiter, lastSeen: now})
+	for l.lru.Len() > l.maxEntries {
+		l.remove(l.lru.Back())
+		l.evicted++
+	}
+	return limiter.Allow()
+}
+
+// sweep drops the buckets idle for longer than ttl.
+func (l *ipRateLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for el := l.lru.Back(); el != nil; el = l.lru.Back() {
+		if now.Sub(el.Value.(*ipLimiterEntry).lastSeen) <= l.ttl {
+			return
+		}
+		l.remove(el)
+		l.expired++
+	}
+}
+
+func (l *ipRateLimiter) remove(el *list.Element) {
+	l.lru.Remove(el)
+	delete(l.clients, el.Value.(*ipLimiterEntry).key)
+}
+
+// startJanitor sweeps idle buckets every interval for the lifetime of the
+// process.
+func (l *ipRateLimiter) startJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			l.sweep(now)
+		}
+	}()
+}
+
+// limiterStats describes the memory held by in-memory rate limiters.
+type limiterStats struct {
+	clients int
+	expired uint64
+	evicted uint64
+}
+
+func (l *ipRateLimiter) stats() limiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return limiterStats{clients: l.lru.Len(), expired: l.expired, evicted: l.evicted}
+}
+
+// tokenBucketScript refills the bucket stored at KEYS[1] and takes one token
+// if available. Running it as a script keeps the update atomic across replicas.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return allowed
+`)
+
+// redisRateLimiter enforces a token bucket per key shared by all replicas.
+type redisRateLimiter struct {
+	client redis.Scripter
+	mu     sync.RWMutex
+	rps    float64
+	burst  int
+	prefix string
+	now    func() time.Time
+}
+
+func newRedisRateLimiter(client redis.Scripter, rps float64, burst int) *redisRateLimiter {
+	return &redisRateLimiter{
+		client: client,
+		rps:    rps,
+		burst:  burst,
+		prefix: "linkchecker:ratelimit:",
+		now:    time.Now,
+	}
+}
+
+func (l *redisRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	if key == "" {
+		key = "unknown"
+	}
+	l.mu.RLock()
+	rps, burst := l.rps, l.burst
+	l.mu.RUnlock()
+	if rps <= 0 {
+		return true, nil
+	}
+	args := []any{
+		strconv.FormatFloat(rps, 'f', -1, 64),
+		burst,
+		l.now().UnixMilli(),
+	}
+	allowed, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis rate limit: %w", err)
+	}
+	return allowed == 1, nil
+}
+
+func (l *redisRateLimiter) SetLimit(rps float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rps = rps
+	l.burst = burst
+}
+
+// rateLimitKey returns the key a request is limited under.
+type rateLimitKey func(r *http.Request) string
+
+// parseRateLimitKey selects the key of RATE_LIMIT_KEY: the client IP, the
+// principal authenticated by authn or both. The limiter runs before the auth
+// middleware, so requests are authenticated here too; those without a valid
+// API key or token fall back to their IP, so that made-up keys do not get
+// buckets of their own.
+func parseRateLimitKey(scope string, authn auth.Authenticator) (rateLimitKey, error) {
+	principal := func(r *http.Request) string {
+		cred := auth.Credential(r)
+		if authn == nil || cred == "" {
+			return ""
+		}
+		p, err := authn.Authenticate(cred)
+		if err != nil {
+			return ""
+		}
+		return "principal:" + p.Name
+	}
+	switch scope {
+	case "", "ip":
+		return clientIP, nil
+	case "key":
+		return func(r *http.Request) string {
+			if key := principal(r); key != "" {
+				return key
+			}
+			return clientIP(r)
+		}, nil
+	case "ip+key":
+		return func(r *http.Request) string {
+			if key := principal(r); key != "" {
+				return clientIP(r) + "|" + key
+			}
+			return clientIP(r)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown RATE_LIMIT_KEY %q", scope)
+}
+
+// routeLimit is an override of RATE_LIMIT_ROUTES.
+type routeLimit struct {
+	rps   float64
+	burst int
+}
+
+// parseRouteLimits parses "route:rps:burst" entries separated by commas,
+// e.g. "/links:2:5,/report:20:40".
+func parseRouteLimits(spec string) (map[string]routeLimit, error) {
+	res := make(map[string]routeLimit)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 || !strings.HasPrefix(parts[0], "/") {
+			return nil, fmt.Errorf("invalid route limit %q", item)
+		}
+		rps, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || rps < 0 {
+			return nil, fmt.Errorf("route %s rps: want a non-negative number, got %q", parts[0], parts[1])
+		}
+		burst, err := strconv.Atoi(parts[2])
+		if err != nil || burst < 0 {
+			return nil, fmt.Errorf("route %s burst: want a non-negative integer, got %q", parts[0], parts[2])
+		}
+		res[parts[0]] = routeLimit{rps: rps, burst: burst}
+	}
+	return res, nil
+}
+
+// routeLimiters limits every route with the RATE_LIMIT_RPS/BURST limiter,
+// except the routes of RATE_LIMIT_ROUTES, which get limiters of their own.
+type routeLimiters struct {
+	def    RateLimiter
+	routes map[string]RateLimiter
+	key    rateLimitKey
+}
+
+func newRouteLimiters(cfg *config.Config, authn auth.Authenticator) (*routeLimiters, error) {
+	key, err := parseRateLimitKey(cfg.RateLimitKey, authn)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := parseRouteLimits(cfg.RateLimitRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("parse RATE_LIMIT_ROUTES: %w", err)
+	}
+	l := &routeLimiters{routes: make(map[string]RateLimiter), key: key}
+	if l.def, err = newRateLimiter(cfg); err != nil {
+		return nil, err
+	}
+	for route, o := range overrides {
+		// создаём с заглушкой, чтобы rps 0 не отключал лимитер насовсем
+		limiter, err := newBackendLimiter(cfg, 1, 1, route)
+		if err != nil {
+			return nil, err
+		}
+		limiter.SetLimit(o.rps, o.burst)
+		l.routes[route] = limiter
+	}
+	return l, nil
+}
+
+// stats sums the stats of the in-memory limiters; Redis keeps its buckets
+// itself.
+func (l *routeLimiters) stats() limiterStats {
+	var sum limiterStats
+	add := func(limiter RateLimiter) {
+		if ip, ok := limiter.(*ipRateLimiter); ok {
+			st := ip.stats()
+			sum.clients += st.clients
+			sum.expired += st.expired
+			sum.evicted += st.evicted
+		}
+	}
+	add(l.def)
+	for _, limiter := range l.routes {
+		add(limiter)
+	}
+	return sum
+}
+
+// wrap limits requests to route.
+func (l *routeLimiters) wrap(log *slog.Logger, route string, next http.Handler) http.Handler {
+	limiter, ok := l.routes[route]
+	if !ok {
+		limiter = l.def
+	}
+	return rateLimitMiddleware(log, limiter, l.key, next)
+}
+
+// SetLimits applies the default limit of cfg and the route overrides parsed
+// from its RATE_LIMIT_ROUTES. Routes added since startup need a restart;
+// removed ones fall back to the default rate but keep their own buckets.
+func (l *routeLimiters) SetLimits(cfg *config.Config, overrides map[string]routeLimit) {
+	if l.def != nil {
+		l.def.SetLimit(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+	for route, limiter := range l.routes {
+		o, ok := overrides[route]
+		if !ok {
+			o = routeLimit{rps: cfg.RateLimitRPS, burst: cfg.RateLimitBurst}
+		}
+		limiter.SetLimit(o.rps, o.burst)
+	}
+}
+
+// rateLimitMiddleware rejects requests over the limit of their key. Limiter
+// errors fail open so that an unavailable backend does not take the API down.
+func rateLimitMiddleware(log *slog.Logger, limiter RateLimiter, key rateLimitKey, next http.Handler) http.Handler {
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, err := limiter.Allow(r.Context(), key(r))
+		if err != nil {
+			log.Warn("rate limiter unavailable", "err", err)
+			ok = true
+		}
+		if !ok {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
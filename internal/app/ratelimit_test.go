@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/olgkv/linkchecker/internal/auth"
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+func TestRedisRateLimiter_SharedAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	now := time.Unix(1_700_000_000, 0)
+	clock := func() time.Time { return now }
+	// Два экземпляра с общим Redis должны делить один бюджет.
+	a := newRedisRateLimiter(client, 1, 2)
+	a.now = clock
+	b := newRedisRateLimiter(client, 1, 2)
+	b.now = clock
+
+	ctx := context.Background()
+	for i, l := range []*redisRateLimiter{a, b} {
+		ok, err := l.Allow(ctx, "1.1.1.1")
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if !ok {
+			t.Fatalf("request %d: expected allowed", i)
+		}
+	}
+	if ok, _ := a.Allow(ctx, "1.1.1.1"); ok {
+		t.Fatalf("expected burst to be exhausted across instances")
+	}
+	if ok, _ := b.Allow(ctx, "2.2.2.2"); !ok {
+		t.Fatalf("expected other key to be allowed")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := b.Allow(ctx, "1.1.1.1"); !ok {
+		t.Fatalf("expected token refilled after one second")
+	}
+}
+
+func TestRateLimitMiddleware_FailsOpen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	limiter := newRedisRateLimiter(client, 1, 1)
+	mr.Close()
+
+	if _, err := limiter.Allow(context.Background(), "1.1.1.1"); err == nil {
+		t.Fatalf("expected error with redis down")
+	}
+
+	h := rateLimitMiddleware(slog.Default(), limiter, clientIP, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/links", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected request passed through, got %d", rec.Code)
+	}
+}
+
+func TestRouteLimiters_KeyAndRouteOverrides(t *testing.T) {
+	cfg := &config.Config{RateLimitRPS: 100, RateLimitBurst: 100, RateLimitBackend: "memory", RateLimitKey: "key", RateLimitRoutes: "/links:1:1"}
+	keys, err := auth.ParseKeys("team-a:key-a,team-b:key-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits, err := newRouteLimiters(cfg, keys)
+	if err != nil {
+		t.Fatalf("newRouteLimiters: %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	links, report := limits.wrap(slog.Default(), "/links", ok), limits.wrap(slog.Default(), "/report", ok)
+
+	call := func(h http.Handler, ip, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := call(links, "1.1.1.1", "key-a"); code != http.StatusOK {
+		t.Fatalf("first /links request: status %d", code)
+	}
+	// тот же ключ с другого адреса попадает в ту же корзину
+	if code := call(links, "2.2.2.2", "key-a"); code != http.StatusTooManyRequests {
+		t.Fatalf("same key from another IP: status %d, want 429", code)
+	}
+	if code := call(links, "1.1.1.1", "key-b"); code != http.StatusOK {
+		t.Fatalf("other key from the same IP: status %d", code)
+	}
+	// выдуманные ключи не получают своих корзин и делят корзину адреса
+	if code := call(links, "3.3.3.3", "fake-1"); code != http.StatusOK {
+		t.Fatalf("first request with an invalid key: status %d", code)
+	}
+	if code := call(links, "3.3.3.3", "fake-2"); code != http.StatusTooManyRequests {
+		t.Fatalf("another invalid key from the same IP: status %d, want 429", code)
+	}
+	if code := call(links, "3.3.3.3", ""); code != http.StatusTooManyRequests {
+		t.Fatalf("no key from an IP with invalid keys: status %d, want 429", code)
+	}
+	if code := call(report, "1.1.1.1", "key-a"); code != http.StatusOK {
+		t.Fatalf("/report with the default limit: status %d", code)
+	}
+
+	limits.SetLimits(cfg, nil)
+	time.Sleep(20 * time.Millisecond) // корзина наполняется уже с новой скоростью
+	if code := call(links, "2.2.2.2", "key-a"); code != http.StatusOK {
+		t.Fatalf("/links after its override was removed: status %d", code)
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	got, err := parseRouteLimits("/links:2:5, /report:0.5:1")
+	if err != nil || got["/links"] != (routeLimit{rps: 2, burst: 5}) || got["/report"] != (routeLimit{rps: 0.5, burst: 1}) {
+		t.Fatalf("parseRouteLimits = %v, %v", got, err)
+	}
+	for _, spec := range []string{"links:1:1", "/links:1", "/links:x:1", "/links:1:-1"} {
+		if _, err := parseRouteLimits(spec); err == nil {
+			t.Errorf("parseRouteLimits(%q) accepted", spec)
+		}
+	}
+}
+
+func TestIPRateLimiter_CapAndSweep(t *testing.T) {
+	l := newIPRateLimiter(1, 1, time.Minute, 2)
+	l.allow("1.1.1.1")
+	l.allow("2.2.2.2")
+	l.allow("1.1.1.1")
+	l.allow("3.3.3.3")
+
+	if st := l.stats(); st.clients != 2 || st.evicted != 1 {
+		t.Fatalf("unexpected stats after cap: %+v", st)
+	}
+	if _, ok := l.clients["2.2.2.2"]; ok {
+		t.Fatalf("least recently seen client was not evicted")
+	}
+	if l.allow("1.1.1.1") {
+		t.Fatalf("recently seen client lost its bucket")
+	}
+
+	l.sweep(time.Now().Add(2 * time.Minute))
+	if st := l.stats(); st.clients != 0 || st.expired != 2 {
+		t.Fatalf("unexpected stats after sweep: %+v", st)
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/service"
+	"github.com/olgkv/linkchecker/internal/storage"
+)
+
+// readiness tracks startup. The server listens while the task log is being
+// replayed; until then /readyz reports the progress and API routes answer
+// 503, so that clients never see a partially loaded storage.
+type readiness struct {
+	storage *storage.FileStorage
+	ready   atomic.Bool
+}
+
+// ReadyResponse is the body of GET /readyz.
+type ReadyResponse struct {
+	Status string `json:"status"`
+	storage.LoadProgress
+}
+
+// load replays the storage and queues the results deferred before a
+// restart, then marks the server ready. The outcome is sent on the
+// returned channel.
+func (rd *readiness) load(svc *service.Service, log *slog.Logger) <-chan error {
+	loaded := make(chan error, 1)
+	go func() {
+		start := time.Now()
+		err := loadStorage(svc, rd.storage, log)
+		if err == nil {
+			// мониторы удаляют задачи, которых нет в хранилище, поэтому стартуют после загрузки
+			svc.StartMonitors()
+			rd.ready.Store(true)
+			total, _ := rd.storage.Stats()
+			log.Info("storage loaded", "tasks", total, "duration_ms", time.Since(start).Milliseconds())
+		}
+		loaded <- err
+	}()
+	return loaded
+}
+
+// wrap answers 503 until the server is ready.
+func (rd *readiness) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rd.ready.Load() {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "starting up: task log is being loaded", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rd *readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp := ReadyResponse{Status: "ready", LoadProgress: rd.storage.Progress()}
+	code := http.StatusOK
+	if !rd.ready.Load() {
+		resp.Status = "loading"
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/service"
+	"github.com/olgkv/linkchecker/internal/storage"
+)
+
+func TestReadiness_GatesRoutesUntilLoaded(t *testing.T) {
+	st := storage.NewFileStorage(storage.NewJSONRepository(filepath.Join(t.TempDir(), "tasks.json")))
+	svc := service.New(st, http.DefaultClient, 1, time.Second, 1)
+	t.Cleanup(svc.Close)
+	rd := &readiness{storage: st}
+	api := rd.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	rd.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var resp ReadyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || resp.Status != "loading" || resp.Phase != storage.LoadPending {
+		t.Fatalf("before load: status %d, body %+v", rec.Code, resp)
+	}
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("API before load: status %d", rec.Code)
+	}
+
+	if err := <-rd.load(svc, slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	rd.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	resp = ReadyResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Status != "ready" || resp.Percent != 100 {
+		t.Fatalf("after load: status %d, body %+v", rec.Code, resp)
+	}
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("API after load: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/olgkv/linkchecker/internal/config"
+	"github.com/olgkv/linkchecker/internal/httpapi"
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+// reloader re-reads the configuration and applies the settings that can
+// change without restarting listeners or dropping in-flight checks: worker
+// and timeout limits, the size and tenant weights of the shared worker pool,
+// retries, the per-request link limit and rate limits. Other settings keep
+// their startup values until restart.
+type reloader struct {
+	mu      sync.Mutex
+	load    func() (*config.Config, error)
+	svc     *service.Service
+	handler *httpapi.Handler
+	limits  *routeLimiters
+	log     *slog.Logger
+}
+
+func (r *reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.load()
+	if err != nil {
+		return fmt.Errorf("reload config: %w", err)
+	}
+
+	weights, err := service.ParseTenantWeights(cfg.TenantWeights)
+	if err != nil {
+		return fmt.Errorf("parse TENANT_WEIGHTS: %w", err)
+	}
+	routes, err := parseRouteLimits(cfg.RateLimitRoutes)
+	if err != nil {
+		return fmt.Errorf("parse RATE_LIMIT_ROUTES: %w", err)
+	}
+
+	r.svc.SetLimits(cfg.MaxWorkers, cfg.HTTPTimeout)
+	r.svc.ResizeWorkerPool(cfg.GlobalWorkers)
+	r.svc.SetTenantWeights(weights)
+	r.svc.SetRetryPolicy(retryPolicy(cfg))
+	r.handler.SetMaxLinks(cfg.MaxLinks)
+	r.handler.SetMaxBatchLinks(cfg.MaxBatchLinks)
+	r.handler.SetMaxUploadBytes(cfg.MaxUploadBytes)
+	if r.limits != nil {
+		r.limits.SetLimits(cfg, routes)
+	}
+
+	r.log.Info("configuration reloaded",
+		"max_workers", cfg.MaxWorkers,
+		"global_workers", cfg.GlobalWorkers,
+		"http_timeout", cfg.HTTPTimeout,
+		"check_retries", cfg.CheckRetries,
+		"max_links", cfg.MaxLinks,
+		"max_batch_links", cfg.MaxBatchLinks,
+		"max_upload_bytes", cfg.MaxUploadBytes,
+		"rate_limit_rps", cfg.RateLimitRPS,
+		"rate_limit_burst", cfg.RateLimitBurst,
+	)
+	return nil
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+	"github.com/olgkv/linkchecker/internal/httpapi"
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+func TestReloader_AppliesTunableSettings(t *testing.T) {
+	svc := service.New(nil, nil, 4, time.Second, 1)
+	t.Cleanup(svc.Close)
+	limiter := newIPRateLimiter(1, 1, time.Minute, 0)
+	// первый запрос создаёт limiter для IP со старыми параметрами
+	if !limiter.allow("1.1.1.1") || limiter.allow("1.1.1.1") {
+		t.Fatalf("expected burst of one before reload")
+	}
+
+	next := &config.Config{MaxWorkers: 8, HTTPTimeout: 2 * time.Second, MaxLinks: 10, RateLimitRPS: 0, RateLimitBurst: 1}
+	var loadErr error
+	rl := &reloader{
+		load:    func() (*config.Config, error) { return next, loadErr },
+		svc:     svc,
+		handler: httpapi.NewHandler(svc, 50),
+		limits:  &routeLimiters{def: limiter, routes: map[string]RateLimiter{}, key: clientIP},
+		log:     slog.Default(),
+	}
+
+	if err := rl.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !limiter.allow("1.1.1.1") {
+		t.Fatalf("expected RATE_LIMIT_RPS=0 to lift the limit for existing clients")
+	}
+
+	loadErr = errors.New("bad config")
+	if err := rl.Reload(); err == nil {
+		t.Fatalf("expected load error to be returned")
+	}
+}
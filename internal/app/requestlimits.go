@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+// requestLimits bounds the duration and body size of API requests.
+// ROUTE_TIMEOUTS and ROUTE_BODY_LIMITS override REQUEST_TIMEOUT and
+// MAX_BODY_BYTES for single routes.
+type requestLimits struct {
+	timeout  time.Duration
+	maxBody  int64
+	timeouts map[string]time.Duration
+	bodies   map[string]int64
+}
+
+func newRequestLimits(cfg *config.Config) (*requestLimits, error) {
+	timeouts, err := parseRouteValues(cfg.RouteTimeouts, func(v string) (time.Duration, error) {
+		d, err := time.ParseDuration(v)
+		if err == nil && d < 0 {
+			err = fmt.Errorf("negative timeout %s", v)
+		}
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse ROUTE_TIMEOUTS: %w", err)
+	}
+	bodies, err := parseRouteValues(cfg.RouteBodyLimits, func(v string) (int64, error) {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && n <= 0 {
+			err = fmt.Errorf("body limit must be positive, got %d", n)
+		}
+		return n, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse ROUTE_BODY_LIMITS: %w", err)
+	}
+	return &requestLimits{timeout: cfg.RequestTimeout, maxBody: int64(cfg.MaxBodyBytes), timeouts: timeouts, bodies: bodies}, nil
+}
+
+// parseRouteValues parses "route:value" entries separated by commas.
+func parseRouteValues[T any](spec string, parse func(string) (T, error)) (map[string]T, error) {
+	res := make(map[string]T)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		route, v, ok := strings.Cut(item, ":")
+		if !ok || !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("invalid entry %q", item)
+		}
+		val, err := parse(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		res[route] = val
+	}
+	return res, nil
+}
+
+// wrap applies the limits of route: the body is cut off after its size
+// limit and the request context is cancelled after its timeout. A zero
+// timeout lets the request run as long as it needs.
+func (l *requestLimits) wrap(route string, next http.Handler) http.Handler {
+	timeout, ok := l.timeouts[route]
+	if !ok {
+		timeout = l.timeout
+	}
+	maxBody, ok := l.bodies[route]
+	if !ok {
+		maxBody = l.maxBody
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maxBody > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
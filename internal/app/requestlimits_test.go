@@ -0,0 +1,60 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+func TestRequestLimits_PerRoute(t *testing.T) {
+	limits, err := newRequestLimits(&config.Config{
+		MaxBodyBytes:    8,
+		RouteBodyLimits: "/links:16",
+		RouteTimeouts:   "/report:30s",
+	})
+	if err != nil {
+		t.Fatalf("newRequestLimits: %v", err)
+	}
+	var deadline bool
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, deadline = r.Context().Deadline()
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	})
+	call := func(route, body string) int {
+		rec := httptest.NewRecorder()
+		limits.wrap(route, h).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, route, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	body := strings.Repeat("x", 12)
+	if code := call("/links", body); code != http.StatusOK || deadline {
+		t.Fatalf("/links: status %d, deadline %v; want 200 without deadline", code, deadline)
+	}
+	if code := call("/report", body); code != http.StatusRequestEntityTooLarge || !deadline {
+		t.Fatalf("/report: status %d, deadline %v; want 413 with deadline", code, deadline)
+	}
+}
+
+func TestNewRequestLimits_InvalidRoutes(t *testing.T) {
+	for _, cfg := range []*config.Config{
+		{RouteTimeouts: "/report:soon"},
+		{RouteTimeouts: "report:30s"},
+		{RouteBodyLimits: "/links:0"},
+		{RouteBodyLimits: "/links"},
+	} {
+		if _, err := newRequestLimits(cfg); err == nil {
+			t.Errorf("newRequestLimits(%q, %q) accepted", cfg.RouteTimeouts, cfg.RouteBodyLimits)
+		}
+	}
+	limits, err := newRequestLimits(&config.Config{RequestTimeout: time.Minute})
+	if err != nil || limits.timeout != time.Minute {
+		t.Fatalf("newRequestLimits = %+v, %v", limits, err)
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olgkv/linkchecker/internal/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newTLSConfig returns the server TLS settings, or nil when the server should
+// speak plain HTTP. Static certificates take precedence over autocert.
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	switch {
+	case cfg.TLSCertFile != "" || cfg.TLSKeyFile != "":
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS key pair: %w", err)
+		}
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	case cfg.AutocertHosts != "":
+		var hosts []string
+		for _, h := range strings.Split(cfg.AutocertHosts, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hosts = append(hosts, h)
+			}
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		// TLSConfig answers tls-alpn-01 challenges, so no port 80 listener is needed.
+		tlsCfg := m.TLSConfig()
+		tlsCfg.MinVersion = tls.VersionTLS12
+		return tlsCfg, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newAdminTLSConfig builds the admin listener TLS settings: every client must
+// present a certificate signed by ADMIN_CLIENT_CA_FILE. The server certificate
+// falls back to TLS_CERT_FILE/TLS_KEY_FILE when no admin-specific one is set.
+func newAdminTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.AdminClientCAFile == "" {
+		return nil, errors.New("ADMIN_CLIENT_CA_FILE is required with ADMIN_PORT")
+	}
+	pem, err := os.ReadFile(cfg.AdminClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read admin client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("admin client CA file contains no certificates")
+	}
+
+	certFile, keyFile := cfg.AdminTLSCertFile, cfg.AdminTLSKeyFile
+	if certFile == "" && keyFile == "" {
+		certFile, keyFile = cfg.TLSCertFile, cfg.TLSKeyFile
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("ADMIN_TLS_CERT_FILE and ADMIN_TLS_KEY_FILE (or TLS_CERT_FILE and TLS_KEY_FILE) are required with ADMIN_PORT")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load admin TLS key pair: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/config"
+)
+
+func writeSelfSignedCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+
+	tlsCfg, err := newTLSConfig(&config.Config{})
+	if err != nil || tlsCfg != nil {
+		t.Fatalf("expected plain HTTP without TLS settings, got %v, %v", tlsCfg, err)
+	}
+
+	tlsCfg, err = newTLSConfig(&config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("newTLSConfig: %v", err)
+	}
+	if len(tlsCfg.Certificates) != 1 {
+		t.Fatalf("expected loaded certificate, got %d", len(tlsCfg.Certificates))
+	}
+
+	if _, err := newTLSConfig(&config.Config{TLSCertFile: certFile}); err == nil {
+		t.Fatalf("expected error when key file is missing")
+	}
+
+	tlsCfg, err = newTLSConfig(&config.Config{AutocertHosts: "example.com", AutocertCacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("newTLSConfig autocert: %v", err)
+	}
+	if tlsCfg.GetCertificate == nil {
+		t.Fatalf("expected autocert GetCertificate")
+	}
+}
+
+func TestNewAdminTLSConfig(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+
+	if _, err := newAdminTLSConfig(&config.Config{AdminPort: "9443", TLSCertFile: certFile, TLSKeyFile: keyFile}); err == nil {
+		t.Fatalf("expected error without client CA")
+	}
+
+	// Самоподписанный сертификат годится и как CA для клиентов.
+	tlsCfg, err := newAdminTLSConfig(&config.Config{
+		AdminPort:         "9443",
+		AdminClientCAFile: certFile,
+		TLSCertFile:       certFile,
+		TLSKeyFile:        keyFile,
+	})
+	if err != nil {
+		t.Fatalf("newAdminTLSConfig: %v", err)
+	}
+	if tlsCfg.ClientAuth != tls.RequireAndVerifyClientCert || tlsCfg.ClientCAs == nil {
+		t.Fatalf("expected client certificates to be required")
+	}
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role grants access to a group of routes. Roles are ordered: each role can
+// do everything the previous ones can.
+type Role int
+
+const (
+	RoleReader Role = iota + 1
+	RoleSubmitter
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleReader:
+		return "reader"
+	case RoleSubmitter:
+		return "submitter"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+// ParseRole parses "reader", "submitter" or "admin".
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "reader":
+		return RoleReader, nil
+	case "submitter":
+		return RoleSubmitter, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return 0, fmt.Errorf("invalid role %q", s)
+}
+
+// Principal identifies the caller authenticated by an API key.
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// Admin reports whether the principal may bypass tenant isolation.
+func (p Principal) Admin() bool {
+	return p.Role == RoleAdmin
+}
+
+type contextKey struct{ name string }
+
+var principalContextKey = &contextKey{name: "principal"}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey, p)
+}
+
+// FromContext returns the principal stored by the middleware, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalContextKey).(Principal)
+	return p, ok
+}
+
+// Owner returns the owner tasks must be scoped to for the caller in ctx.
+// It is empty when authentication is disabled or the caller is an admin.
+func Owner(ctx context.Context) string {
+	p, ok := FromContext(ctx)
+	if !ok || p.Admin() {
+		return ""
+	}
+	return p.Name
+}
+
+type apiKey struct {
+	secret    []byte
+	principal Principal
+}
+
+// KeyStore holds the configured API keys.
+type KeyStore struct {
+	keys []apiKey
+}
+
+// ParseKeys parses a comma-separated list of "name:key" or "name:key:role"
+// entries; the role defaults to submitter. An empty spec yields a nil store,
+// which disables authentication.
+func ParseKeys(spec string) (*KeyStore, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	store := &KeyStore{}
+	names := make(map[string]struct{})
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid api key entry %q", item)
+		}
+		p := Principal{Name: parts[0], Role: RoleSubmitter}
+		if len(parts) == 3 {
+			role, err := ParseRole(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("api key %q: %w", parts[0], err)
+			}
+			p.Role = role
+		}
+		if _, ok := names[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate api key name %q", p.Name)
+		}
+		names[p.Name] = struct{}{}
+		store.keys = append(store.keys, apiKey{secret: []byte(parts[1]), principal: p})
+	}
+	return store, nil
+}
+
+// Lookup returns the principal owning key.
+func (s *KeyStore) Lookup(key string) (Principal, bool) {
+	if s == nil || key == "" {
+		return Principal{}, false
+	}
+	var (
+		found Principal
+		ok    bool
+	)
+	// сравниваем со всеми ключами, чтобы время ответа не зависело от позиции
+	for _, k := range s.keys {
+		if subtle.ConstantTimeCompare(k.secret, []byte(key)) == 1 {
+			found, ok = k.principal, true
+		}
+	}
+	return found, ok
+}
+
+// Authenticate implements Authenticator for static API keys.
+func (s *KeyStore) Authenticate(token string) (Principal, error) {
+	p, ok := s.Lookup(token)
+	if !ok {
+		return Principal{}, ErrInvalidToken
+	}
+	return p, nil
+}
+
+// Authenticator resolves a bearer token to a principal.
+type Authenticator interface {
+	Authenticate(token string) (Principal, error)
+}
+
+// Chain tries authenticators in order and returns the first principal found.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(token string) (Principal, error) {
+	err := error(ErrInvalidToken)
+	for _, a := range c {
+		p, aerr := a.Authenticate(token)
+		if aerr == nil {
+			return p, nil
+		}
+		err = aerr
+	}
+	return Principal{}, err
+}
+
+// PrincipalRecorder is implemented by response writers that want to know who
+// made the request, e.g. for audit logging in outer middlewares.
+type PrincipalRecorder interface {
+	RecordPrincipal(p Principal)
+}
+
+// Middleware rejects requests without a valid token and stores the principal
+// in the request context. A nil authenticator disables the check.
+func Middleware(authn Authenticator, next http.Handler) http.Handler {
+	if authn == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := Credential(r)
+		if token == "" {
+			unauthorized(w)
+			return
+		}
+		p, err := authn.Authenticate(token)
+		if err != nil {
+			unauthorized(w)
+			return
+		}
+		if rec, ok := w.(PrincipalRecorder); ok {
+			rec.RecordPrincipal(p)
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="linkchecker"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// Credential returns the API key or bearer token presented with r, or "".
+func Credential(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if token, ok := strings.CutPrefix(h, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// Policy maps HTTP methods to the minimal role required to call a route.
+// The "*" entry applies to methods without their own entry.
+type Policy map[string]Role
+
+func (p Policy) required(method string) Role {
+	if role, ok := p[method]; ok {
+		return role
+	}
+	if role, ok := p["*"]; ok {
+		return role
+	}
+	return RoleAdmin
+}
+
+// Require enforces policy for the principal stored by Middleware and responds
+// with 403 when its role is insufficient. Requests without a principal pass
+// through, because authentication is disabled for them, unless the method
+// needs the admin role: those get 401, so that an open API never exposes
+// admin routes.
+func Require(policy Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := FromContext(r.Context())
+		if !ok && policy.required(r.Method) == RoleAdmin {
+			unauthorized(w)
+			return
+		}
+		if ok && p.Role < policy.required(r.Method) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	store, err := ParseKeys("team-a:secret1, root:secret2:admin, viewer:secret3:reader")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	if p, ok := store.Lookup("secret1"); !ok || p.Name != "team-a" || p.Role != RoleSubmitter {
+		t.Fatalf("unexpected principal for secret1: %+v %v", p, ok)
+	}
+	if p, ok := store.Lookup("secret2"); !ok || !p.Admin() {
+		t.Fatalf("expected admin principal, got %+v %v", p, ok)
+	}
+	if p, ok := store.Lookup("secret3"); !ok || p.Role != RoleReader {
+		t.Fatalf("expected reader principal, got %+v %v", p, ok)
+	}
+	if _, ok := store.Lookup("nope"); ok {
+		t.Fatalf("unexpected match for unknown key")
+	}
+
+	for _, bad := range []string{"noseparator", "a:b:superuser", "a:x,a:y", ":key"} {
+		if _, err := ParseKeys(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	if store, err := ParseKeys(""); err != nil || store != nil {
+		t.Fatalf("expected nil store for empty spec, got %v %v", store, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	store, _ := ParseKeys("team-a:secret1")
+	var got Principal
+	h := Middleware(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req.Header.Set("Authorization", "Bearer secret1")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || got.Name != "team-a" {
+		t.Fatalf("expected authenticated request, got %d %+v", rec.Code, got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req.Header.Set("X-API-Key", "secret1")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected X-API-Key to be accepted, got %d", rec.Code)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	policy := Policy{http.MethodGet: RoleReader, http.MethodPost: RoleSubmitter}
+	h := Require(policy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		role   Role
+		method string
+		want   int
+	}{
+		{"reader can read", RoleReader, http.MethodGet, http.StatusOK},
+		{"reader cannot submit", RoleReader, http.MethodPost, http.StatusForbidden},
+		{"submitter can submit", RoleSubmitter, http.MethodPost, http.StatusOK},
+		{"unlisted method needs admin", RoleSubmitter, http.MethodDelete, http.StatusForbidden},
+		{"admin can do anything", RoleAdmin, http.MethodDelete, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			req = req.WithContext(WithPrincipal(req.Context(), Principal{Name: "x", Role: tc.role}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected pass-through without principal, got %d", rec.Code)
+	}
+
+	// без аутентификации админские методы закрыты
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("admin method without principal: status = %d, want 401", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	Require(Policy{"*": RoleAdmin}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("admin route served without principal")
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("admin policy without principal: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	var got Principal
+	h := ClientCertMiddleware(RoleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/breaker", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status without certificate = %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/breaker", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "ops"}},
+	}}}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got.Name != "ops" || got.Role != RoleAdmin {
+		t.Fatalf("unexpected principal: %+v", got)
+	}
+}
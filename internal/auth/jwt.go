@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jwksCacheTTL       = time.Hour
+	jwksMinRefreshWait = time.Minute
+	clockSkew          = time.Minute
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+// JWTConfig configures validation of OIDC access tokens.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	JWKSURL  string
+	// RoleClaim names the claim holding the caller role; callers without it
+	// get DefaultRole.
+	RoleClaim   string
+	DefaultRole Role
+}
+
+// JWTVerifier validates RS256/ES256 signed JWTs against keys published at a JWKS URL.
+type JWTVerifier struct {
+	cfg    JWTConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// attemptedAt is when the keys were last requested, successfully or
+	// not, and fetchErr why the last request failed.
+	attemptedAt time.Time
+	fetchErr    error
+}
+
+func NewJWTVerifier(cfg JWTConfig, client *http.Client) *JWTVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "role"
+	}
+	if cfg.DefaultRole == 0 {
+		cfg.DefaultRole = RoleSubmitter
+	}
+	return &JWTVerifier{cfg: cfg, client: client, now: time.Now}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate verifies token and maps its subject to a principal.
+func (v *JWTVerifier) Authenticate(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return Principal{}, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return Principal{}, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	return v.principal(claims)
+}
+
+func (v *JWTVerifier) principal(claims map[string]any) (Principal, error) {
+	now := v.now()
+	if exp, ok := numericClaim(claims, "exp"); !ok || now.After(exp.Add(clockSkew)) {
+		return Principal{}, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(clockSkew).Before(nbf) {
+		return Principal{}, fmt.Errorf("%w: not yet valid", ErrInvalidToken)
+	}
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return Principal{}, fmt.Errorf("%w: issuer mismatch", ErrInvalidToken)
+	}
+	if v.cfg.Audience != "" && !hasAudience(claims["aud"], v.cfg.Audience) {
+		return Principal{}, fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return Principal{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	p := Principal{Name: sub, Role: v.cfg.DefaultRole}
+	if raw, ok := claims[v.cfg.RoleClaim].(string); ok && raw != "" {
+		role, err := ParseRole(raw)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		p.Role = role
+	}
+	return p, nil
+}
+
+// key returns the key kid, refreshing the keys when kid is unknown or the
+// keys are stale. Keys are requested at most once per jwksMinRefreshWait,
+// failed requests included, and without holding the lock, so a slow or
+// failing JWKS endpoint does not hold up requests with known keys.
+func (v *JWTVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	now := v.now()
+	key, ok := v.keys[kid]
+	stale := now.Sub(v.fetchedAt) > jwksCacheTTL
+	// неизвестный kid обычно означает ротацию ключей у провайдера
+	refresh := (!ok || stale) && now.Sub(v.attemptedAt) > jwksMinRefreshWait
+	if refresh {
+		// попытка отмечается сразу, чтобы параллельные запросы ее не повторяли
+		v.attemptedAt = now
+	}
+	fetchErr := v.fetchErr
+	v.mu.Unlock()
+
+	if refresh {
+		keys, err := v.fetchKeys()
+		v.mu.Lock()
+		v.fetchErr = err
+		if err == nil {
+			v.keys, v.fetchedAt = keys, now
+			key, ok = keys[kid]
+		}
+		v.mu.Unlock()
+		fetchErr = err
+	}
+	switch {
+	case ok:
+		return key, nil
+	case fetchErr != nil:
+		return nil, fmt.Errorf("fetch jwks: %w", fetchErr)
+	default:
+		return nil, ErrUnknownKey
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWTVerifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	resp, err := v.client.Get(v.cfg.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidToken
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			return ErrInvalidToken
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrInvalidToken
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+func numericClaim(claims map[string]any, name string) (time.Time, bool) {
+	v, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+func hasAudience(raw any, want string) bool {
+	switch aud := raw.(type) {
+	case string:
+		return aud == want
+	case []any:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
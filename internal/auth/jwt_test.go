@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newJWKSServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerifier_Authenticate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	srv := newJWKSServer(t, key, "k1")
+	v := NewJWTVerifier(JWTConfig{Issuer: "https://idp", Audience: "linkchecker", JWKSURL: srv.URL}, srv.Client())
+
+	valid := map[string]any{
+		"iss":  "https://idp",
+		"aud":  []string{"other", "linkchecker"},
+		"sub":  "alice",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+		"role": "reader",
+	}
+	p, err := v.Authenticate(signRS256(t, key, "k1", valid))
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if p.Name != "alice" || p.Role != RoleReader {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+
+	withClaim := func(name string, value any) map[string]any {
+		c := make(map[string]any, len(valid))
+		for k, v := range valid {
+			c[k] = v
+		}
+		c[name] = value
+		return c
+	}
+	bad := map[string]string{
+		"expired":        signRS256(t, key, "k1", withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"wrong issuer":   signRS256(t, key, "k1", withClaim("iss", "https://evil")),
+		"wrong audience": signRS256(t, key, "k1", withClaim("aud", "other")),
+		"unknown kid":    signRS256(t, key, "k2", valid),
+		"malformed":      "not.a.jwt",
+	}
+	for name, token := range bad {
+		if _, err := v.Authenticate(token); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := v.Authenticate(signRS256(t, other, "k1", valid)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected signature check to fail, got %v", err)
+	}
+}
+
+func TestJWTVerifier_FetchBackoff(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	good := newJWKSServer(t, key, "k1")
+	var requests atomic.Int32
+	failing := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		resp, err := good.Client().Get(good.URL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(srv.Close)
+	now := time.Now()
+	v := NewJWTVerifier(JWTConfig{Issuer: "https://idp", Audience: "linkchecker", JWKSURL: srv.URL}, srv.Client())
+	v.now = func() time.Time { return now }
+	token := signRS256(t, key, "k1", map[string]any{"iss": "https://idp", "aud": "linkchecker", "sub": "alice", "exp": now.Add(time.Hour).Unix()})
+
+	// неудачная попытка тоже выдерживает паузу перед повтором
+	for range 3 {
+		if _, err := v.Authenticate(token); err == nil {
+			t.Fatal("expected an error while the JWKS endpoint fails")
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("JWKS requested %d times within the refresh wait, want 1", n)
+	}
+
+	failing = false
+	now = now.Add(jwksMinRefreshWait + time.Second)
+	if _, err := v.Authenticate(token); err != nil {
+		t.Fatalf("Authenticate after the wait: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("JWKS requested %d times, want 2", n)
+	}
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// ClientCertMiddleware authenticates callers by the verified TLS client
+// certificate and grants them role. The certificate's common name becomes the
+// principal name. It expects the listener to verify certificates itself
+// (tls.RequireAndVerifyClientCert); requests without a verified chain get 401.
+func ClientCertMiddleware(role Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		p := Principal{Name: r.TLS.VerifiedChains[0][0].Subject.CommonName, Role: role}
+		if rec, ok := w.(PrincipalRecorder); ok {
+			rec.RecordPrincipal(p)
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+func TestDiskStore(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	if err := d.Put(ctx, "reports/a/1.pdf", []byte("one")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := d.Put(ctx, "other/2.pdf", []byte("two")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	rc, err := d.Get(ctx, "reports/a/1.pdf")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "one" {
+		t.Fatalf("Get = %q, want one", data)
+	}
+
+	list, err := d.List(ctx, "reports/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].Key != "reports/a/1.pdf" || list[0].Size != 3 {
+		t.Fatalf("List = %+v", list)
+	}
+
+	if err := d.Delete(ctx, "reports/a/1.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := d.Get(ctx, "reports/a/1.pdf"); !errors.Is(err, ports.ErrBlobNotFound) {
+		t.Fatalf("Get after Delete: %v, want ErrBlobNotFound", err)
+	}
+	if err := d.Delete(ctx, "reports/a/1.pdf"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+	if err := d.Put(ctx, "../escape", nil); err == nil {
+		t.Fatalf("Put outside the directory succeeded")
+	}
+}
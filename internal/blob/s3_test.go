@@ -0,0 +1,156 @@
+package blob
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// fakeS3 serves path-style object requests of one bucket from memory and
+// lists one key per page to exercise continuation.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AK/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/bucket")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(data) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = data
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, r.URL.Query().Get("prefix")) && k > r.URL.Query().Get("continuation-token") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	type content struct {
+		Key          string
+		Size         int
+		LastModified time.Time
+	}
+	var page struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}
+	if len(keys) > 0 {
+		page.Contents = []content{{Key: keys[0], Size: len(f.objects[keys[0]]), LastModified: time.Now().UTC()}}
+		page.IsTruncated = len(keys) > 1
+		if page.IsTruncated {
+			page.NextContinuationToken = keys[0]
+		}
+	}
+	_ = xml.NewEncoder(w).Encode(page)
+}
+
+func TestS3Store(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{objects: make(map[string][]byte)})
+	defer srv.Close()
+	ctx := context.Background()
+
+	s, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "bucket", Region: "eu-west-1", AccessKey: "AK", SecretKey: "SK"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	for _, key := range []string{"reports/_/1.pdf", "reports/ab/2.pdf", "other/3.pdf"} {
+		if err := s.Put(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+
+	rc, err := s.Get(ctx, "reports/ab/2.pdf")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "reports/ab/2.pdf" {
+		t.Fatalf("Get = %q", data)
+	}
+
+	list, err := s.List(ctx, "reports/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].Key != "reports/_/1.pdf" || list[1].Key != "reports/ab/2.pdf" {
+		t.Fatalf("List = %+v", list)
+	}
+
+	if err := s.Delete(ctx, "reports/ab/2.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "reports/ab/2.pdf"); !errors.Is(err, ports.ErrBlobNotFound) {
+		t.Fatalf("Get after Delete: %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestS3Store_RejectedRequest(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{objects: make(map[string][]byte)})
+	defer srv.Close()
+
+	s, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "bucket", AccessKey: "AK"}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	// регион по умолчанию us-east-1 фейковый сервер не принимает
+	if err := s.Put(context.Background(), "k", nil); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Fatalf("Put = %v, want status 403", err)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if got := escape("reports/a b+c~", true); got != "reports/a%20b%2Bc~" {
+		t.Fatalf("escape path = %q", got)
+	}
+	if got := escape("reports/", false); got != "reports%2F" {
+		t.Fatalf("escape query = %q", got)
+	}
+}
@@ -1,92 +1,423 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata" // TIMEZONE не зависит от базы зон в образе
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
-// Config describes runtime settings loaded from environment variables.
+// Config describes runtime settings. Each field is named by its env tag; the
+// same name in lower case is the key in the optional config file.
 type Config struct {
-	Port           string        `env:"PORT" envDefault:"8080"`
-	TasksFile      string        `env:"TASKS_FILE" envDefault:"tasks.json"`
-	HTTPTimeout    time.Duration `env:"HTTP_TIMEOUT" envDefault:"5s"`
-	MaxLinks       int           `env:"MAX_LINKS" envDefault:"50"`
-	MaxWorkers     int           `env:"MAX_WORKERS" envDefault:"100"`
-	RateLimitRPS   float64       `env:"RATE_LIMIT_RPS" envDefault:"10"`
-	RateLimitBurst int           `env:"RATE_LIMIT_BURST" envDefault:"20"`
-	ReportWorkers  int           `env:"REPORT_WORKERS" envDefault:"2"`
+	Mode                    string        `env:"MODE" envDefault:"server"`
+	Port                    string        `env:"PORT" envDefault:"8080"`
+	Listen                  string        `env:"LISTEN"`
+	TasksFile               string        `env:"TASKS_FILE" envDefault:"tasks.json"`
+	HTTPTimeout             time.Duration `env:"HTTP_TIMEOUT" envDefault:"5s"`
+	HTTPMaxIdleConns        int           `env:"HTTP_MAX_IDLE_CONNS" envDefault:"100"`
+	HTTPMaxIdleConnsPerHost int           `env:"HTTP_MAX_IDLE_CONNS_PER_HOST" envDefault:"10"`
+	HTTPIdleConnTimeout     time.Duration `env:"HTTP_IDLE_CONN_TIMEOUT" envDefault:"90s"`
+	HTTPTLSHandshakeTimeout time.Duration `env:"HTTP_TLS_HANDSHAKE_TIMEOUT" envDefault:"10s"`
+	HTTPDialTimeout         time.Duration `env:"HTTP_DIAL_TIMEOUT" envDefault:"5s"`
+	HTTPFallbackDelay       time.Duration `env:"HTTP_FALLBACK_DELAY" envDefault:"300ms"`
+	OutboundSourceIP        string        `env:"OUTBOUND_SOURCE_IP"`
+	OutboundBandwidth       int           `env:"OUTBOUND_BANDWIDTH" envDefault:"0"`
+	HTTP3Probe              bool          `env:"HTTP3_PROBE" envDefault:"false"`
+	CheckRetries            int           `env:"CHECK_RETRIES" envDefault:"2"`
+	CheckBackoffBase        time.Duration `env:"CHECK_BACKOFF_BASE" envDefault:"100ms"`
+	CheckBackoffMax         time.Duration `env:"CHECK_BACKOFF_MAX" envDefault:"1s"`
+	CheckBackoffJitter      float64       `env:"CHECK_BACKOFF_JITTER" envDefault:"0.2"`
+	OutboundLogSampleRate   float64       `env:"OUTBOUND_LOG_SAMPLE_RATE" envDefault:"0"`
+	RobotsTxt               bool          `env:"ROBOTS_TXT" envDefault:"false"`
+	RobotsUserAgent         string        `env:"ROBOTS_USER_AGENT" envDefault:"linkchecker"`
+	RobotsCacheTTL          time.Duration `env:"ROBOTS_CACHE_TTL" envDefault:"1h"`
+	ConditionalChecks       bool          `env:"CONDITIONAL_CHECKS" envDefault:"false"`
+	ConditionalCacheSize    int           `env:"CONDITIONAL_CACHE_SIZE" envDefault:"10000"`
+	BreakerMaxHosts         int           `env:"BREAKER_MAX_HOSTS" envDefault:"10000"`
+	SMTPProbe               bool          `env:"SMTP_PROBE" envDefault:"false"`
+	SMTPProbeHelo           string        `env:"SMTP_PROBE_HELO" envDefault:"localhost"`
+	SMTPProbeFrom           string        `env:"SMTP_PROBE_FROM"`
+	SFTPKnownHosts          string        `env:"SFTP_KNOWN_HOSTS"`
+	SFTPPrivateKey          string        `env:"SFTP_PRIVATE_KEY"`
+	MaxLinks                int           `env:"MAX_LINKS" envDefault:"50"`
+	MaxBatchLinks           int           `env:"MAX_BATCH_LINKS" envDefault:"0"`
+	MaxUploadBytes          int           `env:"MAX_UPLOAD_BYTES" envDefault:"1048576"`
+	MaxBodyBytes            int           `env:"MAX_BODY_BYTES" envDefault:"10485760"`
+	RouteBodyLimits         string        `env:"ROUTE_BODY_LIMITS" envDefault:"/report/sla:1048576"`
+	RequestTimeout          time.Duration `env:"REQUEST_TIMEOUT" envDefault:"0"`
+	RouteTimeouts           string        `env:"ROUTE_TIMEOUTS" envDefault:"/report:30s"`
+	MaxWorkers              int           `env:"MAX_WORKERS" envDefault:"100"`
+	GlobalWorkers           int           `env:"GLOBAL_WORKERS" envDefault:"0"`
+	TenantWeights           string        `env:"TENANT_WEIGHTS"`
+	RateLimitRPS            float64       `env:"RATE_LIMIT_RPS" envDefault:"10"`
+	RateLimitBurst          int           `env:"RATE_LIMIT_BURST" envDefault:"20"`
+	RateLimitBackend        string        `env:"RATE_LIMIT_BACKEND" envDefault:"memory"`
+	RateLimitKey            string        `env:"RATE_LIMIT_KEY" envDefault:"ip"`
+	RateLimitRoutes         string        `env:"RATE_LIMIT_ROUTES"`
+	RateLimitMaxClients     int           `env:"RATE_LIMIT_MAX_CLIENTS" envDefault:"100000"`
+	RedisURL                string        `env:"REDIS_URL" secret:"true"`
+	ReportWorkers           int           `env:"REPORT_WORKERS" envDefault:"2"`
+	ReportWorkersMin        int           `env:"REPORT_WORKERS_MIN" envDefault:"1"`
+	ReportQueue             int           `env:"REPORT_QUEUE" envDefault:"64"`
+	ReportStore             string        `env:"REPORT_STORE"`
+	ReportDir               string        `env:"REPORT_DIR" envDefault:"reports"`
+	ReportRetention         time.Duration `env:"REPORT_RETENTION" envDefault:"720h"`
+	ReportS3Endpoint        string        `env:"REPORT_S3_ENDPOINT"`
+	ReportS3Bucket          string        `env:"REPORT_S3_BUCKET"`
+	ReportS3Region          string        `env:"REPORT_S3_REGION" envDefault:"us-east-1"`
+	ReportS3AccessKey       string        `env:"REPORT_S3_ACCESS_KEY" secret:"true"`
+	ReportS3SecretKey       string        `env:"REPORT_S3_SECRET_KEY" secret:"true"`
+	ReportCompany           string        `env:"REPORT_COMPANY"`
+	ReportHeader            string        `env:"REPORT_HEADER"`
+	ReportFooter            string        `env:"REPORT_FOOTER"`
+	ReportAccentColor       string        `env:"REPORT_ACCENT_COLOR"`
+	ReportLogo              string        `env:"REPORT_LOGO"`
+	ReportPDFPassword       string        `env:"REPORT_PDF_PASSWORD" secret:"true"`
+	ReportPDFOwnerPassword  string        `env:"REPORT_PDF_OWNER_PASSWORD" secret:"true"`
+	ReportLinkSecret        string        `env:"REPORT_LINK_SECRET" secret:"true"`
+	ReportLinkTTL           time.Duration `env:"REPORT_LINK_TTL" envDefault:"168h"`
+	TaskRetention           time.Duration `env:"TASK_RETENTION" envDefault:"0"`
+	DedupWindow             time.Duration `env:"DEDUP_WINDOW" envDefault:"0"`
+	FsyncPolicy             string        `env:"FSYNC_POLICY" envDefault:"always"`
+	StorageSlowAppend       time.Duration `env:"STORAGE_SLOW_APPEND" envDefault:"100ms"`
+	APIKeys                 string        `env:"API_KEYS" secret:"true"`
+	OIDCIssuer              string        `env:"OIDC_ISSUER"`
+	OIDCAudience            string        `env:"OIDC_AUDIENCE"`
+	OIDCJWKSURL             string        `env:"OIDC_JWKS_URL"`
+	OIDCRoleClaim           string        `env:"OIDC_ROLE_CLAIM" envDefault:"role"`
+	QuotaDaily              int           `env:"QUOTA_DAILY" envDefault:"0"`
+	QuotaMonthly            int           `env:"QUOTA_MONTHLY" envDefault:"0"`
+	QuotaOverrides          string        `env:"QUOTA_OVERRIDES"`
+	QuotaFile               string        `env:"QUOTA_FILE" envDefault:"usage.json"`
+	MaintenanceFile         string        `env:"MAINTENANCE_FILE" envDefault:"maintenance.json"`
+	MonitorsFile            string        `env:"MONITORS_FILE" envDefault:"monitors.json"`
+	BlocklistFile           string        `env:"BLOCKLIST_FILE"`
+	SafeBrowsingKey         string        `env:"SAFE_BROWSING_API_KEY" secret:"true"`
+	StatusRules             string        `env:"STATUS_RULES"`
+	AllowedSchemes          string        `env:"ALLOWED_SCHEMES"`
+	HTTPSPolicy             string        `env:"HTTPS_POLICY" envDefault:"as-written"`
+	TLSCertFile             string        `env:"TLS_CERT_FILE"`
+	TLSKeyFile              string        `env:"TLS_KEY_FILE"`
+	AutocertHosts           string        `env:"AUTOCERT_HOSTS"`
+	AutocertEmail           string        `env:"AUTOCERT_EMAIL"`
+	AutocertCacheDir        string        `env:"AUTOCERT_CACHE_DIR" envDefault:"certs"`
+	AdminPort               string        `env:"ADMIN_PORT"`
+	AdminTLSCertFile        string        `env:"ADMIN_TLS_CERT_FILE"`
+	AdminTLSKeyFile         string        `env:"ADMIN_TLS_KEY_FILE"`
+	AdminClientCAFile       string        `env:"ADMIN_CLIENT_CA_FILE"`
+	NATSURL                 string        `env:"NATS_URL" secret:"true"`
+	NATSSubject             string        `env:"NATS_SUBJECT" envDefault:"linkchecker.jobs"`
+	NATSResultsSubject      string        `env:"NATS_RESULTS_SUBJECT" envDefault:"linkchecker.results"`
+	NATSQueueGroup          string        `env:"NATS_QUEUE_GROUP" envDefault:"linkchecker"`
+	ConsumerConcurrency     int           `env:"CONSUMER_CONCURRENCY" envDefault:"4"`
+	EventsSubject           string        `env:"EVENTS_SUBJECT"`
+	SlackWebhooks           string        `env:"SLACK_WEBHOOKS" secret:"true"`
+	TeamsWebhooks           string        `env:"TEAMS_WEBHOOKS" secret:"true"`
+	PublicURL               string        `env:"PUBLIC_URL"`
+	Timezone                string        `env:"TIMEZONE" envDefault:"UTC"`
+	LogLevel                string        `env:"LOG_LEVEL" envDefault:"info"`
+	LogFormat               string        `env:"LOG_FORMAT" envDefault:"json"`
+	LogOutput               string        `env:"LOG_OUTPUT" envDefault:"stdout"`
+	LogFile                 string        `env:"LOG_FILE" envDefault:"linkchecker.log"`
+
+	// Overrides holds settings given on the command line, keyed by env name.
+	// They take precedence over the environment and are kept for reloads.
+	Overrides map[string]string
 }
 
-// Load reads configuration from environment variables, applying defaults when necessary.
+// Load builds the configuration from defaults, the optional CONFIG_FILE
+// (YAML or TOML) and environment variables, in increasing precedence. Every
+// invalid setting is reported in the returned error, not just the first one.
 func Load() (*Config, error) {
-	cfg := &Config{
-		Port:           "8080",
-		TasksFile:      "tasks.json",
-		HTTPTimeout:    5 * time.Second,
-		MaxLinks:       50,
-		MaxWorkers:     100,
-		RateLimitRPS:   10,
-		RateLimitBurst: 20,
-		ReportWorkers:  2,
+	return LoadWith(nil)
+}
+
+// LoadWith is Load with command-line overrides applied on top of the
+// environment. CONFIG_FILE may itself be overridden.
+func LoadWith(overrides map[string]string) (*Config, error) {
+	cfg := &Config{Overrides: overrides}
+	var errs []error
+	for _, f := range fields(cfg) {
+		if def, ok := f.tag.Lookup("envDefault"); ok {
+			errs = append(errs, f.set(def))
+		}
 	}
 
-	if port := os.Getenv("PORT"); port != "" {
-		cfg.Port = port
+	path := os.Getenv("CONFIG_FILE")
+	if p, ok := overrides["CONFIG_FILE"]; ok {
+		path = p
+	}
+	if path != "" {
+		errs = append(errs, loadFile(cfg, path))
 	}
 
-	if tasksFile := os.Getenv("TASKS_FILE"); tasksFile != "" {
-		cfg.TasksFile = tasksFile
+	for _, f := range fields(cfg) {
+		if value := os.Getenv(f.name); value != "" {
+			errs = append(errs, f.set(value))
+		}
+	}
+	for name, value := range overrides {
+		if name != "CONFIG_FILE" {
+			errs = append(errs, cfg.Set(name, value))
+		}
 	}
 
-	if httpTimeout := os.Getenv("HTTP_TIMEOUT"); httpTimeout != "" {
-		dur, err := time.ParseDuration(httpTimeout)
-		if err != nil {
-			return nil, fmt.Errorf("parse HTTP_TIMEOUT: %w", err)
+	errs = append(errs, cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Setting describes a configuration option.
+type Setting struct {
+	Name    string
+	Default string
+}
+
+// Settings lists all options in declaration order.
+func Settings() []Setting {
+	var out []Setting
+	for _, f := range fields(&Config{}) {
+		out = append(out, Setting{Name: f.name, Default: f.tag.Get("envDefault")})
+	}
+	return out
+}
+
+// Location returns the time zone named by TIMEZONE, or UTC if it is
+// unknown; Validate reports that case.
+func (c *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Dump writes the effective configuration as YAML using config file keys.
+// Secrets are masked.
+func (c *Config) Dump(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields(c) {
+		value := fmt.Sprint(f.value.Interface())
+		if f.tag.Get("secret") == "true" && value != "" {
+			value = "***"
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		if value == "" {
+			node.Style = yaml.DoubleQuotedStyle
 		}
-		cfg.HTTPTimeout = dur
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(f.name)}, node)
 	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
 
-	if maxLinks := os.Getenv("MAX_LINKS"); maxLinks != "" {
-		value, err := strconv.Atoi(maxLinks)
-		if err != nil {
-			return nil, fmt.Errorf("parse MAX_LINKS: %w", err)
+// Set assigns the setting named by its env key, e.g. "MAX_WORKERS".
+func (c *Config) Set(name, value string) error {
+	for _, f := range fields(c) {
+		if f.name == name {
+			return f.set(value)
 		}
-		cfg.MaxLinks = value
 	}
+	return fmt.Errorf("unknown setting %s", name)
+}
 
-	if maxWorkers := os.Getenv("MAX_WORKERS"); maxWorkers != "" {
-		value, err := strconv.Atoi(maxWorkers)
-		if err != nil {
-			return nil, fmt.Errorf("parse MAX_WORKERS: %w", err)
+// Validate checks value ranges and combinations of settings.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
 		}
-		cfg.MaxWorkers = value
 	}
 
-	if rps := os.Getenv("RATE_LIMIT_RPS"); rps != "" {
-		value, err := strconv.ParseFloat(rps, 64)
-		if err != nil {
-			return nil, fmt.Errorf("parse RATE_LIMIT_RPS: %w", err)
+	if port, err := strconv.Atoi(c.Port); c.Listen == "" && (err != nil || port < 1 || port > 65535) {
+		errs = append(errs, fmt.Errorf("PORT: %q is not a valid port", c.Port))
+	}
+	check(c.TasksFile != "", "TASKS_FILE: must not be empty")
+	check(c.HTTPTimeout > 0, "HTTP_TIMEOUT: must be positive, got %s", c.HTTPTimeout)
+	check(c.Mode == "server" || c.Mode == "consumer", "MODE: want server or consumer, got %q", c.Mode)
+	check(c.Mode != "consumer" || c.NATSURL != "", "NATS_URL: required with MODE=consumer")
+	check(c.Mode != "consumer" || c.NATSSubject != "", "NATS_SUBJECT: required with MODE=consumer")
+	check(c.EventsSubject == "" || c.NATSURL != "", "NATS_URL: required with EVENTS_SUBJECT")
+	check(!strings.ContainsAny(c.NATSSubject+c.NATSResultsSubject+c.NATSQueueGroup+c.EventsSubject, " \t\r\n"),
+		"NATS_SUBJECT, NATS_RESULTS_SUBJECT, NATS_QUEUE_GROUP, EVENTS_SUBJECT: must not contain whitespace")
+	check(c.PublicURL == "" || strings.HasPrefix(c.PublicURL, "https://") || strings.HasPrefix(c.PublicURL, "http://"),
+		"PUBLIC_URL: want an http or https URL, got %q", c.PublicURL)
+	check(c.ConsumerConcurrency > 0, "CONSUMER_CONCURRENCY: must be positive, got %d", c.ConsumerConcurrency)
+	check(c.HTTPMaxIdleConns >= 0, "HTTP_MAX_IDLE_CONNS: must not be negative, got %d", c.HTTPMaxIdleConns)
+	check(c.HTTPMaxIdleConnsPerHost >= 0, "HTTP_MAX_IDLE_CONNS_PER_HOST: must not be negative, got %d", c.HTTPMaxIdleConnsPerHost)
+	check(c.HTTPIdleConnTimeout >= 0, "HTTP_IDLE_CONN_TIMEOUT: must not be negative, got %s", c.HTTPIdleConnTimeout)
+	check(c.HTTPTLSHandshakeTimeout >= 0, "HTTP_TLS_HANDSHAKE_TIMEOUT: must not be negative, got %s", c.HTTPTLSHandshakeTimeout)
+	check(c.HTTPDialTimeout >= 0, "HTTP_DIAL_TIMEOUT: must not be negative, got %s", c.HTTPDialTimeout)
+	check(c.OutboundSourceIP == "" || net.ParseIP(c.OutboundSourceIP) != nil,
+		"OUTBOUND_SOURCE_IP: want an IP address, got %q", c.OutboundSourceIP)
+	check(c.OutboundBandwidth >= 0, "OUTBOUND_BANDWIDTH: must not be negative, got %d", c.OutboundBandwidth)
+	check(c.CheckRetries >= 0, "CHECK_RETRIES: must not be negative, got %d", c.CheckRetries)
+	check(c.CheckBackoffBase >= 0, "CHECK_BACKOFF_BASE: must not be negative, got %s", c.CheckBackoffBase)
+	check(c.CheckBackoffMax >= c.CheckBackoffBase,
+		"CHECK_BACKOFF_MAX: must not be less than CHECK_BACKOFF_BASE, got %s", c.CheckBackoffMax)
+	check(c.CheckBackoffJitter >= 0 && c.CheckBackoffJitter <= 1,
+		"CHECK_BACKOFF_JITTER: must be between 0 and 1, got %g", c.CheckBackoffJitter)
+	check(c.OutboundLogSampleRate >= 0 && c.OutboundLogSampleRate <= 1,
+		"OUTBOUND_LOG_SAMPLE_RATE: must be between 0 and 1, got %g", c.OutboundLogSampleRate)
+	check(!c.RobotsTxt || c.RobotsUserAgent != "", "ROBOTS_USER_AGENT: required with ROBOTS_TXT")
+	check(c.RobotsCacheTTL > 0, "ROBOTS_CACHE_TTL: must be positive, got %s", c.RobotsCacheTTL)
+	check(c.ConditionalCacheSize > 0, "CONDITIONAL_CACHE_SIZE: must be positive, got %d", c.ConditionalCacheSize)
+	check(!c.SMTPProbe || c.SMTPProbeHelo != "", "SMTP_PROBE_HELO: required with SMTP_PROBE")
+	check(!strings.ContainsAny(c.SMTPProbeHelo+c.SMTPProbeFrom, "\r\n<> "),
+		"SMTP_PROBE_HELO, SMTP_PROBE_FROM: must not contain spaces, angle brackets or line breaks")
+	check(c.MaxLinks > 0, "MAX_LINKS: must be positive, got %d", c.MaxLinks)
+	check(c.RateLimitMaxClients > 0, "RATE_LIMIT_MAX_CLIENTS: must be positive, got %d", c.RateLimitMaxClients)
+	check(c.BreakerMaxHosts > 0, "BREAKER_MAX_HOSTS: must be positive, got %d", c.BreakerMaxHosts)
+	check(c.MaxBatchLinks == 0 || c.MaxBatchLinks > c.MaxLinks,
+		"MAX_BATCH_LINKS: must be 0 or greater than MAX_LINKS, got %d", c.MaxBatchLinks)
+	check(c.MaxUploadBytes > 0, "MAX_UPLOAD_BYTES: must be positive, got %d", c.MaxUploadBytes)
+	check(c.MaxBodyBytes > 0, "MAX_BODY_BYTES: must be positive, got %d", c.MaxBodyBytes)
+	check(c.RequestTimeout >= 0, "REQUEST_TIMEOUT: must not be negative, got %s", c.RequestTimeout)
+	check(c.MaxWorkers > 0, "MAX_WORKERS: must be positive, got %d", c.MaxWorkers)
+	check(c.GlobalWorkers >= 0, "GLOBAL_WORKERS: must not be negative, got %d", c.GlobalWorkers)
+	check(c.ReportWorkers > 0, "REPORT_WORKERS: must be positive, got %d", c.ReportWorkers)
+	check(c.ReportWorkersMin > 0 && c.ReportWorkersMin <= c.ReportWorkers,
+		"REPORT_WORKERS_MIN: must be between 1 and REPORT_WORKERS, got %d", c.ReportWorkersMin)
+	check(c.ReportQueue >= 0, "REPORT_QUEUE: must not be negative, got %d", c.ReportQueue)
+	check(c.ReportStore == "" || c.ReportStore == "disk" || c.ReportStore == "s3",
+		"REPORT_STORE: want disk or s3, got %q", c.ReportStore)
+	check(c.ReportStore != "disk" || c.ReportDir != "", "REPORT_DIR: required with REPORT_STORE=disk")
+	check(c.ReportStore != "s3" || c.ReportS3Endpoint != "" && c.ReportS3Bucket != "",
+		"REPORT_S3_ENDPOINT, REPORT_S3_BUCKET: required with REPORT_STORE=s3")
+	check(c.ReportRetention >= 0, "REPORT_RETENTION: must not be negative, got %s", c.ReportRetention)
+	check(len(c.ReportPDFPassword) <= 32 && len(c.ReportPDFOwnerPassword) <= 32,
+		"REPORT_PDF_PASSWORD, REPORT_PDF_OWNER_PASSWORD: want at most 32 bytes")
+	check(c.ReportPDFOwnerPassword == "" || c.ReportPDFPassword != "", "REPORT_PDF_PASSWORD: required with REPORT_PDF_OWNER_PASSWORD")
+	check(c.ReportLinkSecret == "" || c.ReportStore != "", "REPORT_STORE: required with REPORT_LINK_SECRET")
+	check(c.ReportLinkSecret == "" || len(c.ReportLinkSecret) >= 32, "REPORT_LINK_SECRET: want at least 32 bytes")
+	check(c.ReportLinkTTL > 0, "REPORT_LINK_TTL: must be positive, got %s", c.ReportLinkTTL)
+	check(c.RateLimitRPS >= 0, "RATE_LIMIT_RPS: must not be negative, got %g", c.RateLimitRPS)
+	check(c.RateLimitBurst >= 0, "RATE_LIMIT_BURST: must not be negative, got %d", c.RateLimitBurst)
+	check(c.RateLimitBackend == "memory" || c.RateLimitBackend == "redis",
+		"RATE_LIMIT_BACKEND: want memory or redis, got %q", c.RateLimitBackend)
+	check(c.RateLimitKey == "ip" || c.RateLimitKey == "key" || c.RateLimitKey == "ip+key",
+		"RATE_LIMIT_KEY: want ip, key or ip+key, got %q", c.RateLimitKey)
+	check(c.RateLimitBackend != "redis" || c.RedisURL != "", "REDIS_URL: required with RATE_LIMIT_BACKEND=redis")
+	check(c.TaskRetention >= 0, "TASK_RETENTION: must not be negative, got %s", c.TaskRetention)
+	check(c.StorageSlowAppend >= 0, "STORAGE_SLOW_APPEND: must not be negative, got %s", c.StorageSlowAppend)
+	check(c.DedupWindow >= 0, "DEDUP_WINDOW: must not be negative, got %s", c.DedupWindow)
+	check(c.QuotaDaily >= 0, "QUOTA_DAILY: must not be negative, got %d", c.QuotaDaily)
+	check(c.QuotaMonthly >= 0, "QUOTA_MONTHLY: must not be negative, got %d", c.QuotaMonthly)
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE, TLS_KEY_FILE: must be set together")
+	check(c.AdminPort == "" || c.AdminClientCAFile != "", "ADMIN_CLIENT_CA_FILE: required with ADMIN_PORT")
+	var level slog.Level
+	_, tzErr := time.LoadLocation(c.Timezone)
+	check(tzErr == nil, "TIMEZONE: unknown time zone %q", c.Timezone)
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "LOG_LEVEL: want debug, info, warn or error, got %q", c.LogLevel)
+	check(c.LogFormat == "json" || c.LogFormat == "text", "LOG_FORMAT: want json or text, got %q", c.LogFormat)
+	check(c.LogOutput == "stdout" || c.LogOutput == "stderr" || c.LogOutput == "file",
+		"LOG_OUTPUT: want stdout, stderr or file, got %q", c.LogOutput)
+
+	return errors.Join(errs...)
+}
+
+// loadFile applies settings from a YAML (.yaml, .yml) or TOML (.toml) file.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read CONFIG_FILE: %w", err)
+	}
+
+	values := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("CONFIG_FILE: unsupported extension %q, want .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse CONFIG_FILE: %w", err)
+	}
+
+	var errs []error
+	for key, value := range values {
+		if value == nil {
+			continue
 		}
-		cfg.RateLimitRPS = value
+		errs = append(errs, cfg.Set(strings.ToUpper(key), fmt.Sprint(value)))
 	}
+	return errors.Join(errs...)
+}
 
-	if burst := os.Getenv("RATE_LIMIT_BURST"); burst != "" {
-		value, err := strconv.Atoi(burst)
-		if err != nil {
-			return nil, fmt.Errorf("parse RATE_LIMIT_BURST: %w", err)
+type field struct {
+	name  string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+func fields(cfg *Config) []field {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	out := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		if tag.Get("env") == "" {
+			continue
 		}
-		cfg.RateLimitBurst = value
+		out = append(out, field{name: tag.Get("env"), tag: tag, value: v.Field(i)})
 	}
+	return out
+}
 
-	if reportWorkers := os.Getenv("REPORT_WORKERS"); reportWorkers != "" {
-		value, err := strconv.Atoi(reportWorkers)
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (f field) set(raw string) error {
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", f.name, err)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(raw)
+	case f.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
 		if err != nil {
-			return nil, fmt.Errorf("parse REPORT_WORKERS: %w", err)
+			return fmt.Errorf("parse %s: %w", f.name, err)
 		}
-		cfg.ReportWorkers = value
+		f.value.SetInt(int64(n))
+	case f.value.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", f.name, err)
+		}
+		f.value.SetFloat(n)
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", f.name, err)
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("%s: unsupported field type %s", f.name, f.value.Type())
 	}
-
-	return cfg, nil
+	return nil
 }
@@ -1,6 +1,12 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestLoad(t *testing.T) {
 	t.Setenv("PORT", "9090")
@@ -19,3 +25,94 @@ func TestLoad(t *testing.T) {
 		t.Fatalf("expected HTTP timeout 10s, got %s", cfg.HTTPTimeout)
 	}
 }
+
+func TestLoad_ConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "linkchecker.yaml")
+	if err := os.WriteFile(yamlFile, []byte("port: 7070\nmax_workers: 8\nhttp_timeout: 3s\nrate_limit_rps: 2.5\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", yamlFile)
+	t.Setenv("MAX_WORKERS", "16")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Port != "7070" || cfg.HTTPTimeout != 3*time.Second || cfg.RateLimitRPS != 2.5 {
+		t.Fatalf("file values not applied: %+v", cfg)
+	}
+	// переменная окружения важнее файла
+	if cfg.MaxWorkers != 16 {
+		t.Fatalf("expected env to override file, got MaxWorkers=%d", cfg.MaxWorkers)
+	}
+	if cfg.MaxLinks != 50 {
+		t.Fatalf("expected default MaxLinks 50, got %d", cfg.MaxLinks)
+	}
+
+	tomlFile := filepath.Join(dir, "linkchecker.toml")
+	if err := os.WriteFile(tomlFile, []byte("tasks_file = \"/data/tasks.json\"\ntask_retention = \"720h\"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", tomlFile)
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.TasksFile != "/data/tasks.json" || cfg.TaskRetention != 720*time.Hour {
+		t.Fatalf("toml values not applied: %+v", cfg)
+	}
+}
+
+func TestLoad_ReportsAllErrors(t *testing.T) {
+	t.Setenv("MAX_LINKS", "many")
+	t.Setenv("MAX_WORKERS", "0")
+	t.Setenv("RATE_LIMIT_BACKEND", "memcached")
+	t.Setenv("CHECK_BACKOFF_MAX", "10ms")
+	t.Setenv("CHECK_BACKOFF_JITTER", "1.5")
+	t.Setenv("TIMEZONE", "Mars/Olympus")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, want := range []string{"MAX_LINKS", "MAX_WORKERS", "RATE_LIMIT_BACKEND", "CHECK_BACKOFF_MAX", "CHECK_BACKOFF_JITTER", "TIMEZONE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadWith_OverridesAndDump(t *testing.T) {
+	t.Setenv("MAX_WORKERS", "16")
+	t.Setenv("API_KEYS", "ci:secret")
+	t.Setenv("SAFE_BROWSING_API_KEY", "sb-secret")
+
+	cfg, err := LoadWith(map[string]string{"MAX_WORKERS": "4"})
+	if err != nil {
+		t.Fatalf("LoadWith: %v", err)
+	}
+	if cfg.MaxWorkers != 4 {
+		t.Fatalf("expected flag override to win, got %d", cfg.MaxWorkers)
+	}
+
+	var buf strings.Builder
+	if err := cfg.Dump(&buf); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "max_workers: 4\n") || strings.Contains(out, "ci:secret") || strings.Contains(out, "sb-secret") {
+		t.Fatalf("unexpected dump:\n%s", out)
+	}
+
+	// выгруженный конфиг можно загрузить обратно
+	file := filepath.Join(t.TempDir(), "dump.yaml")
+	if err := os.WriteFile(file, []byte(out), 0o600); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	t.Setenv("API_KEYS", "")
+	t.Setenv("CONFIG_FILE", file)
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load dumped config: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package consumer checks links submitted as jobs on a message queue
+// instead of over HTTP and publishes the results back to the queue.
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/queue"
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+// Queue delivers job messages and publishes results.
+type Queue interface {
+	Messages() <-chan queue.Message
+	Publish(subject string, data []byte) error
+	Err() error
+}
+
+// Job is a link-check request received from the queue. ID is an optional
+// correlation ID copied to the result.
+type Job struct {
+	ID              string   `json:"id,omitempty"`
+	Links           []string `json:"links"`
+	Name            string   `json:"name,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	FailAfter       int      `json:"fail_after,omitempty"`
+	FollowRedirects *bool    `json:"follow_redirects,omitempty"`
+	Content         bool     `json:"content,omitempty"`
+	Security        bool     `json:"security,omitempty"`
+	Notify          []string `json:"notify,omitempty"`
+	Priority        string   `json:"priority,omitempty"`
+}
+
+// Result is published for every job, including rejected ones, which carry
+// only Error.
+type Result struct {
+	JobID      string `json:"job_id,omitempty"`
+	TaskID     int    `json:"task_id,omitempty"`
+	LinksCount int    `json:"links_count,omitempty"`
+	// LinksNum repeats TaskID under its old name.
+	//
+	// Deprecated: use TaskID.
+	LinksNum  int                          `json:"links_num,omitempty"`
+	Links     map[string]domain.LinkStatus `json:"links,omitempty"`
+	Details   map[string]domain.LinkResult `json:"details,omitempty"`
+	Persisted bool                         `json:"persisted"`
+	Error     string                       `json:"error,omitempty"`
+}
+
+// Consumer runs the jobs received from a Queue through the service.
+type Consumer struct {
+	svc         *service.Service
+	q           Queue
+	results     string
+	maxLinks    int
+	concurrency int
+	log         *slog.Logger
+}
+
+// New returns a consumer publishing results to the results subject. Jobs
+// with more than maxLinks links are rejected; up to concurrency jobs are
+// checked at a time.
+func New(svc *service.Service, q Queue, results string, maxLinks, concurrency int) *Consumer {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Consumer{svc: svc, q: q, results: results, maxLinks: maxLinks, concurrency: concurrency, log: slog.Default()}
+}
+
+// UseLogger sets the logger for job errors.
+func (c *Consumer) UseLogger(log *slog.Logger) {
+	if log != nil {
+		c.log = log
+	}
+}
+
+// Run handles jobs until ctx is done or the queue connection is lost, then
+// waits for the jobs in progress. It returns nil when stopped by ctx.
+func (c *Consumer) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	sem := make(chan struct{}, c.concurrency)
+	msgs := c.q.Messages()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sem <- struct{}{}:
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				if err := c.q.Err(); err != nil && !errors.Is(err, queue.ErrClosed) {
+					return fmt.Errorf("queue connection lost: %w", err)
+				}
+				return nil
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				c.handle(ctx, msg)
+			}()
+		}
+	}
+}
+
+// handle checks the links of one job and publishes the result to the
+// results subject and, when the sender asked for a reply, to its inbox.
+func (c *Consumer) handle(ctx context.Context, msg queue.Message) {
+	res := c.check(ctx, msg.Data)
+	data, err := json.Marshal(res)
+	if err != nil {
+		c.log.Error("encode job result", "job_id", res.JobID, "err", err)
+		return
+	}
+	for _, subject := range []string{c.results, msg.Reply} {
+		if subject == "" {
+			continue
+		}
+		if err := c.q.Publish(subject, data); err != nil {
+			c.log.Error("publish job result", "job_id", res.JobID, "subject", subject, "err", err)
+		}
+	}
+}
+
+func (c *Consumer) check(ctx context.Context, data []byte) Result {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return Result{Error: "invalid job: " + err.Error()}
+	}
+	res := Result{JobID: job.ID}
+	switch {
+	case len(job.Links) == 0:
+		res.Error = "no links"
+		return res
+	case len(job.Links) > c.maxLinks:
+		res.Error = fmt.Sprintf("%d links exceed the limit of %d", len(job.Links), c.maxLinks)
+		return res
+	case job.FailAfter < 0:
+		res.Error = "fail_after must not be negative"
+		return res
+	}
+	for _, target := range job.Notify {
+		if !c.svc.ValidNotifyTarget(target) {
+			res.Error = fmt.Sprintf("unknown notify target %q", target)
+			return res
+		}
+	}
+
+	priority, err := service.ParsePriority(job.Priority)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	var tags []string
+	for _, tag := range job.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	id, result, err := c.svc.CheckLinks(ctx, job.Links, service.CheckOptions{
+		Name:        strings.TrimSpace(job.Name),
+		Tags:        tags,
+		FailAfter:   job.FailAfter,
+		NoRedirects: job.FollowRedirects != nil && !*job.FollowRedirects,
+		Content:     job.Content,
+		Security:    job.Security,
+		Notify:      job.Notify,
+		Priority:    priority,
+		RequestID:   job.ID,
+	})
+	if errors.Is(err, service.ErrDeduplicated) {
+		err = nil
+	}
+	if err != nil && !errors.Is(err, service.ErrResultPersistDeferred) {
+		c.log.Error("check job", "job_id", job.ID, "err", err)
+		res.Error = "check failed"
+		return res
+	}
+	res.TaskID, res.LinksCount, res.LinksNum = id, len(job.Links), id
+	res.Persisted = err == nil
+	res.Details = result
+	res.Links = make(map[string]domain.LinkStatus, len(result))
+	for link, r := range result {
+		res.Links[link] = r.Status
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/queue"
+	"github.com/olgkv/linkchecker/internal/service"
+	"github.com/olgkv/linkchecker/internal/storage"
+)
+
+type fakeQueue struct {
+	msgs chan queue.Message
+	mu   sync.Mutex
+	pubs map[string][][]byte
+}
+
+func (q *fakeQueue) Messages() <-chan queue.Message { return q.msgs }
+func (q *fakeQueue) Err() error                     { return nil }
+
+func (q *fakeQueue) Publish(subject string, data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pubs[subject] = append(q.pubs[subject], data)
+	return nil
+}
+
+type okTransport struct{}
+
+func (okTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestConsumer_Run(t *testing.T) {
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), &http.Client{Transport: okTransport{}}, 4, time.Second, 1)
+	defer svc.Close()
+	q := &fakeQueue{msgs: make(chan queue.Message, 3), pubs: make(map[string][][]byte)}
+	c := New(svc, q, "results", 2, 2)
+
+	q.msgs <- queue.Message{Subject: "jobs", Reply: "_INBOX.7", Data: []byte(`{"id":"j1","links":["1.1.1.1"],"tags":["nightly"]}`)}
+	q.msgs <- queue.Message{Subject: "jobs", Data: []byte(`{"id":"j2","links":["a","b","c"]}`)}
+	q.msgs <- queue.Message{Subject: "jobs", Data: []byte(`not json`)}
+	close(q.msgs)
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	results := make(map[string]Result)
+	for _, data := range q.pubs["results"] {
+		var res Result
+		if err := json.Unmarshal(data, &res); err != nil {
+			t.Fatalf("decode result: %v", err)
+		}
+		results[res.JobID] = res
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results["j1"]; r.TaskID == 0 || r.LinksNum != r.TaskID || !r.Persisted || r.Links["1.1.1.1"] == "" || r.Error != "" {
+		t.Fatalf("unexpected result for j1: %+v", r)
+	}
+	if len(q.pubs["_INBOX.7"]) != 1 {
+		t.Fatalf("reply subject got %d results, want 1", len(q.pubs["_INBOX.7"]))
+	}
+	if r := results["j2"]; r.Error == "" || r.LinksNum != 0 {
+		t.Fatalf("job over the link limit not rejected: %+v", r)
+	}
+	if r := results[""]; !strings.HasPrefix(r.Error, "invalid job") {
+		t.Fatalf("malformed job not rejected: %+v", r)
+	}
+}
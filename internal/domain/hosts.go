@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HostSummary counts the results of links on one host.
+type HostSummary struct {
+	Host      string `json:"host"`
+	Checked   int    `json:"checked"`
+	Available int    `json:"available"`
+	Broken    int    `json:"broken"`
+	// Maintenance counts links skipped during a maintenance window.
+	Maintenance int `json:"maintenance,omitempty"`
+}
+
+// LinkHost returns the lowercased hostname of link, or the trimmed link
+// itself when it cannot be parsed.
+func LinkHost(link string) string {
+	link = strings.ToLower(strings.TrimSpace(link))
+	raw := link
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" {
+		return link
+	}
+	return parsed.Hostname()
+}
+
+// SummarizeByHost counts results of links per host, ordered by host. Links
+// without a result count as broken, as they do in reports; links in
+// maintenance do not.
+func SummarizeByHost(links []string, result map[string]string) []HostSummary {
+	byHost := make(map[string]*HostSummary)
+	for _, link := range links {
+		host := LinkHost(link)
+		s, ok := byHost[host]
+		if !ok {
+			s = &HostSummary{Host: host}
+			byHost[host] = s
+		}
+		s.Checked++
+		switch result[link] {
+		case string(StatusAvailable):
+			s.Available++
+		case string(StatusMaintenance):
+			s.Maintenance++
+		default:
+			s.Broken++
+		}
+	}
+
+	out := make([]HostSummary, 0, len(byHost))
+	for _, s := range byHost {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
+
+// DomainSummary aggregates the recorded checks of every link on a host,
+// the latest result of each link in each task.
+type DomainSummary struct {
+	Host        string `json:"host"`
+	Tasks       int    `json:"tasks"`
+	URLs        int    `json:"urls"`
+	Checks      int    `json:"checks"`
+	Available   int    `json:"available"`
+	Broken      int    `json:"broken"`
+	Maintenance int    `json:"maintenance,omitempty"`
+	// AvailabilityRatio is the share of available checks, links in
+	// maintenance not counted.
+	AvailabilityRatio float64   `json:"availability_ratio"`
+	AvgLatencyMS      float64   `json:"avg_latency_ms"`
+	LastChecked       time.Time `json:"last_checked,omitzero"`
+	// LastFailure is the most recent check that found a link broken.
+	LastFailure *DomainFailure `json:"last_failure,omitempty"`
+}
+
+// DomainFailure is a failed check of a link.
+type DomainFailure struct {
+	URL    string    `json:"url"`
+	Status string    `json:"status"`
+	TaskID int       `json:"task_id"`
+	At     time.Time `json:"at,omitzero"`
+}
+
+// SummarizeDomain aggregates the results of links of tasks on host. A check
+// without timing is dated when its task completed.
+func SummarizeDomain(host string, tasks []*Task) DomainSummary {
+	host = strings.ToLower(host)
+	sum := DomainSummary{Host: host}
+	urls := make(map[string]struct{})
+	var latency, timed int64
+	for _, t := range tasks {
+		counted := false
+		for _, link := range t.Links {
+			status, ok := t.Result[link]
+			if !ok || LinkHost(link) != host {
+				continue
+			}
+			if !counted {
+				sum.Tasks++
+				counted = true
+			}
+			urls[link] = struct{}{}
+			sum.Checks++
+			at := t.CompletedAt
+			if timing, ok := t.Timings[link]; ok && !timing.CheckedAt.IsZero() {
+				at = timing.CheckedAt
+				latency += timing.DurationMS
+				timed++
+			}
+			if at.After(sum.LastChecked) {
+				sum.LastChecked = at
+			}
+			switch LinkStatus(status) {
+			case StatusAvailable:
+				sum.Available++
+			case StatusMaintenance:
+				sum.Maintenance++
+			default:
+				sum.Broken++
+				if sum.LastFailure == nil || at.After(sum.LastFailure.At) {
+					sum.LastFailure = &DomainFailure{URL: link, Status: status, TaskID: t.ID, At: at}
+				}
+			}
+		}
+	}
+	sum.URLs = len(urls)
+	if n := sum.Checks - sum.Maintenance; n > 0 {
+		sum.AvailabilityRatio = math.Round(float64(sum.Available)/float64(n)*1e4) / 1e4
+	}
+	if timed > 0 {
+		sum.AvgLatencyMS = math.Round(float64(latency)/float64(timed)*10) / 10
+	}
+	return sum
+}
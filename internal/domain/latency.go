@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// latencyGrowth is the ratio between the bounds of consecutive latency
+// buckets. As in an HDR histogram the error is relative: a percentile read
+// from the buckets is within 2% of the measured response time.
+const latencyGrowth = 1.04
+
+// LatencyBucket returns the histogram bucket counting response time d.
+// Bucket 0 holds times below a millisecond.
+func LatencyBucket(d time.Duration) int {
+	ms := float64(d) / float64(time.Millisecond)
+	if ms < 1 {
+		return 0
+	}
+	return int(math.Log(ms)/math.Log(latencyGrowth)) + 1
+}
+
+// bucketMS returns the middle of bucket b in milliseconds.
+func bucketMS(b int) float64 {
+	if b <= 0 {
+		return 0.5
+	}
+	lo := math.Pow(latencyGrowth, float64(b-1))
+	return math.Round(lo*(1+latencyGrowth)/2*10) / 10
+}
+
+// LatencyPercentiles summarizes response times in milliseconds.
+type LatencyPercentiles struct {
+	Samples uint64  `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P95     float64 `json:"p95_ms"`
+	P99     float64 `json:"p99_ms"`
+}
+
+// Percentiles computes the percentiles of the histograms added up; each
+// maps a LatencyBucket to the number of response times in it.
+func Percentiles(histograms ...map[int]uint64) LatencyPercentiles {
+	counts := make(map[int]uint64)
+	var res LatencyPercentiles
+	for _, h := range histograms {
+		for b, n := range h {
+			counts[b] += n
+			res.Samples += n
+		}
+	}
+	if res.Samples == 0 {
+		return res
+	}
+	buckets := make([]int, 0, len(counts))
+	for b := range counts {
+		buckets = append(buckets, b)
+	}
+	slices.Sort(buckets)
+
+	targets := []struct {
+		q   float64
+		out *float64
+	}{{0.50, &res.P50}, {0.95, &res.P95}, {0.99, &res.P99}}
+	var seen uint64
+	next := 0
+	for _, b := range buckets {
+		seen += counts[b]
+		for next < len(targets) && float64(seen) >= targets[next].q*float64(res.Samples) {
+			*targets[next].out = bucketMS(b)
+			next++
+		}
+	}
+	return res
+}
@@ -1,14 +1,193 @@
 package domain
 
+import "time"
+
 type LinkStatus string
 
 const (
 	StatusAvailable    LinkStatus = "available"
 	StatusNotAvailable LinkStatus = "not available"
+	// StatusMaintenance marks links not checked because their host was in a
+	// maintenance window; they count neither as available nor as broken.
+	StatusMaintenance LinkStatus = "maintenance"
+	// StatusFlaggedUnsafe marks links a blocklist lists as malware or
+	// phishing; they are never requested and count as broken.
+	StatusFlaggedUnsafe LinkStatus = "flagged_unsafe"
 )
 
+// LinkResult is the outcome of checking one link.
+type LinkResult struct {
+	Status LinkStatus `json:"status"`
+	// Protocol is the HTTP version the host answered with, e.g. "HTTP/2.0";
+	// empty when it did not respond.
+	Protocol string `json:"protocol,omitempty"`
+	// HTTP3 reports whether the host also answered over HTTP/3; nil when the
+	// HTTP/3 probe is disabled or the host did not respond.
+	HTTP3 *bool `json:"http3,omitempty"`
+	// Skipped is set when the link was not requested because the check timed
+	// out or stopped early; Status is then "not available".
+	Skipped bool `json:"skipped,omitempty"`
+	// StatusCode is the HTTP status of the last response and Location the
+	// target of a redirect that was not followed.
+	StatusCode int    `json:"status_code,omitempty"`
+	Location   string `json:"location,omitempty"`
+	// Upgraded is set when an http:// link was checked over HTTPS as its
+	// host supports it, and Downgraded when HTTPS failed and the link was
+	// checked over plain HTTP; see HTTPS_POLICY.
+	Upgraded   bool `json:"upgraded,omitempty"`
+	Downgraded bool `json:"downgraded,omitempty"`
+	// Unchanged is set when a conditional re-check got 304 Not Modified.
+	Unchanged bool `json:"unchanged,omitempty"`
+	// Findings lists problems in the page content when content checks were
+	// requested.
+	Findings []Finding `json:"findings,omitempty"`
+	// Weight estimates the size of the page and its subresources when
+	// content checks were requested.
+	Weight *PageWeight `json:"weight,omitempty"`
+	// Security rates the security headers of HTTPS pages when security
+	// checks were requested.
+	Security *SecurityAudit `json:"security,omitempty"`
+	// RemoteIP is the address the last request of an HTTP check connected
+	// to and IPFamily its family, ipv4 or ipv6; neither is stored.
+	RemoteIP string `json:"remote_ip,omitempty"`
+	IPFamily string `json:"ip_family,omitempty"`
+	// Phases breaks the last request of an HTTP check down; it is not
+	// stored.
+	Phases *LinkPhases `json:"phases,omitempty"`
+	// Deliverability is set for mailto: links: deliverable, likely or
+	// undeliverable.
+	Deliverability string `json:"deliverability,omitempty"`
+	// Error describes why the link is not available and ErrorKind classifies
+	// it: invalid_link, private_address, circuit_open, dns, timeout, tls,
+	// connection, http_status, redirect, redirect_loop, canceled, skipped,
+	// skipped_robots, undeliverable, rejected or flagged_unsafe.
+	Error     string `json:"error,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"`
+	LinkTiming
+}
+
+// Finding is a problem found in the content of a checked page: a
+// mixed_content subresource or a broken canonical or hreflang link. Detail
+// says where it was found or what is wrong.
+type Finding struct {
+	Kind   string `json:"kind"`
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// PageWeight is a lightweight performance audit of a checked page: its size,
+// the number of subresources it loads and the estimated total transfer.
+// UnknownSize counts subresources whose size could not be determined.
+type PageWeight struct {
+	HTMLBytes     int64 `json:"html_bytes"`
+	Resources     int   `json:"resources"`
+	ResourceBytes int64 `json:"resource_bytes"`
+	UnknownSize   int   `json:"unknown_size,omitempty"`
+	TotalBytes    int64 `json:"total_bytes"`
+}
+
+// LinkPhases are the DNS lookup, TCP connect, TLS handshake and time from
+// sending the request to the first byte of the response, in milliseconds.
+// The first three are zero when a kept-alive connection was reused, and a
+// phase the request failed in or never reached is zero too: a timeout with
+// connect_ms but no ttfb_ms means the server accepted the connection and
+// did not answer.
+type LinkPhases struct {
+	DNSMS     float64 `json:"dns_ms"`
+	ConnectMS float64 `json:"connect_ms"`
+	TLSMS     float64 `json:"tls_ms"`
+	TTFBMS    float64 `json:"ttfb_ms"`
+}
+
+// SecurityAudit rates the security headers of an HTTPS page from 0 to 100:
+// Strict-Transport-Security, Content-Security-Policy, X-Content-Type-Options
+// and X-Frame-Options are worth 25 points each when present and sound.
+// Issues says what is wrong with the others.
+type SecurityAudit struct {
+	Score  int      `json:"score"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+// LinkTiming records when a link was checked and how long the request took.
+type LinkTiming struct {
+	CheckedAt  time.Time `json:"checked_at,omitzero"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
 type Task struct {
-	ID     int               `json:"id"`
+	ID        int      `json:"id"`
+	Name      string   `json:"name,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	CreatedBy string   `json:"created_by,omitempty"`
+	// Owner is the principal that submitted the task. A caller scoped to an
+	// owner sees only that owner's tasks, so tasks without one are visible
+	// only to unscoped callers and admins.
+	Owner  string            `json:"owner,omitempty"`
 	Links  []string          `json:"links"`
 	Result map[string]string `json:"result"`
+	// Timings holds the check time of every link in Result that reached the
+	// network.
+	Timings map[string]LinkTiming `json:"timings,omitempty"`
+	// Findings holds the content findings of links checked with content
+	// checks; links without findings are absent.
+	Findings map[string][]Finding `json:"findings,omitempty"`
+	// Security holds the security header audits of HTTPS links checked with
+	// security checks.
+	Security map[string]SecurityAudit `json:"security,omitempty"`
+	// BatchID is the batch a large submission was split into, zero for tasks
+	// submitted on their own.
+	BatchID int `json:"batch_id,omitempty"`
+	// Version is incremented on every change and used for optimistic concurrency.
+	Version   int       `json:"version,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// CompletedAt is set once the final result has been stored; until then
+	// Result may hold only the links checked so far.
+	CompletedAt time.Time `json:"completed_at,omitzero"`
+}
+
+// HasTag reports whether the task is labelled with tag.
+func (t *Task) HasTag(tag string) bool {
+	for _, v := range t.Tags {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch groups the tasks a submission over the per-request link limit was
+// split into. Its ID is taken from the same sequence as task IDs.
+type Batch struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	TaskIDs   []int     `json:"task_ids"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// Batch statuses reported in BatchSummary.
+const (
+	BatchPending   = "pending"
+	BatchRunning   = "running"
+	BatchCompleted = "completed"
+	// BatchInterrupted is an unfinished batch no longer being checked, e.g.
+	// after a restart.
+	BatchInterrupted = "interrupted"
+)
+
+// BatchSummary is the progress of a batch across its tasks. Tasks deleted
+// since the batch was created are not counted.
+type BatchSummary struct {
+	Batch
+	Status         string `json:"status"`
+	TasksCompleted int    `json:"tasks_completed"`
+	Links          int    `json:"links"`
+	Checked        int    `json:"checked"`
+	Available      int    `json:"available"`
+	Broken         int    `json:"broken"`
+	Maintenance    int    `json:"maintenance,omitempty"`
+	// CompletedAt is when the last task finished, once all have.
+	CompletedAt time.Time `json:"completed_at,omitzero"`
 }
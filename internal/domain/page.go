@@ -0,0 +1,31 @@
+package domain
+
+// PageOf returns t with only limit of its links from offset and the
+// results of those links, without copying the rest of its maps. Results are
+// shared with t. A non-positive limit keeps every link from offset.
+func PageOf(t *Task, offset, limit int) *Task {
+	links := t.Links[min(max(offset, 0), len(t.Links)):]
+	if limit > 0 && limit < len(links) {
+		links = links[:limit]
+	}
+	page := *t
+	page.Links = links
+	page.Result = pick(t.Result, links)
+	page.Timings = pick(t.Timings, links)
+	page.Findings = pick(t.Findings, links)
+	page.Security = pick(t.Security, links)
+	return &page
+}
+
+func pick[V any](src map[string]V, links []string) map[string]V {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]V, min(len(links), len(src)))
+	for _, link := range links {
+		if v, ok := src[link]; ok {
+			dst[link] = v
+		}
+	}
+	return dst
+}
@@ -0,0 +1,126 @@
+package domain
+
+import "time"
+
+// TaskProgress counts the links of a task checked so far. ETAMS estimates
+// the milliseconds left from the pace of the checks; it is zero once the
+// task is completed or before its first link is checked.
+type TaskProgress struct {
+	TaskID      int   `json:"task_id"`
+	Checked     int   `json:"checked"`
+	Total       int   `json:"total"`
+	Available   int   `json:"available"`
+	Failed      int   `json:"failed"`
+	Maintenance int   `json:"maintenance,omitempty"`
+	Completed   bool  `json:"completed"`
+	ETAMS       int64 `json:"eta_ms,omitempty"`
+}
+
+// ProgressOf computes the progress of t at now from the link results stored
+// as they come in.
+func ProgressOf(t *Task, now time.Time) TaskProgress {
+	p := TaskProgress{TaskID: t.ID, Total: len(t.Links), Completed: !t.CompletedAt.IsZero()}
+	var start time.Time
+	for _, link := range t.Links {
+		status, ok := t.Result[link]
+		if !ok {
+			continue
+		}
+		p.Checked++
+		switch status {
+		case string(StatusAvailable):
+			p.Available++
+		case string(StatusMaintenance):
+			p.Maintenance++
+		default:
+			p.Failed++
+		}
+		// задачи пакета ждут в очереди, темп считаем с первой проверки
+		if at := t.Timings[link].CheckedAt; !at.IsZero() && (start.IsZero() || at.Before(start)) {
+			start = at
+		}
+	}
+	if start.IsZero() {
+		start = t.CreatedAt
+	}
+	if p.Completed || p.Checked == 0 || start.IsZero() || p.Checked >= p.Total {
+		return p
+	}
+	elapsed := now.Sub(start)
+	if elapsed > 0 {
+		p.ETAMS = (elapsed * time.Duration(p.Total-p.Checked) / time.Duration(p.Checked)).Milliseconds()
+	}
+	return p
+}
+
+// ResultCounts tallies the results of a set of links. Links without a
+// result are not counted as checked. ByErrorKind splits Broken by the
+// error kind of the links where it is known, i.e. for fresh results only.
+type ResultCounts struct {
+	Links       int            `json:"links"`
+	Checked     int            `json:"checked"`
+	Available   int            `json:"available"`
+	Broken      int            `json:"broken"`
+	Maintenance int            `json:"maintenance,omitempty"`
+	ByErrorKind map[string]int `json:"by_error_kind,omitempty"`
+}
+
+// CountResults counts the results of links.
+func CountResults[S ~string](links []string, result map[string]S) ResultCounts {
+	c := ResultCounts{Links: len(links)}
+	for _, link := range links {
+		status, ok := result[link]
+		if !ok {
+			continue
+		}
+		c.Checked++
+		switch LinkStatus(status) {
+		case StatusAvailable:
+			c.Available++
+		case StatusMaintenance:
+			c.Maintenance++
+		default:
+			c.Broken++
+		}
+	}
+	return c
+}
+
+// CountLinkResults is CountResults for full results, with the broken links
+// also counted by error kind.
+func CountLinkResults(links []string, results map[string]LinkResult) ResultCounts {
+	statuses := make(map[string]LinkStatus, len(results))
+	for link, res := range results {
+		statuses[link] = res.Status
+	}
+	c := CountResults(links, statuses)
+	for _, link := range links {
+		res, ok := results[link]
+		if !ok || res.ErrorKind == "" || res.Status == StatusAvailable || res.Status == StatusMaintenance {
+			continue
+		}
+		if c.ByErrorKind == nil {
+			c.ByErrorKind = make(map[string]int)
+		}
+		c.ByErrorKind[res.ErrorKind]++
+	}
+	return c
+}
+
+// CountStatuses builds the counts of a task with links links from the
+// number of its results per status.
+func CountStatuses(links int, statuses map[string]int) ResultCounts {
+	c := ResultCounts{Links: links}
+	for status, n := range statuses {
+		c.Checked += n
+		switch LinkStatus(status) {
+		case StatusAvailable:
+			c.Available += n
+		case StatusMaintenance:
+			c.Maintenance += n
+		default:
+			c.Broken += n
+		}
+	}
+	return c
+}
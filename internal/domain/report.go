@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// LinksReport is the content of a links report, whatever format it is
+// rendered in.
+type LinksReport struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Tasks       []ReportTask `json:"tasks"`
+	// Hosts adds up the results of all tasks per host, ordered by host.
+	Hosts []HostSummary `json:"hosts"`
+	// Total adds up Hosts; its Host is empty.
+	Total HostSummary `json:"total"`
+	// MissingIDs lists requested tasks that were not found.
+	MissingIDs []int `json:"missing_ids,omitempty"`
+	// Legend explains which HTTP responses count as available.
+	Legend []string `json:"legend,omitempty"`
+}
+
+// ReportTask is a task of a report with its links in submission order.
+type ReportTask struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	CreatedBy   string       `json:"created_by,omitempty"`
+	CreatedAt   time.Time    `json:"created_at,omitzero"`
+	CompletedAt time.Time    `json:"completed_at,omitzero"`
+	Links       []ReportLink `json:"links"`
+}
+
+// ReportLink is the result of one link. Links without a result are
+// reported as not available; CheckedAt is zero for links that did not
+// reach the network.
+type ReportLink struct {
+	URL      string     `json:"url"`
+	Status   LinkStatus `json:"status"`
+	Findings []Finding  `json:"findings,omitempty"`
+	// Security is set for links whose security headers were rated.
+	Security *SecurityAudit `json:"security,omitempty"`
+	LinkTiming
+}
+
+// BuildLinksReport assembles the report of tasks as of now, with every
+// time in loc.
+func BuildLinksReport(tasks []*Task, now time.Time, loc *time.Location) *LinksReport {
+	in := func(t time.Time) time.Time {
+		if t.IsZero() {
+			return t
+		}
+		return t.In(loc)
+	}
+	r := &LinksReport{GeneratedAt: in(now), Tasks: make([]ReportTask, 0, len(tasks))}
+	for _, t := range tasks {
+		rt := ReportTask{
+			ID:          t.ID,
+			Name:        t.Name,
+			Tags:        t.Tags,
+			CreatedBy:   t.CreatedBy,
+			CreatedAt:   in(t.CreatedAt),
+			CompletedAt: in(t.CompletedAt),
+			Links:       make([]ReportLink, 0, len(t.Links)),
+		}
+		for _, link := range t.Links {
+			status := LinkStatus(t.Result[link])
+			if status == "" {
+				status = StatusNotAvailable
+			}
+			timing := t.Timings[link]
+			timing.CheckedAt = in(timing.CheckedAt)
+			rl := ReportLink{URL: link, Status: status, Findings: t.Findings[link], LinkTiming: timing}
+			if a, ok := t.Security[link]; ok {
+				rl.Security = &a
+			}
+			rt.Links = append(rt.Links, rl)
+		}
+		r.Tasks = append(r.Tasks, rt)
+	}
+	r.Hosts = summarizeTasks(tasks)
+	for _, h := range r.Hosts {
+		r.Total.Checked += h.Checked
+		r.Total.Available += h.Available
+		r.Total.Broken += h.Broken
+		r.Total.Maintenance += h.Maintenance
+	}
+	return r
+}
+
+// summarizeTasks adds up per-host counts of all tasks; a link checked by
+// several tasks is counted once per task.
+func summarizeTasks(tasks []*Task) []HostSummary {
+	all := []HostSummary{}
+	index := make(map[string]int)
+	for _, t := range tasks {
+		for _, s := range SummarizeByHost(t.Links, t.Result) {
+			i, ok := index[s.Host]
+			if !ok {
+				index[s.Host] = len(all)
+				all = append(all, s)
+				continue
+			}
+			all[i].Checked += s.Checked
+			all[i].Available += s.Available
+			all[i].Broken += s.Broken
+			all[i].Maintenance += s.Maintenance
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Host < all[j].Host })
+	return all
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// SLAIncident is a period a URL was not available: from the first failed
+// check to the next successful one, clipped to the reported period. Open is
+// set when no successful check followed before the end of the period.
+type SLAIncident struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Open  bool      `json:"open,omitempty"`
+}
+
+// Minutes returns the length of the incident in minutes.
+func (i SLAIncident) Minutes() float64 {
+	return i.End.Sub(i.Start).Minutes()
+}
+
+// SLAEntry is the uptime of one URL over the reported period, measured
+// from its first check in or before the period.
+type SLAEntry struct {
+	URL             string        `json:"url"`
+	Checks          int           `json:"checks"`
+	Failed          int           `json:"failed"`
+	UptimePercent   float64       `json:"uptime_percent"`
+	DowntimeMinutes float64       `json:"downtime_minutes"`
+	Incidents       []SLAIncident `json:"incidents"`
+	// Latency holds the recent response times of monitored URLs.
+	Latency *LatencyPercentiles `json:"latency,omitempty"`
+}
+
+// SLAReport lists the uptime of every URL checked in [From, To).
+type SLAReport struct {
+	From    time.Time  `json:"from"`
+	To      time.Time  `json:"to"`
+	Entries []SLAEntry `json:"entries"`
+}
+
+type slaCheck struct {
+	at        time.Time
+	available bool
+}
+
+// BuildSLAReport computes the uptime of the links of tasks over [from, to)
+// from their timed check results, ordered by URL. Checks before from only
+// set the state the period starts in, and links skipped for maintenance are
+// left out. A period still running ends at now. When urls is not empty, only
+// those links are reported.
+func BuildSLAReport(tasks []*Task, from, to, now time.Time, urls []string) *SLAReport {
+	end := to
+	if now.Before(end) {
+		end = now
+	}
+	wanted := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		wanted[u] = true
+	}
+
+	checks := make(map[string][]slaCheck)
+	for _, t := range tasks {
+		for link, status := range t.Result {
+			timing, ok := t.Timings[link]
+			if !ok || timing.CheckedAt.IsZero() || !timing.CheckedAt.Before(end) || len(wanted) > 0 && !wanted[link] || status == string(StatusMaintenance) {
+				continue
+			}
+			checks[link] = append(checks[link], slaCheck{at: timing.CheckedAt, available: status == string(StatusAvailable)})
+		}
+	}
+
+	report := &SLAReport{From: from, To: to, Entries: []SLAEntry{}}
+	for link, cs := range checks {
+		if e, ok := slaEntry(link, cs, from, end); ok {
+			report.Entries = append(report.Entries, e)
+		}
+	}
+	sort.Slice(report.Entries, func(i, j int) bool { return report.Entries[i].URL < report.Entries[j].URL })
+	return report
+}
+
+// slaEntry replays the checks of one URL; ok is false when the URL was not
+// checked in the period nor before it.
+func slaEntry(link string, cs []slaCheck, from, end time.Time) (SLAEntry, bool) {
+	sort.Slice(cs, func(i, j int) bool { return cs[i].at.Before(cs[j].at) })
+	e := SLAEntry{URL: link, Incidents: []SLAIncident{}}
+
+	var (
+		start   time.Time // начало наблюдения в периоде
+		down    bool
+		downAt  time.Time
+		started bool
+	)
+	for _, c := range cs {
+		at := c.at
+		if at.Before(from) {
+			// проверки до периода задают только начальное состояние
+			started, start = true, from
+			down, downAt = !c.available, from
+			continue
+		}
+		if !started {
+			started, start = true, at
+		}
+		e.Checks++
+		switch {
+		case !c.available:
+			e.Failed++
+			if !down {
+				down, downAt = true, at
+			}
+		case down:
+			down = false
+			e.Incidents = append(e.Incidents, SLAIncident{Start: downAt, End: at})
+		}
+	}
+	if !started || e.Checks == 0 && !down {
+		return e, false
+	}
+	if down {
+		e.Incidents = append(e.Incidents, SLAIncident{Start: downAt, End: end, Open: true})
+	}
+
+	for _, inc := range e.Incidents {
+		e.DowntimeMinutes += inc.Minutes()
+	}
+	observed := end.Sub(start).Minutes()
+	e.UptimePercent = 100
+	if observed > 0 {
+		e.UptimePercent = 100 * (1 - e.DowntimeMinutes/observed)
+	} else if down {
+		e.UptimePercent = 0
+	}
+	return e, true
+}
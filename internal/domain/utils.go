@@ -10,3 +10,37 @@ func CopyStringMap(src map[string]string) map[string]string {
 	}
 	return dst
 }
+
+func CopyTimings(src map[string]LinkTiming) map[string]LinkTiming {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]LinkTiming, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func CopyFindings(src map[string][]Finding) map[string][]Finding {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string][]Finding, len(src))
+	for k, v := range src {
+		dst[k] = append([]Finding(nil), v...)
+	}
+	return dst
+}
+
+func CopySecurity(src map[string]SecurityAudit) map[string]SecurityAudit {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]SecurityAudit, len(src))
+	for k, v := range src {
+		v.Issues = append([]string(nil), v.Issues...)
+		dst[k] = v
+	}
+	return dst
+}
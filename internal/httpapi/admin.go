@@ -0,0 +1,181 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+const maxImportBytes = 512 << 20
+
+type ImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// Export streams every stored task as a JSON array independent of the on-disk log format.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tasks, err := h.svc.ListTasks(r.Context(), "", "")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", "attachment; filename=tasks-export.json")
+	_, _ = w.Write([]byte("["))
+	enc := json.NewEncoder(w)
+	for i, t := range tasks {
+		if i > 0 {
+			_, _ = w.Write([]byte(","))
+		}
+		// выгрузка — резервная копия, время оставляем в UTC
+		if err := enc.Encode(taskResponse(t, time.UTC)); err != nil {
+			return
+		}
+	}
+	_, _ = w.Write([]byte("]\n"))
+}
+
+// Import loads a dump produced by Export. Existing task IDs are rejected with 409.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var dump []TaskResponse
+	if err := json.NewDecoder(r.Body).Decode(&dump); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	tasks := make([]*domain.Task, 0, len(dump))
+	for _, t := range dump {
+		if t.ID <= 0 || len(t.Links) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tasks = append(tasks, &domain.Task{
+			ID:          t.ID,
+			Name:        t.Name,
+			Tags:        t.Tags,
+			CreatedBy:   t.CreatedBy,
+			Owner:       t.Owner,
+			Links:       t.Links,
+			Result:      t.Result,
+			Timings:     t.Timings,
+			Findings:    t.Findings,
+			Security:    t.Security,
+			BatchID:     t.BatchID,
+			Version:     t.Version,
+			CreatedAt:   t.CreatedAt,
+			CompletedAt: t.CompletedAt,
+		})
+	}
+
+	if err := h.svc.ImportTasks(r.Context(), tasks); err != nil {
+		if errors.Is(err, ports.ErrTaskExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ImportResponse{Imported: len(tasks)})
+}
+
+type BreakerResponse struct {
+	Hosts []service.BreakerHost `json:"hosts"`
+}
+
+// Breaker lists circuit breaker state on GET and resets it on DELETE
+// (?host= limits the reset to one host).
+func (h *Handler) Breaker(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		h.svc.ResetBreaker(strings.TrimSpace(r.URL.Query().Get("host")))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(BreakerResponse{Hosts: h.svc.BreakerHosts()})
+}
+
+// RateLimiterState describes the clients tracked by the in-memory rate
+// limiters.
+type RateLimiterState struct {
+	Clients int    `json:"clients"`
+	Expired uint64 `json:"expired"`
+	Evicted uint64 `json:"evicted"`
+}
+
+// StateResponse is the diagnostic snapshot of GET /admin/state.
+type StateResponse struct {
+	service.State
+	// RateLimiter is nil unless limits are kept in memory.
+	RateLimiter *RateLimiterState `json:"rate_limiter,omitempty"`
+}
+
+// Snapshot returns the current diagnostic state.
+func (h *Handler) Snapshot() StateResponse {
+	resp := StateResponse{State: h.svc.State()}
+	if h.limiterStats != nil {
+		st := h.limiterStats()
+		resp.RateLimiter = &st
+	}
+	return resp
+}
+
+// State serves Snapshot for debugging instances that seem stuck.
+func (h *Handler) State(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.Snapshot())
+}
+
+// Cleanup deletes tasks created before ?before= (RFC 3339). It serves
+// POST /admin/cleanup and DELETE /tasks, which are admin routes.
+func (h *Handler) Cleanup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	h.deleteTasks(w, r)
+}
+
+// Reload re-reads configuration and applies runtime-tunable settings.
+func (h *Handler) Reload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.reload == nil {
+		http.Error(w, "reload is not supported", http.StatusNotFound)
+		return
+	}
+	if err := h.reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
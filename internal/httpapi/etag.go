@@ -0,0 +1,36 @@
+package httpapi
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+)
+
+// taskETag identifies the representation of t at its current version.
+// Other fields or pages of links are different representations, told apart
+// by variant, and get their own tag.
+func taskETag(t *domain.Task, variant string) string {
+	if variant != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(variant))
+		return fmt.Sprintf(`"%d-%d-%08x"`, t.ID, t.Version, h.Sum32())
+	}
+	return fmt.Sprintf(`"%d-%d"`, t.ID, t.Version)
+}
+
+// etagMatch reports whether an If-None-Match header lists etag. Weak
+// comparison is used, as is usual for GET.
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
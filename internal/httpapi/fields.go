@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Field sets of verbosity=summary: identifiers, metadata and counts, but
+// no per-link data.
+var (
+	taskSummaryFields  = []string{"id", "name", "tags", "created_by", "owner", "batch_id", "version", "created_at", "completed_at", "summary"}
+	linksSummaryFields = []string{"task_id", "links_count", "links_num", "persisted", "deduplicated", "monitor", "truncated", "summary"}
+)
+
+// responseView selects the top-level fields of the objects in a response.
+// A nil fields set keeps every field.
+type responseView struct {
+	fields map[string]bool
+}
+
+// parseView reads ?fields=a,b and ?verbosity=summary|full for responses
+// of type T; fields takes precedence over verbosity. Unknown names are
+// reported as field errors.
+func parseView[T any](q url.Values, summary []string) (responseView, []FieldError) {
+	var errs []FieldError
+	known := jsonFields(reflect.TypeFor[T]())
+	var names []string
+	switch v := q.Get("verbosity"); v {
+	case "", "full":
+	case "summary":
+		names = summary
+	default:
+		errs = append(errs, FieldError{Field: "verbosity", Value: v, Reason: "want summary or full"})
+	}
+	if v := q.Get("fields"); v != "" {
+		names = nil
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !known[name] {
+				errs = append(errs, FieldError{Field: "fields", Value: name, Reason: "unknown field"})
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	if names == nil {
+		return responseView{}, errs
+	}
+	view := responseView{fields: make(map[string]bool, len(names))}
+	for _, name := range names {
+		view.fields[name] = true
+	}
+	return view, errs
+}
+
+// apply returns v with only the selected fields, or v itself when the
+// view keeps every field.
+func (rv responseView) apply(v any) (any, error) {
+	if rv.fields == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name := range all {
+		if !rv.fields[name] {
+			delete(all, name)
+		}
+	}
+	return all, nil
+}
+
+// key identifies the selected fields, empty when every field is kept.
+func (rv responseView) key() string {
+	if rv.fields == nil {
+		return ""
+	}
+	names := make([]string, 0, len(rv.fields))
+	for name := range rv.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// jsonFields lists the JSON names of the fields of struct type t.
+func jsonFields(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" && f.IsExported() {
+			names[name] = true
+		}
+	}
+	return names
+}
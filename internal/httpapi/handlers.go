@@ -1,46 +1,291 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/olgkv/linkchecker/internal/auth"
 	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/maintenance"
+	"github.com/olgkv/linkchecker/internal/quota"
 	"github.com/olgkv/linkchecker/internal/service"
 )
 
 type contextKey struct{ name string }
 
-var LinksNumContextKey = &contextKey{name: "links_num"}
+// TaskIDContextKey holds the id of the task created by POST /links.
+var TaskIDContextKey = &contextKey{name: "task_id"}
 
-const reportGenerationTimeout = 30 * time.Second
+// LinksNumContextKey is the old name of TaskIDContextKey.
+//
+// Deprecated: use TaskIDContextKey.
+var LinksNumContextKey = TaskIDContextKey
+
+// LinksRecorder is implemented by response writers that want the outcome
+// of a POST /links request, e.g. for the request log in outer middlewares.
+type LinksRecorder interface {
+	RecordLinks(taskID int, summary domain.ResultCounts)
+}
+
+// RequestIDContextKey holds the ID of the API request, a string, which is
+// passed on to the checks it submits.
+var RequestIDContextKey = &contextKey{name: "request_id"}
 
 type LinksRequest struct {
 	Links []string `json:"links"`
+	Name  string   `json:"name,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// FailAfter stops the check after this many links are not available.
+	FailAfter int `json:"fail_after,omitempty"`
+	// FollowRedirects set to false reports redirects instead of following
+	// them. Redirects are followed by default.
+	FollowRedirects *bool `json:"follow_redirects,omitempty"`
+	// Content also parses available HTML pages and reports findings such
+	// as mixed content.
+	Content bool `json:"content,omitempty"`
+	// Security rates the security headers of available HTTPS pages.
+	Security bool `json:"security,omitempty"`
+	// Cookies keeps cookies set by the checked sites for the rest of the
+	// batch.
+	Cookies bool `json:"cookies,omitempty"`
+	// Auth authenticates the check requests; it is never stored.
+	Auth *LinksAuth `json:"auth,omitempty"`
+	// Notify lists targets such as "slack:#alerts" to post a summary to
+	// when links are broken.
+	Notify []string `json:"notify,omitempty"`
+	// Priority is high, normal (the default) or low; with a shared worker
+	// pool, higher priority checks run first.
+	Priority string `json:"priority,omitempty"`
+	// Monitor keeps re-checking the links of the task at an interval.
+	Monitor *MonitorRequest `json:"monitor,omitempty"`
+}
+
+// MonitorRequest turns a task into a monitor; Interval is a duration such
+// as "5m". Overlap is what a run that comes due while the previous one is
+// still going does: "skip" (the default), "queue" or "concurrent".
+type MonitorRequest struct {
+	Interval string `json:"interval"`
+	Overlap  string `json:"overlap,omitempty"`
+}
+
+// LinksAuth holds credentials for checking links behind a login: Username
+// and Password with type "basic", Token with type "bearer". They are sent
+// only to Hosts.
+type LinksAuth struct {
+	Type     string   `json:"type"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Token    string   `json:"token,omitempty"`
+	Hosts    []string `json:"hosts"`
+}
+
+// credentials validates a and converts it for the service.
+func (a *LinksAuth) credentials() (*service.Credentials, bool) {
+	if len(a.Hosts) == 0 || slices.Contains(a.Hosts, "") {
+		return nil, false
+	}
+	switch a.Type {
+	case "basic":
+		return &service.Credentials{Username: a.Username, Password: a.Password, Hosts: a.Hosts}, a.Username != ""
+	case "bearer":
+		return &service.Credentials{Token: a.Token, Hosts: a.Hosts}, a.Token != ""
+	default:
+		return nil, false
+	}
 }
 
 type LinksResponse struct {
-	Links     map[string]domain.LinkStatus `json:"links"`
-	LinksNum  int                          `json:"links_num"`
-	Persisted bool                         `json:"persisted"`
+	Links map[string]domain.LinkStatus `json:"links"`
+	// TaskID is the task the links were checked in and LinksCount the
+	// number of links it checked.
+	TaskID     int `json:"task_id"`
+	LinksCount int `json:"links_count"`
+	// LinksNum repeats TaskID for clients written against its old name.
+	//
+	// Deprecated: use TaskID; links_num goes away with the next API version.
+	LinksNum  int  `json:"links_num"`
+	Persisted bool `json:"persisted"`
+	// Details adds the negotiated protocol and HTTP/3 support per link.
+	Details map[string]domain.LinkResult `json:"details,omitempty"`
+	// Deduplicated is set when the results come from an identical batch
+	// checked recently; TaskID then refers to that earlier task.
+	Deduplicated bool `json:"deduplicated,omitempty"`
+	// Hosts groups the results by hostname when requested with group_by=host.
+	Hosts []HostGroup `json:"hosts,omitempty"`
+	// Monitor is the monitor created for the task, if asked for.
+	Monitor *MonitorResponse `json:"monitor,omitempty"`
+	// Truncated is set when partial=true cut the submission down to the
+	// per-request limit.
+	Truncated *Truncation `json:"truncated,omitempty"`
+	// Summary counts the results of the checked links.
+	Summary domain.ResultCounts `json:"summary"`
+}
+
+// Truncation reports the links of a partial=true submission left out of
+// the check, and the batch they were queued in with queue_remainder=true.
+// Error is set instead of the batch when queueing failed.
+type Truncation struct {
+	Submitted int    `json:"submitted"`
+	Checked   int    `json:"checked"`
+	Remaining int    `json:"remaining"`
+	BatchID   int    `json:"batch_id,omitempty"`
+	Tasks     []int  `json:"tasks,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HostGroup holds the results of one host together with its subtotals.
+type HostGroup struct {
+	domain.HostSummary
+	Links map[string]domain.LinkStatus `json:"links"`
+}
+
+// BatchResponse answers a submission that was split into a batch; the
+// tasks are checked in the background.
+type BatchResponse struct {
+	BatchID int   `json:"batch_id"`
+	Tasks   []int `json:"tasks"`
+	Links   int   `json:"links"`
 }
 
 type ReportRequest struct {
-	LinksList []int `json:"links_list"`
+	LinksList []int  `json:"links_list"`
+	Tag       string `json:"tag,omitempty"`
+	// Batch adds the tasks of a batch to LinksList.
+	Batch int `json:"batch,omitempty"`
+	// Format is pdf (the default), json with the data behind the PDF, or
+	// bundle, a ZIP archive with the PDF, CSV and JSON versions.
+	Format string `json:"format,omitempty"`
+	// Password encrypts the PDF; it is never stored or logged.
+	Password string `json:"password,omitempty"`
+}
+
+// SLAReportRequest asks for the uptime of checked URLs over a calendar
+// month given as "2024-05", in the time zone of responses.
+type SLAReportRequest struct {
+	Month  string   `json:"month"`
+	URLs   []string `json:"urls,omitempty"`
+	Tag    string   `json:"tag,omitempty"`
+	Format string   `json:"format,omitempty"`
+}
+
+type TaskResponse struct {
+	ID          int                             `json:"id"`
+	Name        string                          `json:"name,omitempty"`
+	Tags        []string                        `json:"tags,omitempty"`
+	CreatedBy   string                          `json:"created_by,omitempty"`
+	Owner       string                          `json:"owner,omitempty"`
+	Links       []string                        `json:"links"`
+	Result      map[string]string               `json:"result"`
+	Timings     map[string]domain.LinkTiming    `json:"timings,omitempty"`
+	Findings    map[string][]domain.Finding     `json:"findings,omitempty"`
+	Security    map[string]domain.SecurityAudit `json:"security,omitempty"`
+	BatchID     int                             `json:"batch_id,omitempty"`
+	Version     int                             `json:"version"`
+	CreatedAt   time.Time                       `json:"created_at,omitzero"`
+	CompletedAt time.Time                       `json:"completed_at,omitzero"`
+	// Summary counts the results; it is left out of admin exports.
+	Summary *domain.ResultCounts `json:"summary,omitempty"`
+	// Page is set when GET /tasks/{id} returns only some of the links.
+	Page *LinksPage `json:"page,omitempty"`
+}
+
+// LinksPage locates the links of a paged task among all of its links.
+type LinksPage struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit,omitempty"`
+	Total  int `json:"total"`
+}
+
+type TasksResponse struct {
+	Tasks []TaskResponse `json:"tasks"`
 }
 
 type Handler struct {
-	svc      *service.Service
-	maxLinks int
+	svc           *service.Service
+	maxLinks      atomic.Int64
+	maxBatchLinks atomic.Int64
+	maxUpload     atomic.Int64
+	quota         *quota.Tracker
+	maintenance   *maintenance.Schedule
+	reload        func() error
+	limiterStats  func() RateLimiterState
+	// loc is the time zone of the times in responses.
+	loc *time.Location
 }
 
 func NewHandler(svc *service.Service, maxLinks int) *Handler {
 	if maxLinks <= 0 {
 		maxLinks = 50
 	}
-	return &Handler{svc: svc, maxLinks: maxLinks}
+	h := &Handler{svc: svc, loc: time.UTC}
+	h.maxLinks.Store(int64(maxLinks))
+	h.maxUpload.Store(defaultMaxUploadBytes)
+	return h
+}
+
+// SetLocation renders the times of responses in loc instead of UTC. Call
+// it before serving requests.
+func (h *Handler) SetLocation(loc *time.Location) {
+	h.loc = loc
+}
+
+// localTime returns t in loc; zero stays zero.
+func localTime(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(loc)
+}
+
+// SetMaxLinks changes the per-request link limit; non-positive values are ignored.
+func (h *Handler) SetMaxLinks(maxLinks int) {
+	if maxLinks > 0 {
+		h.maxLinks.Store(int64(maxLinks))
+	}
+}
+
+// SetMaxBatchLinks lets /links accept up to maxLinks links by splitting
+// submissions over the per-request limit into a batch of tasks. Zero
+// rejects such submissions again; negative values are ignored.
+func (h *Handler) SetMaxBatchLinks(maxLinks int) {
+	if maxLinks >= 0 {
+		h.maxBatchLinks.Store(int64(maxLinks))
+	}
+}
+
+// SetMaxUploadBytes changes the size limit of link list files uploaded to
+// /links; non-positive values are ignored.
+func (h *Handler) SetMaxUploadBytes(n int) {
+	if n > 0 {
+		h.maxUpload.Store(int64(n))
+	}
+}
+
+// UseReloader enables POST /admin/reload, which calls fn.
+func (h *Handler) UseReloader(fn func() error) {
+	h.reload = fn
+}
+
+// UseRateLimiterStats adds the state of the in-memory rate limiters, as
+// returned by fn, to the /admin/state snapshot.
+func (h *Handler) UseRateLimiterStats(fn func() RateLimiterState) {
+	h.limiterStats = fn
+}
+
+// UseQuota enables per-principal link-check quotas on /links.
+func (h *Handler) UseQuota(t *quota.Tracker) {
+	h.quota = t
 }
 
 func (h *Handler) Links(w http.ResponseWriter, r *http.Request) {
@@ -49,72 +294,812 @@ func (h *Handler) Links(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
 	var req LinksRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if isUpload(r) {
+		maxFile := h.maxUpload.Load()
+		r.Body = http.MaxBytesReader(w, r.Body, maxFile+maxFormFieldBytes*4)
+		var err error
+		if req, err = decodeUpload(r, maxFile); err != nil {
+			if errors.Is(err, errUploadTooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				writeValidationError(w, "invalid upload", nil)
+			}
+			return
+		}
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeValidationError(w, "invalid JSON", nil)
+			return
+		}
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	partial, queueRest, errs := partialQuery(r.URL.Query())
+	view, viewErrs := parseView[LinksResponse](r.URL.Query(), linksSummaryFields)
+	errs = append(errs, viewErrs...)
+	if errs = append(errs, h.validateLinks(req, groupBy, partial)...); len(errs) > 0 {
+		writeValidationError(w, "invalid request", errs)
 		return
 	}
-	if len(req.Links) == 0 || len(req.Links) > h.maxLinks {
-		w.WriteHeader(http.StatusBadRequest)
+	limit := h.maxLinks.Load()
+	submitted := len(req.Links)
+	var rest []string
+	if partial && int64(submitted) > limit {
+		req.Links, rest = req.Links[:limit], req.Links[limit:]
+	}
+	batch := int64(len(req.Links)) > limit
+	var creds *service.Credentials
+	if req.Auth != nil {
+		creds, _ = req.Auth.credentials()
+	}
+	priority, _ := service.ParsePriority(req.Priority)
+
+	reserve := len(req.Links)
+	if queueRest {
+		reserve += len(rest)
+	}
+	if !h.reserveQuota(w, r, reserve) {
 		return
 	}
+	// на 500 резерв возвращается, кроме ссылок, уже поставленных в батч
+	failed := func(links int) {
+		h.releaseQuota(r, links)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 
-	id, result, err := h.svc.CheckLinks(r.Context(), req.Links)
+	opts := service.CheckOptions{
+		Name:        strings.TrimSpace(req.Name),
+		Tags:        normalizeTags(req.Tags),
+		FailAfter:   req.FailAfter,
+		NoRedirects: req.FollowRedirects != nil && !*req.FollowRedirects,
+		Content:     req.Content,
+		Security:    req.Security,
+		Cookies:     req.Cookies,
+		Credentials: creds,
+		Notify:      req.Notify,
+		Priority:    priority,
+	}
+	opts.RequestID, _ = r.Context().Value(RequestIDContextKey).(string)
+	if p, ok := auth.FromContext(r.Context()); ok {
+		opts.CreatedBy = p.Name
+		opts.Owner = p.Name
+	}
+	if batch {
+		h.submitBatch(w, r, req.Links, int(limit), opts)
+		return
+	}
+	id, result, err := h.svc.CheckLinks(r.Context(), req.Links, opts)
+	deduplicated := errors.Is(err, service.ErrDeduplicated)
+	if deduplicated {
+		err = nil
+	}
 	if err != nil && !errors.Is(err, service.ErrResultPersistDeferred) {
-		w.WriteHeader(http.StatusInternalServerError)
+		failed(reserve)
 		return
 	}
-	ctxWithNum := context.WithValue(r.Context(), LinksNumContextKey, id)
-	*r = *r.WithContext(ctxWithNum)
+	*r = *r.WithContext(context.WithValue(r.Context(), TaskIDContextKey, id))
 
-	resp := LinksResponse{Links: result, LinksNum: id, Persisted: err == nil}
+	statuses := make(map[string]domain.LinkStatus, len(result))
+	for link, res := range result {
+		statuses[link] = res.Status
+	}
+	resp := LinksResponse{
+		Links:        statuses,
+		TaskID:       id,
+		LinksCount:   len(req.Links),
+		LinksNum:     id,
+		Persisted:    err == nil,
+		Deduplicated: deduplicated,
+		Summary:      domain.CountLinkResults(req.Links, result),
+	}
+	if rec, ok := w.(LinksRecorder); ok {
+		rec.RecordLinks(id, resp.Summary)
+	}
+	if !deduplicated {
+		resp.Details = make(map[string]domain.LinkResult, len(result))
+		for link, res := range result {
+			res.CheckedAt = localTime(res.CheckedAt, h.loc)
+			resp.Details[link] = res
+		}
+	}
+	if groupBy == "host" {
+		resp.Hosts = groupByHost(req.Links, statuses)
+	}
+	if len(rest) > 0 {
+		resp.Truncated = &Truncation{Submitted: submitted, Checked: len(req.Links), Remaining: len(rest)}
+		if queueRest {
+			// проверка могла исчерпать срок запроса, а ответ еще отправляется
+			b, err := h.svc.SubmitBatch(context.WithoutCancel(r.Context()), rest, int(limit), opts)
+			if err != nil {
+				// проверенные ссылки уже сохранены, отдаем их без остатка
+				slog.Error("queue remainder", "task_id", id, "links", len(rest), "err", err)
+				h.releaseQuota(r, len(rest))
+				resp.Truncated.Error = "remaining links could not be queued"
+			} else {
+				resp.Truncated.BatchID, resp.Truncated.Tasks = b.ID, b.TaskIDs
+			}
+		}
+	}
+	if req.Monitor != nil {
+		interval, _ := time.ParseDuration(req.Monitor.Interval)
+		first := result
+		if deduplicated {
+			// результаты повторены из недавней проверки, это не новое измерение
+			first = nil
+		}
+		m, err := h.svc.CreateMonitor(id, interval, service.OverlapPolicy(req.Monitor.Overlap), opts, first)
+		if err != nil {
+			failed(len(req.Links))
+			return
+		}
+		resp.Monitor = h.monitorResponse(m, nil)
+	}
 	status := http.StatusOK
 	if err != nil {
 		status = http.StatusAccepted
 	}
+	body, err := view.apply(resp)
+	if err != nil {
+		failed(len(req.Links))
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(resp)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// submitBatch splits links into tasks of chunkSize links and answers 202
+// with the batch; its progress is available from GET /batches. The quota
+// reserved for links is refunded if the batch cannot be created.
+func (h *Handler) submitBatch(w http.ResponseWriter, r *http.Request, links []string, chunkSize int, opts service.CheckOptions) {
+	b, err := h.svc.SubmitBatch(r.Context(), links, chunkSize, opts)
+	if err != nil {
+		h.releaseQuota(r, len(links))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/v1/batches?id=%d", b.ID))
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(BatchResponse{BatchID: b.ID, Tasks: b.TaskIDs, Links: len(links)})
+}
+
+// Batches serves GET /batches?id=N with the progress of a batch.
+func (h *Handler) Batches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sum, err := h.svc.GetBatch(r.Context(), id, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sum == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sum.CompletedAt = localTime(sum.CompletedAt, h.loc)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sum)
 }
 
 func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	var req ReportRequest
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeValidationError(w, "invalid JSON", nil)
+			return
+		}
+	case http.MethodGet:
+		var ok bool
+		if req, ok = reportQuery(r); !ok {
+			writeValidationError(w, "invalid query", nil)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	req.Tag = strings.TrimSpace(req.Tag)
+	if errs := h.validateReport(req); len(errs) > 0 {
+		writeValidationError(w, "invalid request", errs)
+		return
+	}
+
+	// срок генерации задаёт таймаут маршрута (ROUTE_TIMEOUTS)
+	owner := auth.Owner(r.Context())
+	pw := &reportWriter{w: w, contentType: "application/pdf", filename: "report.pdf"}
+	switch {
+	case req.Format == service.ReportFormatJSON:
+		pw.contentType, pw.filename = "application/json", "report.json"
+	case req.Format == service.ReportFormatBundle:
+		pw.contentType, pw.filename = "application/zip", "report.zip"
+	case h.svc.StoresReports():
+		pw.id = service.NewReportID()
+		pw.copy = &bytes.Buffer{}
+	}
+	err := h.svc.GenerateReport(r.Context(), service.ReportQuery{
+		IDs:      req.LinksList,
+		Batch:    req.Batch,
+		Tag:      req.Tag,
+		Owner:    owner,
+		Format:   req.Format,
+		Password: req.Password,
+		OnMissing: func(ids []int) {
+			pw.missing = ids
+		},
+	}, pw)
+	if err == nil && pw.id != "" {
+		// отчёт уже отправлен, ошибку сохранения сервис залогировал
+		_ = h.svc.StoreReport(context.WithoutCancel(r.Context()), pw.id, owner, pw.copy.Bytes())
+	}
+	if err == nil || pw.started {
+		// после начала передачи статус уже не изменить, клиент получит обрезанный файл
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		http.Error(w, "report generation timeout", http.StatusGatewayTimeout)
+		return
+	}
+	if errors.Is(err, service.ErrTasksNotFound) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(MissingTasksResponse{Error: err.Error(), MissingIDs: pw.missing})
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// MissingTasksResponse answers a report request none of whose tasks exist.
+type MissingTasksResponse struct {
+	Error      string `json:"error"`
+	MissingIDs []int  `json:"missing_ids"`
+}
+
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// StoredReport serves GET /reports/{id} with a report kept by
+// REPORT_STORE, without generating it again.
+func (h *Handler) StoredReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rc, err := h.svc.OpenReport(r.Context(), r.PathValue("id"), auth.Owner(r.Context()))
+	if errors.Is(err, service.ErrReportNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeStoredReport(w, r.PathValue("id"), rc)
+}
+
+func writeStoredReport(w http.ResponseWriter, id string, rc io.ReadCloser) {
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename=report-"+id+".pdf")
+	_, _ = io.Copy(w, rc)
+}
+
+// ShareReportResponse carries a signed download link of a stored report.
+type ShareReportResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ShareReport serves POST /reports/{id}/share with a download link that
+// works without authentication until it expires; ?ttl=1h shortens it.
+func (h *Handler) ShareReport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		var err error
+		if ttl, err = time.ParseDuration(v); err != nil || ttl <= 0 {
+			http.Error(w, "ttl: want a positive duration", http.StatusBadRequest)
+			return
+		}
+	}
+	link, expires, err := h.svc.ShareReport(r.Context(), r.PathValue("id"), auth.Owner(r.Context()), ttl)
+	switch {
+	case errors.Is(err, service.ErrReportLinkInvalid):
+		http.Error(w, "report links are disabled", http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrReportNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ShareReportResponse{URL: link, ExpiresAt: localTime(expires, h.loc)})
+}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
-	var req ReportRequest
+// SharedReport serves GET /reports/{id}/download?exp=...&sig=... for links
+// made by ShareReport. It is mounted without authentication; the signature
+// is the credential.
+func (h *Handler) SharedReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	rc, err := h.svc.OpenSharedReport(r.Context(), r.PathValue("id"), q.Get("exp"), q.Get("sig"))
+	switch {
+	case errors.Is(err, service.ErrReportLinkInvalid):
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrReportLinkExpired):
+		http.Error(w, "link expired", http.StatusGone)
+		return
+	case errors.Is(err, service.ErrReportNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeStoredReport(w, r.PathValue("id"), rc)
+}
+
+// SLAReport serves POST /report/sla with the uptime report of a month as
+// PDF (the default) or CSV.
+func (h *Handler) SLAReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req SLAReportRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if len(req.LinksList) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
+	from, err := time.ParseInLocation("2006-01", req.Month, h.loc)
+	if err != nil {
+		http.Error(w, "month: want YYYY-MM", http.StatusBadRequest)
 		return
 	}
-	for _, id := range req.LinksList {
-		if id <= 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			return
+	if req.Format == "" {
+		req.Format = service.SLAFormatPDF
+	}
+	contentType := map[string]string{service.SLAFormatPDF: "application/pdf", service.SLAFormatCSV: "text/csv"}[req.Format]
+	if contentType == "" {
+		http.Error(w, "format: want pdf or csv", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = h.svc.WriteSLAReport(r.Context(), service.SLAQuery{
+		From:  from,
+		To:    from.AddDate(0, 1, 0),
+		URLs:  req.URLs,
+		Tag:   strings.TrimSpace(req.Tag),
+		Owner: auth.Owner(r.Context()),
+	}, req.Format, &buf)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=sla-%s.%s", from.Format("2006-01"), req.Format))
+	_, _ = buf.WriteTo(w)
+}
+
+// reportQuery reads a report request from the query of GET /report, with
+// links_list as comma separated task IDs, so reports can be linked to.
+func reportQuery(r *http.Request) (ReportRequest, bool) {
+	q := r.URL.Query()
+	req := ReportRequest{Tag: q.Get("tag"), Format: q.Get("format")}
+	if v := q.Get("links_list"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return req, false
+			}
+			req.LinksList = append(req.LinksList, id)
 		}
 	}
+	if v := q.Get("batch"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return req, false
+		}
+		req.Batch = id
+	}
+	return req, true
+}
 
-	ctx, cancel := context.WithTimeout(r.Context(), reportGenerationTimeout)
-	defer cancel()
+// reportWriter sends the file headers with the first chunk of the report,
+// so an error raised before rendering can still be answered with an error
+// status. Without a Content-Length the response is streamed with chunked
+// encoding.
+type reportWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+	// id is announced in X-Report-ID when the report is kept; copy then
+	// collects it for storing.
+	id   string
+	copy *bytes.Buffer
+	// missing lists requested tasks that were not found; the report is then
+	// sent as 206 with them in X-Missing-Tasks.
+	missing []int
+}
 
-	data, err := h.svc.GenerateReport(ctx, req.LinksList)
+func (p *reportWriter) Write(b []byte) (int, error) {
+	if !p.started {
+		p.started = true
+		p.w.Header().Set("Content-Type", p.contentType)
+		p.w.Header().Set("Content-Disposition", "attachment; filename="+p.filename)
+		if p.id != "" {
+			p.w.Header().Set("X-Report-ID", p.id)
+		}
+		if len(p.missing) > 0 {
+			p.w.Header().Set("X-Missing-Tasks", joinIDs(p.missing))
+			p.w.WriteHeader(http.StatusPartialContent)
+		}
+	}
+	if p.copy != nil {
+		p.copy.Write(b)
+	}
+	return p.w.Write(b)
+}
+
+type DeleteTasksResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+func (h *Handler) Tasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	h.listTasks(w, r)
+}
+
+func (h *Handler) SearchTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	url := strings.TrimSpace(r.URL.Query().Get("url"))
+	if url == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	view, errs := parseView[TaskResponse](r.URL.Query(), taskSummaryFields)
+	if len(errs) > 0 {
+		writeValidationError(w, "invalid query", errs)
+		return
+	}
+
+	tasks, err := h.svc.SearchTasks(r.Context(), url, auth.Owner(r.Context()))
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			http.Error(w, "report generation timeout", http.StatusGatewayTimeout)
-			return
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	h.writeTasks(w, tasks, view)
+}
+
+// DomainSummary serves GET /domains/{host}/summary with the aggregated
+// checks of every link on host.
+func (h *Handler) DomainSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	host := strings.ToLower(strings.TrimSpace(r.PathValue("host")))
+	if host == "" || strings.ContainsAny(host, "/:@ ") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sum, err := h.svc.DomainSummary(r.Context(), host, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sum == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sum.LastChecked = localTime(sum.LastChecked, h.loc)
+	if sum.LastFailure != nil {
+		sum.LastFailure.At = localTime(sum.LastFailure.At, h.loc)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sum)
+}
+
+// TaskProgress serves GET /tasks/{id}/progress with the counts of a task
+// still being checked, or of a completed one.
+func (h *Handler) TaskProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	task, err := h.svc.GetTask(r.Context(), id, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if task == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(domain.ProgressOf(task, time.Now()))
+}
+
+// maxTaskWait bounds the wait parameter of GET /tasks/{id}, below the idle
+// timeouts of common proxies.
+const maxTaskWait = time.Minute
+
+// Task serves GET and DELETE /tasks/{id}.
+func (h *Handler) Task(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getTaskByID(w, r)
+	case http.MethodDelete:
+		h.DeleteTask(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// getTaskByID answers with a single task. Its ETag changes with the task
+// version, so polling clients sending If-None-Match get 304 until another
+// link is checked. With ?wait=30s the answer is held back until the task
+// completes or the wait passes, for clients that cannot use streaming.
+func (h *Handler) getTaskByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	view, errs := parseView[TaskResponse](q, taskSummaryFields)
+	var wait time.Duration
+	if v := q.Get("wait"); v != "" {
+		if wait, err = time.ParseDuration(v); err != nil || wait < 0 {
+			errs = append(errs, FieldError{Field: "wait", Value: v, Reason: "want a duration such as 30s"})
 		}
+	}
+	page, paged, pageErrs := linkPageQuery(q)
+	if errs = append(errs, pageErrs...); len(errs) > 0 {
+		writeValidationError(w, "invalid query", errs)
+		return
+	}
+	p, err := h.svc.WaitTask(r.Context(), id, auth.Owner(r.Context()), page, min(wait, maxTaskWait))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	variant := view.key()
+	if paged {
+		variant += fmt.Sprintf("|links=%d+%d", page.Offset, page.Limit)
+	}
+	etag := taskETag(p.Task, variant)
+	w.Header().Set("ETag", etag)
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	tr := taskResponse(p.Task, h.loc)
+	tr.Summary = &p.Summary
+	if paged {
+		tr.Page = &LinksPage{Offset: page.Offset, Limit: page.Limit, Total: p.Total}
+	}
+	v, err := view.apply(tr)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
 
-	w.Header().Set("Content-Type", "application/pdf")
-	w.Header().Set("Content-Disposition", "attachment; filename=report.pdf")
-	_, _ = w.Write(data)
+// DeleteTask serves DELETE /tasks/{id}. Tasks of another owner are
+// reported as missing.
+func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	deleted, err := h.svc.DeleteTask(r.Context(), id, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteTasks(w http.ResponseWriter, r *http.Request) {
+	before, err := time.Parse(time.RFC3339, r.URL.Query().Get("before"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.svc.DeleteTasksBefore(r.Context(), before)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(DeleteTasksResponse{Deleted: deleted})
+}
+
+func (h *Handler) listTasks(w http.ResponseWriter, r *http.Request) {
+	view, errs := parseView[TaskResponse](r.URL.Query(), taskSummaryFields)
+	if len(errs) > 0 {
+		writeValidationError(w, "invalid query", errs)
+		return
+	}
+	if v := r.URL.Query().Get("id"); v != "" {
+		h.getTask(w, r, v, view)
+		return
+	}
+	tasks, err := h.svc.ListTasks(r.Context(), strings.TrimSpace(r.URL.Query().Get("tag")), auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.writeTasks(w, tasks, view)
+}
+
+// getTask serves GET /tasks?id=N with a list of the one task, empty when
+// there is no such task.
+func (h *Handler) getTask(w http.ResponseWriter, r *http.Request, v string, view responseView) {
+	id, err := strconv.Atoi(v)
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	task, err := h.svc.GetTask(r.Context(), id, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var tasks []*domain.Task
+	if task != nil {
+		tasks = append(tasks, task)
+	}
+	h.writeTasks(w, tasks, view)
+}
+
+// writeTasks answers with tasks, each cut down to the fields of view.
+func (h *Handler) writeTasks(w http.ResponseWriter, tasks []*domain.Task, view responseView) {
+	var resp struct {
+		Tasks []any `json:"tasks"`
+	}
+	resp.Tasks = make([]any, 0, len(tasks))
+	for _, t := range tasks {
+		v, err := h.taskView(t, view)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp.Tasks = append(resp.Tasks, v)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// taskView converts t with the summary of its results and cuts it down to
+// view.
+func (h *Handler) taskView(t *domain.Task, view responseView) (any, error) {
+	tr := taskResponse(t, h.loc)
+	counts := domain.CountResults(t.Links, t.Result)
+	tr.Summary = &counts
+	return view.apply(tr)
+}
+
+// taskResponse converts t with its times in loc.
+func taskResponse(t *domain.Task, loc *time.Location) TaskResponse {
+	timings := t.Timings
+	if loc != time.UTC && len(timings) > 0 {
+		timings = make(map[string]domain.LinkTiming, len(t.Timings))
+		for link, timing := range t.Timings {
+			timing.CheckedAt = localTime(timing.CheckedAt, loc)
+			timings[link] = timing
+		}
+	}
+	return TaskResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		Tags:        t.Tags,
+		CreatedBy:   t.CreatedBy,
+		Owner:       t.Owner,
+		Links:       t.Links,
+		Result:      t.Result,
+		Timings:     timings,
+		Findings:    t.Findings,
+		Security:    t.Security,
+		BatchID:     t.BatchID,
+		Version:     t.Version,
+		CreatedAt:   localTime(t.CreatedAt, loc),
+		CompletedAt: localTime(t.CompletedAt, loc),
+	}
+}
+
+func normalizeTags(tags []string) []string {
+	var res []string
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
+	}
+	return res
+}
+
+// groupByHost splits statuses by the host of each link. Links missing from
+// statuses count as broken.
+func groupByHost(links []string, statuses map[string]domain.LinkStatus) []HostGroup {
+	links = slices.Compact(slices.Sorted(slices.Values(links)))
+	result := make(map[string]string, len(statuses))
+	for link, st := range statuses {
+		result[link] = string(st)
+	}
+
+	groups := make([]HostGroup, 0)
+	index := make(map[string]int)
+	for _, summary := range domain.SummarizeByHost(links, result) {
+		index[summary.Host] = len(groups)
+		groups = append(groups, HostGroup{HostSummary: summary, Links: make(map[string]domain.LinkStatus)})
+	}
+	for _, link := range links {
+		st, ok := statuses[link]
+		if !ok {
+			st = domain.StatusNotAvailable
+		}
+		groups[index[domain.LinkHost(link)]].Links[link] = st
+	}
+	return groups
 }
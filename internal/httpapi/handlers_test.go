@@ -2,32 +2,62 @@ package httpapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/olgkv/linkchecker/internal/auth"
+	"github.com/olgkv/linkchecker/internal/blob"
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/monitor"
 	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/internal/quota"
 	"github.com/olgkv/linkchecker/internal/service"
+	"github.com/olgkv/linkchecker/internal/storage"
 )
 
 type stubStorage struct {
 	ports.TaskStorage
-	created       *ports.TaskDTO
+	mu            sync.Mutex
+	tasks         []*ports.TaskDTO
 	storedResults map[int]map[string]string
 }
 
-func (s *stubStorage) CreateTask(links []string) (*ports.TaskDTO, error) {
-	if s.storedResults == nil {
-		s.storedResults = make(map[int]map[string]string)
+func (s *stubStorage) CreateTask(ctx context.Context, links []string, meta ports.TaskMeta) (*ports.TaskDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &ports.TaskDTO{
+		ID:        len(s.tasks) + 1,
+		Name:      meta.Name,
+		Tags:      meta.Tags,
+		CreatedBy: meta.CreatedBy,
+		Owner:     meta.Owner,
+		Links:     links,
+		Result:    make(map[string]string),
+		CreatedAt: time.Now(),
 	}
-	t := &ports.TaskDTO{ID: 1, Links: links, Result: make(map[string]string)}
-	s.created = t
+	s.tasks = append(s.tasks, t)
 	return t, nil
 }
 
-func (s *stubStorage) UpdateTaskResult(id int, result map[string]string) error {
+func (s *stubStorage) AppendLinkResult(ctx context.Context, id int, link string, status string, timing ports.LinkTiming, findings []ports.Finding, security *ports.SecurityAudit) error {
+	return nil
+}
+
+func (s *stubStorage) UpdateTaskResult(ctx context.Context, id int, version int, result map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.storedResults == nil {
 		s.storedResults = make(map[int]map[string]string)
 	}
@@ -35,11 +65,85 @@ func (s *stubStorage) UpdateTaskResult(id int, result map[string]string) error {
 	return nil
 }
 
-func (s *stubStorage) GetTasks(ids []int) ([]*ports.TaskDTO, error) {
-	if s.created == nil {
-		return nil, nil
+func (s *stubStorage) GetTasks(ctx context.Context, ids []int) ([]*ports.TaskDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*ports.TaskDTO
+	for _, id := range ids {
+		for _, t := range s.tasks {
+			if t.ID == id {
+				res = append(res, t)
+			}
+		}
 	}
-	return []*ports.TaskDTO{s.created}, nil
+	return res, nil
+}
+
+func (s *stubStorage) DeleteTasksBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []*ports.TaskDTO
+	for _, t := range s.tasks {
+		if !t.CreatedAt.Before(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	deleted := len(s.tasks) - len(kept)
+	s.tasks = kept
+	return deleted, nil
+}
+
+func (s *stubStorage) DeleteTask(ctx context.Context, id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.tasks {
+		if t.ID == id {
+			s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *stubStorage) ImportTasks(ctx context.Context, tasks []*ports.TaskDTO) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tasks {
+		for _, existing := range s.tasks {
+			if existing.ID == t.ID {
+				return ports.ErrTaskExists
+			}
+		}
+	}
+	s.tasks = append(s.tasks, tasks...)
+	return nil
+}
+
+func (s *stubStorage) SearchTasks(ctx context.Context, url string) ([]*ports.TaskDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*ports.TaskDTO
+	for _, t := range s.tasks {
+		for _, link := range t.Links {
+			if link == url {
+				res = append(res, t)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+func (s *stubStorage) ListTasks(ctx context.Context, tag string) ([]*ports.TaskDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*ports.TaskDTO
+	for _, t := range s.tasks {
+		if tag == "" || slices.Contains(t.Tags, tag) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
 }
 
 // минимальный http.Client, чтобы не ходить в сеть в тестах
@@ -91,8 +195,8 @@ func TestLinksHandler(t *testing.T) {
 			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 				t.Fatalf("decode resp: %v", err)
 			}
-			if resp.LinksNum == 0 {
-				t.Fatalf("expected non-zero links_num")
+			if resp.TaskID == 0 || resp.LinksNum != resp.TaskID || resp.LinksCount != tc.wantCount {
+				t.Fatalf("task_id %d, links_num %d, links_count %d", resp.TaskID, resp.LinksNum, resp.LinksCount)
 			}
 			if len(resp.Links) != tc.wantCount {
 				t.Fatalf("expected %d links in response, got %d", tc.wantCount, len(resp.Links))
@@ -101,6 +205,133 @@ func TestLinksHandler(t *testing.T) {
 	}
 }
 
+// linksRecorder is a ResponseRecorder that implements LinksRecorder.
+type linksRecorder struct {
+	*httptest.ResponseRecorder
+	taskID  int
+	summary domain.ResultCounts
+}
+
+func (r *linksRecorder) RecordLinks(taskID int, summary domain.ResultCounts) {
+	r.taskID, r.summary = taskID, summary
+}
+
+func TestLinksHandler_Summary(t *testing.T) {
+	h := newTestHandler(t)
+	// адреса вместо имен, чтобы проверка не ждала DNS
+	body, _ := json.Marshal(LinksRequest{Links: []string{"192.0.2.1", "10.0.0.1"}})
+	rec := &linksRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp LinksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := domain.ResultCounts{Links: 2, Checked: 2, Broken: 2, ByErrorKind: map[string]int{"http_status": 1, "private_address": 1}}
+	if !reflect.DeepEqual(resp.Summary, want) {
+		t.Fatalf("summary = %+v, want %+v", resp.Summary, want)
+	}
+	if rec.taskID != resp.TaskID || !reflect.DeepEqual(rec.summary, want) {
+		t.Fatalf("recorded task %d with %+v", rec.taskID, rec.summary)
+	}
+}
+
+func TestLinksHandler_GroupByHost(t *testing.T) {
+	h := newTestHandler(t)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"go.dev", "Example.com", "example.com"}})
+	rec := httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links?group_by=host", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp LinksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	if len(resp.Hosts) != 2 || resp.Hosts[0].Host != "example.com" || resp.Hosts[1].Host != "go.dev" {
+		t.Fatalf("unexpected host groups: %+v", resp.Hosts)
+	}
+	g := resp.Hosts[0]
+	if g.Checked != 2 || g.Broken != 2 || g.Available != 0 || len(g.Links) != 2 {
+		t.Fatalf("unexpected example.com subtotals: %+v", g)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links?group_by=path", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown group_by: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLinksHandler_ValidationErrors(t *testing.T) {
+	h := newTestHandler(t)
+
+	body, _ := json.Marshal(LinksRequest{
+		Links:     []string{"1.1.1.1", "gopher://files.example", "https://", strings.Repeat("a", maxLinkLength+1)},
+		FailAfter: -1,
+		Priority:  "urgent",
+		// без hosts учётные данные некуда отправлять
+		Auth: &LinksAuth{Type: "bearer", Token: "t"},
+	})
+	rec := httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	var resp ValidationErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	var got []string
+	for _, f := range resp.Fields {
+		if f.Index != nil {
+			got = append(got, fmt.Sprintf("%s[%d]", f.Field, *f.Index))
+		} else {
+			got = append(got, f.Field)
+		}
+	}
+	want := []string{"links[1]", "links[2]", "links[3]", "fail_after", "auth", "priority"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid JSON") {
+		t.Fatalf("invalid JSON: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReportHandler_ValidationErrors(t *testing.T) {
+	h := newTestHandler(t)
+
+	body, _ := json.Marshal(ReportRequest{LinksList: []int{1, 0, -2}})
+	rec := httptest.NewRecorder()
+	h.Report(rec, httptest.NewRequest(http.MethodPost, "/report", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var resp ValidationErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	if len(resp.Fields) != 2 || *resp.Fields[0].Index != 1 || *resp.Fields[1].Index != 2 {
+		t.Fatalf("unexpected fields %+v", resp.Fields)
+	}
+
+	body, _ = json.Marshal(ReportRequest{LinksList: []int{1}, Format: "bundle", Password: "s3cret"})
+	rec = httptest.NewRecorder()
+	h.Report(rec, httptest.NewRequest(http.MethodPost, "/report", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"password"`) {
+		t.Fatalf("password with bundle: status = %d, body %q", rec.Code, rec.Body.String())
+	}
+}
+
 func TestReportHandler(t *testing.T) {
 	h := newTestHandler(t)
 
@@ -131,3 +362,910 @@ func TestReportHandler(t *testing.T) {
 		t.Fatalf("empty pdf body")
 	}
 }
+
+func TestReportHandler_MissingTasks(t *testing.T) {
+	h := newTestHandler(t)
+
+	bodyLinks, _ := json.Marshal(LinksRequest{Links: []string{"example.com"}})
+	recLinks := httptest.NewRecorder()
+	h.Links(recLinks, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(bodyLinks)))
+	var lr LinksResponse
+	if err := json.NewDecoder(recLinks.Body).Decode(&lr); err != nil {
+		t.Fatalf("decode links resp: %v", err)
+	}
+
+	report := func(ids ...int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ReportRequest{LinksList: ids, Format: "json"})
+		rec := httptest.NewRecorder()
+		h.Report(rec, httptest.NewRequest(http.MethodPost, "/report", bytes.NewReader(body)))
+		return rec
+	}
+
+	rec := report(lr.LinksNum, 998)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusPartialContent)
+	}
+	if got := rec.Header().Get("X-Missing-Tasks"); got != "998" {
+		t.Fatalf("X-Missing-Tasks = %q, want 998", got)
+	}
+	var doc domain.LinksReport
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(doc.Tasks) != 1 || len(doc.MissingIDs) != 1 || doc.MissingIDs[0] != 998 {
+		t.Fatalf("report = %+v, want one task and 998 missing", doc)
+	}
+
+	rec = report(998, 999)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	var resp MissingTasksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	if len(resp.MissingIDs) != 2 {
+		t.Fatalf("missing = %v, want [998 999]", resp.MissingIDs)
+	}
+}
+
+func TestReportHandler_StoredReport(t *testing.T) {
+	h := newTestHandler(t)
+	store, err := blob.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	h.svc.UseReportStore(store, 0)
+
+	bodyLinks, _ := json.Marshal(LinksRequest{Links: []string{"1.1.1.1"}})
+	recLinks := httptest.NewRecorder()
+	h.Links(recLinks, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(bodyLinks)))
+	var lr LinksResponse
+	if err := json.NewDecoder(recLinks.Body).Decode(&lr); err != nil {
+		t.Fatalf("decode links resp: %v", err)
+	}
+
+	body, _ := json.Marshal(ReportRequest{LinksList: []int{lr.LinksNum}})
+	rec := httptest.NewRecorder()
+	h.Report(rec, httptest.NewRequest(http.MethodPost, "/report", bytes.NewReader(body)))
+	id := rec.Header().Get("X-Report-ID")
+	if rec.Code != http.StatusOK || id == "" {
+		t.Fatalf("status = %d, report id %q", rec.Code, id)
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want int
+	}{
+		{id, http.StatusOK},
+		{service.NewReportID(), http.StatusNotFound},
+		{"x", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/reports/"+tc.id, nil)
+		req.SetPathValue("id", tc.id)
+		got := httptest.NewRecorder()
+		h.StoredReport(got, req)
+		if got.Code != tc.want {
+			t.Fatalf("id %s: status = %d, want %d", tc.id, got.Code, tc.want)
+		}
+		if tc.want == http.StatusOK && !bytes.Equal(got.Body.Bytes(), rec.Body.Bytes()) {
+			t.Fatalf("stored report differs from the generated one")
+		}
+	}
+}
+
+func TestTaskProgressHandler(t *testing.T) {
+	client := &http.Client{Transport: dummyRoundTripper{}}
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), client, 10, time.Second, 2)
+	t.Cleanup(svc.Close)
+	h := NewHandler(svc, 5)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"1.1.1.1", "8.8.8.8"}})
+	recLinks := httptest.NewRecorder()
+	h.Links(recLinks, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+	if recLinks.Code != http.StatusOK {
+		t.Fatalf("links status = %d", recLinks.Code)
+	}
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"1", http.StatusOK},
+		{"2", http.StatusNotFound},
+		{"x", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/tasks/"+tc.id+"/progress", nil)
+		req.SetPathValue("id", tc.id)
+		rec := httptest.NewRecorder()
+		h.TaskProgress(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("id %s: status = %d, want %d", tc.id, rec.Code, tc.want)
+		}
+		if tc.want != http.StatusOK {
+			continue
+		}
+		var p domain.TaskProgress
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("decode progress: %v", err)
+		}
+		if p.TaskID != 1 || p.Total != 2 || p.Checked != 2 || p.Failed != 2 || !p.Completed || p.ETAMS != 0 {
+			t.Fatalf("unexpected progress %+v", p)
+		}
+	}
+}
+
+func TestTasksHandler_FilterByTag(t *testing.T) {
+	h := newTestHandler(t)
+
+	bodyLinks, _ := json.Marshal(LinksRequest{Links: []string{"example.com"}, Name: "nightly", Tags: []string{"docs", " docs ", ""}})
+	recLinks := httptest.NewRecorder()
+	h.Links(recLinks, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(bodyLinks)))
+	if recLinks.Code != http.StatusOK {
+		t.Fatalf("links status = %d", recLinks.Code)
+	}
+
+	tests := []struct {
+		tag       string
+		wantCount int
+	}{
+		{"docs", 1},
+		{"other", 0},
+	}
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks?tag="+tc.tag, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		var resp TasksResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode resp: %v", err)
+		}
+		if len(resp.Tasks) != tc.wantCount {
+			t.Fatalf("tag %q: expected %d tasks, got %d", tc.tag, tc.wantCount, len(resp.Tasks))
+		}
+		if tc.wantCount == 1 && (resp.Tasks[0].Name != "nightly" || len(resp.Tasks[0].Tags) != 1) {
+			t.Fatalf("unexpected task metadata: %+v", resp.Tasks[0])
+		}
+	}
+
+	for query, want := range map[string]int{"id=1": 1, "id=2": 0} {
+		rec := httptest.NewRecorder()
+		h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks?"+query, nil))
+		var resp TasksResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode resp: %v", err)
+		}
+		if len(resp.Tasks) != want {
+			t.Fatalf("%s: expected %d tasks, got %d", query, want, len(resp.Tasks))
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks?id=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad id: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestTasksHandler_FieldsAndVerbosity(t *testing.T) {
+	client := &http.Client{Transport: dummyRoundTripper{}}
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), client, 10, time.Second, 2)
+	t.Cleanup(svc.Close)
+	h := NewHandler(svc, 5)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"a.example", "b.example"}, Name: "nightly"})
+	rec := httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links?verbosity=summary", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("links status = %d", rec.Code)
+	}
+	var links map[string]json.RawMessage
+	if err := json.NewDecoder(rec.Body).Decode(&links); err != nil {
+		t.Fatalf("decode links: %v", err)
+	}
+	if _, ok := links["links"]; ok {
+		t.Fatalf("summary links response has per-link data: %v", links)
+	}
+	var counts domain.ResultCounts
+	if err := json.Unmarshal(links["summary"], &counts); err != nil || counts.Links != 2 || counts.Checked != 2 || counts.Broken != 2 {
+		t.Fatalf("summary = %s, %v", links["summary"], err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"verbosity=summary", taskSummaryFields},
+		{"fields=id,summary", []string{"id", "summary"}},
+		{"verbosity=summary&fields=id", []string{"id"}},
+	}
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks?"+tc.query, nil))
+		var resp struct {
+			Tasks []map[string]json.RawMessage `json:"tasks"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Tasks) != 1 {
+			t.Fatalf("%s: decode: %v, %d tasks", tc.query, err, len(resp.Tasks))
+		}
+		for name := range resp.Tasks[0] {
+			if !slices.Contains(tc.want, name) {
+				t.Fatalf("%s: unexpected field %q", tc.query, name)
+			}
+		}
+		if _, ok := resp.Tasks[0]["id"]; !ok {
+			t.Fatalf("%s: id missing from %v", tc.query, resp.Tasks[0])
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	var full TasksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&full); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(full.Tasks) != 1 || len(full.Tasks[0].Result) != 2 || full.Tasks[0].Summary == nil || full.Tasks[0].Summary.Broken != 2 {
+		t.Fatalf("unexpected full response %+v", full)
+	}
+
+	for _, query := range []string{"fields=id,nope", "verbosity=terse"} {
+		rec := httptest.NewRecorder()
+		h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestTasksHandler_Timezone(t *testing.T) {
+	h := newTestHandler(t)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	h.SetLocation(tokyo)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"example.com"}})
+	h.Links(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+
+	rec := httptest.NewRecorder()
+	h.Tasks(rec, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), `+09:00"`) {
+		t.Fatalf("times not rendered in Asia/Tokyo: %s", rec.Body.String())
+	}
+}
+
+func TestTasksHandler_DeleteBefore(t *testing.T) {
+	h := newTestHandler(t)
+
+	bodyLinks, _ := json.Marshal(LinksRequest{Links: []string{"example.com"}})
+	h.Links(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(bodyLinks)))
+
+	rec := httptest.NewRecorder()
+	h.Tasks(rec, httptest.NewRequest(http.MethodDelete, "/tasks?before=not-a-time", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE on the public tasks handler: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Cleanup(rec, httptest.NewRequest(http.MethodDelete, "/tasks?before=not-a-time", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	before := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	rec = httptest.NewRecorder()
+	h.Cleanup(rec, httptest.NewRequest(http.MethodDelete, "/tasks?before="+before, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp DeleteTasksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	if resp.Deleted != 1 {
+		t.Fatalf("expected 1 deleted task, got %d", resp.Deleted)
+	}
+}
+
+func TestTaskHandler_ETag(t *testing.T) {
+	st := storage.NewFileStorage(storage.NewMemoryRepository())
+	client := &http.Client{Transport: dummyRoundTripper{}}
+	svc := service.New(st, client, 10, time.Second, 2)
+	t.Cleanup(svc.Close)
+	h := NewHandler(svc, 5)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"a.example"}})
+	h.Links(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+
+	get := func(id, query, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/tasks/"+id+query, nil)
+		req.SetPathValue("id", id)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.Task(rec, req)
+		return rec
+	}
+
+	rec := get("1", "", "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, etag %q", rec.Code, etag)
+	}
+	var task TaskResponse
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil || task.ID != 1 || task.Summary == nil {
+		t.Fatalf("decode: %+v, %v", task, err)
+	}
+
+	rec = get("1", "", etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+		t.Fatalf("unchanged: status = %d, etag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := get("1", "", `"other", W/`+etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("weak tag in a list: status = %d, want 304", rec.Code)
+	}
+	if rec := get("1", "?verbosity=summary", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("other view: status = %d, etag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	if err := st.AppendLinkResult(context.Background(), 1, "a.example", string(domain.StatusAvailable), ports.LinkTiming{}, nil, nil); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if rec := get("1", "", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("changed: status = %d, etag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	for id, want := range map[string]int{"2": http.StatusNotFound, "x": http.StatusBadRequest} {
+		if rec := get(id, "", ""); rec.Code != want {
+			t.Fatalf("id %s: status = %d, want %d", id, rec.Code, want)
+		}
+	}
+	// задача уже завершена, ожидание не нужно
+	start := time.Now()
+	if rec := get("1", "?wait=30s", ""); rec.Code != http.StatusOK || time.Since(start) > 5*time.Second {
+		t.Fatalf("wait on completed task: status = %d after %s", rec.Code, time.Since(start))
+	}
+	for _, query := range []string{"?wait=soon", "?wait=-1s"} {
+		if rec := get("1", query, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestTaskHandler_LinkPaging(t *testing.T) {
+	client := &http.Client{Transport: dummyRoundTripper{}}
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), client, 10, time.Second, 2)
+	t.Cleanup(svc.Close)
+	h := NewHandler(svc, 5)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"a.example", "b.example", "c.example"}})
+	h.Links(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/tasks/1"+query, nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		h.Task(rec, req)
+		return rec
+	}
+
+	rec := get("?link_offset=1&link_limit=1")
+	var task TaskResponse
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !slices.Equal(task.Links, []string{"b.example"}) || len(task.Result) != 1 || task.Result["b.example"] == "" {
+		t.Fatalf("page links %v, result %v", task.Links, task.Result)
+	}
+	if task.Page == nil || *task.Page != (LinksPage{Offset: 1, Limit: 1, Total: 3}) {
+		t.Fatalf("page = %+v", task.Page)
+	}
+	if task.Summary == nil || task.Summary.Links != 3 || task.Summary.Checked != 3 {
+		t.Fatalf("summary of the page instead of the task: %+v", task.Summary)
+	}
+	if rec.Header().Get("ETag") == get("").Header().Get("ETag") {
+		t.Fatal("page shares the ETag of the whole task")
+	}
+
+	task = TaskResponse{}
+	if err := json.NewDecoder(get("").Body).Decode(&task); err != nil || len(task.Links) != 3 || task.Page != nil {
+		t.Fatalf("unpaged: %+v, %v", task, err)
+	}
+	for _, query := range []string{"?link_offset=-1", "?link_limit=0", "?link_limit=x"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestDeleteTaskHandler(t *testing.T) {
+	h := newTestHandler(t)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"a.com"}})
+	req := httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "team-a", Role: auth.RoleSubmitter}))
+	h.Links(httptest.NewRecorder(), req)
+
+	del := func(p auth.Principal, id string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/tasks/"+id, nil)
+		req.SetPathValue("id", id)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		rec := httptest.NewRecorder()
+		h.DeleteTask(rec, req)
+		return rec.Code
+	}
+	teamA := auth.Principal{Name: "team-a", Role: auth.RoleSubmitter}
+	teamB := auth.Principal{Name: "team-b", Role: auth.RoleSubmitter}
+
+	if code := del(teamA, "x"); code != http.StatusBadRequest {
+		t.Fatalf("bad id: status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := del(teamB, "1"); code != http.StatusNotFound {
+		t.Fatalf("other owner: status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := del(teamA, "1"); code != http.StatusNoContent {
+		t.Fatalf("owner: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := del(teamA, "1"); code != http.StatusNotFound {
+		t.Fatalf("deleted task: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestMonitorHandlers(t *testing.T) {
+	h := newTestHandler(t)
+
+	submit := func(overlap string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(LinksRequest{Links: []string{"a.com"}, Monitor: &MonitorRequest{Interval: "5m", Overlap: overlap}})
+		rec := httptest.NewRecorder()
+		h.Links(rec, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+		return rec
+	}
+	if rec := submit(""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("monitors disabled: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	store, _ := monitor.Open("")
+	h.svc.UseMonitors(store)
+	if rec := submit("later"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown overlap policy: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec := submit("queue")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var lr LinksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&lr); err != nil {
+		t.Fatalf("decode links resp: %v", err)
+	}
+	if lr.Monitor == nil || lr.Monitor.Interval != "5m0s" || lr.Monitor.State != "active" || lr.Monitor.Overlap != "queue" {
+		t.Fatalf("monitor = %+v, want an active 5m monitor queueing overlapping runs", lr.Monitor)
+	}
+	id := strconv.Itoa(lr.LinksNum)
+
+	action := func(name string) int {
+		req := httptest.NewRequest(http.MethodPost, "/monitors/"+id+"/"+name, nil)
+		req.SetPathValue("id", id)
+		req.SetPathValue("action", name)
+		rec := httptest.NewRecorder()
+		h.MonitorAction(rec, req)
+		return rec.Code
+	}
+	if code := action("pause"); code != http.StatusNoContent {
+		t.Fatalf("pause: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := action("rewind"); code != http.StatusNotFound {
+		t.Fatalf("unknown action: status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/monitors/"+id, nil)
+	req.SetPathValue("id", id)
+	rec = httptest.NewRecorder()
+	h.Monitor(rec, req)
+	var mr MonitorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&mr); err != nil {
+		t.Fatalf("decode monitor: %v", err)
+	}
+	if mr.State != "paused" || mr.Links["a.com"] == "" {
+		t.Fatalf("monitor = %+v, want a paused monitor with the status of a.com", mr)
+	}
+
+	if code := action("stop"); code != http.StatusNoContent {
+		t.Fatalf("stop: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := action("start"); code != http.StatusNotFound {
+		t.Fatalf("start after stop: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestStateHandler(t *testing.T) {
+	h := newTestHandler(t)
+	h.UseRateLimiterStats(func() RateLimiterState { return RateLimiterState{Clients: 3} })
+
+	rec := httptest.NewRecorder()
+	h.State(rec, httptest.NewRequest(http.MethodGet, "/admin/state", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp StateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	if resp.RateLimiter == nil || resp.RateLimiter.Clients != 3 || resp.ReportQueue.Capacity == 0 || resp.ActiveChecks == nil {
+		t.Fatalf("unexpected state: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	h.State(rec, httptest.NewRequest(http.MethodPost, "/admin/state", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := newTestHandler(t)
+	bodyLinks, _ := json.Marshal(LinksRequest{Links: []string{"example.com"}, Name: "docs"})
+	src.Links(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(bodyLinks)))
+
+	exportRec := httptest.NewRecorder()
+	src.Export(exportRec, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	if exportRec.Code != http.StatusOK {
+		t.Fatalf("export status = %d", exportRec.Code)
+	}
+	dump := exportRec.Body.Bytes()
+
+	dst := newTestHandler(t)
+	importRec := httptest.NewRecorder()
+	dst.Import(importRec, httptest.NewRequest(http.MethodPost, "/admin/import", bytes.NewReader(dump)))
+	if importRec.Code != http.StatusOK {
+		t.Fatalf("import status = %d, body %s", importRec.Code, importRec.Body.String())
+	}
+	var resp ImportResponse
+	if err := json.NewDecoder(importRec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode resp: %v", err)
+	}
+	if resp.Imported != 1 {
+		t.Fatalf("expected 1 imported task, got %d", resp.Imported)
+	}
+
+	again := httptest.NewRecorder()
+	dst.Import(again, httptest.NewRequest(http.MethodPost, "/admin/import", bytes.NewReader(dump)))
+	if again.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", again.Code, http.StatusConflict)
+	}
+}
+
+func TestTasksHandler_OwnerIsolation(t *testing.T) {
+	h := newTestHandler(t)
+
+	submit := func(p auth.Principal, link string) {
+		body, _ := json.Marshal(LinksRequest{Links: []string{link}})
+		req := httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		rec := httptest.NewRecorder()
+		h.Links(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("links status = %d", rec.Code)
+		}
+	}
+	submit(auth.Principal{Name: "team-a", Role: auth.RoleSubmitter}, "a.com")
+	submit(auth.Principal{Name: "team-b", Role: auth.RoleSubmitter}, "b.com")
+
+	list := func(p auth.Principal) TasksResponse {
+		req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		rec := httptest.NewRecorder()
+		h.Tasks(rec, req)
+		var resp TasksResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode resp: %v", err)
+		}
+		return resp
+	}
+
+	resp := list(auth.Principal{Name: "team-a", Role: auth.RoleSubmitter})
+	if len(resp.Tasks) != 1 || resp.Tasks[0].Owner != "team-a" || resp.Tasks[0].CreatedBy != "team-a" {
+		t.Fatalf("team-a should only see its own task, got %+v", resp.Tasks)
+	}
+	if resp := list(auth.Principal{Name: "root", Role: auth.RoleAdmin}); len(resp.Tasks) != 2 {
+		t.Fatalf("admin should see all tasks, got %d", len(resp.Tasks))
+	}
+}
+
+func TestLinksHandler_QuotaExceeded(t *testing.T) {
+	h := newTestHandler(t)
+	tracker, err := quota.NewTracker("", quota.Limits{Daily: 2}, nil)
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	h.UseQuota(tracker)
+
+	post := func(links []string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(LinksRequest{Links: links})
+		req := httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "team-a", Role: auth.RoleSubmitter}))
+		rec := httptest.NewRecorder()
+		h.Links(rec, req)
+		return rec
+	}
+
+	rec := post([]string{"a.com", "b.com"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("X-Quota-Daily-Remaining"); got != "0" {
+		t.Fatalf("X-Quota-Daily-Remaining = %q, want 0", got)
+	}
+
+	rec = post([]string{"c.com"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After header")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/me/usage", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "team-a", Role: auth.RoleReader}))
+	usageRec := httptest.NewRecorder()
+	h.Usage(usageRec, req)
+	var st quota.Status
+	if err := json.NewDecoder(usageRec.Body).Decode(&st); err != nil {
+		t.Fatalf("decode usage: %v", err)
+	}
+	if st.DailyUsed != 2 || st.DailyLimit != 2 {
+		t.Fatalf("unexpected usage: %+v", st)
+	}
+}
+
+func TestLinksHandler_SplitsLargeSubmissionIntoBatch(t *testing.T) {
+	client := &http.Client{Transport: dummyRoundTripper{}}
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), client, 10, time.Second, 2)
+	t.Cleanup(svc.Close)
+	h := NewHandler(svc, 2)
+
+	body, _ := json.Marshal(LinksRequest{Links: []string{"a.example", "b.example", "c.example"}})
+	rec := httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("without batches: status = %d, want 400", rec.Code)
+	}
+
+	h.SetMaxBatchLinks(10)
+	rec = httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+	var batch BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&batch); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(batch.Tasks) != 2 || batch.Links != 3 {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec = httptest.NewRecorder()
+		h.Batches(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/batches?id=%d", batch.BatchID), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /batches: status = %d", rec.Code)
+		}
+		var sum domain.BatchSummary
+		if err := json.NewDecoder(rec.Body).Decode(&sum); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if sum.Status == domain.BatchCompleted {
+			if sum.Links != 3 || sum.Checked != 3 || sum.Broken != 3 {
+				t.Fatalf("unexpected summary %+v", sum)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("batch still %s", sum.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Batches(rec, httptest.NewRequest(http.MethodGet, "/batches?id=999", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown batch: status = %d, want 404", rec.Code)
+	}
+}
+
+type failingStorage struct{ stubStorage }
+
+func (*failingStorage) CreateTask(context.Context, []string, ports.TaskMeta) (*ports.TaskDTO, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestLinksHandler_QuotaRefundedOnError(t *testing.T) {
+	svc := service.New(&failingStorage{}, &http.Client{Transport: dummyRoundTripper{}}, 10, time.Second, 2)
+	h := NewHandler(svc, 5)
+	tracker, err := quota.NewTracker("", quota.Limits{Daily: 10}, nil)
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	h.UseQuota(tracker)
+	body, _ := json.Marshal(LinksRequest{Links: []string{"192.0.2.1", "192.0.2.2"}})
+	req := httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "team-a", Role: auth.RoleSubmitter}))
+
+	rec := httptest.NewRecorder()
+	h.Links(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if got := tracker.Usage("team-a").DailyUsed; got != 0 {
+		t.Fatalf("quota used = %d after a failed request, want 0", got)
+	}
+}
+
+func TestLinksHandler_QueueRemainderFails(t *testing.T) {
+	// stubStorage не умеет батчи, поэтому остаток не поставить в очередь
+	h := newTestHandler(t)
+	tracker, err := quota.NewTracker("", quota.Limits{Daily: 10}, nil)
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	h.UseQuota(tracker)
+	body, _ := json.Marshal(LinksRequest{Links: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5", "192.0.2.6", "192.0.2.7"}})
+	req := httptest.NewRequest(http.MethodPost, "/links?partial=true&queue_remainder=true", bytes.NewReader(body))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "team-a", Role: auth.RoleSubmitter}))
+
+	rec := httptest.NewRecorder()
+	h.Links(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp LinksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Links) != 5 || resp.Truncated == nil || resp.Truncated.Error == "" || resp.Truncated.BatchID != 0 {
+		t.Fatalf("want the checked links and an error for the rest, got %+v", resp)
+	}
+	if got := tracker.Usage("team-a").DailyUsed; got != 5 {
+		t.Fatalf("quota used = %d, want 5 after the rest was refunded", got)
+	}
+}
+
+func TestLinksHandler_PartialSubmission(t *testing.T) {
+	client := &http.Client{Transport: dummyRoundTripper{}}
+	svc := service.New(storage.NewFileStorage(storage.NewMemoryRepository()), client, 10, time.Second, 2)
+	t.Cleanup(svc.Close)
+	h := NewHandler(svc, 2)
+	// адреса вместо имен, чтобы проверка не ждала DNS
+	body, _ := json.Marshal(LinksRequest{Links: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5"}})
+
+	rec := httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links?partial=true", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp LinksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Links) != 2 || resp.Links["192.0.2.1"] == "" || resp.Links["192.0.2.2"] == "" {
+		t.Fatalf("want the first two links checked, got %v", resp.Links)
+	}
+	want := Truncation{Submitted: 5, Checked: 2, Remaining: 3}
+	if resp.Truncated == nil || resp.Truncated.BatchID != 0 || resp.Truncated.Submitted != want.Submitted ||
+		resp.Truncated.Checked != want.Checked || resp.Truncated.Remaining != want.Remaining {
+		t.Fatalf("truncated = %+v, want %+v", resp.Truncated, want)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links?partial=true&queue_remainder=true", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("queued: status = %d, want 200", rec.Code)
+	}
+	resp = LinksResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Truncated == nil || resp.Truncated.BatchID == 0 || len(resp.Truncated.Tasks) != 2 {
+		t.Fatalf("want the remainder queued as two tasks, got %+v", resp.Truncated)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sum, err := svc.GetBatch(context.Background(), resp.Truncated.BatchID, "")
+		if err != nil || sum == nil {
+			t.Fatalf("GetBatch: %v, %v", sum, err)
+		}
+		if sum.Status == domain.BatchCompleted {
+			if sum.Links != 3 || sum.Checked != 3 {
+				t.Fatalf("unexpected summary %+v", sum)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("batch still %s", sum.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Links(rec, httptest.NewRequest(http.MethodPost, "/links?queue_remainder=true", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("queue_remainder without partial: status = %d, want 400", rec.Code)
+	}
+}
+
+func uploadRequest(t *testing.T, filename, content string, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write([]byte(content))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/links", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestLinksHandler_Upload(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		fields   map[string]string
+		want     []string
+	}{
+		{"lines", "links.txt", "a.example\r\n\n# skipped\nb.example\n", nil, []string{"a.example", "b.example"}},
+		{"csv header", "export.csv", "\ufeffTitle,URL\nHome,a.example\nDocs,\"b.example\"\n", nil, []string{"a.example", "b.example"}},
+		{"csv first column", "export.csv", "a.example,1\nb.example,2\n", nil, []string{"a.example", "b.example"}},
+		{"named column", "export.csv", "page,target\na.example,b.example\n", map[string]string{"column": "Target"}, []string{"b.example"}},
+		{"column index", "export.txt", "x,a.example\ny,b.example\n", map[string]string{"column": "2"}, []string{"a.example", "b.example"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t)
+			rec := httptest.NewRecorder()
+			h.Links(rec, uploadRequest(t, tc.filename, tc.content, tc.fields))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			var resp LinksResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			var got []string
+			for link := range resp.Links {
+				got = append(got, link)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("links = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	h := newTestHandler(t)
+	h.SetMaxUploadBytes(16)
+	rec := httptest.NewRecorder()
+	h.Links(rec, uploadRequest(t, "links.txt", "a.example\nb.example\n", nil))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized file: status = %d, want 413", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.Links(rec, uploadRequest(t, "links.csv", "a,b\n", map[string]string{"column": "url"}))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown column: status = %d, want 400", rec.Code)
+	}
+}
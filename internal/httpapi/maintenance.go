@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/olgkv/linkchecker/internal/maintenance"
+)
+
+const maxMaintenanceBytes = 64 << 10
+
+type MaintenanceResponse struct {
+	Windows []maintenance.Window `json:"windows"`
+}
+
+// UseMaintenance enables managing the maintenance windows of schedule on
+// /admin/maintenance.
+func (h *Handler) UseMaintenance(schedule *maintenance.Schedule) {
+	h.maintenance = schedule
+}
+
+// Maintenance lists maintenance windows on GET, adds one on POST and removes
+// the window ?id= on DELETE.
+func (h *Handler) Maintenance(w http.ResponseWriter, r *http.Request) {
+	if h.maintenance == nil {
+		http.Error(w, "maintenance windows are not enabled", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var win maintenance.Window
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMaintenanceBytes)).Decode(&win); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := win.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		win, err := h.maintenance.Add(win)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(win)
+		return
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id <= 0 {
+			http.Error(w, "id must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		ok, err := h.maintenance.Remove(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(MaintenanceResponse{Windows: h.maintenance.List()})
+}
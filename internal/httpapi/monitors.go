@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/auth"
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+// MonitorResponse describes a monitor; LastTaskID is the task holding its
+// latest re-check. Links holds the latest status of each link and Latency
+// its response time percentiles, both only set for a single monitor.
+type MonitorResponse struct {
+	TaskID     int                                  `json:"task_id"`
+	Interval   string                               `json:"interval"`
+	State      string                               `json:"state"`
+	LastRun    time.Time                            `json:"last_run,omitzero"`
+	NextRun    time.Time                            `json:"next_run,omitzero"`
+	Runs       int                                  `json:"runs"`
+	LastTaskID int                                  `json:"last_task_id,omitempty"`
+	Links      map[string]domain.LinkStatus         `json:"links,omitempty"`
+	Latency    map[string]domain.LatencyPercentiles `json:"latency,omitempty"`
+	// PausedReason says why a monitor was paused without being asked to.
+	PausedReason string `json:"paused_reason,omitempty"`
+	// Overlap is the overlap policy; SkippedRuns counts the runs it dropped.
+	Overlap     string `json:"overlap"`
+	SkippedRuns int    `json:"skipped_runs,omitempty"`
+}
+
+type MonitorsResponse struct {
+	Monitors []*MonitorResponse `json:"monitors"`
+}
+
+func (h *Handler) monitorResponse(m service.Monitor, task *domain.Task) *MonitorResponse {
+	resp := &MonitorResponse{
+		TaskID:      m.TaskID,
+		Interval:    m.Interval.String(),
+		State:       "active",
+		LastRun:     localTime(m.LastRun, h.loc),
+		NextRun:     localTime(m.NextRun, h.loc),
+		Runs:        m.Runs,
+		LastTaskID:  m.LastTaskID,
+		Overlap:     string(service.OverlapSkip),
+		SkippedRuns: m.Skipped,
+	}
+	if m.Options.Overlap != "" {
+		resp.Overlap = m.Options.Overlap
+	}
+	if m.Paused {
+		resp.State, resp.NextRun = "paused", time.Time{}
+		resp.PausedReason = m.PausedReason
+	}
+	if task != nil {
+		resp.Links = make(map[string]domain.LinkStatus, len(task.Links))
+		for _, link := range task.Links {
+			status := domain.LinkStatus(task.Result[link])
+			if status == "" {
+				status = domain.StatusNotAvailable
+			}
+			resp.Links[link] = status
+		}
+	}
+	return resp
+}
+
+// Monitors serves GET /monitors with the monitors of the caller.
+func (h *Handler) Monitors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	resp := MonitorsResponse{Monitors: []*MonitorResponse{}}
+	for _, m := range h.svc.ListMonitors(auth.Owner(r.Context())) {
+		resp.Monitors = append(resp.Monitors, h.monitorResponse(m, nil))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Monitor serves GET /monitors/{id} with a monitor and the latest status of
+// the links of its task.
+func (h *Handler) Monitor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	st, err := h.svc.GetMonitor(r.Context(), id, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if st == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	resp := h.monitorResponse(st.Monitor, st.Task)
+	resp.Latency = st.Latency
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// MonitorAction serves POST /monitors/{id}/start, /pause and /stop. Stopping
+// removes the monitor but keeps the task with its latest results.
+func (h *Handler) MonitorAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var action func(int, string) (bool, error)
+	switch r.PathValue("action") {
+	case "start":
+		action = h.svc.StartMonitor
+	case "pause":
+		action = h.svc.PauseMonitor
+	case "stop":
+		action = h.svc.StopMonitor
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	ok, err := action(id, auth.Owner(r.Context()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/auth"
+	"github.com/olgkv/linkchecker/internal/quota"
+)
+
+// reserveQuota charges links to the caller's quota and writes quota headers.
+// It responds with 429 and returns false when the quota is exhausted.
+func (h *Handler) reserveQuota(w http.ResponseWriter, r *http.Request, links int) bool {
+	if h.quota == nil {
+		return true
+	}
+	p, ok := auth.FromContext(r.Context())
+	if !ok {
+		return true
+	}
+
+	st, err := h.quota.Reserve(p.Name, links)
+	setQuotaHeaders(w, st)
+	if errors.Is(err, quota.ErrExceeded) {
+		reset := st.DailyReset
+		if st.MonthlyLimit > 0 && st.MonthlyUsed+links > st.MonthlyLimit {
+			reset = st.MonthlyReset
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+		return false
+	}
+	if err != nil {
+		slog.Error("reserve quota", "principal", p.Name, "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// releaseQuota refunds links reserved by reserveQuota that were not checked.
+func (h *Handler) releaseQuota(r *http.Request, links int) {
+	if h.quota == nil || links == 0 {
+		return
+	}
+	p, ok := auth.FromContext(r.Context())
+	if !ok {
+		return
+	}
+	if err := h.quota.Release(p.Name, links); err != nil {
+		slog.Error("release quota", "principal", p.Name, "err", err)
+	}
+}
+
+func setQuotaHeaders(w http.ResponseWriter, st quota.Status) {
+	if st.LimitsDisabled {
+		return
+	}
+	if st.DailyLimit > 0 {
+		w.Header().Set("X-Quota-Daily-Limit", strconv.Itoa(st.DailyLimit))
+		w.Header().Set("X-Quota-Daily-Remaining", strconv.Itoa(st.DailyRemaining()))
+		w.Header().Set("X-Quota-Daily-Reset", strconv.FormatInt(st.DailyReset.Unix(), 10))
+	}
+	if st.MonthlyLimit > 0 {
+		w.Header().Set("X-Quota-Monthly-Limit", strconv.Itoa(st.MonthlyLimit))
+		w.Header().Set("X-Quota-Monthly-Remaining", strconv.Itoa(st.MonthlyRemaining()))
+		w.Header().Set("X-Quota-Monthly-Reset", strconv.FormatInt(st.MonthlyReset.Unix(), 10))
+	}
+}
+
+// Usage reports quota usage of the authenticated caller.
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	p, ok := auth.FromContext(r.Context())
+	if !ok || h.quota == nil {
+		http.Error(w, "usage accounting is disabled", http.StatusNotFound)
+		return
+	}
+
+	st := h.quota.Usage(p.Name)
+	setQuotaHeaders(w, st)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(st)
+}
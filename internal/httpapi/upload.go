@@ -0,0 +1,188 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxUploadBytes limits a link list file uploaded to /links.
+const defaultMaxUploadBytes = 1 << 20
+
+// maxFormFieldBytes limits the other form fields of an upload.
+const maxFormFieldBytes = 64 << 10
+
+var (
+	errUploadTooLarge = errors.New("uploaded file too large")
+	errUploadInvalid  = errors.New("invalid upload")
+)
+
+// urlColumns are CSV header names taken as the link column when the upload
+// does not name one.
+var urlColumns = []string{"url", "urls", "link", "links"}
+
+// isUpload reports whether r carries a multipart form instead of JSON.
+func isUpload(r *http.Request) bool {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == "multipart/form-data"
+}
+
+// decodeUpload reads a /links request from a multipart form. The "file"
+// field holds the links, one per line or, for CSV files, in one column
+// picked by the "column" field (a header name or a 1-based index). "name"
+// and "tags" (comma separated) label the task as in a JSON request.
+func decodeUpload(r *http.Request, maxFile int64) (LinksRequest, error) {
+	var req LinksRequest
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return req, errUploadInvalid
+	}
+	var (
+		file    []byte
+		csvFile bool
+		column  string
+		seen    bool
+	)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return req, uploadErr(err)
+		}
+		switch part.FormName() {
+		case "file":
+			if seen {
+				return req, errUploadInvalid
+			}
+			seen = true
+			if file, err = io.ReadAll(io.LimitReader(part, maxFile+1)); err != nil {
+				return req, uploadErr(err)
+			}
+			if int64(len(file)) > maxFile {
+				return req, errUploadTooLarge
+			}
+			ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			csvFile = ct == "text/csv" || strings.EqualFold(path.Ext(part.FileName()), ".csv")
+		case "name", "tags", "column":
+			value, err := io.ReadAll(io.LimitReader(part, maxFormFieldBytes+1))
+			if err != nil {
+				return req, uploadErr(err)
+			}
+			if len(value) > maxFormFieldBytes {
+				return req, errUploadInvalid
+			}
+			switch part.FormName() {
+			case "name":
+				req.Name = string(value)
+			case "tags":
+				req.Tags = append(req.Tags, strings.Split(string(value), ",")...)
+			case "column":
+				column = strings.TrimSpace(string(value))
+			}
+		}
+		part.Close()
+	}
+	if !seen {
+		return req, errUploadInvalid
+	}
+
+	file = bytes.TrimPrefix(file, []byte("\ufeff")) // BOM из экспорта Excel
+	if csvFile || column != "" {
+		req.Links, err = csvLinks(file, column)
+	} else {
+		req.Links = lineLinks(file)
+	}
+	return req, err
+}
+
+// uploadErr maps a body read error, which may come from the request body
+// limit, to the upload errors.
+func uploadErr(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return errUploadTooLarge
+	}
+	return errUploadInvalid
+}
+
+// lineLinks returns the non-empty lines of data, skipping "#" comments.
+func lineLinks(data []byte) []string {
+	var links []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			links = append(links, line)
+		}
+	}
+	return links
+}
+
+// csvLinks returns the non-empty cells of one CSV column. Without column
+// the first column is used, unless the header row names a link column.
+func csvLinks(data []byte, column string) ([]string, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil || len(rows) == 0 {
+		return nil, errUploadInvalid
+	}
+
+	col := -1
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return nil, errUploadInvalid
+		}
+		col = n - 1
+		if col < len(rows[0]) && isURLColumn(strings.TrimSpace(rows[0][col])) {
+			rows = rows[1:]
+		}
+	} else {
+		for i, cell := range rows[0] {
+			cell = strings.TrimSpace(cell)
+			if column != "" && strings.EqualFold(cell, column) || column == "" && isURLColumn(cell) {
+				col = i
+				break
+			}
+		}
+		switch {
+		case col >= 0:
+			rows = rows[1:]
+		case column != "":
+			return nil, errUploadInvalid
+		default:
+			col = 0
+		}
+	}
+
+	var links []string
+	for _, row := range rows {
+		if col < len(row) {
+			if link := strings.TrimSpace(row[col]); link != "" {
+				links = append(links, link)
+			}
+		}
+	}
+	return links, nil
+}
+
+func isURLColumn(name string) bool {
+	for _, c := range urlColumns {
+		if strings.EqualFold(name, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/service"
+)
+
+// maxLinkLength bounds a single link of a check request.
+const maxLinkLength = 2048
+
+// FieldError is one problem of a request. Index is set for the elements of
+// list fields, such as the position of an invalid link.
+type FieldError struct {
+	Field  string `json:"field"`
+	Index  *int   `json:"index,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ValidationErrorResponse is the 400 body of a request that failed
+// validation.
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+func fieldError(field, reason string) FieldError {
+	return FieldError{Field: field, Reason: reason}
+}
+
+func indexError(field string, i int, value, reason string) FieldError {
+	return FieldError{Field: field, Index: &i, Value: value, Reason: reason}
+}
+
+// partialQuery parses the partial and queue_remainder flags of /links.
+func partialQuery(q url.Values) (partial, queueRest bool, errs []FieldError) {
+	var err error
+	if v := q.Get("partial"); v != "" {
+		if partial, err = strconv.ParseBool(v); err != nil {
+			errs = append(errs, FieldError{Field: "partial", Value: v, Reason: "want true or false"})
+		}
+	}
+	if v := q.Get("queue_remainder"); v != "" {
+		if queueRest, err = strconv.ParseBool(v); err != nil {
+			errs = append(errs, FieldError{Field: "queue_remainder", Value: v, Reason: "want true or false"})
+		} else if queueRest && !partial {
+			errs = append(errs, FieldError{Field: "queue_remainder", Value: v, Reason: "requires partial=true"})
+		}
+	}
+	return partial, queueRest, errs
+}
+
+// linkPageQuery parses the link_offset and link_limit parameters of
+// GET /tasks/{id}; paged is false when neither is given.
+func linkPageQuery(q url.Values) (page service.LinkPage, paged bool, errs []FieldError) {
+	for _, p := range []struct {
+		name string
+		dst  *int
+		min  int
+	}{{"link_offset", &page.Offset, 0}, {"link_limit", &page.Limit, 1}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		paged = true
+		n, err := strconv.Atoi(v)
+		if err != nil || n < p.min {
+			errs = append(errs, FieldError{Field: p.name, Value: v, Reason: fmt.Sprintf("want an integer of at least %d", p.min)})
+			continue
+		}
+		*p.dst = n
+	}
+	return page, paged, errs
+}
+
+// validateLinks lists every problem of a check request at once, so a client
+// can fix them in one go.
+func (h *Handler) validateLinks(req LinksRequest, groupBy string, partial bool) []FieldError {
+	var errs []FieldError
+	switch n := int64(len(req.Links)); {
+	case n == 0:
+		errs = append(errs, fieldError("links", "at least one link is required"))
+	case !partial && n > h.maxLinks.Load() && n > h.maxBatchLinks.Load():
+		errs = append(errs, fieldError("links", fmt.Sprintf("at most %d links are allowed, got %d", h.maxBatchLinks.Load(), n)))
+	}
+	for i, link := range req.Links {
+		if len(link) > maxLinkLength {
+			errs = append(errs, indexError("links", i, link[:64]+"...", fmt.Sprintf("link is longer than %d bytes", maxLinkLength)))
+			continue
+		}
+		if err := h.svc.ValidateLink(link); err != nil {
+			errs = append(errs, indexError("links", i, link, err.Error()))
+		}
+	}
+	if req.FailAfter < 0 {
+		errs = append(errs, fieldError("fail_after", "must not be negative"))
+	}
+	if req.Auth != nil {
+		if _, ok := req.Auth.credentials(); !ok {
+			errs = append(errs, fieldError("auth", "want basic username and password or a bearer token and the hosts to send them to"))
+		}
+	}
+	for i, target := range req.Notify {
+		if !h.svc.ValidNotifyTarget(target) {
+			errs = append(errs, indexError("notify", i, target, "unknown notify target"))
+		}
+	}
+	if _, err := service.ParsePriority(req.Priority); err != nil {
+		errs = append(errs, FieldError{Field: "priority", Value: req.Priority, Reason: "want high, normal or low"})
+	}
+	if req.Monitor != nil {
+		errs = append(errs, h.validateMonitor(req, partial)...)
+	}
+	if groupBy != "" && groupBy != "host" {
+		errs = append(errs, FieldError{Field: "group_by", Value: groupBy, Reason: "want host"})
+	}
+	return errs
+}
+
+func (h *Handler) validateMonitor(req LinksRequest, partial bool) []FieldError {
+	var errs []FieldError
+	if !h.svc.MonitorsEnabled() {
+		errs = append(errs, fieldError("monitor", "monitors are not enabled"))
+	}
+	interval, err := time.ParseDuration(req.Monitor.Interval)
+	switch {
+	case err != nil:
+		errs = append(errs, FieldError{Field: "monitor.interval", Value: req.Monitor.Interval, Reason: "want a duration such as 5m"})
+	case interval < service.MinMonitorInterval:
+		errs = append(errs, FieldError{Field: "monitor.interval", Value: req.Monitor.Interval, Reason: fmt.Sprintf("at least %s", service.MinMonitorInterval)})
+	}
+	if _, err := service.ParseOverlapPolicy(req.Monitor.Overlap); err != nil {
+		errs = append(errs, FieldError{Field: "monitor.overlap", Value: req.Monitor.Overlap, Reason: "want skip, queue or concurrent"})
+	}
+	if req.Auth != nil {
+		errs = append(errs, fieldError("monitor", "links checked with auth cannot be monitored, credentials are not stored"))
+	}
+	if !partial && int64(len(req.Links)) > h.maxLinks.Load() {
+		errs = append(errs, fieldError("monitor", fmt.Sprintf("at most %d links can be monitored", h.maxLinks.Load())))
+	}
+	return errs
+}
+
+// maxReportPassword is the longest password the PDF standard security
+// handler uses in full.
+const maxReportPassword = 32
+
+// validateReport lists the problems of a report request.
+func (h *Handler) validateReport(req ReportRequest) []FieldError {
+	var errs []FieldError
+	if len(req.LinksList) == 0 && req.Tag == "" && req.Batch == 0 {
+		errs = append(errs, fieldError("links_list", "links_list, tag or batch is required"))
+	}
+	for i, id := range req.LinksList {
+		if id <= 0 {
+			errs = append(errs, indexError("links_list", i, fmt.Sprint(id), "task id must be positive"))
+		}
+	}
+	pdf := req.Format == "" || req.Format == service.ReportFormatPDF
+	switch {
+	case !service.ValidReportFormat(req.Format):
+		errs = append(errs, FieldError{Field: "format", Value: req.Format, Reason: "want pdf, json or bundle"})
+	case !pdf && h.svc.ReportsEncrypted():
+		errs = append(errs, FieldError{Field: "format", Value: req.Format, Reason: "reports must be encrypted, only pdf can be"})
+	case !pdf && req.Password != "":
+		errs = append(errs, fieldError("password", "only pdf reports can be encrypted"))
+	}
+	if len(req.Password) > maxReportPassword {
+		errs = append(errs, fieldError("password", fmt.Sprintf("at most %d bytes", maxReportPassword)))
+	}
+	if req.Batch < 0 {
+		errs = append(errs, FieldError{Field: "batch", Value: fmt.Sprint(req.Batch), Reason: "batch id must be positive"})
+	}
+	return errs
+}
+
+func writeValidationError(w http.ResponseWriter, msg string, fields []FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(ValidationErrorResponse{Error: msg, Fields: fields})
+}
@@ -0,0 +1,186 @@
+// Package maintenance keeps the maintenance windows during which hosts are
+// not checked.
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repeat values of a window.
+const (
+	RepeatNone   = ""
+	RepeatDaily  = "daily"
+	RepeatWeekly = "weekly"
+)
+
+// Window is a period during which links on hosts matching Hosts are not
+// checked. Hosts is a glob such as "*.example.com" matched against the
+// lowercased host name. A repeating window recurs every day or week after
+// Start with the same length, until Until when it is set.
+type Window struct {
+	ID      int       `json:"id"`
+	Hosts   string    `json:"hosts"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Repeat  string    `json:"repeat,omitempty"`
+	Until   time.Time `json:"until,omitzero"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// Validate reports the first invalid field of w.
+func (w Window) Validate() error {
+	if _, err := path.Match(w.Hosts, ""); err != nil || strings.TrimSpace(w.Hosts) == "" {
+		return fmt.Errorf("hosts: invalid pattern %q", w.Hosts)
+	}
+	if !w.End.After(w.Start) {
+		return errors.New("end: must be after start")
+	}
+	period := w.period()
+	switch {
+	case w.Repeat != RepeatNone && period == 0:
+		return fmt.Errorf("repeat: want daily or weekly, got %q", w.Repeat)
+	case period > 0 && w.End.Sub(w.Start) >= period:
+		return fmt.Errorf("end: a %s window must be shorter than its period", w.Repeat)
+	}
+	return nil
+}
+
+func (w Window) period() time.Duration {
+	switch w.Repeat {
+	case RepeatDaily:
+		return 24 * time.Hour
+	case RepeatWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// Matches reports whether host is in maintenance at t.
+func (w Window) Matches(host string, t time.Time) bool {
+	if ok, _ := path.Match(strings.ToLower(w.Hosts), strings.ToLower(host)); !ok {
+		return false
+	}
+	if t.Before(w.Start) || !w.Until.IsZero() && !t.Before(w.Until) {
+		return false
+	}
+	offset := t.Sub(w.Start)
+	if period := w.period(); period > 0 {
+		offset %= period
+	}
+	return offset < w.End.Sub(w.Start)
+}
+
+// Schedule holds the maintenance windows and persists them to a JSON file.
+type Schedule struct {
+	mu      sync.RWMutex
+	path    string
+	windows []Window
+}
+
+// Open loads the windows saved at path, if it exists. An empty path keeps
+// the windows in memory only.
+func Open(path string) (*Schedule, error) {
+	s := &Schedule{path: path}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.windows); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// List returns the windows ordered by ID.
+func (s *Schedule) List() []Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.windows)
+}
+
+// Add validates w, assigns it the next ID and saves it.
+func (s *Schedule) Add(w Window) (Window, error) {
+	w.Hosts = strings.ToLower(strings.TrimSpace(w.Hosts))
+	if err := w.Validate(); err != nil {
+		return Window{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.ID = 1
+	if n := len(s.windows); n > 0 {
+		w.ID = s.windows[n-1].ID + 1
+	}
+	s.windows = append(s.windows, w)
+	if err := s.saveLocked(); err != nil {
+		s.windows = s.windows[:len(s.windows)-1]
+		return Window{}, err
+	}
+	return w, nil
+}
+
+// Remove deletes window id and reports whether it existed.
+func (s *Schedule) Remove(id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.windows, func(w Window) bool { return w.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	prev := s.windows
+	s.windows = slices.Delete(slices.Clone(s.windows), i, i+1)
+	if err := s.saveLocked(); err != nil {
+		s.windows = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// Active reports whether host is in a maintenance window at t.
+func (s *Schedule) Active(host string, t time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, w := range s.windows {
+		if w.Matches(host, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.windows)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
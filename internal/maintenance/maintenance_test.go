@@ -0,0 +1,89 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWindow_Matches(t *testing.T) {
+	start := time.Date(2024, 5, 6, 2, 0, 0, 0, time.UTC) // понедельник
+	w := Window{Hosts: "*.example.com", Start: start, End: start.Add(2 * time.Hour), Repeat: RepeatWeekly, Until: start.AddDate(0, 0, 14)}
+
+	cases := []struct {
+		host string
+		at   time.Time
+		want bool
+	}{
+		{"api.example.com", start.Add(time.Hour), true},
+		{"API.Example.com", start.Add(time.Hour), true},
+		{"example.com", start.Add(time.Hour), false},
+		{"api.example.com", start.Add(-time.Minute), false},
+		{"api.example.com", start.Add(2 * time.Hour), false},
+		{"api.example.com", start.AddDate(0, 0, 7).Add(30 * time.Minute), true},
+		{"api.example.com", start.AddDate(0, 0, 8).Add(30 * time.Minute), false},
+		{"api.example.com", start.AddDate(0, 0, 14).Add(30 * time.Minute), false},
+	}
+	for _, tc := range cases {
+		if got := w.Matches(tc.host, tc.at); got != tc.want {
+			t.Errorf("Matches(%q, %s) = %v, want %v", tc.host, tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestWindow_Validate(t *testing.T) {
+	start := time.Date(2024, 5, 6, 2, 0, 0, 0, time.UTC)
+	bad := []Window{
+		{Hosts: "", Start: start, End: start.Add(time.Hour)},
+		{Hosts: "[", Start: start, End: start.Add(time.Hour)},
+		{Hosts: "example.com", Start: start, End: start},
+		{Hosts: "example.com", Start: start, End: start.Add(time.Hour), Repeat: "hourly"},
+		{Hosts: "example.com", Start: start, End: start.Add(25 * time.Hour), Repeat: RepeatDaily},
+	}
+	for _, w := range bad {
+		if err := w.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", w)
+		}
+	}
+}
+
+func TestSchedule_AddRemovePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maintenance.json")
+	start := time.Date(2024, 5, 6, 2, 0, 0, 0, time.UTC)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	w1, err := s.Add(Window{Hosts: "DB.example.com", Start: start, End: start.Add(time.Hour), Repeat: RepeatDaily})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	w2, err := s.Add(Window{Hosts: "*.test", Start: start, End: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if w1.ID != 1 || w2.ID != 2 || w1.Hosts != "db.example.com" {
+		t.Fatalf("unexpected windows: %+v, %+v", w1, w2)
+	}
+	if ok, err := s.Remove(2); !ok || err != nil {
+		t.Fatalf("Remove(2) = %v, %v", ok, err)
+	}
+	if ok, _ := s.Remove(2); ok {
+		t.Fatal("Remove(2) twice reported the window as existing")
+	}
+
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := reloaded.List(); len(got) != 1 || got[0].ID != 1 {
+		t.Fatalf("reloaded windows = %+v, want window 1", got)
+	}
+	if !reloaded.Active("db.example.com", start.AddDate(0, 0, 3).Add(10*time.Minute)) {
+		t.Fatal("expected daily window to be active on a later day")
+	}
+	if reloaded.Active("a.test", start.Add(10*time.Minute)) {
+		t.Fatal("removed window is still active")
+	}
+}
@@ -0,0 +1,122 @@
+// Package monitor keeps the monitors that re-check tasks at an interval.
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// Store holds the monitors and persists them to a JSON file.
+type Store struct {
+	mu       sync.RWMutex
+	path     string
+	monitors []ports.Monitor // по возрастанию TaskID
+}
+
+// Open loads the monitors saved at path, if it exists. An empty path keeps
+// the monitors in memory only.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.monitors); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	slices.SortFunc(s.monitors, func(a, b ports.Monitor) int { return a.TaskID - b.TaskID })
+	return s, nil
+}
+
+// Put adds m or replaces the monitor of the same task, and saves them.
+func (s *Store) Put(m ports.Monitor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.monitors
+	i, found := s.find(m.TaskID)
+	s.monitors = slices.Clone(s.monitors)
+	if found {
+		s.monitors[i] = m
+	} else {
+		s.monitors = slices.Insert(s.monitors, i, m)
+	}
+	if err := s.saveLocked(); err != nil {
+		s.monitors = prev
+		return err
+	}
+	return nil
+}
+
+// Get returns the monitor of task id and whether there is one.
+func (s *Store) Get(id int) (ports.Monitor, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i, ok := s.find(id); ok {
+		return s.monitors[i], true
+	}
+	return ports.Monitor{}, false
+}
+
+// List returns the monitors ordered by task ID.
+func (s *Store) List() []ports.Monitor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.monitors)
+}
+
+// Remove deletes the monitor of task id and reports whether it existed.
+func (s *Store) Remove(id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.find(id)
+	if !ok {
+		return false, nil
+	}
+	prev := s.monitors
+	s.monitors = slices.Delete(slices.Clone(s.monitors), i, i+1)
+	if err := s.saveLocked(); err != nil {
+		s.monitors = prev
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Store) find(id int) (int, bool) {
+	return slices.BinarySearchFunc(s.monitors, id, func(m ports.Monitor, id int) int { return m.TaskID - id })
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.monitors)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+func TestStore_PersistsMonitors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitors.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, id := range []int{7, 3, 5} {
+		if err := s.Put(ports.Monitor{TaskID: id, Interval: 5 * time.Minute}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if err := s.Put(ports.Monitor{TaskID: 5, Interval: time.Hour, Paused: true}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if ok, err := s.Remove(7); err != nil || !ok {
+		t.Fatalf("Remove = %v, %v; want true", ok, err)
+	}
+	if ok, _ := s.Remove(7); ok {
+		t.Fatalf("second Remove reported an existing monitor")
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	list := reopened.List()
+	if len(list) != 2 || list[0].TaskID != 3 || list[1].TaskID != 5 {
+		t.Fatalf("List = %+v, want monitors of tasks 3 and 5", list)
+	}
+	if m, ok := reopened.Get(5); !ok || m.Interval != time.Hour || !m.Paused {
+		t.Fatalf("Get(5) = %+v, %v; want the replaced monitor", m, ok)
+	}
+	if _, ok := reopened.Get(7); ok {
+		t.Fatalf("removed monitor came back")
+	}
+}
@@ -0,0 +1,91 @@
+// Package notify posts summaries of tasks with broken links to chat
+// webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultTimeout bounds one webhook request.
+const defaultTimeout = 10 * time.Second
+
+// ParseWebhooks parses "name=url,name=url" into webhook URLs by name.
+func ParseWebhooks(s string) (map[string]string, error) {
+	hooks := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("webhook %q: want name=url", item)
+		}
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return nil, fmt.Errorf("webhook %q: invalid url", name)
+		}
+		hooks[name] = u.String()
+	}
+	return hooks, nil
+}
+
+// webhooks posts JSON bodies to named webhook URLs.
+type webhooks struct {
+	hooks  map[string]string
+	client *http.Client
+}
+
+func newWebhooks(hooks map[string]string, client *http.Client) webhooks {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return webhooks{hooks: hooks, client: client}
+}
+
+// Has implements ports.Notifier.
+func (w webhooks) Has(target string) bool {
+	_, ok := w.hooks[target]
+	return ok
+}
+
+func (w webhooks) post(ctx context.Context, target string, body any) error {
+	hook, ok := w.hooks[target]
+	if !ok {
+		return fmt.Errorf("unknown webhook %q", target)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		// URL вебхука секретный, в ошибку его не пишем
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("post to webhook %q: %w", target, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post to webhook %q: %s", target, resp.Status)
+	}
+	return nil
+}
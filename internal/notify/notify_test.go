@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	hooks, err := ParseWebhooks(" #alerts=https://hooks.example/a , ops=https://hooks.example/b,")
+	if err != nil {
+		t.Fatalf("ParseWebhooks: %v", err)
+	}
+	if len(hooks) != 2 || hooks["#alerts"] != "https://hooks.example/a" || hooks["ops"] != "https://hooks.example/b" {
+		t.Fatalf("unexpected webhooks %v", hooks)
+	}
+	for _, bad := range []string{"https://hooks.example/a", "ops=", "ops=ftp://hooks.example"} {
+		if _, err := ParseWebhooks(bad); err == nil {
+			t.Fatalf("%q accepted", bad)
+		}
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	n := ports.Notification{
+		TaskID:      17,
+		Name:        "docs",
+		Links:       50,
+		Broken:      3,
+		BrokenLinks: []ports.BrokenLink{{URL: "https://a.example/<x>", Error: "http status 404"}},
+		ReportURL:   "https://lc.example/v1/report?links_list=17",
+	}
+	ctx := context.Background()
+
+	slack := NewSlack(map[string]string{"#alerts": srv.URL, "broken": srv.URL + "/fail"}, nil)
+	if !slack.Has("#alerts") || slack.Has("#other") {
+		t.Fatal("Has does not match the configured webhooks")
+	}
+	if err := slack.Notify(ctx, "#alerts", n); err != nil {
+		t.Fatalf("Slack: %v", err)
+	}
+	text, _ := got["text"].(string)
+	for _, want := range []string{"*3 of 50 links broken* in task 17 \"docs\"", "https://a.example/&lt;x&gt; — http status 404", "…and 2 more", "<https://lc.example/v1/report?links_list=17|Open report>"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("Slack text %q lacks %q", text, want)
+		}
+	}
+	if err := slack.Notify(ctx, "broken", n); err == nil || strings.Contains(err.Error(), srv.URL) {
+		t.Fatalf("failed webhook: %v", err)
+	}
+
+	teams := NewTeams(map[string]string{"ops": srv.URL}, nil)
+	if err := teams.Notify(ctx, "ops", n); err != nil {
+		t.Fatalf("Teams: %v", err)
+	}
+	if got["@type"] != "MessageCard" || got["title"] != "3 of 50 links broken in task 17 \"docs\"" || got["potentialAction"] == nil {
+		t.Fatalf("unexpected Teams card %v", got)
+	}
+}
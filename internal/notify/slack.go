@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// Slack posts notifications to Slack incoming webhooks.
+type Slack struct {
+	webhooks
+}
+
+// NewSlack returns a notifier for the named Slack webhooks; a nil client
+// uses one with a 10 second timeout.
+func NewSlack(hooks map[string]string, client *http.Client) *Slack {
+	return &Slack{newWebhooks(hooks, client)}
+}
+
+// Notify implements ports.Notifier.
+func (s *Slack) Notify(ctx context.Context, target string, n ports.Notification) error {
+	return s.post(ctx, target, map[string]string{"text": slackText(n)})
+}
+
+func slackText(n ports.Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ":warning: *%d of %d links broken* in %s\n", n.Broken, n.Links, title(n))
+	for _, l := range n.BrokenLinks {
+		fmt.Fprintf(&b, "• %s — %s\n", slackEscape(l.URL), slackEscape(l.Error))
+	}
+	if more := n.Broken - len(n.BrokenLinks); more > 0 {
+		fmt.Fprintf(&b, "…and %d more\n", more)
+	}
+	if isAbsolute(n.ReportURL) {
+		fmt.Fprintf(&b, "<%s|Open report>", n.ReportURL)
+	} else {
+		fmt.Fprintf(&b, "Report: %s", n.ReportURL)
+	}
+	return b.String()
+}
+
+// slackEscape escapes the characters Slack treats as markup.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func title(n ports.Notification) string {
+	if n.Name != "" {
+		return fmt.Sprintf("task %d %q", n.TaskID, n.Name)
+	}
+	return fmt.Sprintf("task %d", n.TaskID)
+}
+
+func isAbsolute(u string) bool {
+	return strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
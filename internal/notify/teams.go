@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// Teams posts notifications to Microsoft Teams incoming webhooks as
+// message cards.
+type Teams struct {
+	webhooks
+}
+
+// NewTeams returns a notifier for the named Teams webhooks; a nil client
+// uses one with a 10 second timeout.
+func NewTeams(hooks map[string]string, client *http.Client) *Teams {
+	return &Teams{newWebhooks(hooks, client)}
+}
+
+type teamsCard struct {
+	Type       string        `json:"@type"`
+	Context    string        `json:"@context"`
+	ThemeColor string        `json:"themeColor"`
+	Summary    string        `json:"summary"`
+	Title      string        `json:"title"`
+	Text       string        `json:"text"`
+	Actions    []teamsAction `json:"potentialAction,omitempty"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+// Notify implements ports.Notifier.
+func (t *Teams) Notify(ctx context.Context, target string, n ports.Notification) error {
+	summary := fmt.Sprintf("%d of %d links broken", n.Broken, n.Links)
+	var text strings.Builder
+	for _, l := range n.BrokenLinks {
+		fmt.Fprintf(&text, "- %s: %s\n", l.URL, l.Error)
+	}
+	if more := n.Broken - len(n.BrokenLinks); more > 0 {
+		fmt.Fprintf(&text, "\n…and %d more", more)
+	}
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: "D70000",
+		Summary:    summary,
+		Title:      summary + " in " + title(n),
+		Text:       text.String(),
+	}
+	if isAbsolute(n.ReportURL) {
+		card.Actions = []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "Open report",
+			Targets: []teamsTarget{{OS: "default", URI: n.ReportURL}},
+		}}
+	} else {
+		card.Text += "\n\nReport: " + n.ReportURL
+	}
+	return t.post(ctx, target, card)
+}
@@ -0,0 +1,126 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Branding customizes the page header and footer and the accent color of
+// generated reports. A nil *Branding renders plain reports.
+type Branding struct {
+	company string
+	header  string
+	footer  string
+	accent  [3]int
+	logo    []byte
+	// logoType is the gofpdf image type of logo: PNG, JPG or GIF.
+	logoType string
+}
+
+// NewBranding loads the logo at logoPath, if any, and parses accent as a
+// "#rrggbb" color; an empty accent keeps text black.
+func NewBranding(company, header, footer, accent, logoPath string) (*Branding, error) {
+	b := &Branding{company: company, header: header, footer: footer}
+	if accent != "" {
+		hex, ok := strings.CutPrefix(accent, "#")
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if !ok || len(hex) != 6 || err != nil {
+			return nil, fmt.Errorf("accent color: want #rrggbb, got %q", accent)
+		}
+		b.accent = [3]int{int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff)}
+	}
+	if logoPath != "" {
+		data, err := os.ReadFile(logoPath)
+		if err != nil {
+			return nil, fmt.Errorf("read logo: %w", err)
+		}
+		switch http.DetectContentType(data) {
+		case "image/png":
+			b.logoType = "PNG"
+		case "image/jpeg":
+			b.logoType = "JPG"
+		case "image/gif":
+			b.logoType = "GIF"
+		default:
+			return nil, fmt.Errorf("logo %s: want a PNG, JPEG or GIF image", logoPath)
+		}
+		b.logo = data
+	}
+	return b, nil
+}
+
+func (b *Branding) apply(p *gofpdf.Fpdf) {
+	// встроенные шрифты PDF в cp1252, переводим UTF-8 заранее
+	tr := p.UnicodeTranslatorFromDescriptor("")
+	if b.logo != nil {
+		p.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: b.logoType}, bytes.NewReader(b.logo))
+	}
+	p.SetHeaderFunc(func() {
+		left, top, right, _ := p.GetMargins()
+		width, _ := p.GetPageSize()
+		x := left
+		if b.logo != nil {
+			p.ImageOptions("logo", x, top, 0, 12, false, gofpdf.ImageOptions{ImageType: b.logoType}, 0, "")
+			info := p.GetImageInfo("logo")
+			x += 12*info.Width()/info.Height() + 4
+		}
+		p.SetXY(x, top)
+		p.SetFont("Arial", "B", 14)
+		b.setAccent(p)
+		p.CellFormat(width-right-x, 12, tr(b.company), "", 0, "L", false, 0, "")
+		p.SetXY(left, top)
+		p.SetFont("Arial", "", 9)
+		p.SetTextColor(0, 0, 0)
+		p.CellFormat(width-left-right, 12, tr(b.header), "", 0, "R", false, 0, "")
+		p.SetDrawColor(b.accent[0], b.accent[1], b.accent[2])
+		p.Line(left, top+14, width-right, top+14)
+		p.SetXY(left, top+18)
+		p.SetFont("Arial", "", 12)
+	})
+	p.AliasNbPages("")
+	p.SetFooterFunc(func() {
+		p.SetY(-15)
+		p.SetFont("Arial", "", 8)
+		p.SetTextColor(0, 0, 0)
+		p.CellFormat(0, 10, tr(b.footer), "", 0, "L", false, 0, "")
+		p.CellFormat(0, 10, fmt.Sprintf("Page %d/{nb}", p.PageNo()), "", 0, "R", false, 0, "")
+	})
+}
+
+func (b *Branding) setAccent(p *gofpdf.Fpdf) {
+	p.SetTextColor(b.accent[0], b.accent[1], b.accent[2])
+}
+
+// heading writes a section title in the accent color.
+func heading(p *gofpdf.Fpdf, b *Branding, h float64, text string) {
+	if b != nil {
+		b.setAccent(p)
+	}
+	p.Cell(40, h, text)
+	p.SetTextColor(0, 0, 0)
+}
+
+// tableHeader writes the header row of a table, filled with the accent
+// color when there is one.
+func tableHeader(p *gofpdf.Fpdf, b *Branding, widths []float64, cells ...string) {
+	fill := b != nil && b.accent != [3]int{}
+	if fill {
+		p.SetFillColor(b.accent[0], b.accent[1], b.accent[2])
+		p.SetTextColor(255, 255, 255)
+	}
+	for i, c := range cells {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		p.CellFormat(widths[i], 7, c, "1", 0, align, fill, 0, "")
+	}
+	p.Ln(7)
+	p.SetTextColor(0, 0, 0)
+}
@@ -0,0 +1,66 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+)
+
+func TestNewBranding(t *testing.T) {
+	logo := filepath.Join(t.TempDir(), "logo.png")
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatalf("encode logo: %v", err)
+	}
+	if err := os.WriteFile(logo, img.Bytes(), 0o600); err != nil {
+		t.Fatalf("write logo: %v", err)
+	}
+
+	b, err := NewBranding("ООО Ромашка", "Internal", "Confidential", "#1A73e8", logo)
+	if err != nil {
+		t.Fatalf("NewBranding: %v", err)
+	}
+	if b.accent != [3]int{0x1a, 0x73, 0xe8} || b.logoType != "PNG" {
+		t.Fatalf("unexpected branding accent %v, logo type %q", b.accent, b.logoType)
+	}
+
+	tasks := []*domain.Task{{ID: 1, Links: []string{"go.dev"}, Result: map[string]string{"go.dev": "available"}}}
+	var out bytes.Buffer
+	if err := WriteLinksReport(&out, domain.BuildLinksReport(tasks, time.Now(), time.UTC), Options{Branding: b}); err != nil {
+		t.Fatalf("WriteLinksReport: %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("%PDF")) {
+		t.Fatalf("output is not a PDF")
+	}
+
+	for _, accent := range []string{"1a73e8", "#1a73e", "#zzzzzz"} {
+		if _, err := NewBranding("", "", "", accent, ""); err == nil {
+			t.Fatalf("accent %q accepted", accent)
+		}
+	}
+	notImage := filepath.Join(t.TempDir(), "logo.txt")
+	_ = os.WriteFile(notImage, []byte("text"), 0o600)
+	if _, err := NewBranding("", "", "", "", notImage); err == nil {
+		t.Fatalf("non-image logo accepted")
+	}
+}
+
+func TestWriteLinksReport_Encrypted(t *testing.T) {
+	r := domain.BuildLinksReport([]*domain.Task{{ID: 1, Links: []string{"intranet.corp"}}}, time.Now(), time.UTC)
+	var plain, encrypted bytes.Buffer
+	if err := WriteLinksReport(&plain, r, Options{}); err != nil {
+		t.Fatalf("WriteLinksReport: %v", err)
+	}
+	if err := WriteLinksReport(&encrypted, r, Options{UserPassword: "s3cret"}); err != nil {
+		t.Fatalf("WriteLinksReport encrypted: %v", err)
+	}
+	if bytes.Contains(plain.Bytes(), []byte("/Encrypt")) || !bytes.Contains(encrypted.Bytes(), []byte("/Encrypt")) {
+		t.Fatalf("only the report with a password should have an /Encrypt dictionary")
+	}
+}
@@ -0,0 +1,30 @@
+package pdf
+
+import "github.com/jung-kurt/gofpdf"
+
+// Options customize a rendered report. The zero value renders a plain,
+// unprotected report.
+type Options struct {
+	Branding *Branding
+	// UserPassword encrypts the report; readers need it to open the file.
+	UserPassword string
+	// OwnerPassword lifts the print and copy only restriction of an
+	// encrypted report; empty picks a random one.
+	OwnerPassword string
+}
+
+// newDocument starts an A4 report, encrypted if opts has a user password,
+// with the branded header and footer on every page.
+func newDocument(opts Options) *gofpdf.Fpdf {
+	p := gofpdf.New("P", "mm", "A4", "")
+	if opts.UserPassword != "" {
+		// шифрование включается до первого содержимого документа
+		p.SetProtection(gofpdf.CnProtectPrint|gofpdf.CnProtectCopy, opts.UserPassword, opts.OwnerPassword)
+	}
+	if opts.Branding != nil {
+		opts.Branding.apply(p)
+	}
+	p.AddPage()
+	p.SetFont("Arial", "", 12)
+	return p
+}
@@ -1,39 +1,159 @@
 package pdf
 
 import (
-	"bytes"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/olgkv/linkchecker/internal/domain"
 
 	"github.com/jung-kurt/gofpdf"
 )
 
-func BuildLinksReport(tasks []*domain.Task) ([]byte, error) {
-	p := gofpdf.New("P", "mm", "A4", "")
-	p.AddPage()
-	p.SetFont("Arial", "", 12)
+// WriteLinksReport renders r straight to w, without collecting the finished
+// document in a separate buffer.
+func WriteLinksReport(w io.Writer, r *domain.LinksReport, opts Options) error {
+	p := newDocument(opts)
+	b := opts.Branding
 
-	p.Cell(40, 10, "Links report")
+	heading(p, b, 10, "Links report")
 	p.Ln(12)
 
-	for _, t := range tasks {
-		p.Cell(40, 10, fmt.Sprintf("Task #%d", t.ID))
+	if len(r.MissingIDs) > 0 {
+		ids := make([]string, len(r.MissingIDs))
+		for i, id := range r.MissingIDs {
+			ids[i] = "#" + strconv.Itoa(id)
+		}
+		p.Cell(40, 8, "Tasks not found: "+strings.Join(ids, ", "))
+		p.Ln(12)
+	}
+
+	for _, t := range r.Tasks {
+		title := fmt.Sprintf("Task #%d", t.ID)
+		if t.Name != "" {
+			title += " - " + t.Name
+		}
+		p.Cell(40, 10, title)
 		p.Ln(8)
+		for _, line := range taskMetaLines(t) {
+			p.Cell(40, 6, line)
+			p.Ln(6)
+		}
 		for _, link := range t.Links {
-			status := t.Result[link]
-			if status == "" {
-				status = string(domain.StatusNotAvailable)
+			line := fmt.Sprintf("%s - %s", link.URL, link.Status)
+			if !link.CheckedAt.IsZero() {
+				line += fmt.Sprintf(" (checked %s, %d ms)", link.CheckedAt.Format(time.RFC3339), link.DurationMS)
 			}
-			p.Cell(40, 8, fmt.Sprintf("%s - %s", link, status))
+			p.Cell(40, 8, line)
 			p.Ln(8)
+			for _, f := range link.Findings {
+				p.Cell(40, 6, "    "+findingLine(f))
+				p.Ln(6)
+			}
 		}
 		p.Ln(4)
 	}
 
-	var buf bytes.Buffer
-	if err := p.Output(&buf); err != nil {
-		return nil, err
+	writeSecurityScores(p, b, r.Tasks)
+	writeHostSummary(p, b, r.Hosts, r.Total)
+	writeLegend(p, b, r.Legend)
+
+	return p.Output(w)
+}
+
+// writeSecurityScores adds a table of the security header scores of the
+// links that were rated, if there are any, with what each one lacks.
+func writeSecurityScores(p *gofpdf.Fpdf, b *Branding, tasks []domain.ReportTask) {
+	var rated []domain.ReportLink
+	for _, t := range tasks {
+		for _, link := range t.Links {
+			if link.Security != nil {
+				rated = append(rated, link)
+			}
+		}
+	}
+	if len(rated) == 0 {
+		return
+	}
+	heading(p, b, 10, "Security headers")
+	p.Ln(10)
+	widths := []float64{160, 30}
+	tableHeader(p, b, widths, "URL", "Score")
+	for _, link := range rated {
+		p.CellFormat(widths[0], 7, link.URL, "1", 0, "L", false, 0, "")
+		p.CellFormat(widths[1], 7, strconv.Itoa(link.Security.Score)+"/100", "1", 0, "R", false, 0, "")
+		p.Ln(7)
+		for _, issue := range link.Security.Issues {
+			p.Cell(40, 6, "    "+issue)
+			p.Ln(6)
+		}
+	}
+	p.Ln(6)
+}
+
+func findingLine(f domain.Finding) string {
+	line := f.Kind + ": " + f.URL
+	if f.Detail != "" {
+		line += " (" + f.Detail + ")"
+	}
+	return line
+}
+
+func taskMetaLines(t domain.ReportTask) []string {
+	var lines []string
+	if len(t.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(t.Tags, ", "))
+	}
+	if t.CreatedBy != "" {
+		lines = append(lines, "Created by: "+t.CreatedBy)
+	}
+	if !t.CreatedAt.IsZero() {
+		lines = append(lines, "Created at: "+t.CreatedAt.Format(time.RFC3339))
+	}
+	if !t.CompletedAt.IsZero() {
+		lines = append(lines, "Completed at: "+t.CompletedAt.Format(time.RFC3339))
+	}
+	return lines
+}
+
+// writeLegend explains the statuses of the report, the first matching line
+// applying.
+func writeLegend(p *gofpdf.Fpdf, b *Branding, legend []string) {
+	if len(legend) == 0 {
+		return
+	}
+	p.Ln(6)
+	heading(p, b, 10, "Status legend")
+	p.Ln(10)
+	for _, line := range legend {
+		p.Cell(40, 6, line)
+		p.Ln(6)
+	}
+}
+
+func writeHostSummary(p *gofpdf.Fpdf, b *Branding, hosts []domain.HostSummary, total domain.HostSummary) {
+	if len(hosts) == 0 {
+		return
+	}
+	heading(p, b, 10, "Per-domain summary")
+	p.Ln(10)
+
+	widths := []float64{100, 30, 30, 30}
+	row := func(cells ...string) {
+		for i, c := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			p.CellFormat(widths[i], 7, c, "1", 0, align, false, 0, "")
+		}
+		p.Ln(7)
+	}
+	tableHeader(p, b, widths, "Domain", "Checked", "Available", "Broken")
+	for _, h := range hosts {
+		row(h.Host, strconv.Itoa(h.Checked), strconv.Itoa(h.Available), strconv.Itoa(h.Broken))
 	}
-	return buf.Bytes(), nil
+	row("Total", strconv.Itoa(total.Checked), strconv.Itoa(total.Available), strconv.Itoa(total.Broken))
 }
@@ -0,0 +1,36 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+)
+
+func BenchmarkWriteLinksReport(b *testing.B) {
+	tasks := make([]*domain.Task, 20)
+	for i := range tasks {
+		t := &domain.Task{
+			ID:      i + 1,
+			Name:    fmt.Sprintf("audit %d", i),
+			Result:  map[string]string{},
+			Timings: map[string]domain.LinkTiming{},
+		}
+		for j := 0; j < 100; j++ {
+			link := fmt.Sprintf("page-%d.site-%d.example.com", j, j%7)
+			t.Links = append(t.Links, link)
+			t.Result[link] = string(domain.StatusAvailable)
+			t.Timings[link] = domain.LinkTiming{CheckedAt: time.Now(), DurationMS: int64(j)}
+		}
+		tasks[i] = t
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := WriteLinksReport(io.Discard, domain.BuildLinksReport(tasks, time.Now(), time.UTC), Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
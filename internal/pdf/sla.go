@@ -0,0 +1,105 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// WriteSLAReport renders the uptime of every URL in r, each followed by its
+// incidents, straight to w.
+func WriteSLAReport(w io.Writer, r *domain.SLAReport, opts Options) error {
+	p := newDocument(opts)
+	b := opts.Branding
+
+	heading(p, b, 10, "Uptime SLA report")
+	p.Ln(10)
+	// время отчёта — в зоне начала периода (TIMEZONE)
+	loc := r.From.Location()
+	p.Cell(40, 6, fmt.Sprintf("%s - %s (%s)", r.From.Format(time.DateTime), r.To.Format(time.DateTime), loc))
+	p.Ln(10)
+	if len(r.Entries) == 0 {
+		p.Cell(40, 8, "No checks in this period.")
+		return p.Output(w)
+	}
+
+	widths := []float64{85, 20, 20, 25, 30}
+	row := func(cells ...string) {
+		for i, c := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			p.CellFormat(widths[i], 7, c, "1", 0, align, false, 0, "")
+		}
+		p.Ln(7)
+	}
+	tableHeader(p, b, widths, "URL", "Checks", "Failed", "Uptime, %", "Downtime, min")
+	for _, e := range r.Entries {
+		row(e.URL, strconv.Itoa(e.Checks), strconv.Itoa(e.Failed),
+			strconv.FormatFloat(e.UptimePercent, 'f', 3, 64), strconv.FormatFloat(e.DowntimeMinutes, 'f', 1, 64))
+	}
+
+	writeSLALatency(p, b, r.Entries)
+
+	p.Ln(6)
+	heading(p, b, 10, "Incidents")
+	p.Ln(10)
+	for _, e := range r.Entries {
+		if len(e.Incidents) == 0 {
+			continue
+		}
+		p.Cell(40, 8, e.URL)
+		p.Ln(8)
+		for _, inc := range e.Incidents {
+			line := fmt.Sprintf("    %s - %s, %.1f min", inc.Start.In(loc).Format(time.DateTime), inc.End.In(loc).Format(time.DateTime), inc.Minutes())
+			if inc.Open {
+				line += " (ongoing)"
+			}
+			p.Cell(40, 6, line)
+			p.Ln(6)
+		}
+	}
+	return p.Output(w)
+}
+
+// writeSLALatency adds a table of the response times of monitored URLs,
+// if there are any.
+func writeSLALatency(p *gofpdf.Fpdf, b *Branding, entries []domain.SLAEntry) {
+	var monitored []domain.SLAEntry
+	for _, e := range entries {
+		if e.Latency != nil {
+			monitored = append(monitored, e)
+		}
+	}
+	if len(monitored) == 0 {
+		return
+	}
+	p.Ln(6)
+	heading(p, b, 10, "Response times of monitored URLs")
+	p.Ln(10)
+	widths := []float64{85, 25, 25, 25, 20}
+	tableHeader(p, b, widths, "URL", "p50, ms", "p95, ms", "p99, ms", "Samples")
+	for _, e := range monitored {
+		cells := []string{
+			e.URL,
+			strconv.FormatFloat(e.Latency.P50, 'f', 1, 64),
+			strconv.FormatFloat(e.Latency.P95, 'f', 1, 64),
+			strconv.FormatFloat(e.Latency.P99, 'f', 1, 64),
+			strconv.FormatUint(e.Latency.Samples, 10),
+		}
+		for i, c := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			p.CellFormat(widths[i], 7, c, "1", 0, align, false, 0, "")
+		}
+		p.Ln(7)
+	}
+}
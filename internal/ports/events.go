@@ -0,0 +1,29 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// TaskEvent announces that a task finished. Results points to the stored
+// results, relative to the API root.
+type TaskEvent struct {
+	Type        string    `json:"type"`
+	TaskID      int       `json:"task_id"`
+	BatchID     int       `json:"batch_id,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	Links       int       `json:"links"`
+	Available   int       `json:"available"`
+	Broken      int       `json:"broken"`
+	Skipped     int       `json:"skipped"`
+	Persisted   bool      `json:"persisted"`
+	Results     string    `json:"results"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// EventPublisher delivers task events to a message bus.
+type EventPublisher interface {
+	PublishTaskEvent(ctx context.Context, ev TaskEvent) error
+}
@@ -0,0 +1,8 @@
+package ports
+
+import "time"
+
+// MaintenanceSchedule reports whether a host is in a maintenance window.
+type MaintenanceSchedule interface {
+	Active(host string, t time.Time) bool
+}
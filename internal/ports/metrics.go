@@ -0,0 +1,11 @@
+package ports
+
+import "time"
+
+// CheckMetrics receives measurements of the requests made by checks.
+// Implementations must be safe for concurrent use.
+type CheckMetrics interface {
+	// ObservePhase records how long a phase of a check request took: "dns",
+	// "connect", "tls" or "ttfb".
+	ObservePhase(phase string, d time.Duration)
+}
@@ -0,0 +1,62 @@
+package ports
+
+import "time"
+
+// Monitor re-checks the links of a task every Interval until it is stopped.
+// Options are the check options of the task, credentials excepted.
+type Monitor struct {
+	TaskID   int            `json:"task_id"`
+	Owner    string         `json:"owner,omitempty"`
+	Interval time.Duration  `json:"interval"`
+	Paused   bool           `json:"paused,omitempty"`
+	Options  MonitorOptions `json:"options"`
+	LastRun  time.Time      `json:"last_run,omitzero"`
+	NextRun  time.Time      `json:"next_run,omitzero"`
+	Runs     int            `json:"runs"`
+	// LastTaskID is the task holding the results of the latest re-check;
+	// zero until the first one, whose results are in task TaskID.
+	LastTaskID int `json:"last_task_id,omitempty"`
+	// Latency holds the response times of each link.
+	Latency map[string]LatencySketch `json:"latency,omitempty"`
+	// PausedReason says why the service paused the monitor by itself, e.g.
+	// because the quota of its owner ran out; empty when paused on request.
+	PausedReason string `json:"paused_reason,omitempty"`
+	// Skipped counts the runs dropped by the skip overlap policy because
+	// the previous run was still going.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// LatencySketch counts the response times of a link per latency bucket
+// over a rolling window: Current since Since and Previous over the window
+// before.
+type LatencySketch struct {
+	Since    time.Time      `json:"since"`
+	Current  map[int]uint64 `json:"current,omitempty"`
+	Previous map[int]uint64 `json:"previous,omitempty"`
+}
+
+// MonitorOptions are the check options a monitor repeats on every run.
+type MonitorOptions struct {
+	FailAfter   int      `json:"fail_after,omitempty"`
+	NoRedirects bool     `json:"no_redirects,omitempty"`
+	Content     bool     `json:"content,omitempty"`
+	Security    bool     `json:"security,omitempty"`
+	Cookies     bool     `json:"cookies,omitempty"`
+	Notify      []string `json:"notify,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	// Overlap is what a run that comes due while the previous one is still
+	// going does: "skip" (the default), "queue" or "concurrent".
+	Overlap string `json:"overlap,omitempty"`
+}
+
+// MonitorStore keeps monitors by task ID.
+type MonitorStore interface {
+	// Put adds m or replaces the monitor of the same task.
+	Put(m Monitor) error
+	// Get returns the monitor of task id and whether there is one.
+	Get(id int) (Monitor, bool)
+	// List returns the monitors ordered by task ID.
+	List() []Monitor
+	// Remove deletes the monitor of task id and reports whether it existed.
+	Remove(id int) (bool, error)
+}
@@ -0,0 +1,30 @@
+package ports
+
+import "context"
+
+// Notification summarizes a finished task with broken links.
+type Notification struct {
+	TaskID int
+	Name   string
+	Links  int
+	Broken int
+	// BrokenLinks lists the first broken links with their errors.
+	BrokenLinks []BrokenLink
+	// ReportURL points to the PDF report of the task; it is relative to the
+	// API root unless a public base URL is configured.
+	ReportURL string
+}
+
+// BrokenLink is a link that was not available and why.
+type BrokenLink struct {
+	URL   string
+	Error string
+}
+
+// Notifier posts notifications to the destinations of one channel, such as
+// the Slack webhooks configured by name.
+type Notifier interface {
+	// Has reports whether target names a configured destination.
+	Has(target string) bool
+	Notify(ctx context.Context, target string, n Notification) error
+}
@@ -1,16 +1,147 @@
 package ports
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTaskExists is returned when importing a task whose ID is already taken.
+	ErrTaskExists = errors.New("task already exists")
+	// ErrVersionConflict is returned when a conditional update sees a newer task version.
+	ErrVersionConflict = errors.New("task version conflict")
+)
+
 // TaskDTO represents link-checking task data without depending on the domain layer.
 type TaskDTO struct {
-	ID     int
-	Links  []string
-	Result map[string]string
+	ID          int
+	Name        string
+	Tags        []string
+	CreatedBy   string
+	Owner       string
+	Links       []string
+	Result      map[string]string
+	Timings     map[string]LinkTiming
+	Findings    map[string][]Finding
+	Security    map[string]SecurityAudit
+	BatchID     int
+	Version     int
+	CreatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// BatchDTO groups the tasks a large submission was split into.
+type BatchDTO struct {
+	ID        int
+	Name      string
+	Tags      []string
+	CreatedBy string
+	Owner     string
+	TaskIDs   []int
+	CreatedAt time.Time
+}
+
+// LinkTiming records when a link was checked and how long the request took.
+type LinkTiming struct {
+	CheckedAt  time.Time
+	DurationMS int64
+}
+
+// Finding is a problem found in the content of a checked page.
+type Finding struct {
+	Kind   string
+	URL    string
+	Detail string
+}
+
+// SecurityAudit rates the security headers of a checked HTTPS page.
+type SecurityAudit struct {
+	Score  int
+	Issues []string
+}
+
+// TaskMeta holds optional descriptive attributes supplied when a task is created.
+type TaskMeta struct {
+	Name      string
+	Tags      []string
+	CreatedBy string
+	// Owner scopes the task to a tenant; empty means visible to everyone.
+	Owner string
 }
 
 // TaskStorage describes persistence operations required by services dealing with tasks.
+// Every method gives up with the context error once ctx is done; writes
+// that have already started are completed.
 type TaskStorage interface {
-	Load() error
-	CreateTask(links []string) (*TaskDTO, error)
-	UpdateTaskResult(id int, result map[string]string) error
-	GetTasks(ids []int) ([]*TaskDTO, error)
+	Load(ctx context.Context) error
+	CreateTask(ctx context.Context, links []string, meta TaskMeta) (*TaskDTO, error)
+	// AppendLinkResult records the status of one link; timing is stored
+	// unless it is zero, findings unless they are empty and security unless
+	// it is nil.
+	AppendLinkResult(ctx context.Context, id int, link string, status string, timing LinkTiming, findings []Finding, security *SecurityAudit) error
+	// UpdateTaskResult replaces the task result and marks it completed when the
+	// stored version equals version; otherwise it returns ErrVersionConflict.
+	UpdateTaskResult(ctx context.Context, id int, version int, result map[string]string) error
+	GetTasks(ctx context.Context, ids []int) ([]*TaskDTO, error)
+	// ListTasks returns all tasks ordered by ID, limited to those labelled
+	// with tag when it is not empty.
+	ListTasks(ctx context.Context, tag string) ([]*TaskDTO, error)
+	// SearchTasks returns tasks containing url as an exact link or a link on the same host.
+	SearchTasks(ctx context.Context, url string) ([]*TaskDTO, error)
+	// DeleteTasksBefore removes tasks created before cutoff and reports how many were deleted.
+	DeleteTasksBefore(ctx context.Context, cutoff time.Time) (int, error)
+	// DeleteTask removes task id and reports whether it existed. The task
+	// does not survive a replay of the log afterwards.
+	DeleteTask(ctx context.Context, id int) (bool, error)
+	// ImportTasks stores tasks keeping their IDs. No task is imported if any ID
+	// already exists, in which case ErrTaskExists is returned.
+	ImportTasks(ctx context.Context, tasks []*TaskDTO) error
+}
+
+// TaskPage is a task cut down to some of its links.
+type TaskPage struct {
+	// Task holds only the links of the page and their results.
+	Task *TaskDTO
+	// Total is the number of links of the whole task.
+	Total int
+	// Statuses counts the results of the whole task by status.
+	Statuses map[string]int
+}
+
+// TaskPageStorage is implemented by task storages that can return part of
+// a large task without copying all of its results.
+type TaskPageStorage interface {
+	// GetTaskPage returns limit links of task id from offset, every link
+	// from offset when limit is not positive, or nil if there is no task.
+	GetTaskPage(ctx context.Context, id, offset, limit int) (*TaskPage, error)
+}
+
+// BatchStorage is implemented by task storages that can group tasks into
+// batches.
+type BatchStorage interface {
+	// CreateBatch creates a task for every chunk of links, all with meta,
+	// and a batch holding them in order.
+	CreateBatch(ctx context.Context, chunks [][]string, meta TaskMeta) (*BatchDTO, []*TaskDTO, error)
+	// GetBatch returns batch id, or nil if there is none.
+	GetBatch(ctx context.Context, id int) (*BatchDTO, error)
+}
+
+// DeletionCompactor is implemented by task storages that keep the data of
+// deleted tasks on disk until they compact it away.
+type DeletionCompactor interface {
+	// CompactDeleted erases the data of tasks deleted since the last
+	// compaction and returns how many there were.
+	CompactDeleted(ctx context.Context) (int, error)
+}
+
+// ResultSpool durably keeps task results whose persistence was deferred, so
+// they survive a restart and can be written to storage later.
+type ResultSpool interface {
+	// Add stores result for task id, merging with a result already spooled.
+	Add(id int, result map[string]string) error
+	// Remove drops the spooled result of task id.
+	Remove(id int) error
+	// Pending returns all spooled results by task ID.
+	Pending() (map[int]map[string]string, error)
 }
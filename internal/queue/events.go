@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// EventPublisher publishes task events as JSON messages on a subject.
+type EventPublisher struct {
+	nc      *NATS
+	subject string
+}
+
+// NewEventPublisher returns a publisher sending events to subject over nc.
+func NewEventPublisher(nc *NATS, subject string) *EventPublisher {
+	return &EventPublisher{nc: nc, subject: subject}
+}
+
+// PublishTaskEvent implements ports.EventPublisher. Core NATS publishing
+// does not wait for the server, so ctx is not used.
+func (p *EventPublisher) PublishTaskEvent(_ context.Context, ev ports.TaskEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("encode task event: %w", err)
+	}
+	return p.nc.Publish(p.subject, data)
+}
@@ -0,0 +1,449 @@
+// Package queue is a minimal client for the NATS core protocol, enough to
+// consume link-check jobs from a subject and publish their results.
+package queue
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPayload caps the size of a received message when the server does not
+// announce its own limit.
+const maxPayload = 64 << 20
+
+// maxLine caps a protocol line, such as INFO or the header of a MSG.
+const maxLine = 64 << 10
+
+// Reconnect backoff: the first attempt waits reconnectWait, the wait doubles
+// up to maxReconnectWait, and attempts stop after reconnectFor.
+var (
+	reconnectWait    = 100 * time.Millisecond
+	maxReconnectWait = 5 * time.Second
+	reconnectFor     = 2 * time.Minute
+	dialTimeout      = 10 * time.Second
+)
+
+var errLineTooLong = errors.New("nats: protocol line too long")
+
+// ErrClosed is returned after the connection has been closed.
+var ErrClosed = errors.New("nats: connection closed")
+
+// Message is a message delivered on a subscription. Reply is the subject
+// the sender waits for an answer on, if any.
+type Message struct {
+	Subject string
+	Reply   string
+	Data    []byte
+}
+
+// NATS is a connection to a NATS server with at most one subscription.
+// Delivery is at-most-once: messages received while no consumer runs are
+// lost, as with any core NATS subscriber. A lost connection is re-established
+// with backoff and the subscription renewed; messages sent in the meantime
+// are lost and publications fail.
+type NATS struct {
+	url *url.URL
+	log *slog.Logger
+
+	mu sync.Mutex // guards link and the subscription
+	link
+	msgs       chan Message
+	subscribed bool
+	subject    string
+	group      string
+
+	errMu  sync.Mutex
+	err    error
+	closed chan struct{}
+}
+
+// link is one connection to the server. r is read by readLoop only.
+type link struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	w          *bufio.Writer
+	maxPayload int
+}
+
+type serverInfo struct {
+	MaxPayload  int  `json:"max_payload"`
+	TLSRequired bool `json:"tls_required"`
+}
+
+type connectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// Dial connects to the server at rawURL (nats://[user:pass@]host:port or
+// tls://...; a user without password is sent as a token) and waits until
+// the server accepts the connection.
+func Dial(ctx context.Context, rawURL string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse nats url: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" || u.Host == "" {
+		return nil, fmt.Errorf("parse nats url: want nats:// or tls://host:port, got %q", u.Redacted())
+	}
+	l, err := connect(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	n := &NATS{url: u, link: *l, msgs: make(chan Message, 64), closed: make(chan struct{})}
+	go n.readLoop()
+	return n, nil
+}
+
+// UseLogger sets the logger for lost connections and errors the server
+// reports without closing the connection. Call it before subscribing.
+func (n *NATS) UseLogger(l *slog.Logger) {
+	n.log = l
+}
+
+func (n *NATS) logger() *slog.Logger {
+	if n.log != nil {
+		return n.log
+	}
+	return slog.Default()
+}
+
+// connect dials the server of u and completes the handshake.
+func connect(ctx context.Context, u *url.URL) (*link, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial nats: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	l, err := handshake(conn, u)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = l.conn.SetDeadline(time.Time{})
+	return l, nil
+}
+
+func handshake(conn net.Conn, u *url.URL) (*link, error) {
+	r := bufio.NewReader(conn)
+	line, err := readLine(r)
+	if err != nil {
+		return nil, fmt.Errorf("read nats info: %w", err)
+	}
+	op, args, _ := strings.Cut(line, " ")
+	if !strings.EqualFold(op, "INFO") {
+		return nil, fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	var info serverInfo
+	if err := json.Unmarshal([]byte(args), &info); err != nil {
+		return nil, fmt.Errorf("parse nats info: %w", err)
+	}
+	if info.TLSRequired || u.Scheme == "tls" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tc.Handshake(); err != nil {
+			return nil, fmt.Errorf("nats tls handshake: %w", err)
+		}
+		conn = tc
+		r = bufio.NewReader(conn)
+	}
+
+	opts := connectOptions{Name: "linkchecker", Lang: "go", Version: "1", Protocol: 1}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts.User, opts.Pass = u.User.Username(), pass
+		} else {
+			opts.Token = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connect)
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("send nats connect: %w", err)
+	}
+	// Сервер отвечает PONG только если CONNECT принят, иначе -ERR.
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, fmt.Errorf("read nats connect reply: %w", err)
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "PONG":
+			if info.MaxPayload <= 0 {
+				info.MaxPayload = maxPayload
+			}
+			return &link{conn: conn, r: r, w: w, maxPayload: info.MaxPayload}, nil
+		case "-ERR":
+			return nil, fmt.Errorf("nats: connect rejected: %s", strings.Trim(args, "'"))
+		}
+	}
+}
+
+// QueueSubscribe subscribes to subject as a member of queue group, so that
+// every message goes to one member only; an empty group receives all
+// messages. Messages are delivered on Messages.
+func (n *NATS) QueueSubscribe(subject, group string) error {
+	if subject == "" || strings.ContainsAny(subject+group, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q or queue group %q", subject, group)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.subscribed {
+		return errors.New("nats: already subscribed")
+	}
+	n.subscribed, n.subject, n.group = true, subject, group
+	n.subscribeLocked()
+	return n.flushLocked()
+}
+
+func (n *NATS) subscribeLocked() {
+	if n.group != "" {
+		fmt.Fprintf(n.w, "SUB %s %s 1\r\n", n.subject, n.group)
+	} else {
+		fmt.Fprintf(n.w, "SUB %s 1\r\n", n.subject)
+	}
+}
+
+// Messages returns the channel messages of the subscription are delivered
+// on. It is closed when the connection is closed or cannot be re-established;
+// Err then tells why.
+func (n *NATS) Messages() <-chan Message {
+	return n.msgs
+}
+
+// Publish sends data to subject. It fails while the connection is being
+// re-established.
+func (n *NATS) Publish(subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q", subject)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(data) > n.maxPayload {
+		return fmt.Errorf("nats: message of %d bytes exceeds max payload %d", len(data), n.maxPayload)
+	}
+	fmt.Fprintf(n.w, "PUB %s %d\r\n", subject, len(data))
+	n.w.Write(data)
+	n.w.WriteString("\r\n")
+	return n.flushLocked()
+}
+
+func (n *NATS) flushLocked() error {
+	if err := n.Err(); err != nil {
+		return err
+	}
+	if err := n.w.Flush(); err != nil {
+		return fmt.Errorf("nats: write: %w", err)
+	}
+	return nil
+}
+
+// Err returns the error the connection was closed with, if any.
+func (n *NATS) Err() error {
+	n.errMu.Lock()
+	defer n.errMu.Unlock()
+	return n.err
+}
+
+func (n *NATS) fail(err error) {
+	n.errMu.Lock()
+	if n.err == nil {
+		n.err = err
+		close(n.closed)
+	}
+	n.errMu.Unlock()
+	n.mu.Lock()
+	n.conn.Close()
+	n.mu.Unlock()
+}
+
+// Close drains pending publications and closes the connection.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	flushErr := n.w.Flush()
+	n.mu.Unlock()
+	n.fail(ErrClosed)
+	return flushErr
+}
+
+func (n *NATS) readLoop() {
+	defer close(n.msgs)
+	for {
+		err := n.serve()
+		select {
+		case <-n.closed:
+			return
+		default:
+		}
+		n.logger().Warn("nats connection lost, reconnecting", "err", err)
+		if err := n.reconnect(); err != nil {
+			n.fail(err)
+			return
+		}
+		n.logger().Info("nats connection re-established")
+	}
+}
+
+// serve handles the protocol lines of the current connection until it
+// fails, and returns why.
+func (n *NATS) serve() error {
+	defer n.conn.Close()
+	for {
+		line, err := readLine(n.r)
+		if err != nil {
+			return err
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "MSG":
+			msg, err := n.readMsg(strings.Fields(args))
+			if err != nil {
+				return err
+			}
+			select {
+			case n.msgs <- msg:
+			case <-n.closed:
+				return ErrClosed
+			}
+		case "PING":
+			n.mu.Lock()
+			n.w.WriteString("PONG\r\n")
+			err = n.flushLocked()
+			n.mu.Unlock()
+			if err != nil {
+				return err
+			}
+		case "-ERR":
+			err := fmt.Errorf("nats: %s", strings.Trim(args, "'"))
+			if !fatalError(args) {
+				n.logger().Error("nats server error", "err", err)
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// fatalError reports whether the -ERR message msg is one after which the
+// server closes the connection. Permission violations and invalid subjects
+// only reject the one operation.
+func fatalError(msg string) bool {
+	msg = strings.ToLower(strings.Trim(msg, "' "))
+	return !strings.HasPrefix(msg, "permissions violation") && msg != "invalid subject"
+}
+
+// reconnect dials the server again with backoff and renews the
+// subscription. It gives up after reconnectFor or once n is closed.
+func (n *NATS) reconnect() error {
+	deadline := time.Now().Add(reconnectFor)
+	wait := reconnectWait
+	for {
+		select {
+		case <-n.closed:
+			return ErrClosed
+		case <-time.After(wait):
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		l, err := connect(ctx, n.url)
+		cancel()
+		if err == nil {
+			if err = n.resume(l); err == nil {
+				return nil
+			}
+		}
+		if errors.Is(err, ErrClosed) || time.Now().After(deadline) {
+			return fmt.Errorf("nats: reconnect: %w", err)
+		}
+		wait = min(wait*2, maxReconnectWait)
+	}
+}
+
+// resume switches n to l and subscribes again.
+func (n *NATS) resume(l *link) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	select {
+	case <-n.closed:
+		l.conn.Close()
+		return ErrClosed
+	default:
+	}
+	n.link = *l
+	if n.subscribed {
+		n.subscribeLocked()
+	}
+	if err := n.w.Flush(); err != nil {
+		n.conn.Close()
+		return fmt.Errorf("nats: write: %w", err)
+	}
+	return nil
+}
+
+// readMsg reads the payload of a MSG with args "subject sid [reply] size".
+func (n *NATS) readMsg(args []string) (Message, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return Message{}, fmt.Errorf("nats: malformed MSG %q", args)
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || size < 0 || size > max(n.maxPayload, maxPayload) {
+		return Message{}, fmt.Errorf("nats: bad MSG size %q", args[len(args)-1])
+	}
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(n.r, buf); err != nil {
+		return Message{}, err
+	}
+	msg := Message{Subject: args[0], Data: buf[:size]}
+	if len(args) == 4 {
+		msg.Reply = args[2]
+	}
+	return msg, nil
+}
+
+// readLine reads one protocol line of at most maxLine bytes.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLine {
+			return "", errLineTooLong
+		}
+		line = append(line, chunk...)
+		if err == nil {
+			return strings.TrimRight(string(line), "\r\n"), nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// natsServer accepts one client, requires the token "s3cret" and echoes
+// every publication on "jobs.echo" back as a message to the subscriber.
+func natsServer(t *testing.T) (addr string, published chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	published = make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1024}\r\n")
+		var sub []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			op, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+			switch op {
+			case "CONNECT":
+				if !strings.Contains(args, `"auth_token":"s3cret"`) {
+					fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+					return
+				}
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case "SUB":
+				sub = strings.Fields(args)
+				// проверяем, что клиент отвечает на PING сервера
+				fmt.Fprint(conn, "PING\r\n")
+			case "PONG":
+				published <- "PONG"
+			case "PUB":
+				f := strings.Fields(args)
+				n, _ := strconv.Atoi(f[len(f)-1])
+				buf := make([]byte, n+2)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return
+				}
+				published <- f[0] + " " + string(buf[:n])
+				if f[0] == "jobs.echo" && sub != nil {
+					fmt.Fprintf(conn, "MSG %s %s _INBOX.1 %d\r\n%s\r\n", f[0], sub[len(sub)-1], n, buf[:n])
+				}
+			}
+		}
+	}()
+	return ln.Addr().String(), published
+}
+
+func TestNATS_SubscribePublish(t *testing.T) {
+	addr, published := natsServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	nc, err := Dial(ctx, "nats://s3cret@"+addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer nc.Close()
+	if err := nc.QueueSubscribe("jobs.>", "workers"); err != nil {
+		t.Fatalf("QueueSubscribe: %v", err)
+	}
+	if got := <-published; got != "PONG" {
+		t.Fatalf("server ping answered with %q", got)
+	}
+	if err := nc.Publish("jobs.echo", []byte(`{"links":["a.example"]}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got := <-published; got != `jobs.echo {"links":["a.example"]}` {
+		t.Fatalf("published %q", got)
+	}
+	select {
+	case msg := <-nc.Messages():
+		if msg.Subject != "jobs.echo" || msg.Reply != "_INBOX.1" || string(msg.Data) != `{"links":["a.example"]}` {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("no message delivered")
+	}
+	if err := nc.Publish("jobs.big", make([]byte, 2048)); err == nil {
+		t.Fatal("message over max_payload published")
+	}
+
+	nc.Close()
+	if _, ok := <-nc.Messages(); ok {
+		t.Fatal("messages channel open after Close")
+	}
+	if err := nc.Publish("jobs.echo", nil); err == nil {
+		t.Fatal("Publish after Close succeeded")
+	}
+}
+
+func TestNATS_DialRejected(t *testing.T) {
+	addr, _ := natsServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := Dial(ctx, "nats://wrong@"+addr); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatalf("Dial with a wrong token: %v", err)
+	}
+	if _, err := Dial(ctx, "http://"+addr); err == nil {
+		t.Fatal("Dial accepted an http url")
+	}
+}
+
+// flakyServer accepts clients without authentication and reports every SUB
+// it gets. The first client is sent a non-fatal -ERR, then a message, and is
+// disconnected; later ones get the message only.
+func flakyServer(t *testing.T) (addr string, subs chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	subs = make(chan string, 16)
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(first bool) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					op, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+					switch op {
+					case "PING":
+						fmt.Fprint(conn, "PONG\r\n")
+					case "SUB":
+						subs <- args
+						if first {
+							fmt.Fprint(conn, "-ERR 'Permissions Violation for Publish to \"jobs.x\"'\r\n")
+						}
+						fmt.Fprint(conn, "MSG jobs.a 1 2\r\nok\r\n")
+						if first {
+							return
+						}
+					}
+				}
+			}(i == 0)
+		}
+	}()
+	return ln.Addr().String(), subs
+}
+
+func TestNATS_Reconnect(t *testing.T) {
+	defer func(w time.Duration) { reconnectWait = w }(reconnectWait)
+	reconnectWait = time.Millisecond
+	addr, subs := flakyServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	nc, err := Dial(ctx, "nats://"+addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer nc.Close()
+	if err := nc.QueueSubscribe("jobs.>", "workers"); err != nil {
+		t.Fatalf("QueueSubscribe: %v", err)
+	}
+	// сообщение после -ERR о правах доходит, а после обрыва подписка восстанавливается
+	for i := range 2 {
+		select {
+		case msg, ok := <-nc.Messages():
+			if !ok {
+				t.Fatalf("message %d: channel closed: %v", i, nc.Err())
+			}
+			if string(msg.Data) != "ok" {
+				t.Fatalf("message %d: %+v", i, msg)
+			}
+		case <-ctx.Done():
+			t.Fatalf("message %d not delivered", i)
+		}
+		if got := <-subs; got != "jobs.> workers 1" {
+			t.Fatalf("subscription %d: SUB %s", i, got)
+		}
+	}
+	if err := nc.Err(); err != nil {
+		t.Fatalf("Err after reconnect: %v", err)
+	}
+}
+
+func TestReadLine_TooLong(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("INFO " + strings.Repeat("x", maxLine) + "\r\nPING\r\n"))
+	if _, err := readLine(r); err != errLineTooLong {
+		t.Fatalf("readLine of a long line: %v", err)
+	}
+	r = bufio.NewReader(strings.NewReader("PING\r\n"))
+	if line, err := readLine(r); err != nil || line != "PING" {
+		t.Fatalf("readLine = %q, %v", line, err)
+	}
+}
@@ -0,0 +1,237 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrExceeded is returned by Reserve when a request would go over a quota.
+var ErrExceeded = errors.New("quota exceeded")
+
+// Limits caps link checks per UTC day and month. Zero means unlimited.
+type Limits struct {
+	Daily   int
+	Monthly int
+}
+
+func (l Limits) unlimited() bool {
+	return l.Daily <= 0 && l.Monthly <= 0
+}
+
+// Status describes the quota usage of a principal.
+type Status struct {
+	Principal      string    `json:"principal"`
+	DailyUsed      int       `json:"daily_used"`
+	DailyLimit     int       `json:"daily_limit"`
+	DailyReset     time.Time `json:"daily_reset"`
+	MonthlyUsed    int       `json:"monthly_used"`
+	MonthlyLimit   int       `json:"monthly_limit"`
+	MonthlyReset   time.Time `json:"monthly_reset"`
+	LimitsDisabled bool      `json:"limits_disabled,omitempty"`
+}
+
+// DailyRemaining returns how many checks are left today, or -1 if unlimited.
+func (s Status) DailyRemaining() int {
+	return remaining(s.DailyLimit, s.DailyUsed)
+}
+
+// MonthlyRemaining returns how many checks are left this month, or -1 if unlimited.
+func (s Status) MonthlyRemaining() int {
+	return remaining(s.MonthlyLimit, s.MonthlyUsed)
+}
+
+func remaining(limit, used int) int {
+	if limit <= 0 {
+		return -1
+	}
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+type usage struct {
+	Day     string `json:"day"`
+	Daily   int    `json:"daily"`
+	Month   string `json:"month"`
+	Monthly int    `json:"monthly"`
+}
+
+// Tracker counts link checks per principal and persists the counters to a JSON file.
+type Tracker struct {
+	mu        sync.Mutex
+	path      string
+	defaults  Limits
+	overrides map[string]Limits
+	usage     map[string]*usage
+	now       func() time.Time
+}
+
+// NewTracker loads counters from path (if it exists). An empty path keeps
+// counters in memory only.
+func NewTracker(path string, defaults Limits, overrides map[string]Limits) (*Tracker, error) {
+	t := &Tracker{
+		path:      path,
+		defaults:  defaults,
+		overrides: overrides,
+		usage:     make(map[string]*usage),
+		now:       time.Now,
+	}
+	if path == "" {
+		return t, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return t, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &t.usage); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseOverrides parses "name:daily:monthly" entries separated by commas.
+func ParseOverrides(spec string) (map[string]Limits, error) {
+	res := make(map[string]Limits)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid quota entry %q", item)
+		}
+		daily, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("quota %q daily: %w", parts[0], err)
+		}
+		monthly, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("quota %q monthly: %w", parts[0], err)
+		}
+		res[parts[0]] = Limits{Daily: daily, Monthly: monthly}
+	}
+	return res, nil
+}
+
+func (t *Tracker) limits(principal string) Limits {
+	if l, ok := t.overrides[principal]; ok {
+		return l
+	}
+	return t.defaults
+}
+
+// Reserve charges n link checks to principal. It fails with ErrExceeded and
+// leaves the counters untouched if either quota would be exceeded.
+func (t *Tracker) Reserve(principal string, n int) (Status, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+	l := t.limits(principal)
+	if l.unlimited() {
+		return t.statusLocked(principal, now), nil
+	}
+	u := t.currentLocked(principal, now)
+	if (l.Daily > 0 && u.Daily+n > l.Daily) || (l.Monthly > 0 && u.Monthly+n > l.Monthly) {
+		return t.statusLocked(principal, now), ErrExceeded
+	}
+	u.Daily += n
+	u.Monthly += n
+	if err := t.saveLocked(); err != nil {
+		return t.statusLocked(principal, now), fmt.Errorf("persist quota usage: %w", err)
+	}
+	return t.statusLocked(principal, now), nil
+}
+
+// Release gives back n link checks reserved for principal that were not
+// run. Counters of a day or month that has already ended are not touched.
+func (t *Tracker) Release(principal string, n int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.limits(principal).unlimited() {
+		return nil
+	}
+	u := t.currentLocked(principal, t.now().UTC())
+	u.Daily = max(u.Daily-n, 0)
+	u.Monthly = max(u.Monthly-n, 0)
+	if err := t.saveLocked(); err != nil {
+		return fmt.Errorf("persist quota usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the current quota status of principal.
+func (t *Tracker) Usage(principal string) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.statusLocked(principal, t.now().UTC())
+}
+
+func (t *Tracker) currentLocked(principal string, now time.Time) *usage {
+	day, month := now.Format("2006-01-02"), now.Format("2006-01")
+	u, ok := t.usage[principal]
+	if !ok {
+		u = &usage{Day: day, Month: month}
+		t.usage[principal] = u
+	}
+	if u.Day != day {
+		u.Day, u.Daily = day, 0
+	}
+	if u.Month != month {
+		u.Month, u.Monthly = month, 0
+	}
+	return u
+}
+
+func (t *Tracker) statusLocked(principal string, now time.Time) Status {
+	l := t.limits(principal)
+	u := t.currentLocked(principal, now)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return Status{
+		Principal:      principal,
+		DailyUsed:      u.Daily,
+		DailyLimit:     l.Daily,
+		DailyReset:     startOfDay.AddDate(0, 0, 1),
+		MonthlyUsed:    u.Monthly,
+		MonthlyLimit:   l.Monthly,
+		MonthlyReset:   startOfMonth.AddDate(0, 1, 0),
+		LimitsDisabled: l.unlimited(),
+	}
+}
+
+func (t *Tracker) saveLocked() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(t.usage)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.path)
+}
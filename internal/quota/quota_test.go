@@ -0,0 +1,69 @@
+package quota
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTracker_ReserveAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	now := time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	tr, err := NewTracker(path, Limits{Daily: 5, Monthly: 8}, map[string]Limits{"vip": {}})
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	tr.now = func() time.Time { return now }
+
+	st, err := tr.Reserve("team-a", 3)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if st.DailyRemaining() != 2 || st.MonthlyRemaining() != 5 {
+		t.Fatalf("unexpected remaining: %+v", st)
+	}
+	if _, err := tr.Reserve("team-a", 3); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("expected daily quota to be exceeded, got %v", err)
+	}
+	if _, err := tr.Reserve("vip", 1000); err != nil {
+		t.Fatalf("expected unlimited override, got %v", err)
+	}
+	if err := tr.Release("team-a", 1); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := tr.Reserve("team-a", 1); err != nil {
+		t.Fatalf("expected the released check to be available, got %v", err)
+	}
+
+	reloaded, err := NewTracker(path, Limits{Daily: 5, Monthly: 8}, nil)
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	reloaded.now = func() time.Time { return now }
+	if got := reloaded.Usage("team-a"); got.DailyUsed != 3 {
+		t.Fatalf("expected persisted usage 3, got %+v", got)
+	}
+
+	// новый день и новый месяц сбрасывают счётчики
+	reloaded.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if got := reloaded.Usage("team-a"); got.DailyUsed != 0 || got.MonthlyUsed != 0 {
+		t.Fatalf("expected counters reset on new month, got %+v", got)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	got, err := ParseOverrides("a:10:100, b:0:0")
+	if err != nil {
+		t.Fatalf("ParseOverrides: %v", err)
+	}
+	if got["a"] != (Limits{Daily: 10, Monthly: 100}) || got["b"] != (Limits{}) {
+		t.Fatalf("unexpected overrides: %+v", got)
+	}
+	for _, bad := range []string{"a:1", "a:x:1", ":1:1"} {
+		if _, err := ParseOverrides(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
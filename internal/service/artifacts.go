@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// ErrReportNotFound is returned for an unknown report id or a report of
+// another owner.
+var ErrReportNotFound = errors.New("report not found")
+
+const reportPrefix = "reports/"
+
+// NewReportID returns a random id for a generated report. Ids are not
+// guessable, as reports of callers without an owner are shared.
+func NewReportID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validReportID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+// reportKey places report id of owner below a directory named by a hash of
+// the owner, so that owner names do not leak into object keys.
+func reportKey(owner, id string) string {
+	dir := "_"
+	if owner != "" {
+		sum := sha256.Sum256([]byte(owner))
+		dir = hex.EncodeToString(sum[:8])
+	}
+	return reportPrefix + dir + "/" + id + ".pdf"
+}
+
+// UseReportStore keeps generated reports in store so they can be downloaded
+// again with OpenReport, deleting those older than retention; zero keeps
+// them forever. Call it before serving requests.
+func (s *Service) UseReportStore(store ports.BlobStore, retention time.Duration) {
+	s.artifacts = store
+	if retention <= 0 {
+		return
+	}
+	interval := min(retention, time.Hour)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.purgeReports(retention)
+			select {
+			case <-ticker.C:
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// StoresReports reports whether UseReportStore was called.
+func (s *Service) StoresReports() bool {
+	return s.artifacts != nil
+}
+
+// StoreReport saves the rendered report id of owner. Failures are logged
+// as well, since the report has usually been sent already.
+func (s *Service) StoreReport(ctx context.Context, id, owner string, data []byte) error {
+	if s.artifacts == nil {
+		return nil
+	}
+	if err := s.artifacts.Put(ctx, reportKey(owner, id), data); err != nil {
+		s.logger().Error("store report failed", "report_id", id, "err", err)
+		return fmt.Errorf("store report: %w", err)
+	}
+	return nil
+}
+
+// OpenReport returns the stored report id. A non-empty owner only sees its
+// own reports; an empty one, an admin, sees all.
+func (s *Service) OpenReport(ctx context.Context, id, owner string) (io.ReadCloser, error) {
+	if s.artifacts == nil || !validReportID(id) {
+		return nil, ErrReportNotFound
+	}
+	key := reportKey(owner, id)
+	if owner == "" {
+		blobs, err := s.artifacts.List(ctx, reportPrefix)
+		if err != nil {
+			return nil, err
+		}
+		key = ""
+		for _, b := range blobs {
+			if strings.HasSuffix(b.Key, "/"+id+".pdf") {
+				key = b.Key
+				break
+			}
+		}
+		if key == "" {
+			return nil, ErrReportNotFound
+		}
+	}
+	rc, err := s.artifacts.Get(ctx, key)
+	if errors.Is(err, ports.ErrBlobNotFound) {
+		return nil, ErrReportNotFound
+	}
+	return rc, err
+}
+
+func (s *Service) purgeReports(retention time.Duration) {
+	ctx := context.Background()
+	blobs, err := s.artifacts.List(ctx, reportPrefix)
+	if err != nil {
+		s.logger().Error("report retention cleanup failed", "err", err)
+		return
+	}
+	cutoff := time.Now().Add(-retention)
+	deleted := 0
+	for _, b := range blobs {
+		if !b.Modified.Before(cutoff) {
+			continue
+		}
+		if err := s.artifacts.Delete(ctx, b.Key); err != nil {
+			s.logger().Error("report retention cleanup failed", "key", b.Key, "err", err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		s.logger().Info("expired reports deleted", "count", deleted, "retention", retention.String())
+	}
+}
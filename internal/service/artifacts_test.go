@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/blob"
+)
+
+func TestService_StoredReports(t *testing.T) {
+	dir := t.TempDir()
+	store, err := blob.NewDiskStore(dir)
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	s := &Service{done: make(chan struct{})}
+	s.UseReportStore(store, 0)
+	ctx := context.Background()
+
+	id := NewReportID()
+	if !validReportID(id) {
+		t.Fatalf("invalid report id %q", id)
+	}
+	if err := s.StoreReport(ctx, id, "alice", []byte("%PDF")); err != nil {
+		t.Fatalf("StoreReport: %v", err)
+	}
+
+	for _, owner := range []string{"alice", ""} {
+		rc, err := s.OpenReport(ctx, id, owner)
+		if err != nil {
+			t.Fatalf("OpenReport as %q: %v", owner, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != "%PDF" {
+			t.Fatalf("OpenReport as %q = %q", owner, data)
+		}
+	}
+	if _, err := s.OpenReport(ctx, id, "bob"); !errors.Is(err, ErrReportNotFound) {
+		t.Fatalf("OpenReport as another owner: %v, want ErrReportNotFound", err)
+	}
+	if _, err := s.OpenReport(ctx, "../"+id, ""); !errors.Is(err, ErrReportNotFound) {
+		t.Fatalf("OpenReport with invalid id: %v, want ErrReportNotFound", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, reportKey("alice", id)), old, old); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	fresh := NewReportID()
+	if err := s.StoreReport(ctx, fresh, "", []byte("%PDF")); err != nil {
+		t.Fatalf("StoreReport: %v", err)
+	}
+	s.purgeReports(time.Hour)
+	if _, err := s.OpenReport(ctx, id, "alice"); !errors.Is(err, ErrReportNotFound) {
+		t.Fatalf("expired report still stored: %v", err)
+	}
+	rc, err := s.OpenReport(ctx, fresh, "")
+	if err != nil {
+		t.Fatalf("fresh report purged: %v", err)
+	}
+	rc.Close()
+}
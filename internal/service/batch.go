@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// ErrBatchesUnsupported is returned by SubmitBatch when the storage cannot
+// group tasks into batches.
+var ErrBatchesUnsupported = errors.New("storage does not support batches")
+
+// SubmitBatch splits links into tasks of at most chunkSize links, grouped
+// under a new batch, and checks them one after another in the background.
+// It returns as soon as the tasks are created; opts apply to every task,
+// FailAfter included. Checks still running when the service is closed are
+// cancelled, and their remaining links are reported as skipped. Nothing
+// resumes a batch after a restart: GetBatch reports it as interrupted.
+func (s *Service) SubmitBatch(ctx context.Context, links []string, chunkSize int, opts CheckOptions) (*domain.Batch, error) {
+	bs, ok := s.storage.(ports.BatchStorage)
+	if !ok {
+		return nil, ErrBatchesUnsupported
+	}
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	var chunks [][]string
+	for start := 0; start < len(links); start += chunkSize {
+		chunks = append(chunks, links[start:min(start+chunkSize, len(links))])
+	}
+	batch, tasks, err := bs.CreateBatch(ctx, chunks, ports.TaskMeta{
+		Name:      opts.Name,
+		Tags:      opts.Tags,
+		CreatedBy: opts.CreatedBy,
+		Owner:     opts.Owner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.batchMu.Lock()
+	if s.batches == nil {
+		s.batches = make(map[int]struct{})
+	}
+	s.batches[batch.ID] = struct{}{}
+	s.batchMu.Unlock()
+	s.batchWG.Add(1)
+	go func() {
+		defer s.batchWG.Done()
+		defer cancel()
+		defer func() {
+			s.batchMu.Lock()
+			delete(s.batches, batch.ID)
+			s.batchMu.Unlock()
+		}()
+		go func() {
+			select {
+			case <-s.done:
+				cancel()
+			case <-runCtx.Done():
+			}
+		}()
+		for _, t := range tasks {
+			if _, _, err := s.runTask(runCtx, t.ID, batch.ID, t.Links, opts); err != nil && !errors.Is(err, ErrResultPersistDeferred) {
+				s.logger().Error("batch task failed", "batch_id", batch.ID, "task_id", t.ID, "err", err)
+			}
+		}
+		s.logger().Info("batch checked", "batch_id", batch.ID, "tasks", len(tasks), "links", len(links))
+	}()
+	return batchFromDTO(batch), nil
+}
+
+// GetBatch returns the progress of batch id, or nil if there is no such
+// batch or it belongs to another owner than a non-empty owner. An unfinished
+// batch this process is not checking, because it was submitted before a
+// restart, is reported as interrupted.
+func (s *Service) GetBatch(ctx context.Context, id int, owner string) (*domain.BatchSummary, error) {
+	bs, ok := s.storage.(ports.BatchStorage)
+	if !ok {
+		return nil, nil
+	}
+	dto, err := bs.GetBatch(ctx, id)
+	if err != nil || dto == nil {
+		return nil, err
+	}
+	if owner != "" && dto.Owner != owner {
+		return nil, nil
+	}
+	tasks, err := s.storage.GetTasks(ctx, dto.TaskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := &domain.BatchSummary{Batch: *batchFromDTO(dto)}
+	for _, t := range tasks {
+		sum.Links += len(t.Links)
+		for _, st := range t.Result {
+			sum.Checked++
+			switch st {
+			case string(domain.StatusAvailable):
+				sum.Available++
+			case string(domain.StatusMaintenance):
+				sum.Maintenance++
+			default:
+				sum.Broken++
+			}
+		}
+		if !t.CompletedAt.IsZero() {
+			sum.TasksCompleted++
+			if t.CompletedAt.After(sum.CompletedAt) {
+				sum.CompletedAt = t.CompletedAt
+			}
+		}
+	}
+	switch {
+	case sum.TasksCompleted == len(tasks):
+		sum.Status = domain.BatchCompleted
+	case sum.TasksCompleted > 0 || sum.Checked > 0:
+		sum.Status = domain.BatchRunning
+	default:
+		sum.Status = domain.BatchPending
+	}
+	if sum.Status != domain.BatchCompleted {
+		sum.CompletedAt = time.Time{}
+		s.batchMu.Lock()
+		_, running := s.batches[id]
+		s.batchMu.Unlock()
+		if !running {
+			sum.Status = domain.BatchInterrupted
+		}
+	}
+	return sum, nil
+}
+
+func batchFromDTO(b *ports.BatchDTO) *domain.Batch {
+	return &domain.Batch{
+		ID:        b.ID,
+		Name:      b.Name,
+		Tags:      append([]string(nil), b.Tags...),
+		CreatedBy: b.CreatedBy,
+		Owner:     b.Owner,
+		TaskIDs:   append([]int(nil), b.TaskIDs...),
+		CreatedAt: b.CreatedAt,
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDeduplicated is returned by CheckLinks together with the results of an
+// earlier task when the same set of links was checked within the
+// deduplication window.
+var ErrDeduplicated = errors.New("identical batch checked recently")
+
+// dedupCache remembers recently completed batches by content hash.
+type dedupCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	batches map[string]dedupEntry
+	now     func() time.Time
+}
+
+type dedupEntry struct {
+	taskID int
+	at     time.Time
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		batches: make(map[string]dedupEntry),
+		now:     time.Now,
+	}
+}
+
+// batchKey hashes the owner, the options that change results and the set of
+// links, ignoring order, duplicates and surrounding whitespace.
+func batchKey(opts CheckOptions, links []string) string {
+	set := make([]string, 0, len(links))
+	for _, l := range links {
+		set = append(set, strings.TrimSpace(l))
+	}
+	slices.Sort(set)
+	set = slices.Compact(set)
+
+	h := sha256.New()
+	h.Write([]byte(opts.Owner))
+	if opts.NoRedirects {
+		h.Write([]byte("\x00no-redirects"))
+	}
+	if opts.Content {
+		h.Write([]byte("\x00content"))
+	}
+	if opts.Security {
+		h.Write([]byte("\x00security"))
+	}
+	if opts.Cookies {
+		h.Write([]byte("\x00cookies"))
+	}
+	for _, l := range set {
+		h.Write([]byte{0})
+		h.Write([]byte(l))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *dedupCache) lookup(key string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.batches[key]
+	if !ok || c.now().Sub(e.at) > c.window {
+		return 0, false
+	}
+	return e.taskID, true
+}
+
+// remember records a batch submitted at the given time and drops expired ones.
+func (c *dedupCache) remember(key string, taskID int, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, e := range c.batches {
+		if now.Sub(e.at) > c.window {
+			delete(c.batches, k)
+		}
+	}
+	c.batches[key] = dedupEntry{taskID: taskID, at: at}
+}
+
+func (c *dedupCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.batches, key)
+}
+
+// EnableDeduplication makes CheckLinks return the task of an identical batch
+// (same owner and set of links) submitted within window instead of checking
+// the links again. It must be called before the service handles requests.
+func (s *Service) EnableDeduplication(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	s.dedup = newDedupCache(window)
+}
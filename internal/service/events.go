@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// TaskCompletedEvent is the type of events published when a task finishes.
+const TaskCompletedEvent = "task.completed"
+
+// eventPublishTimeout bounds publishing one event, so that a stuck bus
+// does not hold up check responses.
+const eventPublishTimeout = 5 * time.Second
+
+// UseEventPublisher publishes an event through p whenever a task finishes,
+// including tasks stopped early or whose result could not be stored yet.
+// Call it before serving requests.
+func (s *Service) UseEventPublisher(p ports.EventPublisher) {
+	s.events = p
+}
+
+// publishTaskEvent summarizes the result of task id; failures are logged.
+func (s *Service) publishTaskEvent(id, batchID int, opts CheckOptions, result map[string]domain.LinkResult, persisted bool) {
+	if s.events == nil {
+		return
+	}
+	ev := ports.TaskEvent{
+		Type:        TaskCompletedEvent,
+		TaskID:      id,
+		BatchID:     batchID,
+		Name:        opts.Name,
+		Tags:        opts.Tags,
+		Owner:       opts.Owner,
+		Links:       len(result),
+		Persisted:   persisted,
+		Results:     fmt.Sprintf("/v1/tasks?id=%d", id),
+		CompletedAt: time.Now().UTC(),
+	}
+	for _, r := range result {
+		switch {
+		case r.Skipped:
+			ev.Skipped++
+		case r.Status == domain.StatusAvailable:
+			ev.Available++
+		default:
+			ev.Broken++
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	if err := s.events.PublishTaskEvent(ctx, ev); err != nil {
+		s.logger().Error("publish task event", "task_id", id, "err", err)
+	}
+}
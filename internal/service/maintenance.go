@@ -0,0 +1,30 @@
+package service
+
+import (
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/pkg/linkchecker"
+)
+
+// UseMaintenance makes checks skip hosts that are in a window of schedule;
+// their links get the maintenance status and count neither as available nor
+// as broken. Call it before serving requests.
+func (s *Service) UseMaintenance(schedule ports.MaintenanceSchedule) {
+	s.checkerOpts.Maintenance = func(host string) bool {
+		return schedule.Active(host, time.Now())
+	}
+	s.rebuildChecker()
+}
+
+// linkStatus maps the outcome of a check to the stored link status.
+func linkStatus(res linkchecker.Result) domain.LinkStatus {
+	switch res.ErrorKind {
+	case linkchecker.ErrorMaintenance:
+		return domain.StatusMaintenance
+	case linkchecker.ErrorFlaggedUnsafe:
+		return domain.StatusFlaggedUnsafe
+	}
+	return domain.LinkStatus(res.Status)
+}
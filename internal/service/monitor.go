@@ -0,0 +1,524 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/internal/quota"
+)
+
+// MinMonitorInterval is the shortest interval a monitor re-checks its task at.
+const MinMonitorInterval = time.Minute
+
+// monitorTick is how often due monitors are looked for.
+var monitorTick = 5 * time.Second
+
+// monitorRunsKept is how many run tasks of a monitor are kept; older runs
+// are deleted after each re-check.
+var monitorRunsKept = 100
+
+// pausedQuotaExceeded is the PausedReason of monitors whose owner ran out
+// of quota.
+const pausedQuotaExceeded = "quota exceeded"
+
+// OverlapPolicy decides what a monitor does when a run comes due while its
+// previous run is still going.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the runs that come due meanwhile: the next run is
+	// the first one due after the current run ends. Dropped runs are
+	// counted in Monitor.Skipped.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue runs once more right after the current run ends, however
+	// many runs came due meanwhile.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapConcurrent starts the run that comes due alongside the current
+	// one.
+	OverlapConcurrent OverlapPolicy = "concurrent"
+)
+
+// ParseOverlapPolicy parses an overlap policy; empty selects OverlapSkip.
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+	switch p := OverlapPolicy(s); p {
+	case "":
+		return OverlapSkip, nil
+	case OverlapSkip, OverlapQueue, OverlapConcurrent:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown overlap policy %q, want skip, queue or concurrent", s)
+}
+
+// overlapOf returns the overlap policy of m; monitors saved before there
+// was a choice skip.
+func overlapOf(m Monitor) OverlapPolicy {
+	if m.Options.Overlap == "" {
+		return OverlapSkip
+	}
+	return OverlapPolicy(m.Options.Overlap)
+}
+
+// ErrMonitorsDisabled is returned by CreateMonitor without UseMonitors.
+var ErrMonitorsDisabled = errors.New("monitors are not enabled")
+
+// Monitor is a task whose links are re-checked at an interval.
+type Monitor = ports.Monitor
+
+// MonitorTag returns the tag of the tasks holding the re-checks of the
+// monitor of task id.
+func MonitorTag(id int) string {
+	return "monitor-" + strconv.Itoa(id)
+}
+
+// latencyWindow is how long response times of monitored links are kept
+// apart before the older ones are dropped; percentiles cover the last one
+// to two windows.
+const latencyWindow = 24 * time.Hour
+
+// MonitorStatus is a monitor with the task of its latest run and the
+// response time percentiles of each link.
+type MonitorStatus struct {
+	Monitor
+	Task    *domain.Task
+	Latency map[string]domain.LatencyPercentiles
+}
+
+// UseQuota charges the link checks of monitor runs to the principal that
+// created the monitored task; a monitor that would exceed the quota is
+// paused instead. Call it before StartMonitors.
+func (s *Service) UseQuota(t *quota.Tracker) {
+	s.quota = t
+}
+
+// UseMonitors keeps monitors in store. Their tasks are not re-checked until
+// StartMonitors. Call it before serving requests.
+func (s *Service) UseMonitors(store ports.MonitorStore) {
+	s.monitors = store
+}
+
+// StartMonitors re-checks, until Close, the tasks of the active monitors
+// whenever their interval has passed; monitors that were due while the
+// service was down run right away. Call it once the storage is loaded, as
+// monitors of tasks missing from it are removed. It does nothing without
+// UseMonitors.
+func (s *Service) StartMonitors() {
+	if s.monitors == nil {
+		return
+	}
+	s.monitorWG.Add(1)
+	go s.runMonitors()
+}
+
+// MonitorsEnabled reports whether UseMonitors was called.
+func (s *Service) MonitorsEnabled() bool {
+	return s.monitors != nil
+}
+
+func (s *Service) runMonitors() {
+	defer s.monitorWG.Done()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// идущие прогоны монитора и время запуска последнего из них
+	type runs struct {
+		n    int
+		last time.Time
+	}
+	running := make(map[int]*runs)
+	var mu sync.Mutex
+	ticker := time.NewTicker(monitorTick)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		for _, m := range s.dueMonitors(now) {
+			mu.Lock()
+			r := running[m.TaskID]
+			// пока идёт прошлый прогон, новый запускаем только параллельной
+			// политикой и не раньше, чем через интервал после последнего;
+			// skip и queue решают, когда запускать, по окончании прогона
+			if r != nil && (overlapOf(m) != OverlapConcurrent || now.Before(r.last.Add(m.Interval))) {
+				mu.Unlock()
+				continue
+			}
+			if r == nil {
+				r = &runs{}
+				running[m.TaskID] = r
+			}
+			r.n++
+			r.last = now
+			mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.runMonitor(ctx, m)
+				mu.Lock()
+				if r.n--; r.n == 0 {
+					delete(running, m.TaskID)
+				}
+				mu.Unlock()
+			}()
+		}
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// dueMonitors returns the active monitors whose next run is not after now.
+func (s *Service) dueMonitors(now time.Time) []Monitor {
+	var due []Monitor
+	for _, m := range s.monitors.List() {
+		if !m.Paused && !m.NextRun.After(now) {
+			due = append(due, m)
+		}
+	}
+	return due
+}
+
+// runMonitor checks the links of the task of m once, into a new task tagged
+// with MonitorTag so that earlier runs stay in the history, up to
+// monitorRunsKept of them, and schedules the next run as the overlap policy
+// of m says. A monitor whose task
+// is gone is removed, one whose owner is out of quota is paused.
+func (s *Service) runMonitor(ctx context.Context, m Monitor) {
+	tasks, err := s.storage.GetTasks(ctx, []int{m.TaskID})
+	if err != nil {
+		s.logger().Warn("load monitored task", "task_id", m.TaskID, "err", err)
+		return
+	}
+	if len(tasks) == 0 || tasks[0] == nil {
+		s.logger().Info("monitored task deleted, stopping monitor", "task_id", m.TaskID)
+		if _, err := s.monitors.Remove(m.TaskID); err != nil {
+			s.logger().Error("remove monitor", "task_id", m.TaskID, "err", err)
+		}
+		return
+	}
+	task := tasks[0]
+	if !s.chargeMonitorRun(task) {
+		return
+	}
+	started := time.Now().UTC()
+	opts := CheckOptions{
+		Name:        task.Name,
+		Tags:        task.Tags,
+		CreatedBy:   task.CreatedBy,
+		Owner:       task.Owner,
+		FailAfter:   m.Options.FailAfter,
+		NoRedirects: m.Options.NoRedirects,
+		Content:     m.Options.Content,
+		Security:    m.Options.Security,
+		Cookies:     m.Options.Cookies,
+		Notify:      m.Options.Notify,
+		Priority:    Priority(m.Options.Priority),
+	}
+	run, err := s.storage.CreateTask(ctx, task.Links, ports.TaskMeta{
+		Name:      task.Name,
+		Tags:      append(slices.Clip(task.Tags), MonitorTag(task.ID)),
+		CreatedBy: task.CreatedBy,
+		Owner:     task.Owner,
+	})
+	if err != nil {
+		s.logger().Error("create monitor run task", "task_id", task.ID, "err", err)
+		s.refundMonitorRun(task)
+		return
+	}
+	result, _, err := s.runTask(ctx, run.ID, 0, task.Links, opts)
+	if err != nil && !errors.Is(err, ErrResultPersistDeferred) {
+		s.logger().Error("monitor check failed", "task_id", task.ID, "run_task_id", run.ID, "err", err)
+	}
+	if ctx.Err() != nil {
+		// проверку прервало закрытие сервиса, после рестарта монитор сработает снова
+		return
+	}
+
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+	// монитор могли остановить или приостановить во время проверки
+	cur, ok := s.monitors.Get(task.ID)
+	if !ok {
+		return
+	}
+	cur.Runs++
+	cur.Latency = recordLatency(cur.Latency, result, started)
+	// параллельные прогоны могут закончиться не по порядку
+	if !started.Before(cur.LastRun) {
+		cur.LastRun = started
+		cur.LastTaskID = run.ID
+		cur.NextRun = started.Add(cur.Interval)
+		if overlapOf(cur) == OverlapSkip {
+			for now := time.Now(); !cur.NextRun.After(now); cur.NextRun = cur.NextRun.Add(cur.Interval) {
+				cur.Skipped++
+			}
+		}
+	}
+	if err := s.monitors.Put(cur); err != nil {
+		s.logger().Error("save monitor", "task_id", task.ID, "err", err)
+	}
+	s.pruneMonitorRuns(ctx, task.ID)
+}
+
+// chargeMonitorRun charges the links of task to the quota of its creator
+// and reports whether the run may go ahead. When the quota is exhausted the
+// monitor is paused until StartMonitor.
+func (s *Service) chargeMonitorRun(task *ports.TaskDTO) bool {
+	if s.quota == nil || task.CreatedBy == "" {
+		return true
+	}
+	_, err := s.quota.Reserve(task.CreatedBy, len(task.Links))
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, quota.ErrExceeded) {
+		// счётчик уже увеличен в памяти, проверку не пропускаем
+		s.logger().Error("charge monitor run", "task_id", task.ID, "principal", task.CreatedBy, "err", err)
+		return true
+	}
+	s.logger().Warn("quota exceeded, pausing monitor", "task_id", task.ID, "principal", task.CreatedBy)
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+	if cur, ok := s.monitors.Get(task.ID); ok {
+		cur.Paused, cur.PausedReason = true, pausedQuotaExceeded
+		if err := s.monitors.Put(cur); err != nil {
+			s.logger().Error("save monitor", "task_id", task.ID, "err", err)
+		}
+	}
+	return false
+}
+
+// refundMonitorRun gives back the quota charged by chargeMonitorRun for a
+// run that did not happen.
+func (s *Service) refundMonitorRun(task *ports.TaskDTO) {
+	if s.quota == nil || task.CreatedBy == "" {
+		return
+	}
+	if err := s.quota.Release(task.CreatedBy, len(task.Links)); err != nil {
+		s.logger().Error("refund monitor run", "task_id", task.ID, "principal", task.CreatedBy, "err", err)
+	}
+}
+
+// pruneMonitorRuns deletes the run tasks of the monitor of task id but the
+// latest monitorRunsKept.
+func (s *Service) pruneMonitorRuns(ctx context.Context, id int) {
+	runs, err := s.storage.ListTasks(ctx, MonitorTag(id))
+	if err != nil {
+		s.logger().Warn("list monitor runs", "task_id", id, "err", err)
+		return
+	}
+	// ListTasks отдаёт задачи по возрастанию ID, старые прогоны в начале
+	for _, run := range runs[:max(len(runs)-monitorRunsKept, 0)] {
+		if _, err := s.storage.DeleteTask(ctx, run.ID); err != nil {
+			s.logger().Warn("delete old monitor run", "task_id", id, "run_task_id", run.ID, "err", err)
+			return
+		}
+	}
+}
+
+// CreateMonitor makes the links of task id re-checked every interval,
+// starting one interval from now, with opts on every run; overlap says what
+// to do when a run comes due before the previous one ends. result is the
+// check that created the task, counted as the first run; its response
+// times start the latency statistics. Credentials are never stored, so
+// opts must not carry any.
+func (s *Service) CreateMonitor(id int, interval time.Duration, overlap OverlapPolicy, opts CheckOptions, result map[string]domain.LinkResult) (Monitor, error) {
+	if s.monitors == nil {
+		return Monitor{}, ErrMonitorsDisabled
+	}
+	if interval < MinMonitorInterval {
+		return Monitor{}, fmt.Errorf("monitor interval %s is shorter than %s", interval, MinMonitorInterval)
+	}
+	if opts.Credentials != nil {
+		return Monitor{}, errors.New("links checked with credentials cannot be monitored")
+	}
+	overlap, err := ParseOverlapPolicy(string(overlap))
+	if err != nil {
+		return Monitor{}, err
+	}
+	now := time.Now().UTC()
+	m := Monitor{
+		TaskID:   id,
+		Owner:    opts.Owner,
+		Interval: interval,
+		Options: ports.MonitorOptions{
+			FailAfter:   opts.FailAfter,
+			NoRedirects: opts.NoRedirects,
+			Content:     opts.Content,
+			Security:    opts.Security,
+			Cookies:     opts.Cookies,
+			Notify:      opts.Notify,
+			Priority:    string(opts.Priority),
+			Overlap:     string(overlap),
+		},
+		LastRun: now,
+		NextRun: now.Add(interval),
+		Runs:    1,
+	}
+	if result != nil {
+		m.Latency = recordLatency(nil, result, now)
+	}
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+	if err := s.monitors.Put(m); err != nil {
+		return Monitor{}, err
+	}
+	return m, nil
+}
+
+// ListMonitors returns the monitors ordered by task ID; a non-empty owner
+// hides monitors of other owners.
+func (s *Service) ListMonitors(owner string) []Monitor {
+	if s.monitors == nil {
+		return nil
+	}
+	list := s.monitors.List()
+	if owner == "" {
+		return list
+	}
+	mine := list[:0]
+	for _, m := range list {
+		if m.Owner == owner {
+			mine = append(mine, m)
+		}
+	}
+	return mine
+}
+
+// GetMonitor returns the monitor of task id with the task of its latest
+// run, or nil if there is none or it belongs to another owner than a
+// non-empty owner.
+func (s *Service) GetMonitor(ctx context.Context, id int, owner string) (*MonitorStatus, error) {
+	m, ok := s.monitor(id, owner)
+	if !ok {
+		return nil, nil
+	}
+	var task *domain.Task
+	var err error
+	if m.LastTaskID != 0 {
+		if task, err = s.GetTask(ctx, m.LastTaskID, owner); err != nil {
+			return nil, err
+		}
+	}
+	// задачу прогона могло удалить хранение — показываем исходную
+	if task == nil {
+		if task, err = s.GetTask(ctx, id, owner); err != nil || task == nil {
+			return nil, err
+		}
+	}
+	st := &MonitorStatus{Monitor: m, Task: task, Latency: make(map[string]domain.LatencyPercentiles)}
+	now := time.Now()
+	for link, sk := range m.Latency {
+		if p := latencyPercentiles(sk, now); p.Samples > 0 {
+			st.Latency[link] = p
+		}
+	}
+	return st, nil
+}
+
+// StartMonitor resumes a paused monitor; its task is checked at once. It
+// reports whether the monitor exists.
+func (s *Service) StartMonitor(id int, owner string) (bool, error) {
+	return s.updateMonitor(id, owner, func(m *Monitor) {
+		if m.Paused {
+			m.Paused, m.PausedReason = false, ""
+			m.NextRun = time.Now().UTC()
+		}
+	})
+}
+
+// PauseMonitor stops re-checking the task until StartMonitor. It reports
+// whether the monitor exists.
+func (s *Service) PauseMonitor(id int, owner string) (bool, error) {
+	return s.updateMonitor(id, owner, func(m *Monitor) { m.Paused, m.PausedReason = true, "" })
+}
+
+// StopMonitor removes the monitor of task id; the task and its latest
+// results are kept. It reports whether the monitor existed.
+func (s *Service) StopMonitor(id int, owner string) (bool, error) {
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+	if _, ok := s.monitor(id, owner); !ok {
+		return false, nil
+	}
+	return s.monitors.Remove(id)
+}
+
+func (s *Service) updateMonitor(id int, owner string, update func(*Monitor)) (bool, error) {
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+	m, ok := s.monitor(id, owner)
+	if !ok {
+		return false, nil
+	}
+	update(&m)
+	return true, s.monitors.Put(m)
+}
+
+func (s *Service) monitor(id int, owner string) (Monitor, bool) {
+	if s.monitors == nil {
+		return Monitor{}, false
+	}
+	m, ok := s.monitors.Get(id)
+	if !ok || owner != "" && m.Owner != owner {
+		return Monitor{}, false
+	}
+	return m, true
+}
+
+// recordLatency returns stats with the response times of the links in
+// result that were answered added. stats is not modified, as the monitor
+// store hands out shared copies.
+func recordLatency(stats map[string]ports.LatencySketch, result map[string]domain.LinkResult, now time.Time) map[string]ports.LatencySketch {
+	out := maps.Clone(stats)
+	if out == nil {
+		out = make(map[string]ports.LatencySketch, len(result))
+	}
+	for link, res := range result {
+		// время ответа есть только у ссылок, которые ответили
+		if res.Status != domain.StatusAvailable && res.StatusCode == 0 {
+			continue
+		}
+		sk := out[link]
+		switch age := now.Sub(sk.Since); {
+		case sk.Since.IsZero() || age >= 2*latencyWindow:
+			sk = ports.LatencySketch{Since: now}
+		case age >= latencyWindow:
+			sk = ports.LatencySketch{Since: now, Previous: sk.Current}
+		}
+		current := maps.Clone(sk.Current)
+		if current == nil {
+			current = make(map[int]uint64)
+		}
+		current[domain.LatencyBucket(time.Duration(res.DurationMS)*time.Millisecond)]++
+		sk.Current = current
+		out[link] = sk
+	}
+	return out
+}
+
+// latencyPercentiles summarizes sk as of now.
+func latencyPercentiles(sk ports.LatencySketch, now time.Time) domain.LatencyPercentiles {
+	return domain.Percentiles(recentLatency(sk, now)...)
+}
+
+// recentLatency returns the histograms of sk that are still in the rolling
+// window at now.
+func recentLatency(sk ports.LatencySketch, now time.Time) []map[int]uint64 {
+	switch age := now.Sub(sk.Since); {
+	case age >= 2*latencyWindow:
+		return nil
+	case age >= latencyWindow:
+		return []map[int]uint64{sk.Current}
+	}
+	return []map[int]uint64{sk.Current, sk.Previous}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/monitor"
+	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/internal/quota"
+	"github.com/olgkv/linkchecker/internal/storage"
+	"github.com/olgkv/linkchecker/pkg/linkchecker"
+)
+
+func TestService_MonitorRechecksTask(t *testing.T) {
+	client := &httpClientMock{}
+	svc := &Service{
+		storage: storage.NewFileStorage(storage.NewMemoryRepository()),
+		checker: linkchecker.New(linkchecker.Options{Client: client, Resolver: publicResolver}),
+		done:    make(chan struct{}),
+	}
+	store, _ := monitor.Open("")
+	svc.UseMonitors(store)
+
+	opts := CheckOptions{Owner: "team-a"}
+	id, first, err := svc.CheckLinks(context.Background(), []string{"example.com"}, opts)
+	if err != nil {
+		t.Fatalf("CheckLinks: %v", err)
+	}
+	if _, err := svc.CreateMonitor(id, 30*time.Second, "", opts, first); err == nil {
+		t.Fatal("expected an interval below the minimum to be rejected")
+	}
+	if _, err := svc.CreateMonitor(id, time.Hour, "", opts, first); err != nil {
+		t.Fatalf("CreateMonitor: %v", err)
+	}
+	if ok, _ := svc.PauseMonitor(id, "team-b"); ok {
+		t.Fatal("another owner paused the monitor")
+	}
+	if ok, err := svc.PauseMonitor(id, "team-a"); err != nil || !ok {
+		t.Fatalf("PauseMonitor = %v, %v", ok, err)
+	}
+
+	client.mu.Lock()
+	client.codes = map[string]int{"https://example.com": http.StatusNotFound}
+	client.mu.Unlock()
+	old := monitorTick
+	monitorTick = 10 * time.Millisecond
+	defer func() { monitorTick = old }()
+	svc.StartMonitors()
+	// запуск после паузы проверяет задачу сразу
+	if ok, err := svc.StartMonitor(id, "team-a"); err != nil || !ok {
+		t.Fatalf("StartMonitor = %v, %v", ok, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var st *MonitorStatus
+	for {
+		if st, err = svc.GetMonitor(context.Background(), id, "team-a"); err != nil {
+			t.Fatalf("GetMonitor: %v", err)
+		}
+		if st.Runs == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	svc.Close()
+
+	if st.Runs != 2 || st.Paused || !st.NextRun.After(st.LastRun) {
+		t.Fatalf("monitor = %+v, want a second run scheduled an hour later", st.Monitor)
+	}
+	if got := domain.LinkStatus(st.Task.Result["example.com"]); got != domain.StatusNotAvailable || st.Task.ID != st.LastTaskID || st.LastTaskID == id {
+		t.Fatalf("latest run task %d: status %q, want %q in a task of its own", st.Task.ID, got, domain.StatusNotAvailable)
+	}
+	// первый прогон остаётся в истории
+	firstRun, _ := svc.GetTask(context.Background(), id, "team-a")
+	if got := domain.LinkStatus(firstRun.Result["example.com"]); got != domain.StatusAvailable {
+		t.Fatalf("first run status = %q, want %q", got, domain.StatusAvailable)
+	}
+	runs, _ := svc.ListTasks(context.Background(), MonitorTag(id), "team-a")
+	if len(runs) != 1 || runs[0].ID != st.LastTaskID || runs[0].Owner != "team-a" {
+		t.Fatalf("run tasks %+v", runs)
+	}
+	// ответ 404 тоже измеряет время ответа
+	if got := st.Latency["example.com"].Samples; got != 2 {
+		t.Fatalf("latency samples = %d, want 2", got)
+	}
+
+	if ok, err := svc.DeleteTask(context.Background(), id, "team-a"); err != nil || !ok {
+		t.Fatalf("DeleteTask = %v, %v", ok, err)
+	}
+	if len(svc.ListMonitors("")) != 0 {
+		t.Fatal("monitor of a deleted task was kept")
+	}
+}
+
+func TestRecordLatency_RollsWindows(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	observe := func(stats map[string]ports.LatencySketch, ms int64, at time.Time) map[string]ports.LatencySketch {
+		return recordLatency(stats, map[string]domain.LinkResult{
+			"a.com": {Status: domain.StatusAvailable, LinkTiming: domain.LinkTiming{DurationMS: ms}},
+			"b.com": {Status: domain.StatusNotAvailable, ErrorKind: "dns"},
+		}, at)
+	}
+
+	var stats map[string]ports.LatencySketch
+	for i := range 100 {
+		stats = observe(stats, int64(i+1)*10, start)
+	}
+	if _, ok := stats["b.com"]; ok {
+		t.Fatal("a link without an answer got response times")
+	}
+	p := latencyPercentiles(stats["a.com"], start)
+	if p.Samples != 100 || p.P50 < 490 || p.P50 > 510 || p.P95 < 931 || p.P95 > 969 || p.P99 < 970 || p.P99 > 1010 {
+		t.Fatalf("percentiles = %+v, want about 500/950/990 ms", p)
+	}
+
+	before := stats
+	stats = observe(stats, 5, start.Add(latencyWindow))
+	if before["a.com"].Current[domain.LatencyBucket(5*time.Millisecond)] != 0 {
+		t.Fatal("recordLatency modified the stats it was given")
+	}
+	if p := latencyPercentiles(stats["a.com"], start.Add(latencyWindow)); p.Samples != 101 {
+		t.Fatalf("samples after a window = %d, want 101", p.Samples)
+	}
+	if p := latencyPercentiles(stats["a.com"], start.Add(2*latencyWindow)); p.Samples != 1 {
+		t.Fatalf("samples after two windows = %d, want 1", p.Samples)
+	}
+}
+
+func TestService_MonitorRunsBoundedAndCharged(t *testing.T) {
+	old := monitorRunsKept
+	monitorRunsKept = 2
+	defer func() { monitorRunsKept = old }()
+
+	svc := &Service{
+		storage: storage.NewFileStorage(storage.NewMemoryRepository()),
+		checker: linkchecker.New(linkchecker.Options{Client: okClient{}, Resolver: publicResolver}),
+		done:    make(chan struct{}),
+	}
+	store, _ := monitor.Open("")
+	svc.UseMonitors(store)
+	tracker, err := quota.NewTracker(filepath.Join(t.TempDir(), "usage.json"), quota.Limits{Daily: 8}, nil)
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	svc.UseQuota(tracker)
+
+	links := []string{"a.example.com", "b.example.com"}
+	id, first, err := svc.CheckLinks(context.Background(), links, CheckOptions{CreatedBy: "ci", Owner: "ci"})
+	if err != nil {
+		t.Fatalf("CheckLinks: %v", err)
+	}
+	if _, err := svc.CreateMonitor(id, time.Hour, "", CheckOptions{Owner: "ci"}, first); err != nil {
+		t.Fatalf("CreateMonitor: %v", err)
+	}
+
+	// квота на 8 проверок: четыре прогона по две ссылки, пятый ставит монитор на паузу
+	for range 5 {
+		m, _ := svc.monitor(id, "")
+		svc.runMonitor(context.Background(), m)
+	}
+
+	m, _ := svc.monitor(id, "")
+	if m.Runs != 5 || !m.Paused || m.PausedReason != pausedQuotaExceeded {
+		t.Fatalf("monitor = %+v, want 4 re-checks and a pause for quota", m)
+	}
+	if used := tracker.Usage("ci").DailyUsed; used != 8 {
+		t.Fatalf("daily used = %d, want 8", used)
+	}
+	runs, _ := svc.ListTasks(context.Background(), MonitorTag(id), "")
+	if len(runs) != 2 || runs[1].ID != m.LastTaskID {
+		t.Fatalf("kept %d run tasks, want the latest 2", len(runs))
+	}
+	if task, _ := svc.GetTask(context.Background(), id, ""); task == nil {
+		t.Fatal("the monitored task was pruned with the runs")
+	}
+
+	if ok, err := svc.StartMonitor(id, ""); err != nil || !ok {
+		t.Fatalf("StartMonitor = %v, %v", ok, err)
+	}
+	if m, _ = svc.monitor(id, ""); m.Paused || m.PausedReason != "" {
+		t.Fatalf("monitor = %+v, want it resumed", m)
+	}
+}
+
+// holdClient holds every request until release is closed and records how
+// many were in flight at once.
+type holdClient struct {
+	release  chan struct{}
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (c *holdClient) Do(r *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.max = max(c.max, c.inFlight)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+	select {
+	case <-c.release:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+	return &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Body: http.NoBody}, nil
+}
+
+func (c *holdClient) maxInFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.max
+}
+
+func TestService_MonitorOverlapPolicy(t *testing.T) {
+	old := monitorTick
+	monitorTick = 5 * time.Millisecond
+	defer func() { monitorTick = old }()
+
+	for _, policy := range []OverlapPolicy{OverlapSkip, OverlapQueue, OverlapConcurrent} {
+		t.Run(string(policy), func(t *testing.T) {
+			client := &holdClient{release: make(chan struct{})}
+			st := storage.NewFileStorage(storage.NewMemoryRepository())
+			svc := &Service{
+				storage: st,
+				checker: linkchecker.New(linkchecker.Options{Client: client, Resolver: publicResolver, Timeout: 5 * time.Second}),
+				done:    make(chan struct{}),
+			}
+			store, _ := monitor.Open("")
+			svc.UseMonitors(store)
+			task, err := st.CreateTask(context.Background(), []string{"example.com"}, ports.TaskMeta{})
+			if err != nil {
+				t.Fatalf("CreateTask: %v", err)
+			}
+			// интервал короче минимума, чтобы запуски накладывались за время теста
+			now := time.Now().UTC()
+			m := Monitor{TaskID: task.ID, Interval: 20 * time.Millisecond, LastRun: now, NextRun: now, Runs: 1,
+				Options: ports.MonitorOptions{Overlap: string(policy)}}
+			if err := store.Put(m); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			svc.StartMonitors()
+			time.Sleep(150 * time.Millisecond)
+			inFlight := client.maxInFlight()
+			close(client.release)
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				if m, _ = svc.monitor(task.ID, ""); m.Runs >= 3 || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			svc.Close()
+
+			if m.Runs < 3 {
+				t.Fatalf("monitor ran %d times, want the held run and later ones", m.Runs)
+			}
+			switch policy {
+			case OverlapSkip:
+				if inFlight != 1 || m.Skipped == 0 {
+					t.Fatalf("in flight %d, skipped %d; want 1 run at a time and runs skipped", inFlight, m.Skipped)
+				}
+			case OverlapQueue:
+				if inFlight != 1 || m.Skipped != 0 {
+					t.Fatalf("in flight %d, skipped %d; want 1 run at a time and none skipped", inFlight, m.Skipped)
+				}
+			case OverlapConcurrent:
+				if inFlight < 2 || m.Skipped != 0 {
+					t.Fatalf("in flight %d, skipped %d; want runs side by side", inFlight, m.Skipped)
+				}
+			}
+		})
+	}
+}
+
+func TestParseOverlapPolicy(t *testing.T) {
+	if p, err := ParseOverlapPolicy(""); err != nil || p != OverlapSkip {
+		t.Fatalf("empty policy = %q, %v; want skip", p, err)
+	}
+	if _, err := ParseOverlapPolicy("later"); err == nil {
+		t.Fatal("expected an unknown policy to be rejected")
+	}
+}
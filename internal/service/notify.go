@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+const (
+	// maxNotifiedLinks caps the broken links listed in a notification.
+	maxNotifiedLinks = 10
+	// notifyTimeout bounds delivering the notifications of one task.
+	notifyTimeout = 30 * time.Second
+)
+
+// UseNotifier lets tasks request notifications through n with targets
+// "channel:name", e.g. "slack:#alerts". Call it before serving requests.
+func (s *Service) UseNotifier(channel string, n ports.Notifier) {
+	if s.notifiers == nil {
+		s.notifiers = make(map[string]ports.Notifier)
+	}
+	s.notifiers[channel] = n
+}
+
+// SetPublicURL sets the base URL notifications link reports under, e.g.
+// "https://linkchecker.example.com"; without it the links are relative.
+func (s *Service) SetPublicURL(base string) {
+	s.publicURL = strings.TrimRight(base, "/")
+}
+
+// ValidNotifyTarget reports whether target names a configured destination.
+func (s *Service) ValidNotifyTarget(target string) bool {
+	channel, name, ok := strings.Cut(target, ":")
+	n := s.notifiers[channel]
+	return ok && n != nil && n.Has(name)
+}
+
+// notify posts a summary of task id to every target in opts.Notify when
+// some of its links are broken. Delivery runs in the background and
+// failures are logged.
+func (s *Service) notify(id int, opts CheckOptions, result map[string]domain.LinkResult) {
+	if len(opts.Notify) == 0 {
+		return
+	}
+	n := ports.Notification{
+		TaskID:    id,
+		Name:      opts.Name,
+		Links:     len(result),
+		ReportURL: fmt.Sprintf("%s/v1/report?links_list=%d", s.publicURL, id),
+	}
+	links := make([]string, 0, len(result))
+	for link := range result {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	for _, link := range links {
+		r := result[link]
+		if r.Status == domain.StatusAvailable || r.Skipped {
+			continue
+		}
+		n.Broken++
+		if len(n.BrokenLinks) < maxNotifiedLinks {
+			n.BrokenLinks = append(n.BrokenLinks, ports.BrokenLink{URL: link, Error: brokenReason(r)})
+		}
+	}
+	if n.Broken == 0 {
+		return
+	}
+
+	s.persistWG.Add(1)
+	go func() {
+		defer s.persistWG.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		for _, target := range opts.Notify {
+			channel, name, _ := strings.Cut(target, ":")
+			notifier := s.notifiers[channel]
+			if notifier == nil {
+				s.logger().Warn("notification channel not configured", "task_id", id, "target", target)
+				continue
+			}
+			if err := notifier.Notify(ctx, name, n); err != nil {
+				s.logger().Error("notify", "task_id", id, "target", target, "err", err)
+			}
+		}
+	}()
+}
+
+func brokenReason(r domain.LinkResult) string {
+	if r.Error != "" {
+		return r.Error
+	}
+	return string(r.Status)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	"github.com/olgkv/linkchecker/internal/ports"
+)
+
+// LinkPage selects Limit links of a task from Offset; a zero Limit selects
+// every link from Offset.
+type LinkPage struct {
+	Offset int
+	Limit  int
+}
+
+// TaskPage is a task with only the links of a page and their results.
+type TaskPage struct {
+	Task *domain.Task
+	// Total is the number of links of the whole task.
+	Total int
+	// Summary counts the results of the whole task.
+	Summary domain.ResultCounts
+}
+
+// GetTaskPage returns the links of task id selected by page, or nil if the
+// task does not exist or belongs to another owner. Storages implementing
+// ports.TaskPageStorage copy only those links.
+func (s *Service) GetTaskPage(ctx context.Context, id int, owner string, page LinkPage) (*TaskPage, error) {
+	ps, ok := s.storage.(ports.TaskPageStorage)
+	if !ok {
+		task, err := s.GetTask(ctx, id, owner)
+		if err != nil || task == nil {
+			return nil, err
+		}
+		return &TaskPage{
+			Task:    domain.PageOf(task, page.Offset, page.Limit),
+			Total:   len(task.Links),
+			Summary: domain.CountResults(task.Links, task.Result),
+		}, nil
+	}
+	p, err := ps.GetTaskPage(ctx, id, page.Offset, page.Limit)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	found := filterOwner(dtoToDomain([]*ports.TaskDTO{p.Task}), owner)
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return &TaskPage{Task: found[0], Total: p.Total, Summary: domain.CountStatuses(p.Total, p.Statuses)}, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+)
+
+// Report formats.
+const (
+	ReportFormatPDF = "pdf"
+	// ReportFormatJSON mirrors the content of the PDF.
+	ReportFormatJSON = "json"
+	// ReportFormatBundle is a ZIP archive of the PDF, CSV and JSON versions.
+	ReportFormatBundle = "bundle"
+)
+
+// ValidReportFormat reports whether format can be passed in ReportQuery;
+// empty selects PDF.
+func ValidReportFormat(format string) bool {
+	switch format {
+	case "", ReportFormatPDF, ReportFormatJSON, ReportFormatBundle:
+		return true
+	}
+	return false
+}
+
+// renderReport writes the report of tasks to w in the format of q; every
+// format is rendered from the same domain.LinksReport. missing lists the
+// requested tasks that were not found.
+func (s *Service) renderReport(w io.Writer, q ReportQuery, tasks []*domain.Task, missing []int) error {
+	report := domain.BuildLinksReport(tasks, time.Now(), s.location())
+	report.MissingIDs = missing
+	report.Legend = s.checkerOpts.StatusRules.Legend()
+	switch q.Format {
+	case "", ReportFormatPDF:
+		opts := s.pdfOpts
+		if q.Password != "" {
+			opts.UserPassword = q.Password
+		}
+		return s.pdfBuilder(w, report, opts)
+	case ReportFormatJSON:
+		return writeReportJSON(w, report)
+	case ReportFormatBundle:
+		return s.writeReportBundle(w, report)
+	}
+	return fmt.Errorf("unknown report format %q", q.Format)
+}
+
+func (s *Service) writeReportPDF(w io.Writer, report *domain.LinksReport) error {
+	return s.pdfBuilder(w, report, s.pdfOpts)
+}
+
+// writeReportBundle streams a ZIP archive with report.pdf, report.csv and
+// report.json, each rendered straight into its entry.
+func (s *Service) writeReportBundle(w io.Writer, report *domain.LinksReport) error {
+	zw := zip.NewWriter(w)
+	entries := []struct {
+		name  string
+		write func(io.Writer, *domain.LinksReport) error
+	}{
+		{"report.pdf", s.writeReportPDF},
+		{"report.csv", writeReportCSV},
+		{"report.json", writeReportJSON},
+	}
+	for _, e := range entries {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: report.GeneratedAt})
+		if err != nil {
+			return fmt.Errorf("bundle %s: %w", e.name, err)
+		}
+		if err := e.write(f, report); err != nil {
+			return fmt.Errorf("bundle %s: %w", e.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// writeReportCSV writes a row per link of every task; findings are
+// "kind: url" entries separated by semicolons and security_score is empty
+// for links whose security headers were not rated.
+func writeReportCSV(w io.Writer, report *domain.LinksReport) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"task_id", "task_name", "link", "status", "checked_at", "duration_ms", "findings", "security_score"})
+	for _, t := range report.Tasks {
+		for _, link := range t.Links {
+			var checkedAt, duration string
+			if !link.CheckedAt.IsZero() {
+				checkedAt = link.CheckedAt.Format(time.RFC3339)
+				duration = strconv.FormatInt(link.DurationMS, 10)
+			}
+			findings := make([]string, 0, len(link.Findings))
+			for _, f := range link.Findings {
+				findings = append(findings, f.Kind+": "+f.URL)
+			}
+			var score string
+			if link.Security != nil {
+				score = strconv.Itoa(link.Security.Score)
+			}
+			_ = cw.Write([]string{strconv.Itoa(t.ID), t.Name, link.URL, string(link.Status), checkedAt, duration, strings.Join(findings, ";"), score})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeReportJSON(w io.Writer, report *domain.LinksReport) error {
+	return json.NewEncoder(w).Encode(report)
+}
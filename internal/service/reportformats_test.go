@@ -0,0 +1,129 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	pdfgen "github.com/olgkv/linkchecker/internal/pdf"
+)
+
+func TestRenderReport_JSON(t *testing.T) {
+	tasks := []*domain.Task{
+		{ID: 1, Links: []string{"a.example", "b.example"}, Result: map[string]string{"a.example": "available", "b.example": "not available"}},
+		{ID: 2, Links: []string{"a.example", "c.example"}, Result: map[string]string{"a.example": "available", "c.example": "maintenance"}},
+	}
+	var buf bytes.Buffer
+	if err := (&Service{}).renderReport(&buf, ReportQuery{Format: ReportFormatJSON}, tasks, nil); err != nil {
+		t.Fatalf("renderReport: %v", err)
+	}
+	var doc domain.LinksReport
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(doc.Tasks) != 2 || doc.Tasks[0].Links[1].Status != domain.StatusNotAvailable || doc.GeneratedAt.IsZero() {
+		t.Fatalf("unexpected tasks %+v", doc.Tasks)
+	}
+	wantHosts := []domain.HostSummary{
+		{Host: "a.example", Checked: 2, Available: 2},
+		{Host: "b.example", Checked: 1, Broken: 1},
+		{Host: "c.example", Checked: 1, Maintenance: 1},
+	}
+	if !slices.Equal(doc.Hosts, wantHosts) {
+		t.Fatalf("hosts = %+v, want %+v", doc.Hosts, wantHosts)
+	}
+	if want := (domain.HostSummary{Checked: 4, Available: 2, Broken: 1, Maintenance: 1}); doc.Total != want {
+		t.Fatalf("total = %+v, want %+v", doc.Total, want)
+	}
+}
+
+func TestRenderReport_Password(t *testing.T) {
+	var got pdfgen.Options
+	s := &Service{pdfBuilder: func(_ io.Writer, _ *domain.LinksReport, opts pdfgen.Options) error {
+		got = opts
+		return nil
+	}}
+	s.SetReportPasswords("default", "owner")
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"", "default"},
+		{"per-request", "per-request"},
+	}
+	for _, tc := range tests {
+		if err := s.renderReport(io.Discard, ReportQuery{Password: tc.password}, nil, nil); err != nil {
+			t.Fatalf("renderReport: %v", err)
+		}
+		if got.UserPassword != tc.want || got.OwnerPassword != "owner" {
+			t.Fatalf("password %q: options %+v, want user password %q", tc.password, got, tc.want)
+		}
+	}
+}
+
+func TestWriteReportBundle(t *testing.T) {
+	s := &Service{pdfBuilder: func(w io.Writer, _ *domain.LinksReport, _ pdfgen.Options) error {
+		_, err := io.WriteString(w, "%PDF")
+		return err
+	}}
+	checked := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tasks := []*domain.Task{{
+		ID:       3,
+		Name:     "docs",
+		Links:    []string{"go.dev", "broken.example"},
+		Result:   map[string]string{"go.dev": string(domain.StatusAvailable)},
+		Timings:  map[string]domain.LinkTiming{"go.dev": {CheckedAt: checked, DurationMS: 42}},
+		Findings: map[string][]domain.Finding{"go.dev": {{Kind: "mixed_content", URL: "http://go.dev/x.js"}}},
+		Security: map[string]domain.SecurityAudit{"go.dev": {Score: 75, Issues: []string{"missing X-Frame-Options"}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := s.renderReport(&buf, ReportQuery{Format: ReportFormatBundle}, tasks, nil); err != nil {
+		t.Fatalf("renderReport: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	if len(files) != 3 || string(files["report.pdf"]) != "%PDF" {
+		t.Fatalf("unexpected bundle entries %v", files)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(files["report.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	want := [][]string{
+		{"task_id", "task_name", "link", "status", "checked_at", "duration_ms", "findings", "security_score"},
+		{"3", "docs", "go.dev", "available", "2024-05-01T12:00:00Z", "42", "mixed_content: http://go.dev/x.js", "75"},
+		{"3", "docs", "broken.example", "not available", "", "", "", ""},
+	}
+	for i := range want {
+		if i >= len(rows) || !slices.Equal(rows[i], want[i]) {
+			t.Fatalf("csv rows = %q, want %q", rows, want)
+		}
+	}
+
+	var doc domain.LinksReport
+	if err := json.Unmarshal(files["report.json"], &doc); err != nil || len(doc.Tasks) != 1 || doc.Tasks[0].ID != 3 {
+		t.Fatalf("unexpected json %s (%v)", files["report.json"], err)
+	}
+	if sec := doc.Tasks[0].Links[0].Security; sec == nil || sec.Score != 75 || doc.Tasks[0].Links[1].Security != nil {
+		t.Fatalf("unexpected security in json %s", files["report.json"])
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrReportLinkInvalid is returned for a download link with a wrong
+	// signature, or when signed links are disabled.
+	ErrReportLinkInvalid = errors.New("invalid report link")
+	// ErrReportLinkExpired is returned for a download link past its expiry.
+	ErrReportLinkExpired = errors.New("report link expired")
+)
+
+// EnableReportLinks lets stored reports be shared with download links
+// signed with secret. Links are valid for ttl unless ShareReport asks for
+// less. Call it before serving requests.
+func (s *Service) EnableReportLinks(secret []byte, ttl time.Duration) {
+	s.linkSecret = secret
+	s.linkTTL = ttl
+}
+
+func (s *Service) reportSignature(id string, exp int64) string {
+	m := hmac.New(sha256.New, s.linkSecret)
+	fmt.Fprintf(m, "%s\n%d", id, exp)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// ShareReport returns a download link for stored report id of owner that
+// needs no authentication and expires after ttl, or after the configured
+// maximum if ttl is zero or longer. The link is relative unless a public
+// URL is set.
+func (s *Service) ShareReport(ctx context.Context, id, owner string, ttl time.Duration) (string, time.Time, error) {
+	if len(s.linkSecret) == 0 {
+		return "", time.Time{}, ErrReportLinkInvalid
+	}
+	rc, err := s.OpenReport(ctx, id, owner)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	rc.Close()
+	if ttl <= 0 || ttl > s.linkTTL {
+		ttl = s.linkTTL
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	q := url.Values{
+		"exp": {strconv.FormatInt(expires.Unix(), 10)},
+		"sig": {s.reportSignature(id, expires.Unix())},
+	}
+	return fmt.Sprintf("%s/v1/reports/%s/download?%s", s.publicURL, id, q.Encode()), expires, nil
+}
+
+// OpenSharedReport returns stored report id if exp and sig come from a
+// download link made by ShareReport that has not expired yet.
+func (s *Service) OpenSharedReport(ctx context.Context, id, exp, sig string) (io.ReadCloser, error) {
+	if len(s.linkSecret) == 0 || !validReportID(id) {
+		return nil, ErrReportLinkInvalid
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !hmac.Equal([]byte(sig), []byte(s.reportSignature(id, unix))) {
+		return nil, ErrReportLinkInvalid
+	}
+	if time.Now().Unix() > unix {
+		return nil, ErrReportLinkExpired
+	}
+	return s.OpenReport(ctx, id, "")
+}
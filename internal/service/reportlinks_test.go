@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/blob"
+)
+
+func TestService_ReportLinks(t *testing.T) {
+	store, err := blob.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	s := &Service{done: make(chan struct{})}
+	s.UseReportStore(store, 0)
+	s.SetPublicURL("https://lc.example/")
+	ctx := context.Background()
+
+	id := NewReportID()
+	if err := s.StoreReport(ctx, id, "alice", []byte("%PDF")); err != nil {
+		t.Fatalf("StoreReport: %v", err)
+	}
+	if _, _, err := s.ShareReport(ctx, id, "alice", 0); !errors.Is(err, ErrReportLinkInvalid) {
+		t.Fatalf("ShareReport without a secret: %v, want ErrReportLinkInvalid", err)
+	}
+
+	s.EnableReportLinks([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	if _, _, err := s.ShareReport(ctx, id, "bob", 0); !errors.Is(err, ErrReportNotFound) {
+		t.Fatalf("ShareReport of another owner: %v, want ErrReportNotFound", err)
+	}
+	link, expires, err := s.ShareReport(ctx, id, "alice", 48*time.Hour)
+	if err != nil {
+		t.Fatalf("ShareReport: %v", err)
+	}
+	if d := time.Until(expires); d > time.Hour || d < 59*time.Minute {
+		t.Fatalf("link expires in %s, want the 1h maximum", d)
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Host != "lc.example" || u.Path != "/v1/reports/"+id+"/download" {
+		t.Fatalf("unexpected link %q", link)
+	}
+	exp, sig := u.Query().Get("exp"), u.Query().Get("sig")
+
+	rc, err := s.OpenSharedReport(ctx, id, exp, sig)
+	if err != nil {
+		t.Fatalf("OpenSharedReport: %v", err)
+	}
+	rc.Close()
+
+	later := strconv.FormatInt(expires.Add(time.Hour).Unix(), 10)
+	if _, err := s.OpenSharedReport(ctx, id, later, sig); !errors.Is(err, ErrReportLinkInvalid) {
+		t.Fatalf("extended expiry: %v, want ErrReportLinkInvalid", err)
+	}
+	past := time.Now().Add(-time.Minute).Unix()
+	if _, err := s.OpenSharedReport(ctx, id, strconv.FormatInt(past, 10), s.reportSignature(id, past)); !errors.Is(err, ErrReportLinkExpired) {
+		t.Fatalf("expired link: %v, want ErrReportLinkExpired", err)
+	}
+}
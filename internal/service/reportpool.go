@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrServiceClosed is returned by GenerateReport after Close.
+var ErrServiceClosed = errors.New("service closed")
+
+const defaultReportQueue = 64
+
+// reportWorkerIdle is how long a worker above the minimum waits for a job
+// before it exits.
+var reportWorkerIdle = 30 * time.Second
+
+// reportPool runs report jobs on workers that are added while jobs wait in
+// the queue, up to max, and retire after staying idle, down to min.
+type reportPool struct {
+	handle func(*reportJob)
+	idle   time.Duration
+
+	// mu keeps jobs from being closed while a job is being submitted.
+	mu     sync.RWMutex
+	jobs   chan *reportJob
+	closed bool
+
+	scale   sync.Mutex
+	min     int
+	max     int
+	workers int
+	wg      sync.WaitGroup
+}
+
+// newReportPool starts min workers. At least one worker is always kept so
+// that a queued job is never left without one.
+func newReportPool(handle func(*reportJob), min, max, queue int) *reportPool {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	p := &reportPool{
+		handle: handle,
+		idle:   reportWorkerIdle,
+		jobs:   make(chan *reportJob, queue),
+		min:    min,
+		max:    max,
+	}
+	p.scale.Lock()
+	for p.workers < p.min {
+		p.spawn()
+	}
+	p.scale.Unlock()
+	return p
+}
+
+// submit queues job, waiting for room in the queue until ctx is done.
+func (p *reportPool) submit(ctx context.Context, job *reportJob) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrServiceClosed
+	}
+	select {
+	case p.jobs <- job:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.scale.Lock()
+	// очередь не успевают разбирать — добавляем воркер
+	if len(p.jobs) > 0 && p.workers < p.max {
+		p.spawn()
+	}
+	p.scale.Unlock()
+	return nil
+}
+
+// spawn starts a worker; p.scale must be held.
+func (p *reportPool) spawn() {
+	p.workers++
+	p.wg.Add(1)
+	go p.work()
+}
+
+func (p *reportPool) work() {
+	defer p.wg.Done()
+	timer := time.NewTimer(p.idle)
+	defer timer.Stop()
+	for {
+		select {
+		case job, ok := <-p.jobs:
+			if !ok {
+				p.scale.Lock()
+				p.workers--
+				p.scale.Unlock()
+				return
+			}
+			p.handle(job)
+			timer.Reset(p.idle)
+		case <-timer.C:
+			if p.retire() {
+				return
+			}
+			timer.Reset(p.idle)
+		}
+	}
+}
+
+func (p *reportPool) retire() bool {
+	p.scale.Lock()
+	defer p.scale.Unlock()
+	if p.workers <= p.min {
+		return false
+	}
+	p.workers--
+	return true
+}
+
+// size returns the number of running workers.
+func (p *reportPool) size() int {
+	p.scale.Lock()
+	defer p.scale.Unlock()
+	return p.workers
+}
+
+// close stops accepting jobs and waits until the queued ones are handled and
+// all workers have exited.
+func (p *reportPool) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
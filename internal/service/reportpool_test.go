@@ -0,0 +1,110 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	pdfgen "github.com/olgkv/linkchecker/internal/pdf"
+)
+
+func TestReportPool_ScalesWithQueueAndShrinksWhenIdle(t *testing.T) {
+	defer func(d time.Duration) { reportWorkerIdle = d }(reportWorkerIdle)
+	reportWorkerIdle = 20 * time.Millisecond
+
+	release := make(chan struct{})
+	var handled atomic.Int32
+	p := newReportPool(func(*reportJob) {
+		<-release
+		handled.Add(1)
+	}, 1, 3, 10)
+
+	for i := 0; i < 6; i++ {
+		if err := p.submit(context.Background(), &reportJob{}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	if n := p.size(); n != 3 {
+		t.Fatalf("expected pool to grow to 3 workers, got %d", n)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for p.size() > 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := p.size(); n != 1 {
+		t.Fatalf("expected idle workers to retire down to 1, got %d", n)
+	}
+	p.close()
+	if n := handled.Load(); n != 6 {
+		t.Fatalf("expected 6 jobs handled, got %d", n)
+	}
+}
+
+func TestReportPool_CloseDrainsQueue(t *testing.T) {
+	var handled atomic.Int32
+	p := newReportPool(func(*reportJob) {
+		time.Sleep(5 * time.Millisecond)
+		handled.Add(1)
+	}, 1, 1, 5)
+
+	for i := 0; i < 5; i++ {
+		if err := p.submit(context.Background(), &reportJob{}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	p.close()
+	if n := handled.Load(); n != 5 {
+		t.Fatalf("expected queued jobs to finish before close returns, got %d", n)
+	}
+	if err := p.submit(context.Background(), &reportJob{}); !errors.Is(err, ErrServiceClosed) {
+		t.Fatalf("expected ErrServiceClosed after close, got %v", err)
+	}
+	if n := p.size(); n != 0 {
+		t.Fatalf("expected no workers after close, got %d", n)
+	}
+}
+
+func TestGenerateReport_AbandonedJobIsNotRendered(t *testing.T) {
+	release := make(chan struct{})
+	var rendered atomic.Int32
+	s := &Service{storage: &mockTaskStorage{}}
+	s.pdfBuilder = func(w io.Writer, _ *domain.LinksReport, _ pdfgen.Options) error {
+		rendered.Add(1)
+		<-release
+		_, err := w.Write([]byte("%PDF"))
+		return err
+	}
+	s.reports = newReportPool(s.handleReportJob, 1, 1, 1)
+	defer s.Close()
+
+	var first bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- s.GenerateReport(context.Background(), ReportQuery{Tag: "a"}, &first) }()
+	for rendered.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// второй отчёт ждёт в очереди и отменяется до начала рендеринга
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var second bytes.Buffer
+	if err := s.GenerateReport(ctx, ReportQuery{Tag: "b"}, &second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error for queued report, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil || first.String() != "%PDF" {
+		t.Fatalf("first report: err=%v body=%q", err, first.String())
+	}
+	s.Close()
+	if n := rendered.Load(); n != 1 || second.Len() != 0 {
+		t.Fatalf("abandoned report was rendered: calls=%d body=%q", n, second.String())
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -31,13 +32,17 @@ func TestRetryUpdateTaskResult_SingleAttemptNoSleep(t *testing.T) {
 	}
 }
 
-func (m *mockTaskStorage) Load() error { return nil }
+func (m *mockTaskStorage) Load(ctx context.Context) error { return nil }
 
-func (m *mockTaskStorage) CreateTask(links []string) (*ports.TaskDTO, error) {
+func (m *mockTaskStorage) CreateTask(ctx context.Context, links []string, meta ports.TaskMeta) (*ports.TaskDTO, error) {
 	return &ports.TaskDTO{ID: 1, Links: links, Result: map[string]string{}}, nil
 }
 
-func (m *mockTaskStorage) UpdateTaskResult(id int, result map[string]string) error {
+func (m *mockTaskStorage) AppendLinkResult(ctx context.Context, id int, link string, status string, timing ports.LinkTiming, findings []ports.Finding, security *ports.SecurityAudit) error {
+	return nil
+}
+
+func (m *mockTaskStorage) UpdateTaskResult(ctx context.Context, id int, version int, result map[string]string) error {
 	m.updateCalls++
 	if m.updateFunc != nil {
 		return m.updateFunc(m.updateCalls)
@@ -45,7 +50,25 @@ func (m *mockTaskStorage) UpdateTaskResult(id int, result map[string]string) err
 	return nil
 }
 
-func (m *mockTaskStorage) GetTasks(ids []int) ([]*ports.TaskDTO, error) { return nil, nil }
+func (m *mockTaskStorage) GetTasks(ctx context.Context, ids []int) ([]*ports.TaskDTO, error) {
+	return []*ports.TaskDTO{{ID: ids[0], Version: 1}}, nil
+}
+
+func (m *mockTaskStorage) ListTasks(ctx context.Context, tag string) ([]*ports.TaskDTO, error) {
+	return nil, nil
+}
+
+func (m *mockTaskStorage) SearchTasks(ctx context.Context, url string) ([]*ports.TaskDTO, error) {
+	return nil, nil
+}
+
+func (m *mockTaskStorage) DeleteTasksBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockTaskStorage) DeleteTask(ctx context.Context, id int) (bool, error) { return false, nil }
+
+func (m *mockTaskStorage) ImportTasks(ctx context.Context, tasks []*ports.TaskDTO) error { return nil }
 
 func TestRetryUpdateTaskResult_SucceedsAfterRetries(t *testing.T) {
 	m := &mockTaskStorage{
@@ -96,3 +119,89 @@ func TestRetryUpdateTaskResult_GivesUpAfterMaxAttempts(t *testing.T) {
 		t.Fatalf("expected %d sleeps, got %d", resultRetryAttempts, sleepCount)
 	}
 }
+
+type conflictingStorage struct {
+	mockTaskStorage
+	stored  map[string]string
+	version int
+	fails   int
+}
+
+func (c *conflictingStorage) GetTasks(ctx context.Context, ids []int) ([]*ports.TaskDTO, error) {
+	return []*ports.TaskDTO{{ID: ids[0], Version: c.version, Result: c.stored}}, nil
+}
+
+func (c *conflictingStorage) UpdateTaskResult(ctx context.Context, id int, version int, result map[string]string) error {
+	c.updateCalls++
+	if c.fails > 0 {
+		// имитируем конкурентную запись между чтением и обновлением
+		c.fails--
+		c.version++
+		c.stored = map[string]string{"other.com": "available"}
+		return ports.ErrVersionConflict
+	}
+	if version != c.version {
+		return ports.ErrVersionConflict
+	}
+	c.stored = result
+	c.version++
+	return nil
+}
+
+func TestPersistResult_MergesAfterVersionConflict(t *testing.T) {
+	st := &conflictingStorage{version: 1, fails: 1}
+	svc := &Service{storage: st}
+
+	if err := svc.persistResult(context.Background(), 5, map[string]string{"mine.com": "not available"}); err != nil {
+		t.Fatalf("persistResult: %v", err)
+	}
+	if st.updateCalls != 2 {
+		t.Fatalf("expected retry after conflict, got %d update calls", st.updateCalls)
+	}
+	want := map[string]string{"other.com": "available", "mine.com": "not available"}
+	if len(st.stored) != len(want) || st.stored["other.com"] != want["other.com"] || st.stored["mine.com"] != want["mine.com"] {
+		t.Fatalf("expected merged result %v, got %v", want, st.stored)
+	}
+}
+
+type memorySpool struct {
+	pending map[int]map[string]string
+}
+
+func (s *memorySpool) Add(id int, result map[string]string) error {
+	s.pending[id] = result
+	return nil
+}
+
+func (s *memorySpool) Remove(id int) error {
+	delete(s.pending, id)
+	return nil
+}
+
+func (s *memorySpool) Pending() (map[int]map[string]string, error) {
+	out := make(map[int]map[string]string, len(s.pending))
+	for id, r := range s.pending {
+		out[id] = r
+	}
+	return out, nil
+}
+
+func TestDrainSpool_PersistsLeftoverResults(t *testing.T) {
+	m := &mockTaskStorage{}
+	spool := &memorySpool{pending: map[int]map[string]string{9: {"a.com": "available"}}}
+	svc := &Service{storage: m}
+	svc.UseSpool(spool)
+
+	n, err := svc.DrainSpool()
+	if err != nil {
+		t.Fatalf("DrainSpool: %v", err)
+	}
+	svc.Wait()
+
+	if n != 1 || m.updateCalls != 1 {
+		t.Fatalf("expected one spooled result persisted, got n=%d updates=%d", n, m.updateCalls)
+	}
+	if len(spool.pending) != 0 {
+		t.Fatalf("expected spool drained, got %v", spool.pending)
+	}
+}
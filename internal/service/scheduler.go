@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority orders the checks of tasks waiting for the shared workers; see
+// EnableWorkerPool.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// ParsePriority validates p; an empty string selects PriorityNormal.
+func ParsePriority(p string) (Priority, error) {
+	switch Priority(p) {
+	case "":
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return Priority(p), nil
+	}
+	return "", fmt.Errorf("priority: want high, normal or low, got %q", p)
+}
+
+// level ranks p, lower first.
+func (p Priority) level() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// priorityAging is how long a waiting check takes to move up one level, so
+// that a steady stream of high priority tasks cannot starve low ones.
+const priorityAging = 10 * time.Second
+
+// workClass is what the pool ranks the checks of a task by.
+type workClass struct {
+	priority Priority
+	tenant   string
+}
+
+type workClassKey struct{}
+
+func withWorkClass(ctx context.Context, c workClass) context.Context {
+	return context.WithValue(ctx, workClassKey{}, c)
+}
+
+func workClassFrom(ctx context.Context) workClass {
+	c, _ := ctx.Value(workClassKey{}).(workClass)
+	return c
+}
+
+// ParseTenantWeights parses "tenant:weight" entries separated by commas.
+func ParseTenantWeights(spec string) (map[string]int, error) {
+	res := make(map[string]int)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, w, ok := strings.Cut(item, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid tenant weight %q", item)
+		}
+		weight, err := strconv.Atoi(w)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("tenant %q weight: want a positive integer, got %q", name, w)
+		}
+		res[name] = weight
+	}
+	return res, nil
+}
+
+// workerPool caps the links checked at a time across all tasks. A free
+// worker goes to the tenant using the smallest share of the workers for its
+// weight, and within the tenant to the waiting check of the highest
+// priority, oldest first.
+type workerPool struct {
+	mu      sync.Mutex
+	size    int
+	busy    int
+	tenants map[string]int // занятые воркеры по арендаторам
+	weights map[string]int
+	waiters []*poolWaiter
+	now     func() time.Time
+}
+
+type poolWaiter struct {
+	tenant string
+	level  int
+	since  time.Time
+	ready  chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{size: size, tenants: make(map[string]int), now: time.Now}
+}
+
+// Acquire waits for a free worker; the priority and tenant come from ctx.
+func (p *workerPool) Acquire(ctx context.Context) (func(), error) {
+	class := workClassFrom(ctx)
+	release := func() { p.release(class.tenant) }
+	p.mu.Lock()
+	if p.busy < p.size && len(p.waiters) == 0 {
+		p.take(class.tenant)
+		p.mu.Unlock()
+		return release, nil
+	}
+	w := &poolWaiter{tenant: class.tenant, level: class.priority.level(), since: p.now(), ready: make(chan struct{})}
+	p.waiters = append(p.waiters, w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		if i := slices.Index(p.waiters, w); i >= 0 {
+			p.waiters = slices.Delete(p.waiters, i, i+1)
+			p.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		p.mu.Unlock()
+		// воркер уже выдан — отдаём следующему
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+func (p *workerPool) take(tenant string) {
+	p.busy++
+	p.tenants[tenant]++
+}
+
+func (p *workerPool) release(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.busy--
+	if p.tenants[tenant]--; p.tenants[tenant] <= 0 {
+		delete(p.tenants, tenant)
+	}
+	p.dispatchLocked()
+}
+
+// resize changes the number of workers; checks already running finish on
+// the workers they have.
+func (p *workerPool) resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = size
+	p.dispatchLocked()
+}
+
+// setWeights replaces the tenant weights; tenants not listed weigh 1.
+func (p *workerPool) setWeights(weights map[string]int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.weights = weights
+}
+
+func (p *workerPool) weight(tenant string) int {
+	if w := p.weights[tenant]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (p *workerPool) dispatchLocked() {
+	for p.busy < p.size && len(p.waiters) > 0 {
+		i := p.nextLocked()
+		w := p.waiters[i]
+		p.waiters = slices.Delete(p.waiters, i, i+1)
+		p.take(w.tenant)
+		close(w.ready)
+	}
+}
+
+// nextLocked returns the index of the waiter to run next: of the tenants
+// with the fewest busy workers per weight, the waiter of the lowest level
+// after aging, waiting longest among equals.
+func (p *workerPool) nextLocked() int {
+	now := p.now()
+	best, bestRank := -1, 0
+	for i, w := range p.waiters {
+		rank := w.level - int(now.Sub(w.since)/priorityAging)
+		if best < 0 {
+			best, bestRank = i, rank
+			continue
+		}
+		// сравниваем доли busy/weight без деления
+		cur := p.waiters[best].tenant
+		a, b := p.tenants[w.tenant]*p.weight(cur), p.tenants[cur]*p.weight(w.tenant)
+		if a < b || a == b && rank < bestRank {
+			best, bestRank = i, rank
+		}
+	}
+	return best
+}
+
+// EnableWorkerPool caps the links checked at a time across all tasks at
+// size, on top of the per-task limit of SetLimits. Free workers are shared
+// fairly among the owners of the waiting tasks and, for each owner, go by
+// CheckOptions.Priority. Call it before serving requests.
+func (s *Service) EnableWorkerPool(size int) {
+	s.pool = newWorkerPool(size)
+	s.checkerOpts.Slots = s.pool
+	s.rebuildChecker()
+}
+
+// SetTenantWeights sets how many workers of the pool each tenant (the owner
+// of a task) gets relative to the others while they compete for them;
+// tenants not listed weigh 1. It does nothing without a pool.
+func (s *Service) SetTenantWeights(weights map[string]int) {
+	if s.pool != nil {
+		s.pool.setWeights(weights)
+	}
+}
+
+// ResizeWorkerPool changes the size of the pool enabled by EnableWorkerPool.
+// It does nothing without a pool or for a non-positive size.
+func (s *Service) ResizeWorkerPool(size int) {
+	if s.pool != nil && size > 0 {
+		s.pool.resize(size)
+	}
+}
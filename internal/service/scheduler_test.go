@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// enqueue starts an Acquire of class and waits until it is queued; the
+// release func is sent to got once a worker is handed out.
+func enqueue(t *testing.T, pool *workerPool, class workClass, got chan<- grant) {
+	t.Helper()
+	pool.mu.Lock()
+	n := len(pool.waiters)
+	pool.mu.Unlock()
+	go func() {
+		if release, err := pool.Acquire(withWorkClass(context.Background(), class)); err == nil {
+			got <- grant{class, release}
+		}
+	}()
+	for {
+		pool.mu.Lock()
+		queued := len(pool.waiters) > n
+		pool.mu.Unlock()
+		if queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type grant struct {
+	class   workClass
+	release func()
+}
+
+func TestWorkerPool_Priority(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pool := newWorkerPool(1)
+	pool.now = func() time.Time { return now }
+	release, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	got := make(chan grant, 4)
+	enqueue(t, pool, workClass{priority: PriorityLow}, got)
+	enqueue(t, pool, workClass{priority: PriorityNormal}, got)
+	enqueue(t, pool, workClass{priority: PriorityHigh}, got)
+
+	var order []Priority
+	for range 3 {
+		release()
+		g := <-got
+		order, release = append(order, g.class.priority), g.release
+	}
+	if order[0] != PriorityHigh || order[1] != PriorityNormal || order[2] != PriorityLow {
+		t.Fatalf("workers handed out in order %v, want high, normal, low", order)
+	}
+
+	// дождавшаяся низкоприоритетная проверка обгоняет свежую высокую
+	enqueue(t, pool, workClass{priority: PriorityLow}, got)
+	now = now.Add(3 * priorityAging)
+	enqueue(t, pool, workClass{priority: PriorityHigh}, got)
+	release()
+	if g := <-got; g.class.priority != PriorityLow {
+		t.Fatalf("aged low priority check not run first, got %s", g.class.priority)
+	}
+}
+
+func TestWorkerPool_FairShare(t *testing.T) {
+	pool := newWorkerPool(4)
+	pool.setWeights(map[string]int{"team-b": 2})
+	crawl := workClass{tenant: "team-a", priority: PriorityHigh}
+	var held []func()
+	for range 4 {
+		release, err := pool.Acquire(withWorkClass(context.Background(), crawl))
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		held = append(held, release)
+	}
+
+	got := make(chan grant, 8)
+	for range 3 {
+		enqueue(t, pool, crawl, got)
+	}
+	for range 3 {
+		enqueue(t, pool, workClass{tenant: "team-b", priority: PriorityLow}, got)
+	}
+
+	// team-b с весом 2 получает два воркера из каждых трёх освободившихся
+	var tenants []string
+	for _, release := range held[:3] {
+		release()
+		tenants = append(tenants, (<-got).class.tenant)
+	}
+	if tenants[0] != "team-b" || tenants[1] != "team-b" || tenants[2] != "team-a" {
+		t.Fatalf("workers went to %v, want team-b, team-b, team-a", tenants)
+	}
+}
+
+func TestWorkerPool_AcquireCanceled(t *testing.T) {
+	pool := newWorkerPool(1)
+	release, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire on a full pool = %v, want deadline exceeded", err)
+	}
+	release()
+	if pool.busy != 0 || len(pool.waiters) != 0 || len(pool.tenants) != 0 {
+		t.Fatalf("pool not empty after release: busy=%d waiters=%d", pool.busy, len(pool.waiters))
+	}
+
+	pool.resize(2)
+	for range 2 {
+		if _, err := pool.Acquire(context.Background()); err != nil {
+			t.Fatalf("Acquire after resize: %v", err)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	if p, err := ParsePriority(""); err != nil || p != PriorityNormal {
+		t.Fatalf(`ParsePriority("") = %q, %v`, p, err)
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Fatal("expected an unknown priority to be rejected")
+	}
+}
+
+func TestParseTenantWeights(t *testing.T) {
+	w, err := ParseTenantWeights(" team-a:3, team-b:1 ")
+	if err != nil || w["team-a"] != 3 || w["team-b"] != 1 {
+		t.Fatalf("ParseTenantWeights = %v, %v", w, err)
+	}
+	for _, spec := range []string{"team-a", "team-a:0", ":2", "team-a:x"} {
+		if _, err := ParseTenantWeights(spec); err == nil {
+			t.Errorf("ParseTenantWeights(%q) accepted", spec)
+		}
+	}
+}
@@ -3,79 +3,77 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net"
-	"net/http"
-	urlpkg "net/url"
-	"strings"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/olgkv/linkchecker/internal/domain"
 	pdfgen "github.com/olgkv/linkchecker/internal/pdf"
 	"github.com/olgkv/linkchecker/internal/ports"
+	"github.com/olgkv/linkchecker/internal/quota"
+	"github.com/olgkv/linkchecker/pkg/linkchecker"
+
+	"golang.org/x/crypto/ssh"
 )
 
 var sleep = time.Sleep
 
 type Service struct {
-	storage     ports.TaskStorage
-	httpClient  ports.HTTPClient
-	maxWorkers  int
-	httpTimeout time.Duration
-	breaker     *circuitBreaker
-	persistWG   sync.WaitGroup
-	reportJobs  chan reportJob
-	pdfBuilder  func([]*domain.Task) ([]byte, error)
+	storage ports.TaskStorage
+	checker *linkchecker.Checker
+	// checkerOpts are the options checker was built with, kept to rebuild
+	// it when an optional feature is enabled.
+	checkerOpts linkchecker.Options
+	// outboundRate and checkMetrics are what onAttempt reports requests to.
+	outboundRate float64
+	checkMetrics ports.CheckMetrics
+	dedup        *dedupCache
+	spool        ports.ResultSpool
+	events       ports.EventPublisher
+	notifiers    map[string]ports.Notifier
+	publicURL    string
+	artifacts    ports.BlobStore
+	linkSecret   []byte
+	linkTTL      time.Duration
+	pool         *workerPool
+	monitors     ports.MonitorStore
+	quota        *quota.Tracker // charged for monitor runs
+	monitorMu    sync.Mutex     // read-modify-write of monitors
+	monitorWG    sync.WaitGroup
+	log          *slog.Logger
+	persistWG    sync.WaitGroup
+	deferred     atomic.Int64 // results being retried by persistWG
+	activeMu     sync.Mutex
+	active       map[int]ActiveCheck
+	waiters      taskWaiters
+	batchWG      sync.WaitGroup
+	batchMu      sync.Mutex
+	batches      map[int]struct{} // batches being checked by this process
+	reports      *reportPool
+	pdfBuilder   func(io.Writer, *domain.LinksReport, pdfgen.Options) error
+	pdfOpts      pdfgen.Options
+	loc          *time.Location
+	done         chan struct{}
+	closeOnce    sync.Once
 }
 
+// deleteCompactionInterval is how often the data of deleted tasks is erased
+// from storage.
+var deleteCompactionInterval = time.Minute
+
 var ErrResultPersistDeferred = errors.New("result persistence deferred")
 
-const resultRetryAttempts = 5
+var errTaskNotFound = errors.New("task not found")
 
-func isPrivateIP(host string) bool {
-	ip := net.ParseIP(host)
-	if ip == nil {
-		return false
-	}
-	if ip4 := ip.To4(); ip4 != nil {
-		switch {
-		case ip4[0] == 10:
-			return true
-		case ip4[0] == 172 && ip4[1] >= 16 && ip4[1] <= 31:
-			return true
-		case ip4[0] == 192 && ip4[1] == 168:
-			return true
-		case ip4[0] == 127:
-			return true
-		case ip4[0] == 169 && ip4[1] == 254:
-			return true
-		}
-		return false
-	}
-	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
-}
-
-func isPrivateHost(host string) bool {
-	if ip := net.ParseIP(host); ip != nil {
-		return isPrivateIP(host)
-	}
-
-	ips, err := net.LookupIP(host)
-	if err != nil {
-		return true // fail-safe
-	}
-	if len(ips) == 0 {
-		return true
-	}
-	// Проверяем, что ВСЕ адреса приватные
-	for _, ip := range ips {
-		if !isPrivateIP(ip.String()) {
-			return false // публичный IP найден
-		}
-	}
-	return true // все приватные
-}
+const (
+	resultRetryAttempts    = 5
+	versionConflictRetries = 3
+)
 
 func New(storage ports.TaskStorage, client ports.HTTPClient, maxWorkers int, httpTimeout time.Duration, reportWorkers int) *Service {
 	if maxWorkers <= 0 {
@@ -89,189 +87,828 @@ func New(storage ports.TaskStorage, client ports.HTTPClient, maxWorkers int, htt
 	}
 
 	s := &Service{
-		storage:     storage,
-		httpClient:  client,
-		maxWorkers:  maxWorkers,
-		httpTimeout: httpTimeout,
-		breaker:     newCircuitBreaker(3, 30*time.Second),
-		reportJobs:  make(chan reportJob, reportWorkers),
-		pdfBuilder:  pdfgen.BuildLinksReport,
+		storage: storage,
+		checkerOpts: linkchecker.Options{
+			Timeout:     httpTimeout,
+			Concurrency: maxWorkers,
+			Client:      client,
+			Breaker:     linkchecker.NewBreaker(3, 30*time.Second),
+		},
+		done: make(chan struct{}),
 	}
-	for i := 0; i < reportWorkers; i++ {
-		go s.reportWorker()
+	s.pdfBuilder = pdfgen.WriteLinksReport
+	s.checker = linkchecker.New(s.checkerOpts)
+	s.reports = newReportPool(s.handleReportJob, 1, reportWorkers, defaultReportQueue)
+	if c, ok := storage.(ports.DeletionCompactor); ok {
+		go s.compactDeleted(c, deleteCompactionInterval)
 	}
 	return s
 }
 
-func (s *Service) CheckLinks(ctx context.Context, links []string) (int, map[string]domain.LinkStatus, error) {
-	task, err := s.storage.CreateTask(links)
-	if err != nil {
-		return 0, nil, err
+// SetReportBranding applies b to the PDF reports generated from now on.
+// Call it before serving requests.
+func (s *Service) SetReportBranding(b *pdfgen.Branding) {
+	s.pdfOpts.Branding = b
+}
+
+// SetReportPasswords encrypts every PDF report with user as the password to
+// open it, unless ReportQuery.Password replaces it; owner lifts the print
+// and copy only restriction. Call it before serving requests.
+func (s *Service) SetReportPasswords(user, owner string) {
+	s.pdfOpts.UserPassword = user
+	s.pdfOpts.OwnerPassword = owner
+}
+
+// SetLocation renders the times in reports in loc instead of UTC; stored
+// times stay in UTC. Call it before serving requests.
+func (s *Service) SetLocation(loc *time.Location) {
+	s.loc = loc
+}
+
+func (s *Service) location() *time.Location {
+	if s.loc == nil {
+		return time.UTC
 	}
+	return s.loc
+}
 
-	ctx, cancel := context.WithTimeout(ctx, s.httpTimeout)
-	defer cancel()
+// ReportsEncrypted reports whether PDF reports are encrypted by default.
+func (s *Service) ReportsEncrypted() bool {
+	return s.pdfOpts.UserPassword != ""
+}
 
-	result := make(map[string]domain.LinkStatus, len(links))
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, s.maxWorkers)
+// SetReportPool resizes the report workers: between minWorkers and
+// maxWorkers run depending on the backlog, which holds up to queueSize jobs.
+// Call it before serving requests.
+func (s *Service) SetReportPool(minWorkers, maxWorkers, queueSize int) {
+	old := s.reports
+	s.reports = newReportPool(s.handleReportJob, minWorkers, maxWorkers, queueSize)
+	if old != nil {
+		old.close()
+	}
+}
 
-	for _, link := range links {
-		link := link
-		wg.Add(1)
-		go func(link string) {
-			defer wg.Done()
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-				status := s.checkLink(ctx, link)
-				mu.Lock()
-				result[link] = status
-				mu.Unlock()
-			case <-ctx.Done():
-				return
-			}
+// SetLimits changes concurrency and timeout of subsequent CheckLinks calls;
+// checks already running keep their limits. Non-positive values are ignored.
+func (s *Service) SetLimits(maxWorkers int, httpTimeout time.Duration) {
+	s.checker.SetLimits(maxWorkers, httpTimeout)
+}
+
+// RetryPolicy controls retries of failed check requests.
+type RetryPolicy = linkchecker.RetryPolicy
 
-		}(link)
+// SetRetryPolicy changes how subsequent checks retry failed requests.
+func (s *Service) SetRetryPolicy(p RetryPolicy) {
+	s.checker.SetRetryPolicy(p)
+}
+
+// CheckOptions carries optional per-request settings for CheckLinks.
+type CheckOptions struct {
+	Name      string
+	Tags      []string
+	CreatedBy string
+	Owner     string
+	// FailAfter stops checking once this many links are not available; the
+	// remaining links are reported as skipped. Zero checks every link.
+	FailAfter int
+	// NoRedirects reports redirects as not available, with their status
+	// and target, instead of following them.
+	NoRedirects bool
+	// Content parses available HTML pages and records findings such as
+	// mixed content.
+	Content bool
+	// Security rates the security headers of available HTTPS pages.
+	Security bool
+	// Cookies gives the batch its own cookie jar, so that sites which set a
+	// cookie and redirect back are checked correctly.
+	Cookies bool
+	// Credentials, if set, are sent with the check requests. They are not
+	// stored, and batches checked with them are not deduplicated.
+	Credentials *Credentials
+	// Notify lists "channel:name" targets to post a summary to when links
+	// are broken; see UseNotifier.
+	Notify []string
+	// Priority orders the checks of the task on the shared workers; see
+	// EnableWorkerPool. Empty means PriorityNormal.
+	Priority Priority
+	// RequestID identifies the API request or queue job the check was
+	// submitted with in the outbound log; see EnableOutboundLog.
+	RequestID string
+}
+
+// Credentials authenticate check requests to protected sites.
+type Credentials = linkchecker.Credentials
+
+func (s *Service) CheckLinks(ctx context.Context, links []string, opts CheckOptions) (int, map[string]domain.LinkResult, error) {
+	var dedupKey string
+	dedup := s.dedup != nil && opts.Credentials == nil
+	if dedup {
+		dedupKey = batchKey(opts, links)
+		if id, result, ok := s.recentBatch(ctx, dedupKey); ok {
+			return id, result, ErrDeduplicated
+		}
 	}
+	submitted := time.Now()
 
-	wg.Wait()
+	task, err := s.storage.CreateTask(ctx, links, ports.TaskMeta{
+		Name:      opts.Name,
+		Tags:      opts.Tags,
+		CreatedBy: opts.CreatedBy,
+		Owner:     opts.Owner,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result, complete, err := s.runTask(ctx, task.ID, 0, links, opts)
+	// незавершённую из-за таймаута партию не запоминаем
+	if dedup && complete {
+		s.dedup.remember(dedupKey, task.ID, submitted)
+	}
+	return task.ID, result, err
+}
+
+// runTask checks the links of task id, recording each result as it comes
+// and the final result at the end, then publishes the task event and sends
+// the notifications asked for. complete is false when links were skipped
+// because the check ran out of time.
+func (s *Service) runTask(ctx context.Context, id, batchID int, links []string, opts CheckOptions) (result map[string]domain.LinkResult, complete bool, err error) {
+	defer s.trackCheck(id, batchID, opts.Owner, len(links))()
+	// результаты проверок сохраняются и после отмены запроса
+	store := context.WithoutCancel(ctx)
+	ctx = withCheckTags(ctx, checkTags{taskID: id, requestID: opts.RequestID})
+	if opts.NoRedirects {
+		ctx = linkchecker.WithoutRedirects(ctx)
+	}
+	if opts.Content {
+		ctx = linkchecker.WithContent(ctx)
+	}
+	if opts.Security {
+		ctx = linkchecker.WithSecurityHeaders(ctx)
+	}
+	if opts.Credentials != nil {
+		ctx = linkchecker.WithCredentials(ctx, *opts.Credentials)
+	}
+	if opts.Cookies {
+		ctx = linkchecker.WithCookies(ctx)
+	}
+	if s.pool != nil {
+		ctx = withWorkClass(ctx, workClass{priority: opts.Priority, tenant: opts.Owner})
+	}
+	checked := s.checker.CheckFailFast(ctx, links, opts.FailAfter, func(link string, res linkchecker.Result) {
+		timing := ports.LinkTiming{CheckedAt: res.CheckedAt, DurationMS: res.Duration.Milliseconds()}
+		var findings []ports.Finding
+		for _, f := range res.Findings {
+			findings = append(findings, ports.Finding{Kind: string(f.Kind), URL: f.URL, Detail: f.Detail})
+		}
+		var security *ports.SecurityAudit
+		if res.Security != nil {
+			security = &ports.SecurityAudit{Score: res.Security.Score, Issues: res.Security.Issues}
+		}
+		if err := s.storage.AppendLinkResult(store, id, link, string(linkStatus(res)), timing, findings, security); err != nil {
+			s.logger().Warn("append link result failed", "task_id", id, "link", link, "err", err)
+		}
+	})
 
-	strResult := make(map[string]string, len(result))
-	for k, v := range result {
-		strResult[k] = string(v)
+	result = make(map[string]domain.LinkResult, len(checked))
+	strResult := make(map[string]string, len(checked))
+	complete = true
+	for k, v := range checked {
+		result[k] = domain.LinkResult{
+			Status:         linkStatus(v),
+			Protocol:       v.Protocol,
+			HTTP3:          v.HTTP3,
+			StatusCode:     v.StatusCode,
+			Location:       v.Location,
+			Upgraded:       v.Upgraded,
+			Downgraded:     v.Downgraded,
+			Unchanged:      v.Unchanged,
+			Findings:       resultFindings(v.Findings),
+			Weight:         resultWeight(v.Weight),
+			Security:       resultSecurity(v.Security),
+			RemoteIP:       v.RemoteIP,
+			IPFamily:       linkchecker.AddressFamily(v.RemoteIP),
+			Phases:         resultPhases(v.Phases),
+			Deliverability: string(v.Deliverability),
+			Skipped:        v.Skipped,
+			Error:          v.Error,
+			ErrorKind:      string(v.ErrorKind),
+			LinkTiming:     domain.LinkTiming{CheckedAt: v.CheckedAt, DurationMS: v.Duration.Milliseconds()},
+		}
+		strResult[k] = string(linkStatus(v))
+		// пропуск по robots.txt не зависит от таймаута
+		complete = complete && v.ErrorKind != linkchecker.ErrorSkipped
 	}
-	if err := s.storage.UpdateTaskResult(task.ID, strResult); err != nil {
-		slog.Error("update task result failed", "task_id", task.ID, "err", err)
+	if err := s.persistResult(store, id, strResult); err != nil {
+		s.logger().Error("update task result failed", "task_id", id, "err", err)
+		if s.spool != nil {
+			if err := s.spool.Add(id, strResult); err != nil {
+				s.logger().Error("spool deferred task result", "task_id", id, "err", err)
+			}
+		}
 		s.persistWG.Add(1)
+		s.deferred.Add(1)
 		go func(id int, res map[string]string) {
 			defer s.persistWG.Done()
+			defer s.deferred.Add(-1)
 			s.retryUpdateTaskResult(id, res)
-		}(task.ID, domain.CopyStringMap(strResult))
-		return task.ID, result, ErrResultPersistDeferred
+		}(id, domain.CopyStringMap(strResult))
+		s.publishTaskEvent(id, batchID, opts, result, false)
+		s.notify(id, opts, result)
+		return result, complete, ErrResultPersistDeferred
 	}
 
-	return task.ID, result, nil
+	s.publishTaskEvent(id, batchID, opts, result, true)
+	s.notify(id, opts, result)
+	return result, complete, nil
+}
+
+// persistResult merges result into the stored task result and completes the
+// task, re-reading the task when a concurrent writer bumped its version.
+func (s *Service) persistResult(ctx context.Context, id int, result map[string]string) error {
+	var err error
+	for attempt := 0; attempt < versionConflictRetries; attempt++ {
+		var tasks []*ports.TaskDTO
+		tasks, err = s.storage.GetTasks(ctx, []int{id})
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return fmt.Errorf("task %d: %w", id, errTaskNotFound)
+		}
+		current := tasks[0]
+		merged := domain.CopyStringMap(current.Result)
+		if merged == nil {
+			merged = make(map[string]string, len(result))
+		}
+		for link, status := range result {
+			merged[link] = status
+		}
+		err = s.storage.UpdateTaskResult(ctx, id, current.Version, merged)
+		if err == nil {
+			s.waiters.done(id)
+		}
+		if !errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
+	}
+	return err
 }
 
 func (s *Service) retryUpdateTaskResult(id int, result map[string]string) {
 	backoff := time.Second
 	var lastErr error
 	for attempt := 1; attempt <= resultRetryAttempts; attempt++ {
-		if err := s.storage.UpdateTaskResult(id, result); err == nil {
+		err := s.persistResult(context.Background(), id, result)
+		switch {
+		case err == nil:
 			if attempt > 1 {
-				slog.Info("task result persisted after retries", "task_id", id, "attempt", attempt)
+				s.logger().Info("task result persisted after retries", "task_id", id, "attempt", attempt)
 			}
+			s.unspool(id)
+			return
+		case errors.Is(err, errTaskNotFound):
+			// задача удалена, сохранять результат некуда
+			s.logger().Warn("dropping result of deleted task", "task_id", id)
+			s.unspool(id)
 			return
-		} else {
-			lastErr = err
-			sleep(backoff)
-			backoff *= 2
 		}
+		lastErr = err
+		sleep(backoff)
+		backoff *= 2
 	}
-	slog.Error("giving up on persisting task result", "task_id", id, "attempts", resultRetryAttempts, "err", lastErr)
+	s.logger().Error("giving up on persisting task result", "task_id", id, "attempts", resultRetryAttempts, "err", lastErr)
 }
 
-// Wait blocks until all deferred persistence retries finish.
-func (s *Service) Wait() {
-	s.persistWG.Wait()
+func (s *Service) unspool(id int) {
+	if s.spool == nil {
+		return
+	}
+	if err := s.spool.Remove(id); err != nil {
+		s.logger().Warn("remove spooled task result", "task_id", id, "err", err)
+	}
 }
 
-func (s *Service) checkLink(ctx context.Context, link string) domain.LinkStatus {
-	clean := strings.TrimSpace(link)
-	if !validateURL(clean) {
-		return domain.StatusNotAvailable
+// UseLogger sets the logger used by the service; slog.Default by default.
+func (s *Service) UseLogger(l *slog.Logger) {
+	s.log = l
+}
+
+func (s *Service) logger() *slog.Logger {
+	if s.log == nil {
+		return slog.Default()
 	}
+	return s.log
+}
+
+// UseSpool makes deferred task results durable: they are written to spool
+// until persisted, so a crash during retries does not lose them. Call
+// DrainSpool afterwards to persist results left over from a previous run.
+func (s *Service) UseSpool(spool ports.ResultSpool) {
+	s.spool = spool
+}
 
-	url := clean
-	if !(len(url) >= 7 && (url[:7] == "http://" || (len(url) >= 8 && url[:8] == "https://"))) {
-		url = "https://" + clean
+// DrainSpool retries persisting results left in the spool by a previous run
+// in the background and returns how many there were.
+func (s *Service) DrainSpool() (int, error) {
+	if s.spool == nil {
+		return 0, nil
 	}
-	parsed, err := urlpkg.Parse(url)
+	pending, err := s.spool.Pending()
 	if err != nil {
-		return domain.StatusNotAvailable
+		return 0, err
 	}
-	host := parsed.Hostname()
-	if isPrivateHost(host) {
-		return domain.StatusNotAvailable
-	}
-	if s.breaker != nil && !s.breaker.allow(host) {
-		return domain.StatusNotAvailable
+	for id, result := range pending {
+		s.persistWG.Add(1)
+		s.deferred.Add(1)
+		go func() {
+			defer s.persistWG.Done()
+			defer s.deferred.Add(-1)
+			s.retryUpdateTaskResult(id, result)
+		}()
 	}
+	return len(pending), nil
+}
 
-	client := s.httpClient
-	if client == nil {
-		client = &http.Client{Timeout: 5 * time.Second}
-	}
+// Wait blocks until all deferred persistence retries and notifications
+// finish.
+func (s *Service) Wait() {
+	s.persistWG.Wait()
+}
 
-	// небольшой backoff-retry для временных сетевых сбоев
-	backoffs := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond}
-	for i, d := range backoffs {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return domain.StatusNotAvailable
+// Close stops background jobs started by the service, finishes queued
+// reports and closes the storage when it supports it.
+func (s *Service) Close() {
+	s.closeOnce.Do(func() {
+		if s.done != nil {
+			close(s.done)
 		}
-
-		resp, err := client.Do(req)
-		if resp != nil && resp.Body != nil {
-			defer resp.Body.Close()
+		s.batchWG.Wait()
+		s.monitorWG.Wait()
+		if s.reports != nil {
+			s.reports.close()
 		}
-		if err != nil {
-			if s.breaker != nil {
-				s.breaker.failure(host)
+		if c, ok := s.storage.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				s.logger().Error("close storage", "err", err)
 			}
-			// если контекст отменен — дальше не ретраим
+		}
+	})
+}
+
+// recentBatch returns the stored results of a task remembered under key.
+// Protocol details are not stored and are missing.
+func (s *Service) recentBatch(ctx context.Context, key string) (int, map[string]domain.LinkResult, bool) {
+	id, ok := s.dedup.lookup(key)
+	if !ok {
+		return 0, nil, false
+	}
+	tasks, err := s.storage.GetTasks(ctx, []int{id})
+	if err != nil || len(tasks) == 0 || tasks[0] == nil {
+		// задачу могли удалить — проверяем заново
+		s.dedup.forget(key)
+		return 0, nil, false
+	}
+	result := make(map[string]domain.LinkResult, len(tasks[0].Result))
+	for k, v := range tasks[0].Result {
+		timing := tasks[0].Timings[k]
+		result[k] = domain.LinkResult{
+			Status:     domain.LinkStatus(v),
+			Findings:   findingListFromDTO(tasks[0].Findings[k]),
+			Security:   securityAuditFromDTO(tasks[0].Security, k),
+			LinkTiming: domain.LinkTiming{CheckedAt: timing.CheckedAt, DurationMS: timing.DurationMS},
+		}
+	}
+	return id, result, true
+}
+
+// EnableRetention starts a background janitor that deletes tasks older than
+// retention. It runs once immediately and then periodically until Close.
+func (s *Service) EnableRetention(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	interval := retention
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.purgeExpired(retention)
 			select {
-			case <-ctx.Done():
-				return domain.StatusNotAvailable
-			default:
-			}
-		} else {
-			if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-				if s.breaker != nil {
-					s.breaker.success(host)
-				}
-				return domain.StatusAvailable
+			case <-ticker.C:
+			case <-s.done:
+				return
 			}
-			if s.breaker != nil {
-				s.breaker.failure(host)
+		}
+	}()
+}
+
+func (s *Service) purgeExpired(retention time.Duration) {
+	deleted, err := s.DeleteTasksBefore(context.Background(), time.Now().Add(-retention))
+	if err != nil {
+		s.logger().Error("task retention cleanup failed", "err", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger().Info("expired tasks deleted", "count", deleted, "retention", retention.String())
+	}
+}
+
+// compactDeleted erases deleted tasks from storage every interval until
+// Close, independently of retention.
+func (s *Service) compactDeleted(c ports.DeletionCompactor, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n, err := c.CompactDeleted(context.Background())
+			if err != nil {
+				s.logger().Error("compact deleted tasks", "err", err)
+			} else if n > 0 {
+				s.logger().Info("deleted tasks erased from storage", "count", n)
 			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// DeleteTasksBefore removes tasks created before cutoff.
+func (s *Service) DeleteTasksBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	return s.storage.DeleteTasksBefore(ctx, cutoff)
+}
+
+// DeleteTask removes task id if it belongs to owner (any task when owner is
+// empty) and reports whether it was deleted.
+func (s *Service) DeleteTask(ctx context.Context, id int, owner string) (bool, error) {
+	task, err := s.GetTask(ctx, id, owner)
+	if err != nil || task == nil {
+		return false, err
+	}
+	deleted, err := s.storage.DeleteTask(ctx, id)
+	if deleted {
+		s.waiters.done(id)
+	}
+	if deleted && s.monitors != nil {
+		s.monitorMu.Lock()
+		if _, err := s.monitors.Remove(id); err != nil {
+			s.logger().Error("remove monitor of deleted task", "task_id", id, "err", err)
+		}
+		s.monitorMu.Unlock()
+	}
+	return deleted, err
+}
+
+// EnableHTTP3Probe makes every check of a responding host also try HTTP/3
+// through client, which must speak HTTP/3 only. Call it before serving
+// requests.
+func (s *Service) EnableHTTP3Probe(client ports.HTTPClient) {
+	s.checkerOpts.HTTP3Client = client
+	s.rebuildChecker()
+}
+
+// EnableRobots makes checks honor robots.txt for agent, caching the rules of
+// each host for ttl; disallowed links are reported as skipped. Call it
+// before serving requests.
+func (s *Service) EnableRobots(agent string, ttl time.Duration) {
+	s.checkerOpts.Robots = linkchecker.NewRobots(agent, ttl)
+	s.rebuildChecker()
+}
+
+// EnableConditionalChecks remembers the ETag and Last-Modified of up to
+// maxEntries links so that checking them again sends a conditional request;
+// a 304 answer counts as available and marks the result unchanged. Call it
+// before serving requests.
+func (s *Service) EnableConditionalChecks(maxEntries int) {
+	s.checkerOpts.Validators = linkchecker.NewValidators(maxEntries)
+	s.rebuildChecker()
+}
+
+// EnableSMTPProbe makes checks of mailto: links ask the mail exchanger
+// whether it accepts the recipient, greeting with helo and using from as the
+// envelope sender. Call it before serving requests.
+func (s *Service) EnableSMTPProbe(helo, from string) {
+	s.checkerOpts.SMTP = linkchecker.NewSMTPProbe(helo, from)
+	s.rebuildChecker()
+}
+
+// UseSFTPKeys makes sftp:// checks verify servers with hostKey and offer
+// signers besides the password of the link or request. Call it before
+// serving requests.
+func (s *Service) UseSFTPKeys(hostKey ssh.HostKeyCallback, signers ...ssh.Signer) {
+	if s.checkerOpts.Probers == nil {
+		s.checkerOpts.Probers = make(map[string]linkchecker.Prober)
+	}
+	s.checkerOpts.Probers["sftp"] = linkchecker.NewSFTPProber(hostKey, signers...)
+	s.rebuildChecker()
+}
+
+// UseBlocklist makes checks consult bl before requesting HTTP links; links
+// it flags get the flagged_unsafe status and are never requested. Failed
+// lookups are logged and the link is checked anyway. Call it before serving
+// requests.
+func (s *Service) UseBlocklist(bl linkchecker.Blocklist) {
+	s.checkerOpts.Blocklist = linkchecker.BlocklistFunc(func(ctx context.Context, url string) (string, error) {
+		reason, err := bl.Flagged(ctx, url)
+		if err != nil {
+			s.logger().Warn("blocklist lookup failed, checking link anyway", "url", url, "err", err)
 		}
+		return reason, err
+	})
+	s.rebuildChecker()
+}
+
+// SetStatusRules makes checks classify HTTP responses by rules before the
+// default rule; reports list them in their legend. Call it before serving
+// requests.
+func (s *Service) SetStatusRules(rules linkchecker.StatusRules) {
+	s.checkerOpts.StatusRules = rules
+	s.rebuildChecker()
+}
+
+// SetBandwidth caps the bytes per second all checks read from responses
+// and probe connections;
+// zero removes the cap. Call it before serving requests.
+func (s *Service) SetBandwidth(bytesPerSecond int) {
+	s.checkerOpts.Bandwidth = linkchecker.NewBandwidth(bytesPerSecond)
+	s.rebuildChecker()
+}
+
+// UseSourceIP makes tcp://, ping://, ftp://, sftp:// and mailto: checks
+// connect from ip; the HTTP client passed to New has to be bound to it on
+// its own. Call it before serving requests.
+func (s *Service) UseSourceIP(ip net.IP) {
+	s.checkerOpts.SourceIP = ip
+	s.rebuildChecker()
+}
+
+// RestrictSchemes limits checks to links of schemes, bare host names
+// counting as https, and sets how web links are checked over HTTPS. Other
+// links are rejected as invalid. Empty schemes allow every scheme. Call it
+// before serving requests.
+func (s *Service) RestrictSchemes(schemes []string, policy linkchecker.HTTPSPolicy) {
+	s.checkerOpts.Schemes, s.checkerOpts.HTTPS = schemes, policy
+	s.rebuildChecker()
+}
+
+// rebuildChecker replaces the checker with one built from checkerOpts,
+// keeping the limits and retry policy changed since it was created.
+func (s *Service) rebuildChecker() {
+	concurrency, timeout := s.checker.Limits()
+	retry := s.checker.RetryPolicy()
+	s.checkerOpts.Concurrency, s.checkerOpts.Timeout, s.checkerOpts.Retry = concurrency, timeout, &retry
+	s.checker = linkchecker.New(s.checkerOpts)
+}
 
-		// если это не последняя попытка — подождать backoff или выход, если контекст отменен
-		if i < len(backoffs)-1 {
+// BreakerHost describes a host with recorded check failures.
+type BreakerHost = linkchecker.BreakerHost
+
+// ValidateLink reports why link cannot be checked, e.g. an unsupported
+// scheme, or nil if it can.
+func (s *Service) ValidateLink(link string) error {
+	return s.checker.ValidateLink(link)
+}
+
+// BreakerHosts lists hosts with recorded check failures.
+func (s *Service) BreakerHosts() []BreakerHost {
+	return s.checker.Breaker().Hosts()
+}
+
+// ResetBreaker closes the circuit for host, or for all hosts when host is empty.
+func (s *Service) ResetBreaker(host string) {
+	s.checker.Breaker().Reset(host)
+}
+
+// BreakerStats summarizes the circuit breaker state.
+type BreakerStats = linkchecker.BreakerStats
+
+// BreakerStats returns the number of tracked and open hosts and the trips
+// so far.
+func (s *Service) BreakerStats() BreakerStats {
+	return s.checker.Breaker().Stats()
+}
+
+// EnableBreakerJanitor caps the hosts tracked by the circuit breaker at
+// maxHosts and, every cooldown until Close, forgets hosts that have not
+// failed since.
+func (s *Service) EnableBreakerJanitor(maxHosts int) {
+	b := s.checkerOpts.Breaker
+	b.SetMaxHosts(maxHosts)
+	go func() {
+		ticker := time.NewTicker(b.Cooldown())
+		defer ticker.Stop()
+		for {
 			select {
-			case <-ctx.Done():
-				return domain.StatusNotAvailable
-			case <-time.After(d):
+			case <-ticker.C:
+				if n := b.Prune(); n > 0 {
+					s.logger().Debug("stale breaker hosts pruned", "count", n)
+				}
+			case <-s.done:
+				return
 			}
 		}
-	}
+	}()
+}
 
-	return domain.StatusNotAvailable
+// ReportQuery selects tasks included in a report. When IDs is empty all tasks
+// labelled with Tag are used; otherwise Tag additionally filters the IDs.
+// A non-empty Owner hides tasks belonging to other owners.
+type ReportQuery struct {
+	IDs []int
+	// Batch adds the tasks of a batch to IDs.
+	Batch int
+	Tag   string
+	Owner string
+	// Format is one of the ReportFormat constants; empty means PDF.
+	Format string
+	// Password encrypts a PDF report instead of the configured password.
+	// Other formats are never encrypted.
+	Password string
+	// OnMissing, if set, is called with the requested task IDs that were not
+	// found before anything is written.
+	OnMissing func(ids []int)
 }
 
-func (s *Service) GenerateReport(ctx context.Context, ids []int) ([]byte, error) {
+// ErrTasksNotFound is returned by GenerateReport when none of the requested
+// tasks exist.
+var ErrTasksNotFound = errors.New("tasks not found")
+
+// GenerateReport writes the report selected by q to w. Nothing is written
+// when an error is returned before rendering started; an error returned after
+// that means the output is truncated. If ctx ends while the job is still
+// queued, GenerateReport returns ctx.Err() at once; once rendering started it
+// waits for it to finish, as the worker writes to w.
+func (s *Service) GenerateReport(ctx context.Context, q ReportQuery, w io.Writer) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	job := reportJob{
-		ctx:  ctx,
-		ids:  ids,
-		resp: make(chan reportResult, 1),
+	job := &reportJob{
+		ctx:   ctx,
+		query: q,
+		w:     w,
+		resp:  make(chan error, 1),
 	}
-	select {
-	case s.reportJobs <- job:
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	if err := s.reports.submit(ctx, job); err != nil {
+		return err
 	}
 	select {
-	case res := <-job.resp:
-		return res.data, res.err
+	case err := <-job.resp:
+		return err
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		if job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+			return ctx.Err()
+		}
+		return <-job.resp
+	}
+}
+
+// ListTasks returns stored tasks, optionally limited to those labelled with tag.
+// A non-empty owner hides tasks belonging to other owners.
+func (s *Service) ListTasks(ctx context.Context, tag, owner string) ([]*domain.Task, error) {
+	tasks, err := s.storage.ListTasks(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	return filterOwner(dtoToDomain(tasks), owner), nil
+}
+
+func filterOwner(tasks []*domain.Task, owner string) []*domain.Task {
+	if owner == "" {
+		return tasks
+	}
+	filtered := tasks[:0]
+	for _, t := range tasks {
+		if t.Owner == owner {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// GetTask returns task id, or nil if there is no such task or it belongs
+// to another owner than a non-empty owner.
+func (s *Service) GetTask(ctx context.Context, id int, owner string) (*domain.Task, error) {
+	tasks, err := s.storage.GetTasks(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	found := filterOwner(dtoToDomain(tasks), owner)
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return found[0], nil
+}
+
+// DomainSummary aggregates the checks of links on host recorded in the
+// tasks of owner, found through the link index. It returns nil when no
+// link on host was checked.
+func (s *Service) DomainSummary(ctx context.Context, host, owner string) (*domain.DomainSummary, error) {
+	tasks, err := s.SearchTasks(ctx, host, owner)
+	if err != nil {
+		return nil, err
 	}
+	sum := domain.SummarizeDomain(host, tasks)
+	if sum.Checks == 0 {
+		return nil, nil
+	}
+	return &sum, nil
+}
+
+// SearchTasks returns tasks that checked url or another link on the same host.
+func (s *Service) SearchTasks(ctx context.Context, url, owner string) ([]*domain.Task, error) {
+	tasks, err := s.storage.SearchTasks(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return filterOwner(dtoToDomain(tasks), owner), nil
+}
+
+// ImportTasks stores previously exported tasks, keeping their IDs.
+func (s *Service) ImportTasks(ctx context.Context, tasks []*domain.Task) error {
+	dtos := make([]*ports.TaskDTO, 0, len(tasks))
+	for _, t := range tasks {
+		dtos = append(dtos, &ports.TaskDTO{
+			ID:          t.ID,
+			Name:        t.Name,
+			Tags:        t.Tags,
+			CreatedBy:   t.CreatedBy,
+			Owner:       t.Owner,
+			Links:       t.Links,
+			Result:      t.Result,
+			Timings:     timingsToDTO(t.Timings),
+			Findings:    findingsToDTO(t.Findings),
+			Security:    securityToDTO(t.Security),
+			BatchID:     t.BatchID,
+			Version:     t.Version,
+			CreatedAt:   t.CreatedAt,
+			CompletedAt: t.CompletedAt,
+		})
+	}
+	return s.storage.ImportTasks(ctx, dtos)
+}
+
+// loadReportTasks returns the tasks selected by q and the requested IDs
+// that do not exist or belong to another owner. It fails with
+// ErrTasksNotFound when none of the requested IDs is found.
+func (s *Service) loadReportTasks(ctx context.Context, q ReportQuery) ([]*domain.Task, []int, error) {
+	if q.Batch > 0 {
+		if bs, ok := s.storage.(ports.BatchStorage); ok {
+			b, err := bs.GetBatch(ctx, q.Batch)
+			if err != nil {
+				return nil, nil, err
+			}
+			if b != nil {
+				q.IDs = append(slices.Clip(q.IDs), b.TaskIDs...)
+			}
+		}
+		if len(q.IDs) == 0 {
+			return nil, nil, nil
+		}
+	}
+	if len(q.IDs) == 0 {
+		tasks, err := s.ListTasks(ctx, q.Tag, q.Owner)
+		return tasks, nil, err
+	}
+	dtos, err := s.storage.GetTasks(ctx, q.IDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	tasks := filterOwner(dtoToDomain(dtos), q.Owner)
+	missing := missingIDs(q.IDs, tasks)
+	if len(tasks) == 0 {
+		return nil, missing, ErrTasksNotFound
+	}
+	if q.Tag == "" {
+		return tasks, missing, nil
+	}
+	filtered := tasks[:0]
+	for _, t := range tasks {
+		if t.HasTag(q.Tag) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, missing, nil
+}
+
+// missingIDs returns the ids without a task in found, once each and in
+// request order.
+func missingIDs(ids []int, found []*domain.Task) []int {
+	seen := make(map[int]bool, len(ids))
+	for _, t := range found {
+		seen[t.ID] = true
+	}
+	var missing []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			missing = append(missing, id)
+		}
+	}
+	return missing
 }
 
 func dtoToDomain(tasks []*ports.TaskDTO) []*domain.Task {
@@ -281,62 +918,198 @@ func dtoToDomain(tasks []*ports.TaskDTO) []*domain.Task {
 			continue
 		}
 		res = append(res, &domain.Task{
-			ID:     t.ID,
-			Links:  append([]string(nil), t.Links...),
-			Result: domain.CopyStringMap(t.Result),
+			ID:          t.ID,
+			Name:        t.Name,
+			Tags:        append([]string(nil), t.Tags...),
+			CreatedBy:   t.CreatedBy,
+			Owner:       t.Owner,
+			Links:       append([]string(nil), t.Links...),
+			Result:      domain.CopyStringMap(t.Result),
+			Timings:     timingsFromDTO(t.Timings),
+			Findings:    findingsFromDTO(t.Findings),
+			Security:    securityFromDTO(t.Security),
+			BatchID:     t.BatchID,
+			Version:     t.Version,
+			CreatedAt:   t.CreatedAt,
+			CompletedAt: t.CompletedAt,
 		})
 	}
 	return res
 }
 
-func validateURL(link string) bool {
-	if link == "" {
-		return false
+func timingsToDTO(src map[string]domain.LinkTiming) map[string]ports.LinkTiming {
+	if src == nil {
+		return nil
 	}
-	if strings.ContainsAny(link, "/?#:") {
-		return false
+	dst := make(map[string]ports.LinkTiming, len(src))
+	for link, t := range src {
+		dst[link] = ports.LinkTiming{CheckedAt: t.CheckedAt, DurationMS: t.DurationMS}
 	}
-	return true
+	return dst
 }
 
-type reportJob struct {
-	ctx  context.Context
-	ids  []int
-	resp chan reportResult
+func timingsFromDTO(src map[string]ports.LinkTiming) map[string]domain.LinkTiming {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]domain.LinkTiming, len(src))
+	for link, t := range src {
+		dst[link] = domain.LinkTiming{CheckedAt: t.CheckedAt, DurationMS: t.DurationMS}
+	}
+	return dst
 }
 
-type reportResult struct {
-	data []byte
-	err  error
+func resultFindings(src []linkchecker.Finding) []domain.Finding {
+	if len(src) == 0 {
+		return nil
+	}
+	dst := make([]domain.Finding, len(src))
+	for i, f := range src {
+		dst[i] = domain.Finding{Kind: string(f.Kind), URL: f.URL, Detail: f.Detail}
+	}
+	return dst
 }
 
-func (s *Service) reportWorker() {
-	for job := range s.reportJobs {
-		s.handleReportJob(job)
+func resultWeight(w *linkchecker.PageWeight) *domain.PageWeight {
+	if w == nil {
+		return nil
+	}
+	return &domain.PageWeight{
+		HTMLBytes:     w.HTMLBytes,
+		Resources:     w.Resources,
+		ResourceBytes: w.ResourceBytes,
+		UnknownSize:   w.UnknownSize,
+		TotalBytes:    w.TotalBytes,
+	}
+}
+
+func resultPhases(p *linkchecker.Phases) *domain.LinkPhases {
+	if p == nil {
+		return nil
+	}
+	return &domain.LinkPhases{
+		DNSMS:     durationMS(p.DNS),
+		ConnectMS: durationMS(p.Connect),
+		TLSMS:     durationMS(p.TLS),
+		TTFBMS:    durationMS(p.TTFB),
 	}
 }
 
-func (s *Service) handleReportJob(job reportJob) {
+// durationMS returns d in milliseconds rounded to tenths.
+func durationMS(d time.Duration) float64 {
+	return float64(d.Round(100*time.Microsecond)) / float64(time.Millisecond)
+}
+
+func resultSecurity(a *linkchecker.SecurityAudit) *domain.SecurityAudit {
+	if a == nil {
+		return nil
+	}
+	return &domain.SecurityAudit{Score: a.Score, Issues: a.Issues}
+}
+
+func findingListFromDTO(src []ports.Finding) []domain.Finding {
+	if len(src) == 0 {
+		return nil
+	}
+	dst := make([]domain.Finding, len(src))
+	for i, f := range src {
+		dst[i] = domain.Finding{Kind: f.Kind, URL: f.URL, Detail: f.Detail}
+	}
+	return dst
+}
+
+func findingsToDTO(src map[string][]domain.Finding) map[string][]ports.Finding {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string][]ports.Finding, len(src))
+	for link, fs := range src {
+		out := make([]ports.Finding, len(fs))
+		for i, f := range fs {
+			out[i] = ports.Finding{Kind: f.Kind, URL: f.URL, Detail: f.Detail}
+		}
+		dst[link] = out
+	}
+	return dst
+}
+
+func findingsFromDTO(src map[string][]ports.Finding) map[string][]domain.Finding {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string][]domain.Finding, len(src))
+	for link, fs := range src {
+		dst[link] = findingListFromDTO(fs)
+	}
+	return dst
+}
+
+func securityToDTO(src map[string]domain.SecurityAudit) map[string]ports.SecurityAudit {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]ports.SecurityAudit, len(src))
+	for link, a := range src {
+		dst[link] = ports.SecurityAudit{Score: a.Score, Issues: a.Issues}
+	}
+	return dst
+}
+
+func securityFromDTO(src map[string]ports.SecurityAudit) map[string]domain.SecurityAudit {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]domain.SecurityAudit, len(src))
+	for link, a := range src {
+		dst[link] = domain.SecurityAudit{Score: a.Score, Issues: a.Issues}
+	}
+	return dst
+}
+
+// securityAuditFromDTO returns the stored audit of link, or nil.
+func securityAuditFromDTO(src map[string]ports.SecurityAudit, link string) *domain.SecurityAudit {
+	a, ok := src[link]
+	if !ok {
+		return nil
+	}
+	return &domain.SecurityAudit{Score: a.Score, Issues: a.Issues}
+}
+
+// Report job states; a job is rendered only if a worker moves it from
+// jobQueued to jobRunning before the requester gives up on it.
+const (
+	jobQueued int32 = iota
+	jobRunning
+	jobAbandoned
+)
+
+type reportJob struct {
+	ctx   context.Context
+	query ReportQuery
+	w     io.Writer
+	state atomic.Int32
+	resp  chan error
+}
+
+func (s *Service) handleReportJob(job *reportJob) {
+	if !job.state.CompareAndSwap(jobQueued, jobRunning) {
+		return
+	}
 	if err := job.ctx.Err(); err != nil {
-		job.respond(nil, err)
+		job.resp <- err
 		return
 	}
-	tasks, err := s.storage.GetTasks(job.ids)
+	tasks, missing, err := s.loadReportTasks(job.ctx, job.query)
+	if len(missing) > 0 && job.query.OnMissing != nil {
+		job.query.OnMissing(missing)
+	}
 	if err != nil {
-		job.respond(nil, err)
+		job.resp <- err
 		return
 	}
 	if err := job.ctx.Err(); err != nil {
-		job.respond(nil, err)
+		job.resp <- err
 		return
 	}
-	data, err := s.pdfBuilder(dtoToDomain(tasks))
-	job.respond(data, err)
-}
-
-func (j reportJob) respond(data []byte, err error) {
-	select {
-	case j.resp <- reportResult{data: data, err: err}:
-	case <-j.ctx.Done():
-	}
+	job.resp <- s.renderReport(job.w, job.query, tasks, missing)
 }
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+	pdfgen "github.com/olgkv/linkchecker/internal/pdf"
+)
+
+// SLA report formats.
+const (
+	SLAFormatPDF = "pdf"
+	SLAFormatCSV = "csv"
+)
+
+// SLAQuery selects the period, tasks and URLs of an SLA report. Empty URLs
+// reports every link; Tag and Owner filter tasks as in ListTasks.
+type SLAQuery struct {
+	From  time.Time
+	To    time.Time
+	URLs  []string
+	Tag   string
+	Owner string
+}
+
+// SLAReport computes the uptime of the links checked by the tasks matching
+// q from their stored check times.
+func (s *Service) SLAReport(ctx context.Context, q SLAQuery) (*domain.SLAReport, error) {
+	tasks, err := s.ListTasks(ctx, q.Tag, q.Owner)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	report := domain.BuildSLAReport(tasks, q.From, q.To, now, q.URLs)
+	s.addMonitorLatency(report, tasks, now)
+	return report, nil
+}
+
+// addMonitorLatency sets the response time percentiles of the entries of
+// report whose URL is monitored by one of tasks. They cover the recent
+// latency window rather than the period of the report.
+func (s *Service) addMonitorLatency(report *domain.SLAReport, tasks []*domain.Task, now time.Time) {
+	if s.monitors == nil {
+		return
+	}
+	listed := make(map[int]bool, len(tasks))
+	for _, t := range tasks {
+		listed[t.ID] = true
+	}
+	histograms := make(map[string][]map[int]uint64)
+	for _, m := range s.monitors.List() {
+		if !listed[m.TaskID] {
+			continue
+		}
+		for link, sk := range m.Latency {
+			histograms[link] = append(histograms[link], recentLatency(sk, now)...)
+		}
+	}
+	for i := range report.Entries {
+		e := &report.Entries[i]
+		if p := domain.Percentiles(histograms[e.URL]...); p.Samples > 0 {
+			e.Latency = &p
+		}
+	}
+}
+
+// WriteSLAReport renders the SLA report for q to w as PDF or CSV.
+func (s *Service) WriteSLAReport(ctx context.Context, q SLAQuery, format string, w io.Writer) error {
+	report, err := s.SLAReport(ctx, q)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case SLAFormatPDF:
+		return pdfgen.WriteSLAReport(w, report, s.pdfOpts)
+	case SLAFormatCSV:
+		return writeSLACSV(w, report)
+	}
+	return fmt.Errorf("unknown SLA report format %q", format)
+}
+
+// writeSLACSV writes a row per URL; incidents are "start/end" pairs
+// separated by semicolons, in the time zone of r.From. The percentiles are
+// empty for URLs that are not monitored.
+func writeSLACSV(w io.Writer, r *domain.SLAReport) error {
+	loc := r.From.Location()
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"url", "checks", "failed", "uptime_percent", "downtime_minutes", "incidents", "p50_ms", "p95_ms", "p99_ms"})
+	for _, e := range r.Entries {
+		incidents := make([]string, 0, len(e.Incidents))
+		for _, inc := range e.Incidents {
+			incidents = append(incidents, inc.Start.In(loc).Format(time.RFC3339)+"/"+inc.End.In(loc).Format(time.RFC3339))
+		}
+		var p50, p95, p99 string
+		if e.Latency != nil {
+			p50 = strconv.FormatFloat(e.Latency.P50, 'f', 1, 64)
+			p95 = strconv.FormatFloat(e.Latency.P95, 'f', 1, 64)
+			p99 = strconv.FormatFloat(e.Latency.P99, 'f', 1, 64)
+		}
+		_ = cw.Write([]string{
+			e.URL,
+			strconv.Itoa(e.Checks),
+			strconv.Itoa(e.Failed),
+			strconv.FormatFloat(e.UptimePercent, 'f', 3, 64),
+			strconv.FormatFloat(e.DowntimeMinutes, 'f', 1, 64),
+			strings.Join(incidents, ";"),
+			p50, p95, p99,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
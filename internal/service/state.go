@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sort"
+	"time"
+)
+
+// ActiveCheck is a task whose links are being checked.
+type ActiveCheck struct {
+	TaskID  int       `json:"task_id"`
+	BatchID int       `json:"batch_id,omitempty"`
+	Owner   string    `json:"owner,omitempty"`
+	Links   int       `json:"links"`
+	Started time.Time `json:"started"`
+}
+
+// ReportQueueState describes the report workers and their backlog.
+type ReportQueueState struct {
+	Queued   int `json:"queued"`
+	Capacity int `json:"capacity"`
+	Workers  int `json:"workers"`
+}
+
+// WorkerPoolState describes the shared check workers of EnableWorkerPool.
+type WorkerPoolState struct {
+	Size    int `json:"size"`
+	Busy    int `json:"busy"`
+	Waiting int `json:"waiting"`
+}
+
+// State is a snapshot of the work in progress, for diagnosing instances
+// that seem stuck.
+type State struct {
+	ActiveChecks []ActiveCheck    `json:"active_checks"`
+	ReportQueue  ReportQueueState `json:"report_queue"`
+	// WorkerPool is nil unless the shared worker pool is enabled.
+	WorkerPool *WorkerPoolState `json:"worker_pool,omitempty"`
+	Breaker    []BreakerHost    `json:"breaker"`
+	// DeferredResults counts task results still being retried after the
+	// storage failed to persist them.
+	DeferredResults int64 `json:"deferred_results"`
+}
+
+// State returns a snapshot of the checks, queues and retries in progress.
+func (s *Service) State() State {
+	st := State{
+		ActiveChecks:    []ActiveCheck{},
+		Breaker:         []BreakerHost{},
+		DeferredResults: s.deferred.Load(),
+	}
+	if s.checker.Breaker() != nil {
+		st.Breaker = s.BreakerHosts()
+	}
+	s.activeMu.Lock()
+	for _, c := range s.active {
+		st.ActiveChecks = append(st.ActiveChecks, c)
+	}
+	s.activeMu.Unlock()
+	sort.Slice(st.ActiveChecks, func(i, j int) bool { return st.ActiveChecks[i].TaskID < st.ActiveChecks[j].TaskID })
+	if s.reports != nil {
+		st.ReportQueue = s.reports.state()
+	}
+	if s.pool != nil {
+		pool := s.pool.state()
+		st.WorkerPool = &pool
+	}
+	return st
+}
+
+// trackCheck lists task id among the active checks until the returned
+// function is called.
+func (s *Service) trackCheck(id, batchID int, owner string, links int) func() {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+	if s.active == nil {
+		s.active = make(map[int]ActiveCheck)
+	}
+	s.active[id] = ActiveCheck{TaskID: id, BatchID: batchID, Owner: owner, Links: links, Started: time.Now().UTC()}
+	return func() {
+		s.activeMu.Lock()
+		defer s.activeMu.Unlock()
+		delete(s.active, id)
+	}
+}
+
+func (p *reportPool) state() ReportQueueState {
+	p.scale.Lock()
+	defer p.scale.Unlock()
+	return ReportQueueState{Queued: len(p.jobs), Capacity: cap(p.jobs), Workers: p.workers}
+}
+
+func (p *workerPool) state() WorkerPoolState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return WorkerPoolState{Size: p.size, Busy: p.busy, Waiting: len(p.waiters)}
+}
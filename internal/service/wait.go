@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// taskWaiters wakes the callers of WaitTask when their task completes.
+type taskWaiters struct {
+	mu sync.Mutex
+	m  map[int][]chan struct{}
+}
+
+// add registers a channel closed when task id completes; remove forgets it
+// if it is no longer needed.
+func (w *taskWaiters) add(id int) (ch chan struct{}, remove func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.m == nil {
+		w.m = make(map[int][]chan struct{})
+	}
+	ch = make(chan struct{})
+	w.m[id] = append(w.m[id], ch)
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		chans := w.m[id]
+		for i, c := range chans {
+			if c == ch {
+				chans = append(chans[:i], chans[i+1:]...)
+				break
+			}
+		}
+		if len(chans) == 0 {
+			delete(w.m, id)
+		} else {
+			w.m[id] = chans
+		}
+	}
+}
+
+// done wakes every waiter of task id.
+func (w *taskWaiters) done(id int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ch := range w.m[id] {
+		close(ch)
+	}
+	delete(w.m, id)
+}
+
+// WaitTask returns page of task id of owner once the task is completed, or
+// as it is when wait passes, ctx is done or the service is closed. Only
+// completions in this process end the wait early; tasks completed by a
+// consumer are seen when the wait runs out.
+func (s *Service) WaitTask(ctx context.Context, id int, owner string, page LinkPage, wait time.Duration) (*TaskPage, error) {
+	// регистрируемся до чтения задачи, чтобы не пропустить завершение между ними
+	ch, remove := s.waiters.add(id)
+	defer remove()
+	p, err := s.GetTaskPage(ctx, id, owner, page)
+	if err != nil || p == nil || !p.Task.CompletedAt.IsZero() || wait <= 0 {
+		return p, err
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ch:
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-s.done:
+	}
+	// задачу отдаем как есть, даже если ожидание прервано
+	return s.GetTaskPage(context.WithoutCancel(ctx), id, owner, page)
+}
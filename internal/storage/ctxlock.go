@@ -0,0 +1,44 @@
+package storage
+
+import "context"
+
+// checkEvery is how many tasks read operations copy between looks at their
+// context.
+const checkEvery = 256
+
+// lock takes the write lock unless ctx is done first, so that a request
+// cancelled while a slow append holds the lock gives up instead of queueing
+// behind it. The lock is held only when nil is returned.
+func (s *FileStorage) lock(ctx context.Context) error {
+	return acquire(ctx, s.mu.TryLock, s.mu.Lock, s.mu.Unlock)
+}
+
+// rlock is lock for the read lock.
+func (s *FileStorage) rlock(ctx context.Context) error {
+	return acquire(ctx, s.mu.TryRLock, s.mu.RLock, s.mu.RUnlock)
+}
+
+func acquire(ctx context.Context, try func() bool, lock, unlock func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if try() {
+		return nil
+	}
+	locked := make(chan struct{})
+	go func() {
+		lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		// блокировку все равно получим — сразу отдаем
+		go func() {
+			<-locked
+			unlock()
+		}()
+		return ctx.Err()
+	}
+}
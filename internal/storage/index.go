@@ -0,0 +1,104 @@
+package storage
+
+import (
+	urlpkg "net/url"
+	"sort"
+	"strings"
+
+	"github.com/olgkv/linkchecker/internal/domain"
+)
+
+// linkIndex maps normalized links and their hosts to the IDs of tasks that contain them.
+type linkIndex struct {
+	byLink map[string]map[int]struct{}
+	byHost map[string]map[int]struct{}
+}
+
+func newLinkIndex() *linkIndex {
+	return &linkIndex{
+		byLink: make(map[string]map[int]struct{}),
+		byHost: make(map[string]map[int]struct{}),
+	}
+}
+
+func (ix *linkIndex) add(t *domain.Task) {
+	for _, link := range t.Links {
+		key, host := normalizeLink(link)
+		if key == "" {
+			continue
+		}
+		addID(ix.byLink, key, t.ID)
+		if host != "" {
+			addID(ix.byHost, host, t.ID)
+		}
+	}
+}
+
+func (ix *linkIndex) remove(t *domain.Task) {
+	for _, link := range t.Links {
+		key, host := normalizeLink(link)
+		removeID(ix.byLink, key, t.ID)
+		removeID(ix.byHost, host, t.ID)
+	}
+}
+
+// lookup returns IDs of tasks containing query either as the same link or as
+// a link on the same host, in ascending order.
+func (ix *linkIndex) lookup(query string) []int {
+	key, host := normalizeLink(query)
+	if key == "" {
+		return nil
+	}
+	seen := make(map[int]struct{})
+	for id := range ix.byLink[key] {
+		seen[id] = struct{}{}
+	}
+	for id := range ix.byHost[host] {
+		seen[id] = struct{}{}
+	}
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// normalizeLink lowercases link, drops the scheme and trailing slash and
+// returns it together with its hostname.
+func normalizeLink(link string) (key, host string) {
+	link = strings.ToLower(strings.TrimSpace(link))
+	if link == "" {
+		return "", ""
+	}
+	raw := link
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := urlpkg.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return link, ""
+	}
+	key = strings.TrimSuffix(parsed.Host+parsed.RequestURI(), "/")
+	return key, parsed.Hostname()
+}
+
+func addID(m map[string]map[int]struct{}, key string, id int) {
+	ids, ok := m[key]
+	if !ok {
+		ids = make(map[int]struct{})
+		m[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removeID(m map[string]map[int]struct{}, key string, id int) {
+	ids, ok := m[key]
+	if !ok {
+		return
+	}
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(m, key)
+	}
+}
@@ -1,71 +1,526 @@
 package storage
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	maxLogFileSize   = 100 << 20 // 100MB
-	logRetentionDays = 7
+	maxLogFileSize = 100 << 20 // 100MB
+	// segmentMaxAge is how old a rotated segment may get before the log
+	// asks for a compaction to fold it in; see CompactionDue.
+	segmentMaxAge = 7 * 24 * time.Hour
 )
 
+// ErrLocked is returned by Lock when another process already holds the log.
+var ErrLocked = errors.New("tasks file is locked by another process")
+
+// SyncMode controls when appended entries are fsynced to disk.
+type SyncMode int
+
+const (
+	// SyncAlways fsyncs after every append.
+	SyncAlways SyncMode = iota
+	// SyncInterval fsyncs from a background flusher, bounding loss to one interval.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// SyncPolicy describes the durability trade-off of the append log.
+type SyncPolicy struct {
+	Mode     SyncMode
+	Interval time.Duration
+}
+
+// ParseSyncPolicy parses "always", "never" or "interval=<duration>".
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || s == "always":
+		return SyncPolicy{Mode: SyncAlways}, nil
+	case s == "never":
+		return SyncPolicy{Mode: SyncNever}, nil
+	case strings.HasPrefix(s, "interval="):
+		d, err := time.ParseDuration(strings.TrimPrefix(s, "interval="))
+		if err != nil {
+			return SyncPolicy{}, fmt.Errorf("sync interval: %w", err)
+		}
+		if d <= 0 {
+			return SyncPolicy{}, errors.New("sync interval must be positive")
+		}
+		return SyncPolicy{Mode: SyncInterval, Interval: d}, nil
+	}
+	return SyncPolicy{}, fmt.Errorf("unknown sync policy %q", s)
+}
+
+// Option configures a JSONRepository.
+type Option func(*JSONRepository)
+
+// WithSyncPolicy sets the fsync policy used by Append.
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(r *JSONRepository) {
+		r.policy = p
+	}
+}
+
+// WithLogger sets the logger for background errors; slog.Default by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *JSONRepository) {
+		r.log = l
+	}
+}
+
+// WithMetrics reports operation durations, rotations and removed segments
+// to m.
+func WithMetrics(m Metrics) Option {
+	return func(r *JSONRepository) {
+		r.metrics = m
+	}
+}
+
+// WithSlowAppend logs a warning for every Append taking longer than d;
+// zero disables the warning.
+func WithSlowAppend(d time.Duration) Option {
+	return func(r *JSONRepository) {
+		r.slowAppend = d
+	}
+}
+
 // JSONRepository stores log entries in a newline-delimited JSON file.
 type JSONRepository struct {
-	path string
+	path       string
+	policy     SyncPolicy
+	lockFile   *os.File
+	log        *slog.Logger
+	metrics    Metrics
+	slowAppend time.Duration
+	// loadRead and loadSize are the bytes read by the running or last Load
+	// and the size of the files it reads.
+	loadRead atomic.Int64
+	loadSize atomic.Int64
+
+	mu    sync.Mutex
+	f     *os.File
+	dirty bool
+	// compactDue is set by a rotation that found segments older than
+	// segmentMaxAge and cleared by Rewrite.
+	compactDue bool
+	stop       chan struct{}
+	done       chan struct{}
 }
 
-func NewJSONRepository(path string) *JSONRepository {
-	return &JSONRepository{path: path}
+func NewJSONRepository(path string, opts ...Option) *JSONRepository {
+	r := &JSONRepository{path: path, log: slog.Default(), metrics: nopMetrics{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.policy.Mode == SyncInterval && r.policy.Interval > 0 {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.flushLoop()
+	}
+	return r
 }
 
-func (r *JSONRepository) Load() ([]*LogEntry, error) {
-	f, err := os.Open(r.path)
+// Lock takes an exclusive advisory lock on a sidecar "<path>.lock" file so two
+// processes never append to or rotate the same log. It fails fast with
+// ErrLocked instead of waiting for the other process.
+func (r *JSONRepository) Lock() error {
+	if r.lockFile != nil {
+		return nil
+	}
+	f, err := os.OpenFile(r.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, os.ErrNotExist
+		return err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return fmt.Errorf("%s: %w", r.path, ErrLocked)
+		}
+		return err
+	}
+	r.lockFile = f
+	return nil
+}
+
+// Close stops the background flusher, syncs and closes the log file and
+// releases the lock taken by Lock.
+func (r *JSONRepository) Close() error {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+		r.stop = nil
+	}
+
+	r.mu.Lock()
+	err := r.closeFileLocked()
+	r.mu.Unlock()
+
+	if r.lockFile != nil {
+		f := r.lockFile
+		r.lockFile = nil
+		if uerr := unlockFile(f); uerr != nil && err == nil {
+			err = uerr
+		}
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
 		}
+	}
+	return err
+}
+
+func (r *JSONRepository) flushLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.policy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				r.log.Error("flush tasks log", "err", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Flush fsyncs entries appended since the last sync.
+func (r *JSONRepository) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil || !r.dirty {
+		return nil
+	}
+	r.dirty = false
+	return r.f.Sync()
+}
+
+func (r *JSONRepository) closeFileLocked() error {
+	if r.f == nil {
+		return nil
+	}
+	var err error
+	if r.dirty {
+		err = r.f.Sync()
+		r.dirty = false
+	}
+	if cerr := r.f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	r.f = nil
+	return err
+}
+
+// Load returns the entries of the rotated segments (plain or gzipped) in
+// chronological order followed by those of the active log file.
+func (r *JSONRepository) Load() ([]*LogEntry, error) {
+	var entries []*LogEntry
+	if err := r.Replay(context.Background(), func(e *LogEntry) { entries = append(entries, e) }); err != nil {
 		return nil, err
 	}
+	return entries, nil
+}
+
+// replayBuffer is how many decoded entries a log file may hold ahead of the
+// entries being applied.
+const replayBuffer = 1024
+
+// logStream carries the entries of one log file from its decoder; err is
+// set before entries is closed.
+type logStream struct {
+	entries chan *LogEntry
+	err     error
+}
+
+// Replay passes the entries of the rotated segments and then of the active
+// log file to apply, in log order, as they are decoded. Up to GOMAXPROCS
+// files are decoded in parallel, each holding at most replayBuffer entries
+// ahead of apply, so memory does not grow with the size of the log. It
+// returns os.ErrNotExist if there is no log at all, and ctx.Err() as soon as
+// ctx is done, without reading the rest.
+func (r *JSONRepository) Replay(ctx context.Context, apply func(*LogEntry)) error {
+	defer r.observe("load", time.Now())
+	segments, err := r.segments()
+	if err != nil {
+		return err
+	}
+	files := append(segments[:len(segments):len(segments)], r.path)
+	var size int64
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	r.loadRead.Store(0)
+	r.loadSize.Store(size)
+
+	streams := make([]*logStream, len(files))
+	for i := range streams {
+		streams[i] = &logStream{entries: make(chan *LogEntry, replayBuffer)}
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+		// слоты занимаются по порядку, поэтому файл, который сейчас
+		// применяется, всегда декодируется
+		for i, s := range streams {
+			select {
+			case sem <- struct{}{}:
+			case <-stop:
+				return
+			}
+			go func() {
+				defer func() { <-sem }()
+				defer close(s.entries)
+				s.err = decodeLogFile(files[i], &r.loadRead, s.entries, stop)
+			}()
+		}
+	}()
+
+	for i, s := range streams {
+		n := 0
+		for entry := range s.entries {
+			if n++; n%checkEvery == 0 && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			apply(entry)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if s.err == nil {
+			continue
+		}
+		if i < len(segments) {
+			return fmt.Errorf("read %s: %w", filepath.Base(files[i]), s.err)
+		}
+		if errors.Is(s.err, os.ErrNotExist) && len(segments) > 0 {
+			return nil
+		}
+		return s.err
+	}
+	return nil
+}
+
+// ReadProgress returns how many bytes of the log files the running or last
+// Replay has read and their total size; gzipped segments count by their
+// compressed size.
+func (r *JSONRepository) ReadProgress() (read, total int64) {
+	return r.loadRead.Load(), r.loadSize.Load()
+}
+
+// errReplayStopped is returned by decodeLogFile when Replay has given up.
+var errReplayStopped = errors.New("replay stopped")
+
+// decodeLogFile sends the entries of path to out until stop is closed,
+// adding the bytes it reads to read.
+func decodeLogFile(path string, read *atomic.Int64, out chan<- *LogEntry, stop <-chan struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
 
-	dec := json.NewDecoder(f)
-	var entries []*LogEntry
+	var src io.Reader = countingReader{r: f, n: read}
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	dec := json.NewDecoder(src)
 	for {
-		var entry LogEntry
-		if err := dec.Decode(&entry); err != nil {
+		entry := new(LogEntry)
+		if err := dec.Decode(entry); err != nil {
 			if errors.Is(err, io.EOF) {
-				break
+				return nil
 			}
-			return nil, err
+			return err
+		}
+		select {
+		case out <- entry:
+		case <-stop:
+			return errReplayStopped
 		}
-		entryCopy := entry
-		entries = append(entries, &entryCopy)
 	}
-	return entries, nil
+}
+
+// countingReader adds the bytes read from r to n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// segments returns rotated log files belonging to this repository sorted
+// oldest first.
+func (r *JSONRepository) segments() ([]string, error) {
+	dir := filepath.Dir(r.path)
+	names, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var res []string
+	for _, entry := range names {
+		if entry.IsDir() || !isSegmentOf(filepath.Base(r.path), entry.Name()) {
+			continue
+		}
+		res = append(res, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// segmentLayout is the UTC timestamp in the names of rotated segments.
+const segmentLayout = "2006-01-02-150405"
+
+// isSegmentOf reports whether name is a rotated segment of the log file base,
+// i.e. "<name>-<timestamp><ext>.gz" as written by rotateLocked. Other files
+// next to the log, such as an export saved as tasks-export.json, are not.
+func isSegmentOf(base, name string) bool {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if stem == "" {
+		stem = base
+	}
+	stamp, ok := strings.CutPrefix(name, stem+"-")
+	if !ok {
+		return false
+	}
+	stamp, ok = strings.CutSuffix(stamp, ext+".gz")
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(segmentLayout, stamp)
+	return err == nil
 }
 
 func (r *JSONRepository) Append(entry *LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		r.metrics.ObserveOp("append", d)
+		if r.slowAppend > 0 && d > r.slowAppend {
+			r.log.Warn("slow storage append", "op", entry.Op, "task_id", entry.TaskID,
+				"duration_ms", d.Milliseconds(), "threshold_ms", r.slowAppend.Milliseconds())
+		}
+	}()
+
 	if err := r.maybeRotate(); err != nil {
 		return err
 	}
-	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if r.f == nil {
+		f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		r.f = f
+	}
+
+	enc := json.NewEncoder(r.f)
+	if err := enc.Encode(entry); err != nil {
+		return err
+	}
+	if r.policy.Mode != SyncAlways {
+		r.dirty = true
+		return nil
+	}
+	return r.f.Sync()
+}
+
+// Rewrite atomically replaces the log contents with entries via a temp file and rename.
+func (r *JSONRepository) Rewrite(entries []*LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.observe("rewrite", time.Now())
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer os.Remove(tmp.Name())
 
-	enc := json.NewEncoder(f)
-	if err := enc.Encode(entry); err != nil {
+	enc := json.NewEncoder(tmp)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
 		return err
 	}
-	return f.Sync()
+	if err := r.closeFileLocked(); err != nil {
+		return err
+	}
+	segments, err := r.segments()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return err
+	}
+	// the rewritten log holds the full state, so older segments would only
+	// resurrect deleted tasks on replay
+	removed := 0
+	defer func() { r.metrics.Deleted("segment", removed) }()
+	for _, seg := range segments {
+		if err := os.Remove(seg); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		removed++
+	}
+	r.compactDue = false
+	return nil
+}
+
+// CompactionDue reports whether the log holds rotated segments older than
+// seven days. Segments are only removed by Rewrite, once their entries have
+// been folded into the log, so the owner of the log should compact it.
+func (r *JSONRepository) CompactionDue() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.compactDue
+}
+
+// observe reports the duration of op started at start.
+func (r *JSONRepository) observe(op string, start time.Time) {
+	r.metrics.ObserveOp(op, time.Since(start))
 }
 
 func (r *JSONRepository) maybeRotate() error {
@@ -79,57 +534,102 @@ func (r *JSONRepository) maybeRotate() error {
 	if info.Size() < maxLogFileSize {
 		return nil
 	}
-	return r.Rotate()
+	return r.rotateLocked()
 }
 
-// Rotate renames the current log file to a timestamped filename in the same directory.
+// Rotate compresses the current log file into a timestamped .gz segment in the same directory.
 func (r *JSONRepository) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotateLocked()
+}
+
+func (r *JSONRepository) rotateLocked() error {
 	if _, err := os.Stat(r.path); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 		return err
 	}
+	if err := r.closeFileLocked(); err != nil {
+		return err
+	}
+	defer r.observe("rotate", time.Now())
 	base := filepath.Base(r.path)
 	ext := filepath.Ext(base)
 	name := strings.TrimSuffix(base, ext)
 	if name == "" {
 		name = base
 	}
-	rotated := fmt.Sprintf("%s-%s%s", name, time.Now().Format("2006-01-02"), ext)
-	if err := os.Rename(r.path, filepath.Join(filepath.Dir(r.path), rotated)); err != nil {
+	rotated := filepath.Join(filepath.Dir(r.path), fmt.Sprintf("%s-%s%s.gz", name, time.Now().UTC().Format(segmentLayout), ext))
+	if err := compressFile(r.path, rotated); err != nil {
 		return err
 	}
-	return cleanupOldLogs(filepath.Dir(r.path), logRetentionDays)
+	if err := os.Remove(r.path); err != nil {
+		return err
+	}
+	r.metrics.Rotated()
+	// старые сегменты удаляет только компакция: в них могут быть живые задачи
+	old, err := segmentsBefore(filepath.Dir(r.path), base, time.Now().Add(-segmentMaxAge))
+	if err != nil {
+		return err
+	}
+	r.compactDue = r.compactDue || old
+	return nil
 }
 
-func cleanupOldLogs(dir string, keepDays int) error {
-	if keepDays <= 0 {
-		return nil
+// compressFile gzips src into dst via a temp file so a crash never leaves a
+// truncated segment behind.
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
 	}
-	entries, err := os.ReadDir(dir)
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	cutoff := time.Now().AddDate(0, 0, -keepDays)
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// segmentsBefore reports whether dir holds a segment of base last modified
+// before cutoff.
+func segmentsBefore(dir, base string, cutoff time.Time) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
 	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-		if !strings.Contains(name, ".json-") || filepath.Ext(name) != ".json" {
+		if entry.IsDir() || !isSegmentOf(base, entry.Name()) {
 			continue
 		}
 		info, err := entry.Info()
 		if err != nil {
-			return err
-		}
-		if info.ModTime().After(cutoff) {
-			continue
+			return false, err
 		}
-		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
-			return err
+		if info.ModTime().Before(cutoff) {
+			return true, nil
 		}
 	}
-	return nil
+	return false, nil
 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// Advisory locking is only available on unix platforms; elsewhere the lock
+// file is created but not enforced.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryRepository is a TaskRepository that keeps the log in memory. It suits
+// one-off runs such as the CLI, where nothing has to survive the process.
+type MemoryRepository struct {
+	mu      sync.Mutex
+	entries []*LogEntry
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+func (r *MemoryRepository) Load() ([]*LogEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*LogEntry(nil), r.entries...), nil
+}
+
+func (r *MemoryRepository) Replay(ctx context.Context, apply func(*LogEntry)) error {
+	entries, _ := r.Load()
+	for i, entry := range entries {
+		if i%checkEvery == checkEvery-1 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		apply(entry)
+	}
+	return ctx.Err()
+}
+
+func (r *MemoryRepository) Append(entry *LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *MemoryRepository) Rewrite(entries []*LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append([]*LogEntry(nil), entries...)
+	return nil
+}
@@ -0,0 +1,22 @@
+package storage
+
+import "time"
+
+// Metrics receives measurements of storage operations. Implementations must
+// be safe for concurrent use.
+type Metrics interface {
+	// ObserveOp records how long op took: "append", "load", "rewrite" or
+	// "rotate".
+	ObserveOp(op string, d time.Duration)
+	// Rotated counts a rotation of the active log into a segment.
+	Rotated()
+	// Deleted counts n items removed by cleanup: "segment" for rotated log
+	// files, "task" for tasks removed by DeleteTasksBefore.
+	Deleted(kind string, n int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveOp(string, time.Duration) {}
+func (nopMetrics) Rotated()                        {}
+func (nopMetrics) Deleted(string, int)             {}
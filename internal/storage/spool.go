@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// FileSpool is a ports.ResultSpool kept in a small JSON file that is
+// rewritten atomically on every change.
+type FileSpool struct {
+	mu      sync.Mutex
+	path    string
+	pending map[int]map[string]string
+}
+
+// OpenFileSpool loads the spool at path; a missing file is an empty spool.
+func OpenFileSpool(path string) (*FileSpool, error) {
+	s := &FileSpool{path: path, pending: make(map[int]map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read result spool: %w", err)
+	}
+
+	var stored map[string]map[string]string
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("decode result spool: %w", err)
+	}
+	for key, result := range stored {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("decode result spool: bad task id %q", key)
+		}
+		s.pending[id] = result
+	}
+	return s, nil
+}
+
+func (s *FileSpool) Add(id int, result map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	merged := make(map[string]string, len(result))
+	maps.Copy(merged, s.pending[id])
+	maps.Copy(merged, result)
+	s.pending[id] = merged
+	return s.saveLocked()
+}
+
+func (s *FileSpool) Remove(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[id]; !ok {
+		return nil
+	}
+	delete(s.pending, id)
+	return s.saveLocked()
+}
+
+func (s *FileSpool) Pending() (map[int]map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[int]map[string]string, len(s.pending))
+	for id, result := range s.pending {
+		out[id] = maps.Clone(result)
+	}
+	return out, nil
+}
+
+func (s *FileSpool) saveLocked() error {
+	if len(s.pending) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(s.pending)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// спул нужен именно на случай падения, поэтому синхронизируем до rename
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSpool_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json.spool")
+	sp, err := OpenFileSpool(path)
+	if err != nil {
+		t.Fatalf("OpenFileSpool: %v", err)
+	}
+	if err := sp.Add(3, map[string]string{"a.com": "available"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := sp.Add(3, map[string]string{"b.com": "not available"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	reopened, err := OpenFileSpool(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	pending, err := reopened.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(pending) != 1 || pending[3]["a.com"] != "available" || pending[3]["b.com"] != "not available" {
+		t.Fatalf("unexpected pending results: %v", pending)
+	}
+
+	if err := reopened.Remove(3); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	// пустой спул не оставляет файла
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected spool file removed, stat err = %v", err)
+	}
+}
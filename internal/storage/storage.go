@@ -1,27 +1,43 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/olgkv/linkchecker/internal/domain"
 	"github.com/olgkv/linkchecker/internal/ports"
 )
 
+// TaskRepository persists the log of storage changes. Replay passes the
+// logged entries to apply in order and returns os.ErrNotExist when there is
+// no log yet; once ctx is done it stops reading and returns ctx.Err().
 type TaskRepository interface {
-	Load() ([]*LogEntry, error)
+	Replay(ctx context.Context, apply func(*LogEntry)) error
 	Append(entry *LogEntry) error
+	Rewrite(entries []*LogEntry) error
 }
 
 type LogEntry struct {
-	Op        string            `json:"op"`
-	Task      *domain.Task      `json:"task,omitempty"`
-	TaskID    int               `json:"task_id,omitempty"`
-	Result    map[string]string `json:"result,omitempty"`
-	Timestamp time.Time         `json:"ts"`
+	Op        string                `json:"op"`
+	NextID    int                   `json:"next_id,omitempty"`
+	Task      *domain.Task          `json:"task,omitempty"`
+	Batch     *domain.Batch         `json:"batch,omitempty"`
+	TaskID    int                   `json:"task_id,omitempty"`
+	Link      string                `json:"link,omitempty"`
+	Status    string                `json:"status,omitempty"`
+	Result    map[string]string     `json:"result,omitempty"`
+	Timing    *domain.LinkTiming    `json:"timing,omitempty"`
+	Findings  []domain.Finding      `json:"findings,omitempty"`
+	Security  *domain.SecurityAudit `json:"security,omitempty"`
+	Timestamp time.Time             `json:"ts"`
 }
 
 type FileStorage struct {
@@ -29,32 +45,102 @@ type FileStorage struct {
 	repo   TaskRepository
 	nextID int
 	tasks  map[int]*domain.Task
+	// batches share the ID sequence with tasks, so "delete" removes either
+	batches map[int]*domain.Batch
+	index   *linkIndex
+	metrics Metrics
+	// tombstones counts the delete entries written by DeleteTask since the
+	// last compaction.
+	tombstones int
+	// phase reports the progress of Load.
+	phase atomic.Value
+}
+
+// compactionRequester is implemented by repositories that ask for a
+// compaction, such as JSONRepository once it holds old rotated segments.
+type compactionRequester interface {
+	CompactionDue() bool
+}
+
+// Phases of Load reported by Progress.
+const (
+	LoadPending   = "pending"
+	LoadReplaying = "replaying"
+	LoadDone      = "done"
+)
+
+// LoadProgress tells how far Load has got: while replaying, Percent is the
+// share of the log read so far, when the repository can tell.
+type LoadProgress struct {
+	Phase   string  `json:"phase"`
+	Percent float64 `json:"percent"`
+}
+
+// Progress returns the progress of the running or last Load.
+func (s *FileStorage) Progress() LoadProgress {
+	phase, _ := s.phase.Load().(string)
+	p := LoadProgress{Phase: phase}
+	switch phase {
+	case "":
+		p.Phase = LoadPending
+	case LoadReplaying:
+		if r, ok := s.repo.(interface{ ReadProgress() (int64, int64) }); ok {
+			p.Percent = percent(r.ReadProgress())
+		}
+	case LoadDone:
+		p.Percent = 100
+	}
+	return p
+}
+
+func percent(done, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(min(done, total))*1000/float64(total)) / 10
 }
 
 func NewFileStorage(repo TaskRepository) *FileStorage {
 	return &FileStorage{
-		repo:   repo,
-		nextID: 1,
-		tasks:  make(map[int]*domain.Task),
+		repo:    repo,
+		nextID:  1,
+		tasks:   make(map[int]*domain.Task),
+		batches: make(map[int]*domain.Batch),
+		index:   newLinkIndex(),
+		metrics: nopMetrics{},
 	}
 }
 
-func (s *FileStorage) Load() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// UseMetrics counts the tasks removed by DeleteTasksBefore in m. Call it
+// before serving requests.
+func (s *FileStorage) UseMetrics(m Metrics) {
+	s.metrics = m
+}
 
-	entries, err := s.repo.Load()
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
-		}
+// Load replays the log into memory; Progress reports how far it has got.
+func (s *FileStorage) Load(ctx context.Context) error {
+	if err := s.lock(ctx); err != nil {
 		return err
 	}
+	defer s.mu.Unlock()
 
 	s.tasks = make(map[int]*domain.Task)
+	s.batches = make(map[int]*domain.Batch)
+	s.index = newLinkIndex()
 	s.nextID = 1
-	for _, entry := range entries {
-		s.applyEntry(entry)
+	s.phase.Store(LoadReplaying)
+	err := s.repo.Replay(ctx, s.applyEntry)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.phase.Store(LoadDone)
+	return nil
+}
+
+// Close releases resources held by the underlying repository, such as the file lock.
+func (s *FileStorage) Close() error {
+	if c, ok := s.repo.(io.Closer); ok {
+		return c.Close()
 	}
 	return nil
 }
@@ -68,10 +154,33 @@ func (s *FileStorage) applyEntry(entry *LogEntry) {
 		if entry.Task.ID >= s.nextID {
 			s.nextID = entry.Task.ID + 1
 		}
-		s.tasks[entry.Task.ID] = &domain.Task{
-			ID:     entry.Task.ID,
-			Links:  append([]string(nil), entry.Task.Links...),
-			Result: domain.CopyStringMap(entry.Task.Result),
+		t := copyTask(entry.Task)
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = entry.Timestamp
+		}
+		if t.Version == 0 {
+			t.Version = 1
+		}
+		s.putTask(t)
+	case "result":
+		if entry.TaskID == 0 || entry.Link == "" {
+			return
+		}
+		if t, ok := s.tasks[entry.TaskID]; ok {
+			if t.Result == nil {
+				t.Result = make(map[string]string)
+			}
+			t.Result[entry.Link] = entry.Status
+			if entry.Timing != nil {
+				setTiming(t, entry.Link, *entry.Timing)
+			}
+			if len(entry.Findings) > 0 {
+				setFindings(t, entry.Link, entry.Findings)
+			}
+			if entry.Security != nil {
+				setSecurity(t, entry.Link, *entry.Security)
+			}
+			t.Version++
 		}
 	case "update":
 		if entry.TaskID == 0 {
@@ -79,7 +188,103 @@ func (s *FileStorage) applyEntry(entry *LogEntry) {
 		}
 		if t, ok := s.tasks[entry.TaskID]; ok {
 			t.Result = domain.CopyStringMap(entry.Result)
+			t.CompletedAt = entry.Timestamp
+			t.Version++
+		}
+	case "batch":
+		if entry.Batch == nil {
+			return
+		}
+		if entry.Batch.ID >= s.nextID {
+			s.nextID = entry.Batch.ID + 1
 		}
+		s.batches[entry.Batch.ID] = copyBatch(entry.Batch)
+	case "delete":
+		s.removeTask(entry.TaskID)
+		delete(s.batches, entry.TaskID)
+	case "checkpoint":
+		if entry.NextID > s.nextID {
+			s.nextID = entry.NextID
+		}
+	}
+}
+
+func setTiming(t *domain.Task, link string, timing domain.LinkTiming) {
+	if t.Timings == nil {
+		t.Timings = make(map[string]domain.LinkTiming)
+	}
+	t.Timings[link] = timing
+}
+
+func setFindings(t *domain.Task, link string, findings []domain.Finding) {
+	if t.Findings == nil {
+		t.Findings = make(map[string][]domain.Finding)
+	}
+	t.Findings[link] = findings
+}
+
+func setSecurity(t *domain.Task, link string, audit domain.SecurityAudit) {
+	if t.Security == nil {
+		t.Security = make(map[string]domain.SecurityAudit)
+	}
+	t.Security[link] = audit
+}
+
+func (s *FileStorage) putTask(t *domain.Task) {
+	if old, ok := s.tasks[t.ID]; ok {
+		s.index.remove(old)
+	}
+	s.tasks[t.ID] = t
+	s.index.add(t)
+}
+
+func (s *FileStorage) removeTask(id int) {
+	if t, ok := s.tasks[id]; ok {
+		s.index.remove(t)
+		delete(s.tasks, id)
+	}
+}
+
+func copyTask(t *domain.Task) *domain.Task {
+	return &domain.Task{
+		ID:          t.ID,
+		Name:        t.Name,
+		Tags:        append([]string(nil), t.Tags...),
+		CreatedBy:   t.CreatedBy,
+		Owner:       t.Owner,
+		Links:       append([]string(nil), t.Links...),
+		Result:      domain.CopyStringMap(t.Result),
+		Timings:     domain.CopyTimings(t.Timings),
+		Findings:    domain.CopyFindings(t.Findings),
+		Security:    domain.CopySecurity(t.Security),
+		BatchID:     t.BatchID,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt,
+		CompletedAt: t.CompletedAt,
+	}
+}
+
+func copyBatch(b *domain.Batch) *domain.Batch {
+	return &domain.Batch{
+		ID:        b.ID,
+		Name:      b.Name,
+		Tags:      append([]string(nil), b.Tags...),
+		CreatedBy: b.CreatedBy,
+		Owner:     b.Owner,
+		TaskIDs:   append([]int(nil), b.TaskIDs...),
+		CreatedAt: b.CreatedAt,
+	}
+}
+
+func batchToDTO(b *domain.Batch) *ports.BatchDTO {
+	return &ports.BatchDTO{
+		ID:        b.ID,
+		Name:      b.Name,
+		Tags:      append([]string(nil), b.Tags...),
+		CreatedBy: b.CreatedBy,
+		Owner:     b.Owner,
+		TaskIDs:   append([]int(nil), b.TaskIDs...),
+		CreatedAt: b.CreatedAt,
 	}
 }
 
@@ -88,46 +293,277 @@ func taskToDTO(t *domain.Task) *ports.TaskDTO {
 		return nil
 	}
 	return &ports.TaskDTO{
-		ID:     t.ID,
-		Links:  append([]string(nil), t.Links...),
-		Result: domain.CopyStringMap(t.Result),
+		ID:          t.ID,
+		Name:        t.Name,
+		Tags:        append([]string(nil), t.Tags...),
+		CreatedBy:   t.CreatedBy,
+		Owner:       t.Owner,
+		Links:       append([]string(nil), t.Links...),
+		Result:      domain.CopyStringMap(t.Result),
+		Timings:     timingsToDTO(t.Timings),
+		Findings:    findingsToDTO(t.Findings),
+		Security:    securityToDTO(t.Security),
+		BatchID:     t.BatchID,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt,
+		CompletedAt: t.CompletedAt,
 	}
 }
 
-func (s *FileStorage) CreateTask(links []string) (*ports.TaskDTO, error) {
-	s.mu.Lock()
+func dtoToTask(t *ports.TaskDTO) *domain.Task {
+	return &domain.Task{
+		ID:          t.ID,
+		Name:        t.Name,
+		Tags:        append([]string(nil), t.Tags...),
+		CreatedBy:   t.CreatedBy,
+		Owner:       t.Owner,
+		Links:       append([]string(nil), t.Links...),
+		Result:      domain.CopyStringMap(t.Result),
+		Timings:     timingsFromDTO(t.Timings),
+		Findings:    findingsFromDTO(t.Findings),
+		Security:    securityFromDTO(t.Security),
+		BatchID:     t.BatchID,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt,
+		CompletedAt: t.CompletedAt,
+	}
+}
+
+func timingsToDTO(src map[string]domain.LinkTiming) map[string]ports.LinkTiming {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]ports.LinkTiming, len(src))
+	for link, t := range src {
+		dst[link] = ports.LinkTiming{CheckedAt: t.CheckedAt, DurationMS: t.DurationMS}
+	}
+	return dst
+}
+
+func timingsFromDTO(src map[string]ports.LinkTiming) map[string]domain.LinkTiming {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]domain.LinkTiming, len(src))
+	for link, t := range src {
+		dst[link] = domain.LinkTiming{CheckedAt: t.CheckedAt, DurationMS: t.DurationMS}
+	}
+	return dst
+}
+
+func findingsToDTO(src map[string][]domain.Finding) map[string][]ports.Finding {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string][]ports.Finding, len(src))
+	for link, fs := range src {
+		out := make([]ports.Finding, len(fs))
+		for i, f := range fs {
+			out[i] = ports.Finding{Kind: f.Kind, URL: f.URL, Detail: f.Detail}
+		}
+		dst[link] = out
+	}
+	return dst
+}
+
+func findingListFromDTO(src []ports.Finding) []domain.Finding {
+	dst := make([]domain.Finding, len(src))
+	for i, f := range src {
+		dst[i] = domain.Finding{Kind: f.Kind, URL: f.URL, Detail: f.Detail}
+	}
+	return dst
+}
+
+func findingsFromDTO(src map[string][]ports.Finding) map[string][]domain.Finding {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string][]domain.Finding, len(src))
+	for link, fs := range src {
+		dst[link] = findingListFromDTO(fs)
+	}
+	return dst
+}
+
+func securityToDTO(src map[string]domain.SecurityAudit) map[string]ports.SecurityAudit {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]ports.SecurityAudit, len(src))
+	for link, a := range src {
+		dst[link] = ports.SecurityAudit{Score: a.Score, Issues: append([]string(nil), a.Issues...)}
+	}
+	return dst
+}
+
+func securityFromDTO(src map[string]ports.SecurityAudit) map[string]domain.SecurityAudit {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]domain.SecurityAudit, len(src))
+	for link, a := range src {
+		dst[link] = domain.SecurityAudit{Score: a.Score, Issues: append([]string(nil), a.Issues...)}
+	}
+	return dst
+}
+
+func (s *FileStorage) CreateTask(ctx context.Context, links []string, meta ports.TaskMeta) (*ports.TaskDTO, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
 	defer s.mu.Unlock()
+	if err := s.compactIfDueLocked(); err != nil {
+		return nil, err
+	}
+
+	t, err := s.createTaskLocked(links, meta, 0, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return taskToDTO(t), nil
+}
 
+func (s *FileStorage) createTaskLocked(links []string, meta ports.TaskMeta, batchID int, now time.Time) (*domain.Task, error) {
 	id := s.nextID
 	s.nextID++
-	linksCopy := append([]string(nil), links...)
-	t := &domain.Task{ID: id, Links: linksCopy, Result: make(map[string]string)}
-	s.tasks[id] = t
-	if err := s.repo.Append(&LogEntry{Op: "create", Task: t, Timestamp: time.Now()}); err != nil {
+	t := &domain.Task{
+		ID:        id,
+		Name:      meta.Name,
+		Tags:      append([]string(nil), meta.Tags...),
+		CreatedBy: meta.CreatedBy,
+		Owner:     meta.Owner,
+		Links:     append([]string(nil), links...),
+		Result:    make(map[string]string),
+		BatchID:   batchID,
+		Version:   1,
+		CreatedAt: now,
+	}
+	s.putTask(t)
+	if err := s.repo.Append(&LogEntry{Op: "create", Task: t, Timestamp: now}); err != nil {
 		return nil, err
 	}
-	return taskToDTO(t), nil
+	return t, nil
 }
 
-func (s *FileStorage) UpdateTaskResult(id int, result map[string]string) error {
-	s.mu.Lock()
+// CreateBatch creates a task for every chunk and a batch grouping them. The
+// batch entry is written last, so after a crash in between the tasks exist
+// on their own.
+func (s *FileStorage) CreateBatch(ctx context.Context, chunks [][]string, meta ports.TaskMeta) (*ports.BatchDTO, []*ports.TaskDTO, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer s.mu.Unlock()
+	if err := s.compactIfDueLocked(); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	b := &domain.Batch{
+		ID:        s.nextID,
+		Name:      meta.Name,
+		Tags:      append([]string(nil), meta.Tags...),
+		CreatedBy: meta.CreatedBy,
+		Owner:     meta.Owner,
+		CreatedAt: now,
+	}
+	s.nextID++
+	tasks := make([]*ports.TaskDTO, 0, len(chunks))
+	for _, links := range chunks {
+		t, err := s.createTaskLocked(links, meta, b.ID, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.TaskIDs = append(b.TaskIDs, t.ID)
+		tasks = append(tasks, taskToDTO(t))
+	}
+	if err := s.repo.Append(&LogEntry{Op: "batch", Batch: b, Timestamp: now}); err != nil {
+		return nil, nil, err
+	}
+	s.batches[b.ID] = b
+	return batchToDTO(b), tasks, nil
+}
+
+// GetBatch returns batch id, or nil if there is none.
+func (s *FileStorage) GetBatch(ctx context.Context, id int) (*ports.BatchDTO, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	b, ok := s.batches[id]
+	if !ok {
+		return nil, nil
+	}
+	return batchToDTO(b), nil
+}
+
+// AppendLinkResult records the status of a single link as soon as it is known,
+// so a crash mid-check keeps the links that were already processed.
+func (s *FileStorage) AppendLinkResult(ctx context.Context, id int, link string, status string, timing ports.LinkTiming, findings []ports.Finding, security *ports.SecurityAudit) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %d not found", id)
+	}
+	if t.Result == nil {
+		t.Result = make(map[string]string)
+	}
+	t.Result[link] = status
+	entry := &LogEntry{Op: "result", TaskID: id, Link: link, Status: status, Timestamp: time.Now()}
+	if !timing.CheckedAt.IsZero() {
+		entry.Timing = &domain.LinkTiming{CheckedAt: timing.CheckedAt, DurationMS: timing.DurationMS}
+		setTiming(t, link, *entry.Timing)
+	}
+	if len(findings) > 0 {
+		entry.Findings = findingListFromDTO(findings)
+		setFindings(t, link, entry.Findings)
+	}
+	if security != nil {
+		entry.Security = &domain.SecurityAudit{Score: security.Score, Issues: append([]string(nil), security.Issues...)}
+		setSecurity(t, link, *entry.Security)
+	}
+	t.Version++
+	return s.repo.Append(entry)
+}
+
+// UpdateTaskResult stores the final result of a task and marks it as completed
+// if the task is still at the expected version.
+func (s *FileStorage) UpdateTaskResult(ctx context.Context, id int, version int, result map[string]string) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
 	defer s.mu.Unlock()
 
 	t, ok := s.tasks[id]
 	if !ok {
 		return fmt.Errorf("task %d not found", id)
 	}
+	if t.Version != version {
+		return fmt.Errorf("task %d at version %d, expected %d: %w", id, t.Version, version, ports.ErrVersionConflict)
+	}
+	now := time.Now().UTC()
 	copyResult := domain.CopyStringMap(result)
 	t.Result = copyResult
-	return s.repo.Append(&LogEntry{Op: "update", TaskID: id, Result: copyResult, Timestamp: time.Now()})
+	t.CompletedAt = now
+	t.Version++
+	return s.repo.Append(&LogEntry{Op: "update", TaskID: id, Result: copyResult, Timestamp: now})
 }
 
-func (s *FileStorage) GetTasks(ids []int) ([]*ports.TaskDTO, error) {
-	s.mu.RLock()
+func (s *FileStorage) GetTasks(ctx context.Context, ids []int) ([]*ports.TaskDTO, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer s.mu.RUnlock()
 
 	res := make([]*ports.TaskDTO, 0, len(ids))
-	for _, id := range ids {
+	for i, id := range ids {
+		if i%checkEvery == checkEvery-1 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if t, ok := s.tasks[id]; ok {
 			res = append(res, taskToDTO(t))
 		}
@@ -135,14 +571,252 @@ func (s *FileStorage) GetTasks(ids []int) ([]*ports.TaskDTO, error) {
 	return res, nil
 }
 
-// Stats возвращает количество всех задач и количество задач, у которых заполнен результат.
+// GetTaskPage returns limit links of task id from offset with their
+// results, copying only those.
+func (s *FileStorage) GetTaskPage(ctx context.Context, id, offset, limit int) (*ports.TaskPage, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, nil
+	}
+	statuses := make(map[string]int)
+	for _, status := range t.Result {
+		statuses[status]++
+	}
+	return &ports.TaskPage{Task: taskToDTO(domain.PageOf(t, offset, limit)), Total: len(t.Links), Statuses: statuses}, nil
+}
+
+// SearchTasks returns tasks containing url as an exact link or a link on the same host.
+func (s *FileStorage) SearchTasks(ctx context.Context, url string) ([]*ports.TaskDTO, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	ids := s.index.lookup(url)
+	res := make([]*ports.TaskDTO, 0, len(ids))
+	for i, id := range ids {
+		if i%checkEvery == checkEvery-1 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if t, ok := s.tasks[id]; ok {
+			res = append(res, taskToDTO(t))
+		}
+	}
+	return res, nil
+}
+
+func (s *FileStorage) ListTasks(ctx context.Context, tag string) ([]*ports.TaskDTO, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	res := make([]*ports.TaskDTO, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		if len(res)%checkEvery == checkEvery-1 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if tag != "" && !t.HasTag(tag) {
+			continue
+		}
+		res = append(res, taskToDTO(t))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+func (s *FileStorage) ImportTasks(ctx context.Context, tasks []*ports.TaskDTO) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	seen := make(map[int]struct{}, len(tasks))
+	for _, dto := range tasks {
+		if dto == nil || dto.ID <= 0 {
+			return errors.New("invalid task id")
+		}
+		if _, ok := s.tasks[dto.ID]; ok {
+			return fmt.Errorf("task %d: %w", dto.ID, ports.ErrTaskExists)
+		}
+		if _, ok := seen[dto.ID]; ok {
+			return fmt.Errorf("task %d: %w", dto.ID, ports.ErrTaskExists)
+		}
+		seen[dto.ID] = struct{}{}
+	}
+
+	now := time.Now().UTC()
+	for _, dto := range tasks {
+		t := dtoToTask(dto)
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = now
+		}
+		if t.Result == nil {
+			t.Result = make(map[string]string)
+		}
+		if t.Version == 0 {
+			t.Version = 1
+		}
+		if err := s.repo.Append(&LogEntry{Op: "create", Task: t, Timestamp: now}); err != nil {
+			return err
+		}
+		s.putTask(t)
+		if t.ID >= s.nextID {
+			s.nextID = t.ID + 1
+		}
+	}
+	return nil
+}
+
+// DeleteTasksBefore removes tasks created before cutoff, writing a delete entry
+// for each of them, and compacts the log when anything was removed or
+// DeleteTask left delete entries behind. Batches created before cutoff are
+// removed as well but not counted.
+func (s *FileStorage) DeleteTasksBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	var ids, batchIDs []int
+	for id, t := range s.tasks {
+		if t.CreatedAt.Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	for id, b := range s.batches {
+		if b.CreatedAt.Before(cutoff) {
+			batchIDs = append(batchIDs, id)
+		}
+	}
+	if len(ids) == 0 && len(batchIDs) == 0 && s.tombstones == 0 {
+		return 0, s.compactIfDueLocked()
+	}
+	sort.Ints(ids)
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		if err := s.repo.Append(&LogEntry{Op: "delete", TaskID: id, Timestamp: now}); err != nil {
+			return 0, err
+		}
+		s.removeTask(id)
+	}
+	s.metrics.Deleted("task", len(ids))
+	for _, id := range batchIDs {
+		if err := s.repo.Append(&LogEntry{Op: "delete", TaskID: id, Timestamp: now}); err != nil {
+			return len(ids), err
+		}
+		delete(s.batches, id)
+	}
+	if err := s.compactLocked(); err != nil {
+		return len(ids), fmt.Errorf("compact log: %w", err)
+	}
+	return len(ids), nil
+}
+
+// DeleteTask removes task id, writing a delete entry for it. Earlier entries
+// of the task stay on disk until the next compaction, done by
+// CompactDeleted or DeleteTasksBefore, so that deletes do not rewrite the
+// whole log.
+func (s *FileStorage) DeleteTask(ctx context.Context, id int) (bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[id]; !ok {
+		return false, nil
+	}
+	if err := s.repo.Append(&LogEntry{Op: "delete", TaskID: id, Timestamp: time.Now().UTC()}); err != nil {
+		return false, err
+	}
+	s.removeTask(id)
+	s.metrics.Deleted("task", 1)
+	s.tombstones++
+	return true, nil
+}
+
+// CompactDeleted compacts the log if DeleteTask left delete entries behind,
+// so that the links and results of deleted tasks are gone from disk, and
+// returns the number of those entries.
+func (s *FileStorage) CompactDeleted(ctx context.Context) (int, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	n := s.tombstones
+	if n == 0 {
+		return 0, nil
+	}
+	if err := s.compactLocked(); err != nil {
+		return 0, fmt.Errorf("compact log: %w", err)
+	}
+	return n, nil
+}
+
+// compactIfDueLocked compacts the log if the repository asks for it. Write
+// operations call it before they change anything, while the log and the
+// memory state still agree.
+func (s *FileStorage) compactIfDueLocked() error {
+	if r, ok := s.repo.(compactionRequester); !ok || !r.CompactionDue() {
+		return nil
+	}
+	if err := s.compactLocked(); err != nil {
+		return fmt.Errorf("compact log: %w", err)
+	}
+	return nil
+}
+
+// Compact rewrites the log so that it holds one entry per live task and batch.
+func (s *FileStorage) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked()
+}
+
+func (s *FileStorage) compactLocked() error {
+	ids := make([]int, 0, len(s.tasks))
+	for id := range s.tasks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	batchIDs := make([]int, 0, len(s.batches))
+	for id := range s.batches {
+		batchIDs = append(batchIDs, id)
+	}
+	sort.Ints(batchIDs)
+
+	now := time.Now().UTC()
+	entries := make([]*LogEntry, 0, len(ids)+len(batchIDs)+1)
+	// checkpoint keeps IDs of deleted tasks from being reused after replay
+	entries = append(entries, &LogEntry{Op: "checkpoint", NextID: s.nextID, Timestamp: now})
+	for _, id := range ids {
+		entries = append(entries, &LogEntry{Op: "create", Task: s.tasks[id], Timestamp: now})
+	}
+	for _, id := range batchIDs {
+		entries = append(entries, &LogEntry{Op: "batch", Batch: s.batches[id], Timestamp: now})
+	}
+	if err := s.repo.Rewrite(entries); err != nil {
+		return err
+	}
+	s.tombstones = 0
+	return nil
+}
+
+// Stats возвращает количество всех задач и количество завершённых задач.
 func (s *FileStorage) Stats() (total int, completed int) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, t := range s.tasks {
 		total++
-		if len(t.Result) > 0 {
+		if !t.CompletedAt.IsZero() {
 			completed++
 		}
 	}
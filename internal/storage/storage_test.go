@@ -1,11 +1,23 @@
 package storage
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/olgkv/linkchecker/internal/ports"
 )
 
 func newTestStorage(t *testing.T) *FileStorage {
@@ -18,7 +30,7 @@ func newTestStorage(t *testing.T) *FileStorage {
 
 	repo := NewJSONRepository(f.Name())
 	st := NewFileStorage(repo)
-	if err := st.Load(); err != nil {
+	if err := st.Load(context.Background()); err != nil {
 		t.Fatalf("Load: %v", err)
 	}
 	return st
@@ -28,12 +40,12 @@ func TestFileStorageCreateAndGet(t *testing.T) {
 	st := newTestStorage(t)
 
 	links := []string{"google.com", "yandex.ru"}
-	task, err := st.CreateTask(links)
+	task, err := st.CreateTask(context.Background(), links, ports.TaskMeta{})
 	if err != nil {
 		t.Fatalf("CreateTask: %v", err)
 	}
 
-	got, err := st.GetTasks([]int{task.ID})
+	got, err := st.GetTasks(context.Background(), []int{task.ID})
 	if err != nil {
 		t.Fatalf("GetTasks: %v", err)
 	}
@@ -53,7 +65,7 @@ func TestFileStorage_ConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			task, err := st.CreateTask([]string{fmt.Sprintf("example-%d.com", idx)})
+			task, err := st.CreateTask(context.Background(), []string{fmt.Sprintf("example-%d.com", idx)}, ports.TaskMeta{})
 			if err != nil {
 				t.Errorf("CreateTask: %v", err)
 				return
@@ -68,7 +80,7 @@ func TestFileStorage_ConcurrentAccess(t *testing.T) {
 			defer wg.Done()
 			select {
 			case id := <-ids:
-				if err := st.UpdateTaskResult(id, map[string]string{"ok": "true"}); err != nil {
+				if err := st.UpdateTaskResult(context.Background(), id, 1, map[string]string{"ok": "true"}); err != nil {
 					t.Errorf("UpdateTaskResult: %v", err)
 				}
 			case <-time.After(time.Second):
@@ -81,7 +93,7 @@ func TestFileStorage_ConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, err := st.GetTasks([]int{})
+			_, err := st.GetTasks(context.Background(), []int{})
 			if err != nil {
 				t.Errorf("GetTasks: %v", err)
 			}
@@ -90,3 +102,853 @@ func TestFileStorage_ConcurrentAccess(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestFileStorage_ReplaysIncrementalResults(t *testing.T) {
+	st := newTestStorage(t)
+
+	task, err := st.CreateTask(context.Background(), []string{"a.com", "b.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	findings := []ports.Finding{{Kind: "mixed_content", URL: "http://a.com/logo.png", Detail: "img"}}
+	security := &ports.SecurityAudit{Score: 75, Issues: []string{"missing X-Frame-Options"}}
+	if err := st.AppendLinkResult(context.Background(), task.ID, "a.com", "available", ports.LinkTiming{CheckedAt: checkedAt, DurationMS: 120}, findings, security); err != nil {
+		t.Fatalf("AppendLinkResult: %v", err)
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, _ := reloaded.GetTasks(context.Background(), []int{task.ID})
+	if len(got) != 1 || got[0].Result["a.com"] != "available" || len(got[0].Result) != 1 {
+		t.Fatalf("unexpected partial result after reload: %#v", got)
+	}
+	if timing := got[0].Timings["a.com"]; !timing.CheckedAt.Equal(checkedAt) || timing.DurationMS != 120 {
+		t.Fatalf("unexpected timing after reload: %+v", timing)
+	}
+	if got := got[0].Findings["a.com"]; len(got) != 1 || got[0] != findings[0] {
+		t.Fatalf("unexpected findings after reload: %+v", got)
+	}
+	if got, ok := got[0].Security["a.com"]; !ok || got.Score != 75 || len(got.Issues) != 1 {
+		t.Fatalf("unexpected security audit after reload: %+v", got)
+	}
+	if total, completed := reloaded.Stats(); total != 1 || completed != 0 {
+		t.Fatalf("expected 1 pending task, got total=%d completed=%d", total, completed)
+	}
+
+	if err := reloaded.UpdateTaskResult(context.Background(), task.ID, 2, map[string]string{"a.com": "available", "b.com": "not available"}); err != nil {
+		t.Fatalf("UpdateTaskResult: %v", err)
+	}
+	if _, completed := reloaded.Stats(); completed != 1 {
+		t.Fatalf("expected task completed after final update, got %d", completed)
+	}
+}
+
+func TestFileStorage_MetadataAndTagFilter(t *testing.T) {
+	st := newTestStorage(t)
+
+	meta := ports.TaskMeta{Name: "docs", Tags: []string{"nightly"}, CreatedBy: "ci"}
+	task, err := st.CreateTask(context.Background(), []string{"a.com"}, meta)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := st.CreateTask(context.Background(), []string{"b.com"}, ports.TaskMeta{}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := st.UpdateTaskResult(context.Background(), task.ID, 1, map[string]string{"a.com": "available"}); err != nil {
+		t.Fatalf("UpdateTaskResult: %v", err)
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, err := reloaded.ListTasks(context.Background(), "nightly")
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != task.ID {
+		t.Fatalf("unexpected tagged tasks: %#v", got)
+	}
+	if got[0].Name != "docs" || got[0].CreatedBy != "ci" || got[0].CreatedAt.IsZero() || got[0].CompletedAt.IsZero() {
+		t.Fatalf("metadata not restored: %#v", got[0])
+	}
+	all, _ := reloaded.ListTasks(context.Background(), "")
+	if len(all) != 2 {
+		t.Fatalf("expected 2 tasks without tag filter, got %d", len(all))
+	}
+}
+
+func TestFileStorage_DeleteTasksBeforeCompactsLog(t *testing.T) {
+	st := newTestStorage(t)
+
+	old, err := st.CreateTask(context.Background(), []string{"old.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	cutoff := time.Now().Add(time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	fresh, err := st.CreateTask(context.Background(), []string{"fresh.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	deleted, err := st.DeleteTasksBefore(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("DeleteTasksBefore: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted task, got %d", deleted)
+	}
+
+	entries, err := st.repo.(*JSONRepository).Load()
+	if err != nil {
+		t.Fatalf("repo Load: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected checkpoint and one task after compaction, got %d entries", len(entries))
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, _ := reloaded.GetTasks(context.Background(), []int{old.ID}); len(got) != 0 {
+		t.Fatalf("expected deleted task to stay deleted, got %#v", got)
+	}
+	if got, _ := reloaded.GetTasks(context.Background(), []int{fresh.ID}); len(got) != 1 {
+		t.Fatalf("expected fresh task to survive compaction")
+	}
+	next, err := reloaded.CreateTask(context.Background(), []string{"next.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if next.ID <= fresh.ID {
+		t.Fatalf("expected IDs not to be reused, got %d after %d", next.ID, fresh.ID)
+	}
+}
+
+func TestFileStorage_DeleteTaskDropsItFromLog(t *testing.T) {
+	st := newTestStorage(t)
+
+	gone, err := st.CreateTask(context.Background(), []string{"gone.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	kept, err := st.CreateTask(context.Background(), []string{"kept.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := st.AppendLinkResult(context.Background(), gone.ID, "gone.com", "available", ports.LinkTiming{}, nil, nil); err != nil {
+		t.Fatalf("AppendLinkResult: %v", err)
+	}
+
+	if ok, err := st.DeleteTask(context.Background(), gone.ID); err != nil || !ok {
+		t.Fatalf("DeleteTask = %v, %v; want true", ok, err)
+	}
+	if ok, err := st.DeleteTask(context.Background(), gone.ID); err != nil || ok {
+		t.Fatalf("second DeleteTask = %v, %v; want false", ok, err)
+	}
+
+	// удаление только дописывает запись, лог не переписывается
+	entries, err := st.repo.(*JSONRepository).Load()
+	if err != nil {
+		t.Fatalf("repo Load: %v", err)
+	}
+	if last := entries[len(entries)-1]; len(entries) != 4 || last.Op != "delete" || last.TaskID != gone.ID {
+		t.Fatalf("expected the delete entry appended to the log, got %d entries", len(entries))
+	}
+	replayed := NewFileStorage(st.repo)
+	if err := replayed.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, _ := replayed.GetTasks(context.Background(), []int{gone.ID}); len(got) != 0 {
+		t.Fatalf("expected deleted task to stay deleted before compaction, got %#v", got)
+	}
+
+	// следующая очистка сжимает лог, даже если устаревших задач нет
+	if n, err := st.DeleteTasksBefore(context.Background(), time.Time{}); err != nil || n != 0 {
+		t.Fatalf("DeleteTasksBefore = %d, %v", n, err)
+	}
+	entries, err = st.repo.(*JSONRepository).Load()
+	if err != nil {
+		t.Fatalf("repo Load: %v", err)
+	}
+	for _, e := range entries {
+		if e.TaskID == gone.ID || (e.Task != nil && e.Task.ID == gone.ID) {
+			t.Fatalf("deleted task left in the log after compaction: %+v", e)
+		}
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, _ := reloaded.GetTasks(context.Background(), []int{gone.ID}); len(got) != 0 {
+		t.Fatalf("expected deleted task to stay deleted, got %#v", got)
+	}
+	if got, _ := reloaded.GetTasks(context.Background(), []int{kept.ID}); len(got) != 1 {
+		t.Fatalf("expected other task to survive")
+	}
+}
+
+func TestFileStorage_CompactDeleted(t *testing.T) {
+	st := newTestStorage(t)
+
+	gone, err := st.CreateTask(context.Background(), []string{"gone.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if n, err := st.CompactDeleted(context.Background()); err != nil || n != 0 {
+		t.Fatalf("CompactDeleted without deletes = %d, %v; want 0", n, err)
+	}
+	if ok, err := st.DeleteTask(context.Background(), gone.ID); err != nil || !ok {
+		t.Fatalf("DeleteTask = %v, %v; want true", ok, err)
+	}
+	if n, err := st.CompactDeleted(context.Background()); err != nil || n != 1 {
+		t.Fatalf("CompactDeleted = %d, %v; want 1", n, err)
+	}
+	entries, err := st.repo.(*JSONRepository).Load()
+	if err != nil {
+		t.Fatalf("repo Load: %v", err)
+	}
+	for _, e := range entries {
+		if e.TaskID == gone.ID || (e.Task != nil && e.Task.ID == gone.ID) {
+			t.Fatalf("deleted task left in the log after compaction: %+v", e)
+		}
+	}
+	if n, err := st.CompactDeleted(context.Background()); err != nil || n != 0 {
+		t.Fatalf("second CompactDeleted = %d, %v; want 0", n, err)
+	}
+}
+
+func TestFileStorage_ImportTasks(t *testing.T) {
+	st := newTestStorage(t)
+
+	imported := []*ports.TaskDTO{
+		{ID: 7, Name: "docs", Links: []string{"a.com"}, Result: map[string]string{"a.com": "available"}, CompletedAt: time.Now().UTC()},
+		{ID: 3, Links: []string{"b.com"}},
+	}
+	if err := st.ImportTasks(context.Background(), imported); err != nil {
+		t.Fatalf("ImportTasks: %v", err)
+	}
+	if err := st.ImportTasks(context.Background(), []*ports.TaskDTO{{ID: 3, Links: []string{"c.com"}}}); !errors.Is(err, ports.ErrTaskExists) {
+		t.Fatalf("expected ErrTaskExists, got %v", err)
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, _ := reloaded.GetTasks(context.Background(), []int{3, 7})
+	if len(got) != 2 || got[1].Result["a.com"] != "available" {
+		t.Fatalf("unexpected imported tasks: %#v", got)
+	}
+	if _, completed := reloaded.Stats(); completed != 1 {
+		t.Fatalf("expected completion state to survive import, got %d", completed)
+	}
+	next, err := reloaded.CreateTask(context.Background(), []string{"d.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if next.ID != 8 {
+		t.Fatalf("expected next ID after imported ones, got %d", next.ID)
+	}
+}
+
+func TestJSONRepository_LockIsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	first := NewJSONRepository(path)
+	if err := first.Lock(); err != nil {
+		t.Fatalf("first Lock: %v", err)
+	}
+
+	second := NewJSONRepository(path)
+	if err := second.Lock(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := second.Lock(); err != nil {
+		t.Fatalf("Lock after release: %v", err)
+	}
+	_ = second.Close()
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SyncPolicy
+		wantErr bool
+	}{
+		{"", SyncPolicy{Mode: SyncAlways}, false},
+		{"always", SyncPolicy{Mode: SyncAlways}, false},
+		{"never", SyncPolicy{Mode: SyncNever}, false},
+		{"interval=1s", SyncPolicy{Mode: SyncInterval, Interval: time.Second}, false},
+		{"interval=0s", SyncPolicy{}, true},
+		{"interval=abc", SyncPolicy{}, true},
+		{"sometimes", SyncPolicy{}, true},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseSyncPolicy(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("ParseSyncPolicy(%q) err = %v, wantErr %v", tc.in, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Fatalf("ParseSyncPolicy(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestJSONRepository_IntervalSyncPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	repo := NewJSONRepository(path, WithSyncPolicy(SyncPolicy{Mode: SyncInterval, Interval: 5 * time.Millisecond}))
+	st := NewFileStorage(repo)
+	if err := st.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		repo.mu.Lock()
+		dirty := repo.dirty
+		repo.mu.Unlock()
+		if !dirty {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("background flusher did not sync the log")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	entries, err := repo.Load()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 entry after close, got %d (%v)", len(entries), err)
+	}
+}
+
+func TestJSONRepository_RotateCompressesAndLoadsSegments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	st := NewFileStorage(NewJSONRepository(path))
+	if err := st.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	first, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := st.repo.(*JSONRepository).Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	second, err := st.CreateTask(context.Background(), []string{"b.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "tasks-*.json.gz"))
+	if len(matches) != 1 {
+		t.Fatalf("expected one gzipped segment, got %v", matches)
+	}
+
+	reloaded := NewFileStorage(NewJSONRepository(path))
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, _ := reloaded.GetTasks(context.Background(), []int{first.ID, second.ID})
+	if len(got) != 2 {
+		t.Fatalf("expected tasks from segment and active log, got %#v", got)
+	}
+}
+
+type recordingMetrics struct {
+	mu      sync.Mutex
+	ops     map[string]int
+	rotated int
+	deleted map[string]int
+}
+
+func (m *recordingMetrics) ObserveOp(op string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops[op]++
+}
+
+func (m *recordingMetrics) Rotated() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rotated++
+}
+
+func (m *recordingMetrics) Deleted(kind string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted[kind] += n
+}
+
+func TestFileStorage_OldSegmentsKeptUntilCompaction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	repo := NewJSONRepository(path)
+	st := NewFileStorage(repo)
+	if err := st.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	create := func(link string) {
+		t.Helper()
+		if _, err := st.CreateTask(context.Background(), []string{link}, ports.TaskMeta{}); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+	create("a.com")
+	if err := repo.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	// сегмент старше недели, но задача из него еще жива
+	matches, _ := filepath.Glob(filepath.Join(dir, "tasks-*.json.gz"))
+	old := filepath.Join(dir, "tasks-2024-01-01-000000.json.gz")
+	if len(matches) != 1 || os.Rename(matches[0], old) != nil {
+		t.Fatalf("segments: %v", matches)
+	}
+	stale := time.Now().Add(-8 * 24 * time.Hour)
+	if err := os.Chtimes(old, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	create("b.com")
+	if err := repo.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := os.Stat(old); err != nil || !repo.CompactionDue() {
+		t.Fatalf("old segment removed by rotation or no compaction asked for: %v", err)
+	}
+
+	create("c.com")
+	if matches, _ := filepath.Glob(filepath.Join(dir, "tasks-*.json.gz")); len(matches) != 0 || repo.CompactionDue() {
+		t.Fatalf("segments left after compaction: %v", matches)
+	}
+	reloaded := NewFileStorage(NewJSONRepository(path))
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, _ := reloaded.GetTasks(context.Background(), []int{1, 2, 3}); len(got) != 3 {
+		t.Fatalf("expected all tasks after compaction, got %d", len(got))
+	}
+}
+
+func TestJSONRepository_MetricsAndSlowAppend(t *testing.T) {
+	var logs bytes.Buffer
+	m := &recordingMetrics{ops: make(map[string]int), deleted: make(map[string]int)}
+	repo := NewJSONRepository(filepath.Join(t.TempDir(), "tasks.json"),
+		WithMetrics(m), WithSlowAppend(time.Nanosecond), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	st := NewFileStorage(repo)
+	st.UseMetrics(m)
+	if err := st.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := repo.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := st.CreateTask(context.Background(), []string{"b.com"}, ports.TaskMeta{}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if n, err := st.DeleteTasksBefore(context.Background(), time.Now().Add(time.Hour)); err != nil || n != 2 {
+		t.Fatalf("DeleteTasksBefore = %d, %v", n, err)
+	}
+
+	for _, op := range []string{"load", "append", "rotate", "rewrite"} {
+		if m.ops[op] == 0 {
+			t.Fatalf("%s not observed: %v", op, m.ops)
+		}
+	}
+	if m.rotated != 1 || m.deleted["task"] != 2 || m.deleted["segment"] != 1 {
+		t.Fatalf("unexpected counters: rotated %d, deleted %v", m.rotated, m.deleted)
+	}
+	if !strings.Contains(logs.String(), "slow storage append") {
+		t.Fatalf("slow append not logged: %s", logs.String())
+	}
+}
+
+func TestFileStorage_LoadProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	st := NewFileStorage(NewJSONRepository(path))
+	if p := st.Progress(); p.Phase != LoadPending {
+		t.Fatalf("before Load: %+v", p)
+	}
+	if err := st.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{}); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+
+	repo := NewJSONRepository(path)
+	reloaded := NewFileStorage(repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p := reloaded.Progress(); p.Phase != LoadDone || p.Percent != 100 {
+		t.Fatalf("after Load: %+v", p)
+	}
+	if read, total := repo.ReadProgress(); total == 0 || read != total {
+		t.Fatalf("read %d of %d bytes", read, total)
+	}
+}
+
+// writeSegment writes entries as a gzipped segment of dir/tasks.json.
+func writeSegment(t *testing.T, dir, stamp string, entries ...*LogEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tasks-"+stamp+".json.gz"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write segment: %v", err)
+	}
+}
+
+func TestJSONRepository_ReplayKeepsLogOrderAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	var want []int
+	for i := 1; i <= 2*runtime.GOMAXPROCS(0)+1; i++ {
+		var entries []*LogEntry
+		for j := 0; j < 3*replayBuffer/2; j++ {
+			id := len(want) + 1
+			want = append(want, id)
+			entries = append(entries, &LogEntry{Op: "delete", TaskID: id})
+		}
+		writeSegment(t, dir, fmt.Sprintf("2024-01-01-%06d", i), entries...)
+	}
+	repo := NewJSONRepository(filepath.Join(dir, "tasks.json"))
+	if err := repo.Append(&LogEntry{Op: "delete", TaskID: len(want) + 1}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	want = append(want, len(want)+1)
+
+	var got []int
+	if err := repo.Replay(context.Background(), func(e *LogEntry) { got = append(got, e.TaskID) }); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("replayed %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("entry %d is task %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestJSONRepository_ReplayStopsAtCorruptSegment(t *testing.T) {
+	dir := t.TempDir()
+	writeSegment(t, dir, "2024-01-01-000001", &LogEntry{Op: "delete", TaskID: 1})
+	if err := os.WriteFile(filepath.Join(dir, "tasks-2024-01-01-000002.json.gz"), []byte("not gzip"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	writeSegment(t, dir, "2024-01-01-000003", &LogEntry{Op: "delete", TaskID: 3})
+
+	var got []int
+	err := NewJSONRepository(filepath.Join(dir, "tasks.json")).Replay(context.Background(), func(e *LogEntry) { got = append(got, e.TaskID) })
+	if err == nil || !strings.Contains(err.Error(), "tasks-2024-01-01-000002.json.gz") {
+		t.Fatalf("expected error naming the corrupt segment, got %v", err)
+	}
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("expected only entries before the corrupt segment, got %v", got)
+	}
+}
+
+func TestJSONRepository_ReplayStopsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	var entries []*LogEntry
+	for id := 1; id <= 4*checkEvery; id++ {
+		entries = append(entries, &LogEntry{Op: "delete", TaskID: id})
+	}
+	writeSegment(t, dir, "2024-01-01-000001", entries...)
+	writeSegment(t, dir, "2024-01-01-000002", entries...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	applied := 0
+	err := NewJSONRepository(filepath.Join(dir, "tasks.json")).Replay(ctx, func(*LogEntry) {
+		// отмена посреди первого сегмента
+		if applied++; applied == checkEvery {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Replay after cancel: %v", err)
+	}
+	if applied >= 2*checkEvery {
+		t.Fatalf("applied %d entries after cancel", applied)
+	}
+}
+
+func TestIsSegmentOf(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"tasks-2024-01-02-150405.json.gz", true},
+		{"tasks-2024-01-02.json", false},
+		{"tasks-2024-01-02-150405.json", false},
+		{"tasks-export.json", false},
+		{"tasks-export.json.gz", false},
+		{"tasks.json", false},
+		{"tasks.json.lock", false},
+		{"tasks.json.tmp-123", false},
+		{"other-2024-01-02.json.gz", false},
+	}
+	for _, tc := range tests {
+		if got := isSegmentOf("tasks.json", tc.name); got != tc.ok {
+			t.Fatalf("isSegmentOf(%q) = %v, want %v", tc.name, got, tc.ok)
+		}
+	}
+}
+
+func TestFileStorage_UpdateTaskResultVersionConflict(t *testing.T) {
+	st := newTestStorage(t)
+
+	task, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if task.Version != 1 {
+		t.Fatalf("expected new task at version 1, got %d", task.Version)
+	}
+	if err := st.AppendLinkResult(context.Background(), task.ID, "a.com", "available", ports.LinkTiming{}, nil, nil); err != nil {
+		t.Fatalf("AppendLinkResult: %v", err)
+	}
+
+	err = st.UpdateTaskResult(context.Background(), task.ID, task.Version, map[string]string{"a.com": "not available"})
+	if !errors.Is(err, ports.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict for stale version, got %v", err)
+	}
+	if err := st.UpdateTaskResult(context.Background(), task.ID, 2, map[string]string{"a.com": "available"}); err != nil {
+		t.Fatalf("UpdateTaskResult: %v", err)
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, _ := reloaded.GetTasks(context.Background(), []int{task.ID})
+	if len(got) != 1 || got[0].Version != 3 {
+		t.Fatalf("expected version 3 after replay, got %#v", got)
+	}
+}
+
+func TestFileStorage_SearchTasks(t *testing.T) {
+	st := newTestStorage(t)
+
+	a, _ := st.CreateTask(context.Background(), []string{"example.com", "go.dev"}, ports.TaskMeta{})
+	b, _ := st.CreateTask(context.Background(), []string{"https://Example.com/"}, ports.TaskMeta{})
+	c, _ := st.CreateTask(context.Background(), []string{"other.org"}, ports.TaskMeta{})
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"example.com", []int{a.ID, b.ID}},
+		{"http://example.com/docs", []int{a.ID, b.ID}},
+		{"go.dev", []int{a.ID}},
+		{"other.org", []int{c.ID}},
+		{"missing.net", nil},
+	}
+	for _, tc := range tests {
+		got, err := st.SearchTasks(context.Background(), tc.query)
+		if err != nil {
+			t.Fatalf("SearchTasks(%q): %v", tc.query, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("SearchTasks(%q) returned %d tasks, want %d", tc.query, len(got), len(tc.want))
+		}
+		for i, id := range tc.want {
+			if got[i].ID != id {
+				t.Fatalf("SearchTasks(%q)[%d] = %d, want %d", tc.query, i, got[i].ID, id)
+			}
+		}
+	}
+
+	if _, err := st.DeleteTasksBefore(context.Background(), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("DeleteTasksBefore: %v", err)
+	}
+	if got, _ := st.SearchTasks(context.Background(), "example.com"); len(got) != 0 {
+		t.Fatalf("expected deleted tasks to leave the index, got %d", len(got))
+	}
+}
+
+func TestFileStorage_GetTaskPage(t *testing.T) {
+	st := newTestStorage(t)
+	task, _ := st.CreateTask(context.Background(), []string{"a.com", "b.com", "c.com", "d.com"}, ports.TaskMeta{})
+	for link, status := range map[string]string{"a.com": "available", "b.com": "not available", "c.com": "available"} {
+		timing := ports.LinkTiming{CheckedAt: time.Now(), DurationMS: 5}
+		if err := st.AppendLinkResult(context.Background(), task.ID, link, status, timing, nil, nil); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	page, err := st.GetTaskPage(context.Background(), task.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("GetTaskPage: %v", err)
+	}
+	if !slices.Equal(page.Task.Links, []string{"b.com", "c.com"}) || page.Total != 4 {
+		t.Fatalf("page links %v of %d", page.Task.Links, page.Total)
+	}
+	if len(page.Task.Result) != 2 || len(page.Task.Timings) != 2 || page.Task.Result["b.com"] != "not available" {
+		t.Fatalf("page results %v, timings %v", page.Task.Result, page.Task.Timings)
+	}
+	if page.Statuses["available"] != 2 || page.Statuses["not available"] != 1 {
+		t.Fatalf("statuses %v", page.Statuses)
+	}
+
+	if page, _ := st.GetTaskPage(context.Background(), task.ID, 3, 0); !slices.Equal(page.Task.Links, []string{"d.com"}) || len(page.Task.Result) != 0 {
+		t.Fatalf("last page %+v", page.Task)
+	}
+	if page, _ := st.GetTaskPage(context.Background(), task.ID, 10, 2); len(page.Task.Links) != 0 {
+		t.Fatalf("page past the end has links %v", page.Task.Links)
+	}
+	if page, err := st.GetTaskPage(context.Background(), 999, 0, 2); err != nil || page != nil {
+		t.Fatalf("missing task: %+v, %v", page, err)
+	}
+}
+
+func TestFileStorage_SearchIndexRebuiltOnLoad(t *testing.T) {
+	st := newTestStorage(t)
+	task, _ := st.CreateTask(context.Background(), []string{"example.com"}, ports.TaskMeta{})
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, _ := reloaded.SearchTasks(context.Background(), "example.com")
+	if len(got) != 1 || got[0].ID != task.ID {
+		t.Fatalf("expected index rebuilt from log, got %#v", got)
+	}
+}
+
+func BenchmarkFileStorage_AppendLinkResult(b *testing.B) {
+	for _, policy := range []string{"always", "never"} {
+		b.Run(policy, func(b *testing.B) {
+			sp, err := ParseSyncPolicy(policy)
+			if err != nil {
+				b.Fatal(err)
+			}
+			repo := NewJSONRepository(filepath.Join(b.TempDir(), "tasks.json"), WithSyncPolicy(sp))
+			b.Cleanup(func() { _ = repo.Close() })
+			st := NewFileStorage(repo)
+			task, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			timing := ports.LinkTiming{CheckedAt: time.Now(), DurationMS: 42}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := st.AppendLinkResult(context.Background(), task.ID, "a.com", "available", timing, nil, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStorage_BatchesSurviveReplayAndCompaction(t *testing.T) {
+	st := newTestStorage(t)
+
+	b, tasks, err := st.CreateBatch(context.Background(), [][]string{{"a.com", "b.com"}, {"c.com"}}, ports.TaskMeta{Name: "big", Owner: "alice"})
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if len(tasks) != 2 || len(b.TaskIDs) != 2 || b.TaskIDs[1] != tasks[1].ID || tasks[0].BatchID != b.ID {
+		t.Fatalf("unexpected batch %#v with tasks %#v", b, tasks)
+	}
+	if b.ID == tasks[0].ID || b.ID == tasks[1].ID {
+		t.Fatalf("batch ID %d collides with a task ID", b.ID)
+	}
+
+	reloaded := NewFileStorage(st.repo)
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got, err := reloaded.GetBatch(context.Background(), b.ID)
+	if err != nil || got == nil || got.Name != "big" || got.Owner != "alice" || len(got.TaskIDs) != 2 {
+		t.Fatalf("GetBatch after replay: %#v, %v", got, err)
+	}
+	if ts, _ := reloaded.GetTasks(context.Background(), got.TaskIDs); len(ts) != 2 || ts[0].BatchID != b.ID {
+		t.Fatalf("batch tasks after replay: %#v", ts)
+	}
+
+	if err := reloaded.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if got, _ := reloaded.GetBatch(context.Background(), b.ID); got == nil {
+		t.Fatalf("batch lost by compaction")
+	}
+	if _, err := reloaded.DeleteTasksBefore(context.Background(), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("DeleteTasksBefore: %v", err)
+	}
+	if got, _ := reloaded.GetBatch(context.Background(), b.ID); got != nil {
+		t.Fatalf("expected batch to expire with its tasks, got %#v", got)
+	}
+}
+
+func TestFileStorage_HonorsContext(t *testing.T) {
+	st := newTestStorage(t)
+	task, err := st.CreateTask(context.Background(), []string{"a.com"}, ports.TaskMeta{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := st.GetTasks(cancelled, []int{task.ID}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetTasks with cancelled context: %v", err)
+	}
+	if _, err := st.CreateTask(cancelled, []string{"b.com"}, ports.TaskMeta{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateTask with cancelled context: %v", err)
+	}
+	if tasks, _ := st.ListTasks(context.Background(), ""); len(tasks) != 1 {
+		t.Fatalf("cancelled CreateTask stored a task: %d tasks", len(tasks))
+	}
+
+	// запрос за занятой блокировкой сдается по сроку и не мешает остальным
+	st.mu.Lock()
+	ctx, stop := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer stop()
+	if err := st.AppendLinkResult(ctx, task.ID, "a.com", "available", ports.LinkTiming{}, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AppendLinkResult behind held lock: %v", err)
+	}
+	if _, err := st.ListTasks(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListTasks behind held lock: %v", err)
+	}
+	st.mu.Unlock()
+
+	got, err := st.GetTasks(context.Background(), []int{task.ID})
+	if err != nil || len(got) != 1 || len(got[0].Result) != 0 {
+		t.Fatalf("after timeouts: %+v, %v", got, err)
+	}
+}
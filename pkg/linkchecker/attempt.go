@@ -0,0 +1,131 @@
+package linkchecker
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Attempt describes one request made to check an HTTP link, for logging.
+// It never carries the response body.
+type Attempt struct {
+	URL string
+	// Try counts the attempts of the check from zero; retries have Try > 0.
+	Try int
+	// RemoteIP is the address the request was sent to; it is empty when no
+	// connection was made or the client does not report it.
+	RemoteIP   string
+	StatusCode int
+	Duration   time.Duration
+	// Phases breaks Duration down; nil when the client does not report
+	// them.
+	Phases *Phases
+	// Err is the request error, nil when a response was received.
+	Err error
+}
+
+// Phases breaks a request down into the DNS lookup, TCP connect and TLS
+// handshake, which are zero when a kept-alive connection was reused, and
+// the time from writing the request to the first byte of the response.
+// A phase that did not finish, e.g. because the request failed in it, is
+// zero as well: a failed check with Connect set but no TTFB reached the
+// server, which did not answer. After redirects only the last hop counts.
+type Phases struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+}
+
+// phaseTrace records the Phases and the remote address of a request. The
+// transport may call the hooks from several goroutines.
+type phaseTrace struct {
+	mu                                   sync.Mutex
+	traced                               bool
+	remoteIP                             string
+	phases                               Phases
+	dnsStart, connStart, tlsStart, wrote time.Time
+}
+
+// trace returns req with t attached as its client trace.
+func (t *phaseTrace) trace(req *http.Request) *http.Request {
+	since := func(start time.Time) time.Duration {
+		if start.IsZero() {
+			return 0
+		}
+		return time.Since(start)
+	}
+	ct := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// новый хоп после редиректа — считаем заново
+			t.phases, t.remoteIP = Phases{}, ""
+			t.dnsStart, t.connStart, t.tlsStart, t.wrote = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if info.Err == nil {
+				t.phases.DNS = since(t.dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) { t.mark(&t.connStart) },
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.phases.Connect = since(t.connStart)
+			}
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.phases.TLS = since(t.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.traced = true
+			if info.Conn == nil {
+				return
+			}
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				t.remoteIP = host
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { t.mark(&t.wrote) },
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.phases.TTFB = since(t.wrote)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), ct))
+}
+
+func (t *phaseTrace) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*at = time.Now()
+	t.traced = true
+}
+
+// result returns the remote address and the phases, nil if the client
+// called none of the hooks.
+func (t *phaseTrace) result() (string, *Phases) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.traced {
+		return t.remoteIP, nil
+	}
+	p := t.phases
+	return t.remoteIP, &p
+}
@@ -0,0 +1,116 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// Bandwidth caps the bytes per second read by the checkers sharing it:
+// response bodies, robots.txt and content checks included, and connections
+// of the built-in probes of other schemes and of mailto: links. Reads wait
+// for their share of a token bucket, so TCP flow control slows the hosts
+// down too. A Bandwidth is safe for concurrent use.
+type Bandwidth struct {
+	limiter *rate.Limiter
+	chunk   int
+}
+
+// NewBandwidth returns a cap of bytesPerSecond, or nil, which does not
+// limit anything, if bytesPerSecond is not positive. A single read takes at
+// most one second worth of bytes.
+func NewBandwidth(bytesPerSecond int) *Bandwidth {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Bandwidth{limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond), chunk: bytesPerSecond}
+}
+
+// wrap returns client with its response bodies throttled by b. An
+// *http.Client keeps its type so that per-check copies still work.
+func (b *Bandwidth) wrap(client HTTPClient) HTTPClient {
+	if b == nil {
+		return client
+	}
+	if hc, ok := client.(*http.Client); ok {
+		cp := *hc
+		base := hc.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		cp.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := base.RoundTrip(req)
+			return b.throttle(req.Context(), resp), err
+		})
+		return &cp
+	}
+	return clientFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := client.Do(req)
+		return b.throttle(req.Context(), resp), err
+	})
+}
+
+func (b *Bandwidth) throttle(ctx context.Context, resp *http.Response) *http.Response {
+	if resp != nil && resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &throttledBody{ReadCloser: resp.Body, ctx: ctx, bw: b}
+	}
+	return resp
+}
+
+type throttledBody struct {
+	io.ReadCloser
+	ctx context.Context
+	bw  *Bandwidth
+}
+
+func (t *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > t.bw.chunk {
+		p = p[:t.bw.chunk]
+	}
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		// токены списываются после чтения: размер заранее неизвестен
+		if werr := t.bw.limiter.WaitN(t.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+type bandwidthKey struct{}
+
+// withBandwidth makes the connections dialed under ctx read at most as fast
+// as b allows; nil leaves them unthrottled.
+func withBandwidth(ctx context.Context, b *Bandwidth) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, bandwidthKey{}, b)
+}
+
+// throttleConn returns conn with its reads throttled by the Bandwidth of ctx.
+func throttleConn(ctx context.Context, conn net.Conn) net.Conn {
+	b, _ := ctx.Value(bandwidthKey{}).(*Bandwidth)
+	if b == nil {
+		return conn
+	}
+	return &throttledConn{Conn: conn, body: throttledBody{ReadCloser: conn, ctx: ctx, bw: b}}
+}
+
+type throttledConn struct {
+	net.Conn
+	body throttledBody
+}
+
+func (t *throttledConn) Read(p []byte) (int, error) { return t.body.Read(p) }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+type clientFunc func(*http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
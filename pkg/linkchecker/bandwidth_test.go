@@ -0,0 +1,82 @@
+package linkchecker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBandwidth(t *testing.T) {
+	if NewBandwidth(0) != nil {
+		t.Fatal("NewBandwidth(0) should not limit")
+	}
+	body := bytes.Repeat([]byte("x"), 3000)
+	base := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	})}
+	client := NewBandwidth(10000).wrap(base)
+	if _, ok := client.(*http.Client); !ok {
+		t.Fatalf("wrapped client is %T, want *http.Client", client)
+	}
+
+	// четыре ответа по 3000 байт делят одну полосу: 10000 байт начального
+	// запаса проходят сразу, остальные 2000 — за ~0.2s
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "https://big.test", nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			if n, err := io.Copy(io.Discard, resp.Body); err != nil || n != int64(len(body)) {
+				t.Errorf("read %d bytes: %v", n, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("12000 bytes at 10000 B/s read in %s", elapsed)
+	}
+}
+
+func TestBandwidth_ProbeConnections(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write(bytes.Repeat([]byte("x"), 12000))
+	}()
+
+	// 10000 байт запаса сразу, остальные 2000 — за ~0.2s
+	ctx := withBandwidth(context.Background(), NewBandwidth(10000))
+	conn, err := dial(ctx, "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	if n, err := io.Copy(io.Discard, conn); err != nil || n != 12000 {
+		t.Fatalf("read %d bytes: %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("12000 bytes at 10000 B/s read in %s", elapsed)
+	}
+}
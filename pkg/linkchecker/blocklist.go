@@ -0,0 +1,265 @@
+package linkchecker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	urlpkg "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocklist tells whether a URL is known to serve malware or phishing.
+// Flagged links are never requested; they fail with ErrorFlaggedUnsafe.
+type Blocklist interface {
+	// Flagged returns why url is unsafe, e.g. "MALWARE", or "" if it is
+	// not listed. A lookup that fails lets the link be checked.
+	Flagged(ctx context.Context, url string) (string, error)
+}
+
+// BlocklistFunc adapts a function to the Blocklist interface.
+type BlocklistFunc func(ctx context.Context, url string) (string, error)
+
+// Flagged calls f(ctx, url).
+func (f BlocklistFunc) Flagged(ctx context.Context, url string) (string, error) {
+	return f(ctx, url)
+}
+
+// Blocklists consults several blocklists in order; a URL flagged by any of
+// them is flagged.
+type Blocklists []Blocklist
+
+// Flagged implements Blocklist. Errors of one list do not keep the next
+// from being consulted.
+func (bs Blocklists) Flagged(ctx context.Context, url string) (string, error) {
+	var firstErr error
+	for _, b := range bs {
+		reason, err := b.Flagged(ctx, url)
+		if reason != "" {
+			return reason, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", firstErr
+}
+
+// HostBlocklist is a static list of hosts and URL prefixes. A host entry
+// also covers its subdomains.
+type HostBlocklist struct {
+	hosts    map[string]struct{}
+	prefixes []string
+}
+
+// NewHostBlocklist returns a blocklist of entries, each a host such as
+// "evil.example" or a URL prefix such as "https://example.com/phish/".
+func NewHostBlocklist(entries []string) *HostBlocklist {
+	b := &HostBlocklist{hosts: make(map[string]struct{})}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		switch {
+		case e == "":
+		case strings.Contains(e, "://"):
+			b.prefixes = append(b.prefixes, strings.ToLower(e))
+		default:
+			b.hosts[strings.ToLower(strings.TrimSuffix(e, "."))] = struct{}{}
+		}
+	}
+	return b
+}
+
+// LoadHostBlocklist reads a blocklist file with one entry per line; blank
+// lines and lines starting with # are ignored.
+func LoadHostBlocklist(path string) (*HostBlocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read blocklist %s: %w", path, err)
+	}
+	return NewHostBlocklist(entries), nil
+}
+
+// Len returns the number of entries.
+func (b *HostBlocklist) Len() int {
+	return len(b.hosts) + len(b.prefixes)
+}
+
+// Flagged implements Blocklist.
+func (b *HostBlocklist) Flagged(_ context.Context, url string) (string, error) {
+	u, err := urlpkg.Parse(url)
+	if err != nil {
+		return "", err
+	}
+	host := strings.ToLower(u.Hostname())
+	for h := host; h != ""; {
+		if _, ok := b.hosts[h]; ok {
+			return "blocklist", nil
+		}
+		_, parent, ok := strings.Cut(h, ".")
+		if !ok {
+			break
+		}
+		h = parent
+	}
+	lower := strings.ToLower(url)
+	for _, p := range b.prefixes {
+		if strings.HasPrefix(lower, p) {
+			return "blocklist", nil
+		}
+	}
+	return "", nil
+}
+
+// safeBrowsingURL is the endpoint of the Safe Browsing Lookup API v4.
+const safeBrowsingURL = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+
+// maxSafeBrowsingCache bounds the number of cached verdicts.
+const maxSafeBrowsingCache = 10000
+
+// SafeBrowsing looks URLs up with the Google Safe Browsing Lookup API and
+// caches the verdicts for the duration the API asks for, five minutes for
+// safe URLs. It is safe for concurrent use.
+type SafeBrowsing struct {
+	key      string
+	client   HTTPClient
+	endpoint string
+
+	mu    sync.Mutex
+	cache map[string]verdict
+	now   func() time.Time
+}
+
+type verdict struct {
+	threat  string
+	expires time.Time
+}
+
+// NewSafeBrowsing returns a Safe Browsing client authenticated by the API
+// key; a nil client defaults to http.DefaultClient.
+func NewSafeBrowsing(key string, client HTTPClient) *SafeBrowsing {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SafeBrowsing{
+		key:      key,
+		client:   client,
+		endpoint: safeBrowsingURL,
+		cache:    make(map[string]verdict),
+		now:      time.Now,
+	}
+}
+
+// Flagged implements Blocklist. The user name and password of url are not
+// sent to Google.
+func (sb *SafeBrowsing) Flagged(ctx context.Context, url string) (string, error) {
+	url = withoutUserinfo(url)
+	sb.mu.Lock()
+	v, ok := sb.cache[url]
+	sb.mu.Unlock()
+	if ok && sb.now().Before(v.expires) {
+		return v.threat, nil
+	}
+
+	threat, ttl, err := sb.lookup(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("safe browsing lookup: %w", err)
+	}
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if len(sb.cache) >= maxSafeBrowsingCache {
+		// проще сбросить кеш целиком, чем вести LRU
+		clear(sb.cache)
+	}
+	sb.cache[url] = verdict{threat: threat, expires: sb.now().Add(ttl)}
+	return threat, nil
+}
+
+// withoutUserinfo drops the user name and password from url; a URL that does
+// not parse is returned as is.
+func withoutUserinfo(url string) string {
+	u, err := urlpkg.Parse(url)
+	if err != nil || u.User == nil {
+		return url
+	}
+	u.User = nil
+	return u.String()
+}
+
+type safeBrowsingRequest struct {
+	Client struct {
+		ClientID      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string            `json:"threatTypes"`
+		PlatformTypes    []string            `json:"platformTypes"`
+		ThreatEntryTypes []string            `json:"threatEntryTypes"`
+		ThreatEntries    []map[string]string `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type safeBrowsingResponse struct {
+	Matches []struct {
+		ThreatType    string `json:"threatType"`
+		CacheDuration string `json:"cacheDuration"`
+	} `json:"matches"`
+}
+
+// lookup asks the API about url and returns the threat type of the first
+// match, if any, with how long the verdict may be cached.
+func (sb *SafeBrowsing) lookup(ctx context.Context, url string) (string, time.Duration, error) {
+	var body safeBrowsingRequest
+	body.Client.ClientID, body.Client.ClientVersion = "linkchecker", "1.0"
+	body.ThreatInfo.ThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+	body.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	body.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	body.ThreatInfo.ThreatEntries = []map[string]string{{"url": url}}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sb.endpoint+"?key="+urlpkg.QueryEscape(sb.key), bytes.NewReader(payload))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := sb.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var out safeBrowsingResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", 0, err
+	}
+	if len(out.Matches) == 0 {
+		return "", 5 * time.Minute, nil
+	}
+	ttl, err := time.ParseDuration(out.Matches[0].CacheDuration)
+	if err != nil || ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return out.Matches[0].ThreatType, ttl, nil
+}
@@ -0,0 +1,227 @@
+package linkchecker
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerMaxHosts is the number of hosts a Breaker tracks unless
+// SetMaxHosts changes it.
+const DefaultBreakerMaxHosts = 10000
+
+// Breaker limits outbound requests to hosts that consistently fail. After
+// threshold consecutive failures a host is skipped until cooldown passes.
+// A Breaker is safe for concurrent use and may be shared between checkers.
+//
+// Memory is bounded: at most maxHosts hosts are tracked, the host with the
+// oldest failure being forgotten first, and Prune drops hosts that have not
+// failed for longer than cooldown.
+type Breaker struct {
+	mu        sync.Mutex
+	hosts     map[string]*list.Element
+	lru       *list.List // *breakerEntry, most recent failure first
+	threshold uint32
+	cooldown  time.Duration
+	maxHosts  int
+	trips     uint64
+}
+
+type breakerEntry struct {
+	host     string
+	failures uint32
+	lastSeen time.Time
+}
+
+// NewBreaker returns a breaker; zero values default to 3 failures and 30s.
+func NewBreaker(threshold uint32, cooldown time.Duration) *Breaker {
+	if threshold == 0 {
+		threshold = 3
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &Breaker{
+		hosts:     make(map[string]*list.Element),
+		lru:       list.New(),
+		threshold: threshold,
+		cooldown:  cooldown,
+		maxHosts:  DefaultBreakerMaxHosts,
+	}
+}
+
+// SetMaxHosts caps the number of tracked hosts, forgetting the ones with the
+// oldest failures if there are more. Non-positive values are ignored.
+func (cb *Breaker) SetMaxHosts(n int) {
+	if n <= 0 {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.maxHosts = n
+	cb.evict()
+}
+
+// Cooldown returns how long a host stays skipped after tripping the breaker.
+func (cb *Breaker) Cooldown() time.Duration {
+	return cb.cooldown
+}
+
+// Allow reports whether host may be contacted.
+func (cb *Breaker) Allow(host string) bool {
+	if host == "" {
+		return true
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	el, ok := cb.hosts[host]
+	if !ok {
+		return true
+	}
+	e := el.Value.(*breakerEntry)
+	if e.failures < cb.threshold {
+		return true
+	}
+	if time.Since(e.lastSeen) > cb.cooldown {
+		cb.remove(el)
+		return true
+	}
+	return false
+}
+
+// Success resets the failure count of host.
+func (cb *Breaker) Success(host string) {
+	if host == "" {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if el, ok := cb.hosts[host]; ok {
+		cb.remove(el)
+	}
+}
+
+// Failure records a failed request to host.
+func (cb *Breaker) Failure(host string) {
+	if host == "" {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	el, ok := cb.hosts[host]
+	if ok {
+		cb.lru.MoveToFront(el)
+	} else {
+		el = cb.lru.PushFront(&breakerEntry{host: host})
+		cb.hosts[host] = el
+	}
+	e := el.Value.(*breakerEntry)
+	e.failures++
+	e.lastSeen = time.Now()
+	if e.failures == cb.threshold {
+		cb.trips++
+	}
+	cb.evict()
+}
+
+// Prune forgets hosts that have not failed for longer than the cooldown:
+// open circuits have closed by then and shorter failure streaks are
+// considered over. It returns the number of hosts dropped.
+func (cb *Breaker) Prune() int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	pruned := 0
+	for el := cb.lru.Back(); el != nil; el = cb.lru.Back() {
+		if time.Since(el.Value.(*breakerEntry).lastSeen) <= cb.cooldown {
+			break
+		}
+		cb.remove(el)
+		pruned++
+	}
+	return pruned
+}
+
+// evict drops the hosts with the oldest failures above maxHosts.
+func (cb *Breaker) evict() {
+	for cb.lru.Len() > cb.maxHosts {
+		cb.remove(cb.lru.Back())
+	}
+}
+
+func (cb *Breaker) remove(el *list.Element) {
+	cb.lru.Remove(el)
+	delete(cb.hosts, el.Value.(*breakerEntry).host)
+}
+
+// BreakerHost describes a host with recorded failures.
+type BreakerHost struct {
+	Host        string    `json:"host"`
+	Failures    uint32    `json:"failures"`
+	Open        bool      `json:"open"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// Hosts lists hosts with recorded failures, sorted by name.
+func (cb *Breaker) Hosts() []BreakerHost {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	hosts := make([]BreakerHost, 0, len(cb.hosts))
+	for el := cb.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*breakerEntry)
+		hosts = append(hosts, BreakerHost{
+			Host:        e.host,
+			Failures:    e.failures,
+			Open:        cb.open(e),
+			LastFailure: e.lastSeen,
+		})
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+func (cb *Breaker) open(e *breakerEntry) bool {
+	return e.failures >= cb.threshold && time.Since(e.lastSeen) <= cb.cooldown
+}
+
+// BreakerStats summarizes the state of a Breaker.
+type BreakerStats struct {
+	// Hosts is the number of hosts with recorded failures.
+	Hosts int
+	// Open is the number of hosts currently skipped.
+	Open int
+	// Trips counts how often a host reached the failure threshold.
+	Trips uint64
+}
+
+// Stats returns the current breaker statistics.
+func (cb *Breaker) Stats() BreakerStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	stats := BreakerStats{Hosts: cb.lru.Len(), Trips: cb.trips}
+	for el := cb.lru.Front(); el != nil; el = el.Next() {
+		if cb.open(el.Value.(*breakerEntry)) {
+			stats.Open++
+		}
+	}
+	return stats
+}
+
+// Reset forgets failures of host, or of every host when host is empty.
+func (cb *Breaker) Reset(host string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if host == "" {
+		clear(cb.hosts)
+		cb.lru.Init()
+		return
+	}
+	if el, ok := cb.hosts[host]; ok {
+		cb.remove(el)
+	}
+}
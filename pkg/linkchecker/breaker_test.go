@@ -0,0 +1,104 @@
+package linkchecker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker_OpensAfterFailures(t *testing.T) {
+	cb := NewBreaker(2, time.Minute)
+	host := "example.com"
+
+	if !cb.Allow(host) {
+		t.Fatalf("expected allow before failures")
+	}
+
+	cb.Failure(host)
+	if !cb.Allow(host) {
+		t.Fatalf("expected allow before reaching threshold")
+	}
+
+	cb.Failure(host)
+	if cb.Allow(host) {
+		t.Fatalf("expected breaker to block after threshold")
+	}
+
+	cb.Success(host)
+	if !cb.Allow(host) {
+		t.Fatalf("expected allow after success reset")
+	}
+}
+
+func TestBreaker_ClosesAfterCooldown(t *testing.T) {
+	cooldown := 10 * time.Millisecond
+	cb := NewBreaker(1, cooldown)
+	host := "cooldown.test"
+
+	cb.Failure(host)
+	if cb.Allow(host) {
+		t.Fatalf("expected breaker open immediately after failure")
+	}
+
+	time.Sleep(cooldown + 5*time.Millisecond)
+	if !cb.Allow(host) {
+		t.Fatalf("expected breaker to close after cooldown")
+	}
+}
+
+func TestBreaker_HostsAndReset(t *testing.T) {
+	cb := NewBreaker(1, time.Minute)
+	cb.Failure("b.test")
+	cb.Failure("a.test")
+
+	hosts := cb.Hosts()
+	if len(hosts) != 2 || hosts[0].Host != "a.test" || !hosts[0].Open {
+		t.Fatalf("unexpected snapshot: %+v", hosts)
+	}
+
+	cb.Reset("a.test")
+	if !cb.Allow("a.test") || cb.Allow("b.test") {
+		t.Fatalf("expected only a.test to be reset")
+	}
+
+	cb.Reset("")
+	if len(cb.Hosts()) != 0 {
+		t.Fatalf("expected all hosts reset")
+	}
+}
+
+func TestBreaker_MaxHostsEvictsOldest(t *testing.T) {
+	cb := NewBreaker(1, time.Minute)
+	cb.SetMaxHosts(2)
+	cb.Failure("a.test")
+	cb.Failure("b.test")
+	cb.Failure("a.test")
+	cb.Failure("c.test")
+
+	hosts := cb.Hosts()
+	if len(hosts) != 2 || hosts[0].Host != "a.test" || hosts[1].Host != "c.test" {
+		t.Fatalf("expected b.test to be evicted: %+v", hosts)
+	}
+	if !cb.Allow("b.test") {
+		t.Fatalf("evicted host must be allowed")
+	}
+}
+
+func TestBreaker_PruneAndStats(t *testing.T) {
+	cooldown := 20 * time.Millisecond
+	cb := NewBreaker(2, cooldown)
+	cb.Failure("old.test")
+	cb.Failure("old.test")
+	time.Sleep(cooldown + 5*time.Millisecond)
+	cb.Failure("new.test")
+
+	if st := cb.Stats(); st.Hosts != 2 || st.Open != 0 || st.Trips != 1 {
+		t.Fatalf("unexpected stats before prune: %+v", st)
+	}
+	if n := cb.Prune(); n != 1 {
+		t.Fatalf("pruned %d hosts, want 1", n)
+	}
+	cb.Failure("new.test")
+	if st := cb.Stats(); st.Hosts != 1 || st.Open != 1 || st.Trips != 2 {
+		t.Fatalf("unexpected stats after prune: %+v", st)
+	}
+}
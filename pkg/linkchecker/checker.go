@@ -0,0 +1,658 @@
+// Package linkchecker checks whether web links are reachable. It is the engine
+// behind the linkchecker server and CLI and can be embedded in other programs:
+//
+//	c := linkchecker.New(linkchecker.Options{Timeout: 10 * time.Second})
+//	results := c.Check(ctx, []string{"example.com", "go.dev"}, nil)
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	urlpkg "net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Status is the outcome of a link check.
+type Status string
+
+const (
+	StatusAvailable    Status = "available"
+	StatusNotAvailable Status = "not available"
+)
+
+// HTTPClient sends check requests; *http.Client satisfies it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Options configure a Checker. Zero values select the defaults.
+type Options struct {
+	// Timeout bounds a whole Check call. Defaults to 5s.
+	Timeout time.Duration
+	// Concurrency caps parallel requests within a Check call. Defaults to 100.
+	Concurrency int
+	// Client sends requests. Defaults to an *http.Client without its own
+	// timeout, as Timeout is enforced through the request context. An
+	// *http.Client without CheckRedirect gets the checker's redirect policy,
+	// which detects loops and supports WithoutRedirects.
+	Client HTTPClient
+	// Breaker skips hosts that keep failing. Nil disables it.
+	Breaker *Breaker
+	// AllowPrivate permits checks of loopback, private and link-local
+	// addresses. By default such hosts are reported as not available so that
+	// user-supplied links cannot probe internal networks (SSRF).
+	AllowPrivate bool
+	// Resolver resolves host names for the private address check.
+	// Defaults to net.LookupIP.
+	Resolver func(host string) ([]net.IP, error)
+	// HTTP3Client, if set, is used to probe every host that responded for
+	// HTTP/3 support; see Result.HTTP3. It must speak HTTP/3 only, e.g. an
+	// *http.Client with a quic-go http3.Transport.
+	HTTP3Client HTTPClient
+	// Retry controls retries of failed requests. Nil selects
+	// DefaultRetryPolicy.
+	Retry *RetryPolicy
+	// Robots, if set, makes checks honor robots.txt: disallowed links are
+	// not requested and reported as skipped. Nil disables it.
+	Robots *Robots
+	// Validators, if set, makes repeated checks of a URL conditional on the
+	// ETag and Last-Modified of the previous response; see Result.Unchanged.
+	Validators *Validators
+	// Probers adds or replaces checkers of non-HTTP links by scheme. The
+	// built-in ones handle tcp://host:port, ping://host, ftp:// and sftp://
+	// links; see NewSFTPProber for the latter.
+	Probers map[string]Prober
+	// SMTP, if set, verifies the recipient of mailto: links with the mail
+	// exchanger of the domain. Nil checks the MX records only.
+	SMTP *SMTPProbe
+	// MXResolver looks up the MX records of mailto: domains. Defaults to
+	// net.LookupMX.
+	MXResolver func(domain string) ([]*net.MX, error)
+	// Maintenance, if set, reports whether a host is in a maintenance
+	// window. Links on such hosts are not requested and reported as skipped
+	// with ErrorMaintenance.
+	Maintenance func(host string) bool
+	// Blocklist, if set, is consulted before HTTP links are requested;
+	// flagged links are not requested and fail with ErrorFlaggedUnsafe.
+	Blocklist Blocklist
+	// StatusRules classify HTTP responses before the default rule, under
+	// which 2xx and 3xx responses are available.
+	StatusRules StatusRules
+	// Schemes, if set, lists the schemes of the links that may be checked,
+	// e.g. https and mailto; bare host names count as https. Links of
+	// other schemes fail with ErrorInvalidLink.
+	Schemes []string
+	// HTTPS decides whether web links are checked over HTTPS or plain HTTP.
+	// The zero value checks them as written.
+	HTTPS HTTPSPolicy
+	// Bandwidth, if set, caps the bytes per second read from responses and
+	// by the built-in probes of other schemes; it may be shared between
+	// checkers for a global cap. Probers from Options.Probers are not capped.
+	Bandwidth *Bandwidth
+	// SourceIP, if set, is the local address tcp://, ping://, ftp://,
+	// sftp:// and mailto: checks connect from. HTTP requests go through
+	// Client, whose transport has to be bound on its own.
+	SourceIP net.IP
+	// OnAttempt, if set, is called after every request made to check an
+	// HTTP link, retries included, with ctx of the check.
+	OnAttempt func(ctx context.Context, a Attempt)
+	// Slots, if set, bounds the links checked at a time across all Check
+	// calls, on top of each call's Concurrency. Waiting for a slot counts
+	// against the call's Timeout.
+	Slots Slots
+}
+
+// Slots hands out permits to check a link. Acquire blocks until a permit is
+// free or ctx is done and returns the func that frees it; ctx carries the
+// values of the Check call, so an implementation can rank its callers.
+type Slots interface {
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// Checker checks links. It is safe for concurrent use.
+type Checker struct {
+	client       HTTPClient
+	http3        HTTPClient
+	breaker      *Breaker
+	robots       *Robots
+	validators   *Validators
+	probers      map[string]Prober
+	smtp         *SMTPProbe
+	allowPrivate bool
+	maintenance  func(host string) bool
+	blocklist    Blocklist
+	statusRules  StatusRules
+	schemes      map[string]bool
+	httpsPolicy  HTTPSPolicy
+	sourceIP     net.IP
+	bandwidth    *Bandwidth
+	onAttempt    func(ctx context.Context, a Attempt)
+	slots        Slots
+	resolve      func(host string) ([]net.IP, error)
+	lookupMX     func(domain string) ([]*net.MX, error)
+
+	mu          sync.RWMutex
+	timeout     time.Duration
+	concurrency int
+	retry       RetryPolicy
+}
+
+// New returns a Checker configured by opts.
+func New(opts Options) *Checker {
+	c := &Checker{
+		client:       opts.Client,
+		http3:        opts.HTTP3Client,
+		breaker:      opts.Breaker,
+		robots:       opts.Robots,
+		validators:   opts.Validators,
+		probers:      defaultProbers(),
+		smtp:         opts.SMTP,
+		allowPrivate: opts.AllowPrivate,
+		maintenance:  opts.Maintenance,
+		blocklist:    opts.Blocklist,
+		statusRules:  opts.StatusRules,
+		httpsPolicy:  opts.HTTPS,
+		sourceIP:     opts.SourceIP,
+		bandwidth:    opts.Bandwidth,
+		onAttempt:    opts.OnAttempt,
+		slots:        opts.Slots,
+		resolve:      opts.Resolver,
+		lookupMX:     opts.MXResolver,
+		timeout:      5 * time.Second,
+		concurrency:  100,
+		retry:        DefaultRetryPolicy,
+	}
+	if opts.Retry != nil {
+		c.retry = *opts.Retry
+	}
+	for scheme, p := range opts.Probers {
+		c.probers[scheme] = p
+	}
+	if len(opts.Schemes) > 0 {
+		c.schemes = make(map[string]bool, len(opts.Schemes))
+		for _, scheme := range opts.Schemes {
+			c.schemes[strings.ToLower(scheme)] = true
+		}
+	}
+	if c.client == nil {
+		c.client = &http.Client{}
+	}
+	c.client = opts.Bandwidth.wrap(withRedirectPolicy(c.client))
+	if c.resolve == nil {
+		c.resolve = net.LookupIP
+	}
+	if c.lookupMX == nil {
+		c.lookupMX = net.LookupMX
+	}
+	c.SetLimits(opts.Concurrency, opts.Timeout)
+	return c
+}
+
+// SetLimits changes concurrency and timeout of subsequent Check calls; calls
+// already running keep their limits. Non-positive values are ignored.
+func (c *Checker) SetLimits(concurrency int, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if concurrency > 0 {
+		c.concurrency = concurrency
+	}
+	if timeout > 0 {
+		c.timeout = timeout
+	}
+}
+
+// Limits returns the current concurrency and timeout.
+func (c *Checker) Limits() (int, time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.concurrency, c.timeout
+}
+
+// SetRetryPolicy changes how subsequent checks retry failed requests.
+func (c *Checker) SetRetryPolicy(p RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retry = p
+}
+
+// RetryPolicy returns the current retry policy.
+func (c *Checker) RetryPolicy() RetryPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.retry
+}
+
+// Breaker returns the breaker the checker uses, or nil.
+func (c *Checker) Breaker() *Breaker {
+	return c.breaker
+}
+
+// Result is the outcome of checking one link.
+type Result struct {
+	Status Status
+	// Protocol is the HTTP version the host answered with, such as
+	// "HTTP/1.1" or "HTTP/2.0"; empty when no response was received.
+	Protocol string
+	// StatusCode is the code of the last response, zero when there was none.
+	// Location is set when that response was a redirect.
+	StatusCode int
+	Location   string
+	// Unchanged is set when the host answered 304 Not Modified to a
+	// conditional request: the link is available and its content is the
+	// same as at the previous check.
+	Unchanged bool
+	// Findings lists problems in the content of the page and Weight
+	// estimates its size with its subresources; both are only filled under
+	// a context from WithContent.
+	Findings []Finding
+	Weight   *PageWeight
+	// Security rates the security headers of an HTTPS page; it is only
+	// filled under a context from WithSecurityHeaders.
+	Security *SecurityAudit
+	// Deliverability estimates whether mail to a mailto: link would be
+	// accepted; it is empty for other links and when DNS failed.
+	Deliverability Deliverability
+	// Upgraded is set when an http:// link was checked over HTTPS because
+	// its host answers there, and Downgraded when HTTPS failed and the link
+	// was checked over plain HTTP instead; see HTTPSUpgrade.
+	Upgraded   bool
+	Downgraded bool
+	// HTTP3 reports whether the host also answered over HTTP/3. It is nil
+	// unless Options.HTTP3Client is set and the host responded at all.
+	HTTP3 *bool
+	// RemoteIP is the address the last request of an HTTP check was sent
+	// to, IPv4 or IPv6 as the dialer connected; see AddressFamily. It is
+	// empty when no connection was made.
+	RemoteIP string
+	// Phases breaks the last request of an HTTP check down into DNS,
+	// connect, TLS and time to first byte; nil when none was traced.
+	Phases *Phases
+	// CheckedAt is when the check started and Duration how long the request
+	// took, retries included. Both are zero for links rejected up front.
+	CheckedAt time.Time
+	Duration  time.Duration
+	// Skipped is set for links that were never requested because the check
+	// ran out of time, gave up early, robots.txt disallows them or their host
+	// is in maintenance.
+	Skipped bool
+	// Error describes the last failure of a link that is not available and
+	// ErrorKind classifies it. Both are empty for available links.
+	Error     string
+	ErrorKind ErrorKind
+}
+
+// skipped is the result of a link that was never requested.
+var skipped = Result{Status: StatusNotAvailable, Skipped: true, Error: "not checked", ErrorKind: ErrorSkipped}
+
+// maintenanceSkip is the result of a link on a host in maintenance.
+var maintenanceSkip = Result{Status: StatusNotAvailable, Skipped: true, Error: "host is in a maintenance window", ErrorKind: ErrorMaintenance}
+
+func (c *Checker) inMaintenance(host string) bool {
+	return c.maintenance != nil && c.maintenance(host)
+}
+
+// flagged returns why the blocklist flags url, or "" if it does not or its
+// lookup failed. Blocklists never see the credentials of url.
+func (c *Checker) flagged(ctx context.Context, url string) string {
+	if c.blocklist == nil {
+		return ""
+	}
+	reason, _ := c.blocklist.Flagged(ctx, withoutUserinfo(url))
+	return reason
+}
+
+func failed(kind ErrorKind, msg string) Result {
+	return Result{Status: StatusNotAvailable, Error: msg, ErrorKind: kind}
+}
+
+// Check checks links concurrently and returns a result for every link. Links
+// not yet started when the timeout or ctx ends are reported as not available
+// with Skipped set. onResult, if not nil, is called for each link as soon as
+// its result is known, possibly from several goroutines at once.
+func (c *Checker) Check(ctx context.Context, links []string, onResult func(link string, res Result)) map[string]Result {
+	return c.check(ctx, links, 0, onResult)
+}
+
+// CheckFailFast is Check that gives up after maxFailures links were found not
+// available: checks still running are cancelled and reported as not
+// available, the rest are skipped. A non-positive maxFailures disables it.
+func (c *Checker) CheckFailFast(ctx context.Context, links []string, maxFailures int, onResult func(link string, res Result)) map[string]Result {
+	return c.check(ctx, links, maxFailures, onResult)
+}
+
+// errFailFast stops the errgroup once enough links failed.
+var errFailFast = errors.New("too many unavailable links")
+
+func (c *Checker) check(ctx context.Context, links []string, maxFailures int, onResult func(link string, res Result)) map[string]Result {
+	concurrency, timeout := c.Limits()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(map[string]Result, len(links))
+	var mu sync.Mutex
+	record := func(link string, res Result) {
+		mu.Lock()
+		result[link] = res
+		mu.Unlock()
+		if onResult != nil {
+			onResult(link, res)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	var failures atomic.Int32
+	for i, link := range links {
+		// Go блокируется, пока все слоты заняты; отмена может наступить за это время
+		if gctx.Err() != nil {
+			for _, rest := range links[i:] {
+				record(rest, skipped)
+			}
+			break
+		}
+		g.Go(func() error {
+			if gctx.Err() != nil {
+				record(link, skipped)
+				return nil
+			}
+			if c.slots != nil {
+				release, err := c.slots.Acquire(gctx)
+				if err != nil {
+					record(link, skipped)
+					return nil
+				}
+				defer release()
+			}
+			res := c.CheckLink(gctx, link)
+			record(link, res)
+			if res.Status != StatusAvailable && maxFailures > 0 && int(failures.Add(1)) >= maxFailures {
+				return errFailFast
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return result
+}
+
+// CheckLink checks a single link: a bare host name such as "example.com",
+// which is requested over HTTPS, or an http:// or https:// URL, checked as
+// Options.HTTPS decides. Responses with 2xx and 3xx codes count as
+// available, the latter only if redirects are followed; network errors and 5xx responses are retried as the retry
+// policy allows until ctx is done. Links written as scheme://target, such as
+// tcp://db.example.com:5432, are checked by the Prober of their scheme and
+// mailto:user@domain links by the mail setup of the domain; see
+// Result.Deliverability.
+func (c *Checker) CheckLink(ctx context.Context, link string) Result {
+	if !c.allowPrivate {
+		ctx = refusePrivate(ctx)
+	}
+	clean := strings.TrimSpace(link)
+	if scheme := linkScheme(clean); !c.schemeAllowed(scheme) {
+		return failed(ErrorInvalidLink, "scheme "+scheme+" is not allowed")
+	}
+	if isMailto(clean) {
+		return c.checkMail(ctx, clean)
+	}
+	if strings.Contains(clean, "://") {
+		if u, err := urlpkg.Parse(clean); err == nil && isWebScheme(u.Scheme) {
+			if u.Hostname() == "" {
+				return failed(ErrorInvalidLink, "link has no host")
+			}
+			return c.checkWeb(ctx, u, false)
+		}
+		return c.checkScheme(ctx, clean)
+	}
+	if !ValidLink(clean) {
+		return failed(ErrorInvalidLink, "link must be a bare host name")
+	}
+	parsed, err := urlpkg.Parse("https://" + clean)
+	if err != nil {
+		return failed(ErrorInvalidLink, err.Error())
+	}
+	return c.checkWeb(ctx, parsed, true)
+}
+
+// checkHTTP requests the http:// or https:// URL parsed.
+func (c *Checker) checkHTTP(ctx context.Context, parsed *urlpkg.URL) Result {
+	url := parsed.String()
+	host := parsed.Hostname()
+	if reason := c.flagged(ctx, url); reason != "" {
+		return failed(ErrorFlaggedUnsafe, "flagged as unsafe: "+reason)
+	}
+	if c.inMaintenance(host) {
+		return maintenanceSkip
+	}
+	if !c.allowPrivate {
+		if private, err := c.isPrivateHost(host); err != nil {
+			return failed(classify(err), err.Error())
+		} else if private {
+			return failed(ErrorPrivateAddress, "host resolves to a private address")
+		}
+	}
+	if c.breaker != nil && !c.breaker.Allow(host) {
+		return failed(ErrorCircuitOpen, "host skipped after repeated failures")
+	}
+	if c.robots != nil && !c.robots.allowed(ctx, c.client, parsed.Scheme, parsed.Host, parsed.RequestURI()) {
+		res := failed(ErrorSkippedRobots, "disallowed by robots.txt")
+		res.Skipped = true
+		return res
+	}
+
+	start := time.Now()
+	res := c.get(ctx, host, url)
+	res.CheckedAt, res.Duration = start, time.Since(start)
+	if res.Protocol != "" && c.http3 != nil && parsed.Scheme == "https" {
+		res.HTTP3 = c.probeHTTP3(ctx, url)
+	}
+	return res
+}
+
+func (c *Checker) get(ctx context.Context, host, url string) Result {
+	res := Result{Status: StatusNotAvailable}
+	policy := c.RetryPolicy()
+	if !retrying(ctx) {
+		policy.Retries = 0
+	}
+	client := c.clientFor(ctx)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			res.Error, res.ErrorKind = err.Error(), ErrorInvalidLink
+			return res
+		}
+		authorize(ctx, req)
+		if c.validators != nil {
+			c.validators.apply(req, url)
+		}
+		trace := &phaseTrace{}
+		req = trace.trace(req)
+
+		sent := time.Now()
+		resp, err := client.Do(req)
+		if resp != nil && resp.Body != nil {
+			defer drainAndClose(resp.Body)
+		}
+		res.RemoteIP, res.Phases = trace.result()
+		if c.onAttempt != nil {
+			a := Attempt{URL: url, Try: attempt, RemoteIP: res.RemoteIP, Duration: time.Since(sent), Phases: res.Phases, Err: err}
+			if resp != nil {
+				a.StatusCode = resp.StatusCode
+			}
+			c.onAttempt(ctx, a)
+		}
+		if err != nil {
+			res.Error, res.ErrorKind = err.Error(), classify(err)
+			if c.breaker != nil {
+				c.breaker.Failure(host)
+			}
+			// петля редиректов не исчезнет при повторе
+			if res.ErrorKind == ErrorRedirectLoop {
+				return res
+			}
+			// если контекст отменен — дальше не ретраим
+			select {
+			case <-ctx.Done():
+				return res
+			default:
+			}
+		} else {
+			res.Protocol, res.StatusCode = resp.Proto, resp.StatusCode
+			if hop := c.statusRules.brokenRedirect(host, resp); hop != nil {
+				if c.breaker != nil {
+					c.breaker.Success(host)
+				}
+				res.Location = hop.Header.Get("Location")
+				res.Error, res.ErrorKind = "redirect "+hop.Status+" to "+res.Location+" classified as not available", ErrorHTTPStatus
+				return res
+			}
+			redirect := resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.StatusCode != http.StatusNotModified
+			available, ruled := c.statusRules.classify(resp.StatusCode, redirect && otherHost(host, resp))
+			if !ruled {
+				available = resp.StatusCode >= 200 && resp.StatusCode < 400
+			}
+			// 304 — ответ на условный запрос, а не редирект
+			if redirect {
+				res.Location = resp.Header.Get("Location")
+				if !followRedirects(ctx) && !(ruled && available) {
+					// хост отвечает, так что для breaker это успех
+					if c.breaker != nil {
+						c.breaker.Success(host)
+					}
+					res.Error, res.ErrorKind = "redirect "+resp.Status, ErrorRedirect
+					if res.Location != "" {
+						res.Error += " to " + res.Location
+					}
+					return res
+				}
+			}
+			if available {
+				if c.breaker != nil {
+					c.breaker.Success(host)
+				}
+				res.Status = StatusAvailable
+				res.Error, res.ErrorKind = "", ""
+				res.Unchanged = resp.StatusCode == http.StatusNotModified
+				if contentMode(ctx) && resp.StatusCode < 300 {
+					if pg := auditPage(url, resp); pg != nil {
+						res.Findings = c.pageFindings(ctx, pg)
+						res.Weight = c.pageWeight(ctx, pg)
+					}
+				}
+				if securityMode(ctx) && resp.StatusCode < 300 {
+					res.Security = auditSecurityHeaders(resp)
+				}
+				if c.validators != nil && resp.StatusCode < 300 {
+					c.validators.update(url, resp)
+				}
+				return res
+			}
+			res.Error, res.ErrorKind = "unexpected status "+resp.Status, ErrorHTTPStatus
+			if ruled {
+				// хост отвечает, статус отнесен к недоступным правилом
+				if c.breaker != nil {
+					c.breaker.Success(host)
+				}
+				res.Error += " classified as not available"
+				return res
+			}
+			if c.breaker != nil {
+				c.breaker.Failure(host)
+			}
+			// 4xx не ретраим: ответ от повтора не изменится
+			if !retryableStatus(resp.StatusCode) {
+				return res
+			}
+		}
+
+		if attempt >= policy.Retries {
+			return res
+		}
+		// подождать backoff или выйти, если контекст отменен
+		select {
+		case <-ctx.Done():
+			return res
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+}
+
+// probeHTTP3 requests url through the HTTP/3 client. Any response counts:
+// the probe checks protocol support, not the status of the page.
+func (c *Checker) probeHTTP3(ctx context.Context, url string) *bool {
+	ok := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = c.http3.Do(req)
+		if err == nil {
+			drainAndClose(resp.Body)
+			ok = true
+		}
+	}
+	return &ok
+}
+
+// maxDrain bounds how much of a response body is read so that its connection
+// can go back to the idle pool; larger bodies are dropped with the connection.
+const maxDrain = 64 << 10
+
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.CopyN(io.Discard, body, maxDrain)
+	_ = body.Close()
+}
+
+// ValidateLink reports why CheckLink would reject link without checking it:
+// an empty link or one with whitespace, a scheme Options.Schemes or
+// Options.HTTPS do not allow, a malformed mailto: link, a scheme without a
+// Prober, a scheme:// link without a host or anything else that is not a
+// bare host name.
+func (c *Checker) ValidateLink(link string) error {
+	clean := strings.TrimSpace(link)
+	switch {
+	case clean == "":
+		return errors.New("link is empty")
+	case strings.ContainsAny(clean, " \t\r\n"):
+		return errors.New("link contains whitespace")
+	case !c.schemeAllowed(linkScheme(clean)):
+		return errors.New("scheme " + linkScheme(clean) + " is not allowed")
+	case isMailto(clean):
+		if _, _, ok := parseMailto(clean); !ok {
+			return errors.New("mailto link must be mailto:user@domain")
+		}
+	case strings.Contains(clean, "://"):
+		u, err := urlpkg.Parse(clean)
+		if err != nil {
+			return err
+		}
+		if _, ok := c.probers[u.Scheme]; !ok && !isWebScheme(u.Scheme) {
+			return errors.New("unsupported scheme " + u.Scheme)
+		}
+		if u.Hostname() == "" {
+			return errors.New("link has no host")
+		}
+	case !ValidLink(clean):
+		return errors.New("link must be a bare host name")
+	}
+	return nil
+}
+
+// ValidLink reports whether link is a bare host name accepted by CheckLink:
+// no scheme, port, path, query or fragment.
+func ValidLink(link string) bool {
+	if link == "" {
+		return false
+	}
+	if strings.ContainsAny(link, "/?#:") {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,313 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidLink(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		ok   bool
+	}{
+		{"empty", "", false},
+		{"plain domain", "example.com", true},
+		{"subdomain", "sub.example.com", true},
+		{"with slash", "example.com/path", false},
+		{"with query", "example.com?x=1", false},
+		{"with fragment", "example.com#hash", false},
+		{"with port", "example.com:8080", false},
+		{"with scheme", "http://example.com", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ValidLink(tc.url)
+			if got != tc.ok {
+				t.Fatalf("ValidLink(%q) = %v, want %v", tc.url, got, tc.ok)
+			}
+		})
+	}
+}
+
+type statusClient map[string]int
+
+func (c statusClient) Do(req *http.Request) (*http.Response, error) {
+	code, ok := c[req.URL.Host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: code, Proto: "HTTP/2.0", Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func publicResolver(string) ([]net.IP, error) {
+	return []net.IP{net.ParseIP("93.184.216.34")}, nil
+}
+
+func TestChecker_Check(t *testing.T) {
+	c := New(Options{
+		Timeout:  time.Second,
+		Client:   statusClient{"ok.test": http.StatusOK, "gone.test": http.StatusNotFound},
+		Resolver: publicResolver,
+	})
+
+	var mu sync.Mutex
+	seen := map[string]Status{}
+	got := c.Check(context.Background(), []string{"ok.test", "gone.test", "bad/link"}, func(link string, res Result) {
+		mu.Lock()
+		seen[link] = res.Status
+		mu.Unlock()
+	})
+
+	want := map[string]Status{"ok.test": StatusAvailable, "gone.test": StatusNotAvailable, "bad/link": StatusNotAvailable}
+	for link, st := range want {
+		if got[link].Status != st || seen[link] != st {
+			t.Fatalf("%s: got %q (callback %q), want %q", link, got[link].Status, seen[link], st)
+		}
+	}
+	if got["ok.test"].Protocol != "HTTP/2.0" || got["bad/link"].Protocol != "" {
+		t.Fatalf("unexpected protocols: %q, %q", got["ok.test"].Protocol, got["bad/link"].Protocol)
+	}
+	if got["ok.test"].HTTP3 != nil {
+		t.Fatalf("expected no HTTP/3 result without a probe client")
+	}
+}
+
+func TestChecker_HTTP3Probe(t *testing.T) {
+	c := New(Options{
+		Timeout:     time.Second,
+		Client:      statusClient{"h3.test": http.StatusOK, "h2.test": http.StatusNotFound},
+		HTTP3Client: statusClient{"h3.test": http.StatusOK},
+		Resolver:    publicResolver,
+	})
+
+	got := c.Check(context.Background(), []string{"h3.test", "h2.test", "down.test"}, nil)
+	if h3 := got["h3.test"].HTTP3; h3 == nil || !*h3 {
+		t.Fatalf("h3.test: expected HTTP/3 available, got %v", h3)
+	}
+	// хост ответил 404 по HTTP/2, но не по HTTP/3
+	if h3 := got["h2.test"].HTTP3; h3 == nil || *h3 {
+		t.Fatalf("h2.test: expected HTTP/3 unavailable, got %v", h3)
+	}
+	if h3 := got["down.test"].HTTP3; h3 != nil {
+		t.Fatalf("down.test: expected no probe for unreachable host, got %v", *h3)
+	}
+}
+
+func TestChecker_PrivateHosts(t *testing.T) {
+	client := statusClient{"127.0.0.1": http.StatusOK}
+
+	strict := New(Options{Client: client})
+	if st := strict.CheckLink(context.Background(), "127.0.0.1").Status; st != StatusNotAvailable {
+		t.Fatalf("expected loopback to be blocked, got %q", st)
+	}
+
+	open := New(Options{Client: client, AllowPrivate: true})
+	if st := open.CheckLink(context.Background(), "127.0.0.1").Status; st != StatusAvailable {
+		t.Fatalf("expected loopback allowed with AllowPrivate, got %q", st)
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{"10.1.2.3", true},
+		{"0.1.2.3", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"100.128.0.1", false},
+		{"93.184.216.34", false},
+		{"::1", true},
+		{"::", true},
+		{"fd12:3456::1", true},
+		{"fc00::1", true},
+		{"fe80::1", true},
+		{"fec0::1", true},
+		{"ff02::1", true},
+		{"::ffff:192.168.1.1", true},
+		{"::ffff:93.184.216.34", false},
+		{"64:ff9b::a00:1", true},
+		{"64:ff9b::5db8:d822", false},
+		{"2002:a9fe:0101::1", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := isPrivateIP(tt.ip); got != tt.private {
+			t.Errorf("isPrivateIP(%q) = %v, want %v", tt.ip, got, tt.private)
+		}
+	}
+
+	// хост с публичным IPv4 и приватным IPv6 не считается приватным
+	c := New(Options{Resolver: func(string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("fd00::1"), net.ParseIP("93.184.216.34")}, nil
+	}})
+	if private, err := c.isPrivateHost("dual.test"); err != nil || private {
+		t.Fatalf("dual-stack host: private=%v err=%v", private, err)
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	d := &net.Dialer{ControlContext: RefusePrivate}
+
+	// имя проверено как публичное, а соединение пошло на loopback
+	_, err = d.DialContext(refusePrivate(context.Background()), "tcp", ln.Addr().String())
+	if !errors.Is(err, errPrivateDial) || classify(err) != ErrorPrivateAddress {
+		t.Fatalf("dial under a checker context: %v", err)
+	}
+	conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial without a checker context: %v", err)
+	}
+	conn.Close()
+}
+
+func TestAddressFamily(t *testing.T) {
+	for ip, want := range map[string]string{"93.184.216.34": "ipv4", "::ffff:10.0.0.1": "ipv4", "2001:db8::1": "ipv6", "": "", "host": ""} {
+		if got := AddressFamily(ip); got != want {
+			t.Errorf("AddressFamily(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestChecker_Maintenance(t *testing.T) {
+	client := statusClient{"up.test": http.StatusOK, "down.test": http.StatusOK}
+	c := New(Options{Client: client, AllowPrivate: true, Maintenance: func(host string) bool { return host == "down.test" }})
+
+	if res := c.CheckLink(context.Background(), "up.test"); res.Status != StatusAvailable {
+		t.Fatalf("up.test: expected available, got %+v", res)
+	}
+	res := c.CheckLink(context.Background(), "down.test")
+	if !res.Skipped || res.ErrorKind != ErrorMaintenance || !res.CheckedAt.IsZero() {
+		t.Fatalf("down.test: expected skipped for maintenance, got %+v", res)
+	}
+	if res := c.CheckLink(context.Background(), "tcp://down.test:5432"); res.ErrorKind != ErrorMaintenance {
+		t.Fatalf("tcp link: expected skipped for maintenance, got %+v", res)
+	}
+}
+
+func TestChecker_SetLimits(t *testing.T) {
+	c := New(Options{Concurrency: 4, Timeout: time.Second})
+
+	c.SetLimits(8, 0)
+	if workers, timeout := c.Limits(); workers != 8 || timeout != time.Second {
+		t.Fatalf("limits = %d, %s; want 8, 1s", workers, timeout)
+	}
+	c.SetLimits(0, 3*time.Second)
+	if workers, timeout := c.Limits(); workers != 8 || timeout != 3*time.Second {
+		t.Fatalf("limits = %d, %s; want 8, 3s", workers, timeout)
+	}
+}
+
+type blockingClient struct{}
+
+func (blockingClient) Do(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestChecker_CheckReportsSkippedLinks(t *testing.T) {
+	c := New(Options{Timeout: 50 * time.Millisecond, Concurrency: 1, Client: blockingClient{}, Resolver: publicResolver})
+
+	links := []string{"a.test", "b.test", "c.test"}
+	got := c.Check(context.Background(), links, nil)
+	if len(got) != len(links) {
+		t.Fatalf("expected a result for every link, got %v", got)
+	}
+	if got["a.test"].Skipped || got["a.test"].Status != StatusNotAvailable {
+		t.Fatalf("a.test: expected timed out check, got %+v", got["a.test"])
+	}
+	for _, link := range links[1:] {
+		if !got[link].Skipped || got[link].Status != StatusNotAvailable {
+			t.Fatalf("%s: expected skipped, got %+v", link, got[link])
+		}
+	}
+}
+
+func TestChecker_CheckFailFast(t *testing.T) {
+	client := statusClient{"gone1.test": http.StatusNotFound, "gone2.test": http.StatusNotFound, "ok.test": http.StatusOK}
+	c := New(Options{Timeout: 5 * time.Second, Concurrency: 1, Client: client, Resolver: publicResolver})
+
+	var calls atomic.Int32
+	got := c.CheckFailFast(context.Background(), []string{"gone1.test", "gone2.test", "ok.test"}, 1, func(string, Result) {
+		calls.Add(1)
+	})
+	if got["gone1.test"].Skipped || got["gone1.test"].Status != StatusNotAvailable {
+		t.Fatalf("gone1.test: expected checked failure, got %+v", got["gone1.test"])
+	}
+	if !got["ok.test"].Skipped {
+		t.Fatalf("ok.test: expected to be skipped after the first failure, got %+v", got["ok.test"])
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected onResult for every link, got %d calls", calls.Load())
+	}
+}
+
+func TestChecker_OnAttempt(t *testing.T) {
+	var attempts []Attempt
+	c := New(Options{
+		Client:    statusClient{"flaky.test": http.StatusServiceUnavailable},
+		Resolver:  publicResolver,
+		Retry:     &RetryPolicy{Retries: 1},
+		OnAttempt: func(_ context.Context, a Attempt) { attempts = append(attempts, a) },
+	})
+
+	c.CheckLink(context.Background(), "flaky.test")
+	c.CheckLink(context.Background(), "down.test")
+	if len(attempts) != 4 {
+		t.Fatalf("got %d attempts, want 2 per link: %+v", len(attempts), attempts)
+	}
+	if a := attempts[1]; a.URL != "https://flaky.test" || a.Try != 1 || a.StatusCode != http.StatusServiceUnavailable || a.Err != nil {
+		t.Fatalf("unexpected retry %+v", a)
+	}
+	if a := attempts[2]; a.URL != "https://down.test" || a.StatusCode != 0 || a.Err == nil {
+		t.Fatalf("unexpected failed attempt %+v", a)
+	}
+}
+
+func TestChecker_Phases(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer srv.Close()
+	// сертификат тестового сервера выписан на example.com
+	client := srv.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	c := New(Options{Client: client, Resolver: publicResolver})
+
+	const host = "example.com"
+	r := c.CheckLink(context.Background(), host)
+	if r.Status != StatusAvailable || r.Phases == nil {
+		t.Fatalf("got %+v", r)
+	}
+	if p := r.Phases; p.DNS != 0 || p.Connect <= 0 || p.TLS <= 0 || p.TTFB < 5*time.Millisecond {
+		t.Fatalf("unexpected phases of a new connection %+v", p)
+	}
+	if r.RemoteIP != "127.0.0.1" || AddressFamily(r.RemoteIP) != "ipv4" {
+		t.Fatalf("remote IP %q", r.RemoteIP)
+	}
+	// второй запрос идет по тому же соединению
+	r = c.CheckLink(context.Background(), host)
+	if p := r.Phases; p == nil || p.Connect != 0 || p.TLS != 0 || p.TTFB <= 0 {
+		t.Fatalf("unexpected phases of a reused connection %+v", p)
+	}
+}
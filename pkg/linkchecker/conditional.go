@@ -0,0 +1,66 @@
+package linkchecker
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Validators remembers the ETag and Last-Modified headers of checked URLs so
+// that repeated checks can be conditional: the next request carries
+// If-None-Match and If-Modified-Since, and a 304 Not Modified answer counts
+// as available without the site sending the page again. A Validators is safe
+// for concurrent use and may be shared between checkers.
+type Validators struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]validator
+}
+
+type validator struct {
+	etag         string
+	lastModified string
+}
+
+// NewValidators returns a store for the validators of up to maxEntries URLs;
+// zero defaults to 10000. When it is full an arbitrary entry is evicted.
+func NewValidators(maxEntries int) *Validators {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &Validators{max: maxEntries, entries: make(map[string]validator)}
+}
+
+// apply adds the conditional headers remembered for url to req.
+func (v *Validators) apply(req *http.Request, url string) {
+	v.mu.Lock()
+	e, ok := v.entries[url]
+	v.mu.Unlock()
+	if !ok {
+		return
+	}
+	if e.etag != "" {
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	if e.lastModified != "" {
+		req.Header.Set("If-Modified-Since", e.lastModified)
+	}
+}
+
+// update remembers the validators of a successful response to url, or
+// forgets url if the response has none.
+func (v *Validators) update(url string, resp *http.Response) {
+	e := validator{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if e == (validator{}) {
+		delete(v.entries, url)
+		return
+	}
+	if _, ok := v.entries[url]; !ok && len(v.entries) >= v.max {
+		for old := range v.entries {
+			delete(v.entries, old)
+			break
+		}
+	}
+	v.entries[url] = e
+}
@@ -0,0 +1,65 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// etagClient serves a page with a fixed ETag and answers 304 when the
+// request carries it.
+type etagClient struct {
+	etag        string
+	conditional int
+}
+
+func (c *etagClient) Do(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: http.Header{}, Body: io.NopCloser(strings.NewReader("page"))}
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		c.conditional++
+		if inm == c.etag {
+			resp.StatusCode = http.StatusNotModified
+			return resp, nil
+		}
+	}
+	resp.Header.Set("ETag", c.etag)
+	return resp, nil
+}
+
+func TestChecker_ConditionalRecheck(t *testing.T) {
+	client := &etagClient{etag: `"v1"`}
+	c := New(Options{Client: client, Resolver: publicResolver, Validators: NewValidators(0)})
+
+	if r := c.CheckLink(context.Background(), "site.test"); r.Status != StatusAvailable || r.Unchanged || client.conditional != 0 {
+		t.Fatalf("first check: got %+v after %d conditional requests", r, client.conditional)
+	}
+	r := c.CheckLink(context.Background(), "site.test")
+	if r.Status != StatusAvailable || !r.Unchanged || r.StatusCode != http.StatusNotModified {
+		t.Fatalf("re-check: got %+v", r)
+	}
+
+	// содержимое изменилось — полный ответ и новый ETag
+	client.etag = `"v2"`
+	if r := c.CheckLink(context.Background(), "site.test"); r.Unchanged {
+		t.Fatalf("changed page reported as unchanged: %+v", r)
+	}
+	if r := c.CheckLink(context.Background(), "site.test"); !r.Unchanged {
+		t.Fatalf("expected the new ETag to be remembered: %+v", r)
+	}
+}
+
+func TestValidators_Evicts(t *testing.T) {
+	v := NewValidators(2)
+	for _, url := range []string{"https://a.test", "https://b.test", "https://c.test"} {
+		v.update(url, &http.Response{Header: http.Header{"Etag": {`"x"`}}})
+	}
+	if len(v.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(v.entries))
+	}
+	v.update("https://c.test", &http.Response{Header: http.Header{}})
+	if _, ok := v.entries["https://c.test"]; ok {
+		t.Fatal("expected a response without validators to forget the URL")
+	}
+}
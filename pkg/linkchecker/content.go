@@ -0,0 +1,176 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	urlpkg "net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxPageSize bounds how much of a page is parsed in content mode.
+const maxPageSize = 1 << 20
+
+// FindingKind classifies a problem found in the content of a page.
+type FindingKind string
+
+const (
+	// FindingMixedContent is a subresource loaded over plain HTTP by an
+	// HTTPS page; browsers block or flag it.
+	FindingMixedContent FindingKind = "mixed_content"
+	// FindingCanonical is a <link rel="canonical"> that is duplicated or
+	// does not lead straight to an available page.
+	FindingCanonical FindingKind = "canonical"
+	// FindingHreflang is an hreflang alternate with an invalid or
+	// conflicting language code or one that does not lead straight to an
+	// available page.
+	FindingHreflang FindingKind = "hreflang"
+)
+
+// Finding is a problem found in the content of an available page. URL is
+// the offending reference and Detail says where it was found, e.g. the tag.
+type Finding struct {
+	Kind   FindingKind
+	URL    string
+	Detail string
+}
+
+type contentKey struct{}
+
+// WithContent returns a context under which checks also read the HTML of
+// available pages, report problems in it as Result.Findings and estimate
+// its weight as Result.Weight. Only the first MiB of a page is parsed.
+func WithContent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contentKey{}, true)
+}
+
+func contentMode(ctx context.Context) bool {
+	on, _ := ctx.Value(contentKey{}).(bool)
+	return on
+}
+
+// page is what auditPage learned from an HTML page.
+type page struct {
+	url        *urlpkg.URL
+	findings   []Finding
+	canonical  []string
+	alternates []alternate
+	// size is the length of the page, resources the subresources it loads.
+	size      int64
+	resources []string
+}
+
+// alternate is a <link rel="alternate" hreflang> annotation.
+type alternate struct {
+	lang string
+	href string
+}
+
+// auditPage parses the HTML page in resp, which was requested as url. It
+// returns nil for other content types.
+func auditPage(url string, resp *http.Response) *page {
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/html" {
+		return nil
+	}
+	pg := &page{}
+	// после редиректов проверяем итоговую страницу
+	if resp.Request != nil && resp.Request.URL != nil {
+		pg.url = resp.Request.URL
+	} else if u, err := urlpkg.Parse(url); err == nil {
+		pg.url = u
+	} else {
+		return nil
+	}
+	secure := pg.url.Scheme == "https"
+
+	body := &countingReader{r: io.LimitReader(resp.Body, maxPageSize)}
+	z := html.NewTokenizer(body)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// страницы больше лимита читаем не целиком, тогда верим Content-Length
+			pg.size = max(body.n, resp.ContentLength)
+			return pg
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			for _, ref := range subresources(tok) {
+				if secure && isPlainHTTP(ref) {
+					pg.findings = append(pg.findings, Finding{Kind: FindingMixedContent, URL: ref, Detail: tok.Data})
+				}
+				pg.resources = append(pg.resources, ref)
+			}
+			if tok.Data != "link" {
+				continue
+			}
+			rels := strings.Fields(strings.ToLower(attr(tok, "rel")))
+			href := strings.TrimSpace(attr(tok, "href"))
+			switch {
+			case slices.Contains(rels, "canonical"):
+				pg.canonical = append(pg.canonical, href)
+			case slices.Contains(rels, "alternate") && hasAttr(tok, "hreflang"):
+				pg.alternates = append(pg.alternates, alternate{lang: strings.TrimSpace(attr(tok, "hreflang")), href: href})
+			}
+		}
+	}
+}
+
+// subresources returns the URLs a tag makes the browser load along with the
+// page; links to other pages are not subresources.
+func subresources(tok html.Token) []string {
+	var refs []string
+	for _, a := range tok.Attr {
+		switch {
+		case a.Key == "src" && (tok.Data == "script" || tok.Data == "img" || tok.Data == "iframe" ||
+			tok.Data == "audio" || tok.Data == "video" || tok.Data == "source" || tok.Data == "embed"):
+			refs = append(refs, a.Val)
+		case a.Key == "srcset" && (tok.Data == "img" || tok.Data == "source"):
+			for _, candidate := range strings.Split(a.Val, ",") {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					refs = append(refs, fields[0])
+				}
+			}
+		case a.Key == "data" && tok.Data == "object":
+			refs = append(refs, a.Val)
+		case a.Key == "href" && tok.Data == "link" && loadsResource(attr(tok, "rel")):
+			refs = append(refs, a.Val)
+		}
+	}
+	return refs
+}
+
+// loadsResource reports whether a <link> with rel is fetched with the page.
+func loadsResource(rel string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet", "icon", "preload", "modulepreload", "manifest":
+			return true
+		}
+	}
+	return false
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(tok html.Token, key string) bool {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isPlainHTTP(ref string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(ref)), "http://")
+}
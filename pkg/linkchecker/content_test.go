@@ -0,0 +1,108 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const mixedPage = `<!doctype html>
+<html><head>
+<link rel="stylesheet" href="http://cdn.test/site.css">
+<link rel="alternate" href="http://old.test/">
+<script src="https://cdn.test/app.js"></script>
+<script src="HTTP://cdn.test/legacy.js"></script>
+</head><body>
+<a href="http://partner.test/">partner</a>
+<img src="/logo.png" srcset="http://cdn.test/a.png 1x, https://cdn.test/b.png 2x">
+<iframe src="//video.test/embed"></iframe>
+</body></html>`
+
+// pageClient serves body as an HTML page for every request.
+type pageClient string
+
+func (c pageClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(string(c))),
+		Request:    req,
+	}, nil
+}
+
+func TestChecker_MixedContent(t *testing.T) {
+	c := New(Options{Client: pageClient(mixedPage), Resolver: publicResolver})
+
+	if r := c.CheckLink(context.Background(), "site.test"); r.Findings != nil {
+		t.Fatalf("expected no findings without content mode, got %+v", r.Findings)
+	}
+
+	r := c.CheckLink(WithContent(context.Background()), "site.test")
+	want := []Finding{
+		{Kind: FindingMixedContent, URL: "http://cdn.test/site.css", Detail: "link"},
+		{Kind: FindingMixedContent, URL: "HTTP://cdn.test/legacy.js", Detail: "script"},
+		{Kind: FindingMixedContent, URL: "http://cdn.test/a.png", Detail: "img"},
+	}
+	if r.Status != StatusAvailable || len(r.Findings) != len(want) {
+		t.Fatalf("got %+v", r)
+	}
+	for i := range want {
+		if r.Findings[i] != want[i] {
+			t.Fatalf("finding %d = %+v, want %+v", i, r.Findings[i], want[i])
+		}
+	}
+}
+
+// weightClient serves page for GET requests and answers HEAD requests with
+// the Content-Length in sizes, or 404 for unknown URLs.
+type weightClient struct {
+	page  string
+	sizes map[string]int64
+}
+
+func (c weightClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodHead {
+		return pageClient(c.page).Do(req)
+	}
+	size, ok := c.sizes[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, ContentLength: size, Body: http.NoBody, Request: req}, nil
+}
+
+func TestChecker_PageWeight(t *testing.T) {
+	const page = `<html><head>
+<link rel="stylesheet" href="/site.css">
+<script src="https://cdn.test/app.js"></script>
+<script src="https://cdn.test/app.js#again"></script>
+</head><body>
+<img src="data:image/png;base64,AAAA">
+<img src="/logo.png" srcset="/logo.png 1x, /logo@2x.png 2x">
+</body></html>`
+	client := weightClient{page: page, sizes: map[string]int64{
+		"https://site.test/site.css": 1000,
+		"https://cdn.test/app.js":    5000,
+		"https://site.test/logo.png": 300,
+	}}
+	c := New(Options{Client: client, Resolver: publicResolver})
+
+	if r := c.CheckLink(context.Background(), "site.test"); r.Weight != nil {
+		t.Fatalf("expected no weight without content mode, got %+v", r.Weight)
+	}
+
+	r := c.CheckLink(WithContent(context.Background()), "site.test")
+	want := PageWeight{
+		HTMLBytes:     int64(len(page)),
+		Resources:     4,
+		ResourceBytes: 6300,
+		UnknownSize:   1,
+		TotalBytes:    int64(len(page)) + 6300,
+	}
+	if r.Weight == nil || *r.Weight != want {
+		t.Fatalf("weight = %+v, want %+v", r.Weight, want)
+	}
+}
@@ -0,0 +1,39 @@
+package linkchecker
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+type jarKey struct{}
+
+// WithCookies returns a context under which check requests share a new
+// cookie jar, so that sites which set a cookie and redirect back before
+// serving content are reported correctly. Every call starts an empty jar;
+// use one per batch of links. It only works with an *http.Client; the
+// client's own Jar, if any, is replaced for these requests.
+func WithCookies(ctx context.Context) context.Context {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return context.WithValue(ctx, jarKey{}, jar)
+}
+
+func cookieJar(ctx context.Context) http.CookieJar {
+	jar, _ := ctx.Value(jarKey{}).(http.CookieJar)
+	return jar
+}
+
+// clientFor returns the client for requests under ctx: the checker's client
+// with the cookie jar of ctx if there is one.
+func (c *Checker) clientFor(ctx context.Context) HTTPClient {
+	jar := cookieJar(ctx)
+	hc, ok := c.client.(*http.Client)
+	if jar == nil || !ok {
+		return c.client
+	}
+	cp := *hc
+	cp.Jar = jar
+	return &cp
+}
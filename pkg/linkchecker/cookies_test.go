@@ -0,0 +1,34 @@
+package linkchecker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// cookieBounce redirects requests without a session cookie to a URL that
+// sets it and redirects back.
+var cookieBounce = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: http.Header{}, Body: http.NoBody, Request: req}
+	switch {
+	case req.URL.Path == "/session":
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Set-Cookie", "sid=1; Path=/")
+		resp.Header.Set("Location", "https://"+req.URL.Host)
+	case req.Header.Get("Cookie") == "":
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Location", "https://"+req.URL.Host+"/session")
+	}
+	return resp, nil
+})
+
+func TestChecker_Cookies(t *testing.T) {
+	c := New(Options{Client: &http.Client{Transport: cookieBounce}, Resolver: publicResolver, Retry: &RetryPolicy{}})
+
+	if r := c.CheckLink(context.Background(), "app.test"); r.ErrorKind != ErrorRedirectLoop {
+		t.Fatalf("without cookies: got %+v", r)
+	}
+	if r := c.CheckLink(WithCookies(context.Background()), "app.test"); r.Status != StatusAvailable {
+		t.Fatalf("with cookies: got %+v", r)
+	}
+}
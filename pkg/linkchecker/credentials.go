@@ -0,0 +1,88 @@
+package linkchecker
+
+import (
+	"context"
+	"net/http"
+	urlpkg "net/url"
+	"strings"
+)
+
+// Credentials authenticate check requests to sites behind a login, such as
+// staging environments. Token, if set, is sent as a bearer token; otherwise
+// Username and Password are sent with basic auth. They are sent only over
+// HTTPS and SFTP and only to Hosts, compared without ports; with no Hosts
+// they are not sent at all.
+//
+// Credentials format as "[redacted]" so that they do not end up in logs.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+	Hosts    []string
+}
+
+func (Credentials) String() string   { return "[redacted]" }
+func (Credentials) GoString() string { return "[redacted]" }
+
+type credentialsKey struct{}
+
+// WithCredentials returns a context under which check requests to the hosts
+// of creds carry them. They are sent to the checked links and to canonical
+// and hreflang links on the same host, but not with robots.txt fetches or
+// HTTP/3 probes, and they are dropped when a redirect leaves HTTPS or the
+// host. FTP logins never use them.
+func WithCredentials(ctx context.Context, creds Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, creds)
+}
+
+func credentials(ctx context.Context) (Credentials, bool) {
+	creds, ok := ctx.Value(credentialsKey{}).(Credentials)
+	return creds, ok
+}
+
+// allows reports whether creds may be sent to host.
+func (creds Credentials) allows(host string) bool {
+	for _, h := range creds.Hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize adds the credentials of ctx, if any, to req when it goes over
+// HTTPS to one of their hosts.
+func authorize(ctx context.Context, req *http.Request) {
+	creds, ok := credentials(ctx)
+	if !ok || req.URL.Scheme != "https" || !creds.allows(req.URL.Hostname()) {
+		return
+	}
+	if creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+		return
+	}
+	req.SetBasicAuth(creds.Username, creds.Password)
+}
+
+// linkLogin returns the user name and password written in the link u; ok is
+// false if it has no user.
+func linkLogin(u *urlpkg.URL) (user, password string, ok bool) {
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", false
+	}
+	password, _ = u.User.Password()
+	return u.User.Username(), password, true
+}
+
+// login returns the user name and password for an encrypted non-HTTP link:
+// those in the link itself, else the basic credentials of ctx if they allow
+// its host. ok is false if there are neither.
+func login(ctx context.Context, u *urlpkg.URL) (user, password string, ok bool) {
+	if user, password, ok = linkLogin(u); ok {
+		return user, password, true
+	}
+	if creds, found := credentials(ctx); found && creds.Username != "" && creds.allows(u.Hostname()) {
+		return creds.Username, creds.Password, true
+	}
+	return "", "", false
+}
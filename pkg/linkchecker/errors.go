@@ -0,0 +1,92 @@
+package linkchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+)
+
+// ErrorKind classifies why a link is not available.
+type ErrorKind string
+
+const (
+	// ErrorInvalidLink means the link is not a bare host name, a
+	// scheme://target link of a registered scheme or a mailto: address.
+	ErrorInvalidLink ErrorKind = "invalid_link"
+	// ErrorPrivateAddress means the host resolves to a private address and
+	// AllowPrivate is not set.
+	ErrorPrivateAddress ErrorKind = "private_address"
+	// ErrorCircuitOpen means the breaker skipped a host that keeps failing.
+	ErrorCircuitOpen ErrorKind = "circuit_open"
+	// ErrorDNS means the host name could not be resolved.
+	ErrorDNS ErrorKind = "dns"
+	// ErrorTimeout means the request did not finish in time.
+	ErrorTimeout ErrorKind = "timeout"
+	// ErrorTLS means the TLS handshake or certificate verification failed.
+	ErrorTLS ErrorKind = "tls"
+	// ErrorConnection covers other network failures, such as a refused or
+	// reset connection.
+	ErrorConnection ErrorKind = "connection"
+	// ErrorHTTPStatus means the host answered with a 4xx or 5xx status.
+	ErrorHTTPStatus ErrorKind = "http_status"
+	// ErrorRedirect means the host answered with a redirect that was not
+	// followed; see WithoutRedirects.
+	ErrorRedirect ErrorKind = "redirect"
+	// ErrorRedirectLoop means the redirects came back to a URL already
+	// visited or did not end after 10 hops.
+	ErrorRedirectLoop ErrorKind = "redirect_loop"
+	// ErrorCanceled means the check was cancelled, e.g. by fail-fast.
+	ErrorCanceled ErrorKind = "canceled"
+	// ErrorSkipped means the link was never requested; see Result.Skipped.
+	ErrorSkipped ErrorKind = "skipped"
+	// ErrorSkippedRobots means robots.txt of the host disallows the link;
+	// see Options.Robots.
+	ErrorSkippedRobots ErrorKind = "skipped_robots"
+	// ErrorUndeliverable means the domain of a mailto: link accepts no mail
+	// or its mail exchanger rejected the recipient.
+	ErrorUndeliverable ErrorKind = "undeliverable"
+	// ErrorRejected means the server of an ftp:// or sftp:// link refused
+	// the login or reported that the path does not exist.
+	ErrorRejected ErrorKind = "rejected"
+	// ErrorMaintenance means the host is in a maintenance window and the
+	// link was not requested; see Options.Maintenance.
+	ErrorMaintenance ErrorKind = "maintenance"
+	// ErrorFlaggedUnsafe means a blocklist lists the link as malware or
+	// phishing and it was not requested; see Options.Blocklist.
+	ErrorFlaggedUnsafe ErrorKind = "flagged_unsafe"
+)
+
+// classify maps a request error to its kind. The checks are ordered from the
+// most to the least specific, as a TLS or DNS error may also be a net.Error.
+func classify(err error) ErrorKind {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errRedirectLoop):
+		return ErrorRedirectLoop
+	case errors.Is(err, errPrivateDial):
+		return ErrorPrivateAddress
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return ErrorTimeout
+		}
+		return ErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ErrorTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	default:
+		return ErrorConnection
+	}
+}
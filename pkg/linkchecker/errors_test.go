@@ -0,0 +1,68 @@
+package linkchecker
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"dns", &url.Error{Op: "Get", URL: "https://x.test", Err: &net.DNSError{Err: "no such host", Name: "x.test", IsNotFound: true}}, ErrorDNS},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, ErrorTimeout},
+		{"deadline", &url.Error{Op: "Get", Err: context.DeadlineExceeded}, ErrorTimeout},
+		{"net timeout", &net.OpError{Op: "dial", Err: timeoutErr{}}, ErrorTimeout},
+		{"tls", fmt.Errorf("handshake: %w", x509.UnknownAuthorityError{}), ErrorTLS},
+		{"canceled", &url.Error{Op: "Get", Err: context.Canceled}, ErrorCanceled},
+		{"refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorConnection},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classify(tc.err); got != tc.want {
+				t.Fatalf("classify(%v) = %q, want %q", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestChecker_ErrorDetails(t *testing.T) {
+	c := New(Options{
+		Timeout:  time.Second,
+		Client:   statusClient{"down.test": http.StatusInternalServerError},
+		Resolver: publicResolver,
+	})
+
+	got := c.Check(context.Background(), []string{"down.test", "bad/link"}, nil)
+	if r := got["down.test"]; r.ErrorKind != ErrorHTTPStatus || r.Error == "" {
+		t.Fatalf("down.test: got %+v", r)
+	}
+	if r := got["bad/link"]; r.ErrorKind != ErrorInvalidLink {
+		t.Fatalf("bad/link: got %+v", r)
+	}
+
+	strict := New(Options{Resolver: func(string) ([]net.IP, error) {
+		return nil, &net.DNSError{Err: "no such host", IsNotFound: true}
+	}})
+	if r := strict.CheckLink(context.Background(), "missing.test"); r.ErrorKind != ErrorDNS {
+		t.Fatalf("missing.test: got %+v", r)
+	}
+	if r := strict.CheckLink(context.Background(), "127.0.0.1"); r.ErrorKind != ErrorPrivateAddress {
+		t.Fatalf("127.0.0.1: got %+v", r)
+	}
+}
@@ -0,0 +1,130 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	urlpkg "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeFTP checks an ftp://host/path link: it logs in, anonymously unless
+// the link supplies a user, and lists path over a passive data connection.
+// Credentials from WithCredentials are not used: FTP sends them in clear
+// text.
+func probeFTP(ctx context.Context, u *urlpkg.URL) Result {
+	user, password, ok := linkLogin(u)
+	if !ok {
+		user, password = "anonymous", "anonymous@"
+	}
+	if strings.ContainsAny(u.Path+user+password, "\r\n") {
+		return failed(ErrorInvalidLink, "ftp link contains a line break")
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "21")
+	}
+
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return failed(classify(err), err.Error())
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+	tp := textproto.NewConn(conn)
+
+	if _, _, err := tp.ReadResponse(2); err != nil {
+		return ftpFailed(ctx, err)
+	}
+	code, _, err := ftpCmd(tp, 0, "USER %s", user)
+	if err == nil && code == 331 {
+		code, _, err = ftpCmd(tp, 2, "PASS %s", password)
+	}
+	if err == nil && code/100 != 2 {
+		err = &textproto.Error{Code: code, Msg: "login failed"}
+	}
+	if err != nil {
+		return ftpFailed(ctx, err)
+	}
+
+	data, err := ftpPassive(ctx, tp, conn)
+	if err != nil {
+		return ftpFailed(ctx, err)
+	}
+	defer data.Close()
+	list := "LIST"
+	if u.Path != "" && u.Path != "/" {
+		list += " " + u.Path
+	}
+	if _, _, err := ftpCmd(tp, 1, "%s", list); err != nil {
+		return ftpFailed(ctx, err)
+	}
+	_, _ = io.Copy(io.Discard, data)
+	if _, _, err := tp.ReadResponse(2); err != nil {
+		return ftpFailed(ctx, err)
+	}
+	_, _, _ = ftpCmd(tp, 0, "QUIT")
+	return Result{Status: StatusAvailable, Protocol: "FTP"}
+}
+
+// ftpPassive opens a data connection. The address is taken from the control
+// connection, whatever the server announces in its PASV reply, so that a
+// server cannot point the checker at another host.
+func ftpPassive(ctx context.Context, tp *textproto.Conn, control net.Conn) (net.Conn, error) {
+	port := 0
+	_, msg, err := ftpCmd(tp, 229, "EPSV")
+	if err == nil {
+		// 229 Entering Extended Passive Mode (|||port|)
+		if i, j := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)"); i >= 0 && j > i+4 {
+			port, _ = strconv.Atoi(msg[i+4 : j])
+		}
+	} else if _, msg, err = ftpCmd(tp, 227, "PASV"); err == nil {
+		// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+		fields := strings.Split(strings.Trim(msg[strings.IndexByte(msg, '(')+1:], ").\r\n "), ",")
+		if len(fields) == 6 {
+			hi, _ := strconv.Atoi(fields[4])
+			lo, _ := strconv.Atoi(fields[5])
+			port = hi<<8 | lo
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("unexpected passive mode reply %q", msg)
+	}
+	host, _, _ := net.SplitHostPort(control.RemoteAddr().String())
+	return dial(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// ftpCmd sends a command and reads its reply; see textproto.Conn.ReadResponse
+// for expect.
+func ftpCmd(tp *textproto.Conn, expect int, format string, args ...any) (int, string, error) {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+	return tp.ReadResponse(expect)
+}
+
+// ftpFailed reports an error reply as ErrorRejected and anything else by its
+// network class.
+func ftpFailed(ctx context.Context, err error) Result {
+	var tpErr *textproto.Error
+	switch {
+	case ctx.Err() != nil:
+		return failed(classify(ctx.Err()), err.Error())
+	case errors.As(err, &tpErr):
+		return failed(ErrorRejected, fmt.Sprintf("%d %s", tpErr.Code, tpErr.Msg))
+	default:
+		return failed(classify(err), err.Error())
+	}
+}
@@ -0,0 +1,123 @@
+package linkchecker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ftpServer serves the directory listings in dirs to user "anonymous" and
+// to "qa" with password "secret".
+func ftpServer(t *testing.T, dirs map[string]string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFTP(conn, dirs)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFTP(conn net.Conn, dirs map[string]string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) { fmt.Fprintf(conn, format+"\r\n", args...) }
+	reply("220 ftp.test ready")
+	var user string
+	var data net.Listener
+	defer func() {
+		if data != nil {
+			data.Close()
+		}
+	}()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch cmd {
+		case "USER":
+			user = arg
+			reply("331 password please")
+		case "PASS":
+			if user == "anonymous" || user == "qa" && arg == "secret" {
+				reply("230 logged in")
+			} else {
+				reply("530 login incorrect")
+			}
+		case "EPSV":
+			data, _ = net.Listen("tcp", "127.0.0.1:0")
+			reply("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "LIST":
+			if arg == "" {
+				arg = "/"
+			}
+			listing, ok := dirs[arg]
+			if !ok || data == nil {
+				reply("550 no such file or directory")
+				continue
+			}
+			reply("150 here it comes")
+			if dc, err := data.Accept(); err == nil {
+				fmt.Fprint(dc, listing)
+				dc.Close()
+			}
+			reply("226 done")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestChecker_FTP(t *testing.T) {
+	addr := ftpServer(t, map[string]string{
+		"/":         "drwxr-xr-x 2 ftp ftp 4096 Jan 1 00:00 pub\r\n",
+		"/pub/a.gz": "-rw-r--r-- 1 ftp ftp 42 Jan 1 00:00 a.gz\r\n",
+	})
+	c := New(Options{Timeout: time.Second, AllowPrivate: true})
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		link  string
+		kind  ErrorKind
+		avail bool
+	}{
+		{"anonymous root", context.Background(), "ftp://" + addr, "", true},
+		{"file", context.Background(), "ftp://" + addr + "/pub/a.gz", "", true},
+		{"missing", context.Background(), "ftp://" + addr + "/pub/b.gz", ErrorRejected, false},
+		{"user in link", context.Background(), "ftp://qa:secret@" + addr + "/", "", true},
+		{"wrong password", context.Background(), "ftp://qa:nope@" + addr + "/", ErrorRejected, false},
+		// FTP не получает учётные данные запроса: вход остаётся анонимным
+		{"credentials unused", WithCredentials(context.Background(), Credentials{Username: "qa", Password: "nope", Hosts: []string{"127.0.0.1"}}), "ftp://" + addr + "/pub/a.gz", "", true},
+		{"line break", context.Background(), "ftp://" + addr + "/a%0D%0ADELE%20x", ErrorInvalidLink, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := c.CheckLink(tc.ctx, tc.link)
+			if (res.Status == StatusAvailable) != tc.avail || res.ErrorKind != tc.kind {
+				t.Fatalf("got %+v", res)
+			}
+			if tc.avail && res.Protocol != "FTP" {
+				t.Fatalf("protocol %q", res.Protocol)
+			}
+		})
+	}
+}
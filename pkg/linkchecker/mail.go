@@ -0,0 +1,193 @@
+package linkchecker
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	urlpkg "net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxMXProbes bounds how many mail exchangers of a domain the SMTP probe
+// tries before settling for DeliverabilityLikely.
+const maxMXProbes = 2
+
+// Deliverability estimates whether mail to a mailto: link would be accepted.
+type Deliverability string
+
+const (
+	// DeliverabilityDeliverable means a mail exchanger accepted the
+	// recipient; see Options.SMTP.
+	DeliverabilityDeliverable Deliverability = "deliverable"
+	// DeliverabilityLikely means the domain accepts mail but the mailbox
+	// was not verified, because the SMTP probe is disabled or inconclusive.
+	DeliverabilityLikely Deliverability = "likely"
+	// DeliverabilityUndeliverable means the domain accepts no mail or a
+	// mail exchanger rejected the recipient.
+	DeliverabilityUndeliverable Deliverability = "undeliverable"
+)
+
+// SMTPProbe asks the mail exchanger of a mailto: link whether it accepts the
+// recipient: it connects to port 25, sends EHLO, MAIL FROM and RCPT TO and
+// quits without sending a message. Many providers accept any recipient or
+// block such probes, so a rejection is conclusive and an acceptance is not
+// a guarantee.
+type SMTPProbe struct {
+	helo string
+	from string
+	port string
+}
+
+// NewSMTPProbe returns a probe that greets with helo and uses from as the
+// envelope sender. An empty helo defaults to "localhost" and an empty from
+// sends the null sender "<>".
+func NewSMTPProbe(helo, from string) *SMTPProbe {
+	if helo == "" {
+		helo = "localhost"
+	}
+	return &SMTPProbe{helo: helo, from: from, port: "25"}
+}
+
+// checkMail checks a mailto:user@domain link by the MX records of the domain
+// and, with Options.SMTP, by asking a mail exchanger about the recipient.
+func (c *Checker) checkMail(ctx context.Context, link string) Result {
+	addr, domain, ok := parseMailto(link)
+	if !ok {
+		return failed(ErrorInvalidLink, "mailto link must be mailto:user@domain")
+	}
+	if c.inMaintenance(domain) {
+		return maintenanceSkip
+	}
+	if c.breaker != nil && !c.breaker.Allow(domain) {
+		return failed(ErrorCircuitOpen, "host skipped after repeated failures")
+	}
+
+	start := time.Now()
+	res := c.probeMail(c.probeContext(ctx), addr, domain)
+	res.CheckedAt, res.Duration = start, time.Since(start)
+	// отказ в доставке — ответ домена, а не сбой
+	if c.breaker != nil {
+		if res.Deliverability != "" {
+			c.breaker.Success(domain)
+		} else {
+			c.breaker.Failure(domain)
+		}
+	}
+	return res
+}
+
+func (c *Checker) probeMail(ctx context.Context, addr, domain string) Result {
+	mxs, err := c.lookupMX(domain)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return failed(classify(err), err.Error())
+	}
+	if len(mxs) == 0 {
+		// без MX почта идёт на A/AAAA домена (RFC 5321, 5.1)
+		if ips, err := c.resolve(domain); err != nil || len(ips) == 0 {
+			return undeliverable("domain has no mail exchanger")
+		}
+		mxs = []*net.MX{{Host: domain}}
+	}
+	if len(mxs) == 1 && (mxs[0].Host == "." || mxs[0].Host == "") {
+		return undeliverable("domain accepts no mail (null MX)")
+	}
+
+	likely := Result{Status: StatusAvailable, Protocol: "DNS", Deliverability: DeliverabilityLikely}
+	if c.smtp == nil {
+		return likely
+	}
+	slices.SortStableFunc(mxs, func(a, b *net.MX) int { return cmp.Compare(a.Pref, b.Pref) })
+	tried := 0
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		if !c.allowPrivate {
+			if private, _ := c.isPrivateHost(host); private {
+				continue
+			}
+		}
+		if tried == maxMXProbes {
+			break
+		}
+		tried++
+		accepted, err := c.smtp.rcpt(ctx, host, addr)
+		var tpErr *textproto.Error
+		switch {
+		case err == nil && accepted:
+			return Result{Status: StatusAvailable, Protocol: "SMTP", Deliverability: DeliverabilityDeliverable}
+		case errors.As(err, &tpErr) && tpErr.Code >= 500 && !accepted:
+			return undeliverable("recipient rejected: " + tpErr.Error())
+		}
+		// 4xx, отказ отправителю или недоступный сервер ничего не говорят о ящике
+	}
+	return likely
+}
+
+// rcpt reports whether the mail exchanger host accepts addr as a recipient.
+// A rejected recipient is returned as a *textproto.Error with accepted
+// false; errors before RCPT TO are returned with accepted true so that
+// callers cannot mistake them for a rejection.
+func (p *SMTPProbe) rcpt(ctx context.Context, host, addr string) (accepted bool, err error) {
+	conn, err := dial(ctx, "tcp", net.JoinHostPort(host, p.port))
+	if err != nil {
+		return true, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return true, err
+	}
+	defer client.Close()
+	if err := client.Hello(p.helo); err != nil {
+		return true, err
+	}
+	if err := client.Mail(p.from); err != nil {
+		return true, err
+	}
+	if err := client.Rcpt(addr); err != nil {
+		return false, err
+	}
+	_ = client.Quit()
+	return true, nil
+}
+
+func undeliverable(msg string) Result {
+	res := failed(ErrorUndeliverable, msg)
+	res.Deliverability = DeliverabilityUndeliverable
+	return res
+}
+
+// isMailto reports whether link uses the mailto: scheme.
+func isMailto(link string) bool {
+	return len(link) >= 7 && strings.EqualFold(link[:7], "mailto:")
+}
+
+// parseMailto returns the single recipient of a mailto: link and its
+// lowercased domain. Header fields such as ?subject= are ignored.
+func parseMailto(link string) (addr, domain string, ok bool) {
+	u, err := urlpkg.Parse(link)
+	if err != nil || u.Opaque == "" {
+		return "", "", false
+	}
+	addr, err = urlpkg.PathUnescape(u.Opaque)
+	if err != nil || strings.ContainsAny(addr, ",;<> \r\n") {
+		return "", "", false
+	}
+	at := strings.LastIndexByte(addr, '@')
+	if at <= 0 || at == len(addr)-1 {
+		return "", "", false
+	}
+	domain = strings.TrimSuffix(strings.ToLower(addr[at+1:]), ".")
+	if !ValidLink(domain) {
+		return "", "", false
+	}
+	return addr, domain, true
+}
@@ -0,0 +1,132 @@
+package linkchecker
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer accepts RCPT TO only for mailboxes in known.
+func smtpServer(t *testing.T, known ...string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+				reply("220 mx.test ESMTP")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.ToUpper(strings.TrimSpace(line))
+					switch {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "MAIL"):
+						reply("250 OK")
+					case strings.HasPrefix(cmd, "RCPT"):
+						ok := false
+						for _, k := range known {
+							ok = ok || strings.Contains(cmd, "<"+strings.ToUpper(k)+">")
+						}
+						if ok {
+							reply("250 OK")
+						} else {
+							reply("550 5.1.1 no such user")
+						}
+					case strings.HasPrefix(cmd, "QUIT"):
+						reply("221 bye")
+						return
+					default:
+						reply("502 not implemented")
+					}
+				}
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func mxResolver(records map[string][]*net.MX) func(string) ([]*net.MX, error) {
+	return func(domain string) ([]*net.MX, error) {
+		if mx, ok := records[domain]; ok {
+			return mx, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: domain, IsNotFound: true}
+	}
+}
+
+func TestChecker_Mailto(t *testing.T) {
+	probe := NewSMTPProbe("checker.test", "")
+	probe.port = smtpServer(t, "info@mail.test")
+	records := map[string][]*net.MX{
+		"mail.test":   {{Host: "127.0.0.1.", Pref: 10}},
+		"nomail.test": {{Host: ".", Pref: 0}},
+	}
+	resolver := func(host string) ([]net.IP, error) {
+		if host == "web.test" {
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	ctx := context.Background()
+
+	dnsOnly := New(Options{Timeout: time.Second, MXResolver: mxResolver(records), Resolver: resolver})
+	probing := New(Options{Timeout: time.Second, MXResolver: mxResolver(records), Resolver: resolver, SMTP: probe, AllowPrivate: true})
+
+	tests := []struct {
+		name    string
+		c       *Checker
+		link    string
+		status  Status
+		deliver Deliverability
+		kind    ErrorKind
+	}{
+		{"mx only", dnsOnly, "mailto:anyone@mail.test", StatusAvailable, DeliverabilityLikely, ""},
+		{"implicit mx", dnsOnly, "mailto:anyone@web.test", StatusAvailable, DeliverabilityLikely, ""},
+		{"no mail", dnsOnly, "mailto:anyone@void.test", StatusNotAvailable, DeliverabilityUndeliverable, ErrorUndeliverable},
+		{"null mx", dnsOnly, "mailto:anyone@nomail.test", StatusNotAvailable, DeliverabilityUndeliverable, ErrorUndeliverable},
+		{"accepted", probing, "MAILTO:info@Mail.test?subject=hi", StatusAvailable, DeliverabilityDeliverable, ""},
+		{"rejected", probing, "mailto:nobody@mail.test", StatusNotAvailable, DeliverabilityUndeliverable, ErrorUndeliverable},
+		{"no domain", dnsOnly, "mailto:nobody", StatusNotAvailable, "", ErrorInvalidLink},
+		{"two recipients", dnsOnly, "mailto:a@mail.test,b@mail.test", StatusNotAvailable, "", ErrorInvalidLink},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := tc.c.CheckLink(ctx, tc.link)
+			if res.Status != tc.status || res.Deliverability != tc.deliver || res.ErrorKind != tc.kind {
+				t.Fatalf("got %+v", res)
+			}
+		})
+	}
+}
+
+func TestChecker_MailtoSkipsPrivateMX(t *testing.T) {
+	probe := NewSMTPProbe("", "")
+	probe.port = smtpServer(t)
+	c := New(Options{
+		Timeout:    time.Second,
+		MXResolver: mxResolver(map[string][]*net.MX{"mail.test": {{Host: "127.0.0.1", Pref: 10}}}),
+		SMTP:       probe,
+	})
+
+	// без AllowPrivate внутренний MX не опрашивается, остаётся только DNS
+	res := c.CheckLink(context.Background(), "mailto:nobody@mail.test")
+	if res.Status != StatusAvailable || res.Deliverability != DeliverabilityLikely {
+		t.Fatalf("got %+v", res)
+	}
+}
@@ -0,0 +1,132 @@
+package linkchecker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	urlpkg "net/url"
+	"strings"
+)
+
+// maxPageLinks bounds how many canonical and hreflang URLs of one page are
+// requested, so that a page with hundreds of alternates cannot turn one
+// check into hundreds of requests.
+const maxPageLinks = 20
+
+// pageFindings returns the findings of pg: its mixed content and problems
+// with its canonical and hreflang links, whose targets are requested without
+// following redirects.
+func (c *Checker) pageFindings(ctx context.Context, pg *page) []Finding {
+	findings := pg.findings
+	budget := maxPageLinks
+
+	if len(pg.canonical) > 1 {
+		findings = append(findings, Finding{Kind: FindingCanonical, URL: strings.Join(pg.canonical, " "), Detail: "multiple canonical links"})
+	}
+	for _, href := range pg.canonical {
+		if problem := c.verifyPageLink(ctx, pg.url, href, &budget); problem != "" {
+			findings = append(findings, Finding{Kind: FindingCanonical, URL: href, Detail: problem})
+		}
+	}
+
+	seen := make(map[string]string, len(pg.alternates))
+	for _, alt := range pg.alternates {
+		if !validHreflang(alt.lang) {
+			findings = append(findings, Finding{Kind: FindingHreflang, URL: alt.href, Detail: fmt.Sprintf("invalid language code %q", alt.lang)})
+			continue
+		}
+		lang := strings.ToLower(alt.lang)
+		if prev, ok := seen[lang]; ok {
+			if prev != alt.href {
+				findings = append(findings, Finding{Kind: FindingHreflang, URL: alt.href, Detail: fmt.Sprintf("%s also points to %s", alt.lang, prev)})
+			}
+			continue
+		}
+		seen[lang] = alt.href
+		if problem := c.verifyPageLink(ctx, pg.url, alt.href, &budget); problem != "" {
+			findings = append(findings, Finding{Kind: FindingHreflang, URL: alt.href, Detail: alt.lang + ": " + problem})
+		}
+	}
+	return findings
+}
+
+// verifyPageLink requests href, relative to base, and describes why it is
+// not an available page, or returns "" if it is. A link to the page itself
+// is not requested again, and neither is anything once budget runs out.
+func (c *Checker) verifyPageLink(ctx context.Context, base *urlpkg.URL, href string, budget *int) string {
+	if href == "" {
+		return "empty href"
+	}
+	ref, err := base.Parse(href)
+	if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || ref.Host == "" {
+		return "invalid URL"
+	}
+	ref.Fragment = ""
+	if ref.String() == base.String() || *budget <= 0 {
+		return ""
+	}
+	*budget--
+
+	if !c.allowPrivate {
+		if private, err := c.isPrivateHost(ref.Hostname()); err != nil {
+			return err.Error()
+		} else if private {
+			return "host resolves to a private address"
+		}
+	}
+	req, err := http.NewRequestWithContext(WithoutRedirects(ctx), http.MethodGet, ref.String(), nil)
+	if err != nil {
+		return "invalid URL"
+	}
+	if ref.Host == base.Host {
+		authorize(ctx, req)
+	}
+	resp, err := c.clientFor(ctx).Do(req)
+	if err != nil {
+		return err.Error()
+	}
+	defer drainAndClose(resp.Body)
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return ""
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		return "redirects to " + resp.Header.Get("Location")
+	default:
+		return "status " + resp.Status
+	}
+}
+
+// validHreflang reports whether lang is "x-default" or a language tag such
+// as "en" or "pt-BR".
+func validHreflang(lang string) bool {
+	if strings.EqualFold(lang, "x-default") {
+		return true
+	}
+	for i, part := range strings.Split(lang, "-") {
+		if i == 0 && (len(part) < 2 || len(part) > 3 || !isAlpha(part)) {
+			return false
+		}
+		if len(part) == 0 || len(part) > 8 || !isAlnum(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
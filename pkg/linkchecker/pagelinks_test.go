@@ -0,0 +1,73 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// siteClient serves HTML pages and statuses by URL; unknown URLs get 404.
+type siteClient map[string]string
+
+func (c siteClient) Do(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Proto: "HTTP/1.1", Header: http.Header{}, Body: http.NoBody, Request: req}
+	body, ok := c[req.URL.String()]
+	switch {
+	case !ok:
+	case strings.HasPrefix(body, "redirect:"):
+		resp.StatusCode, resp.Status = http.StatusMovedPermanently, "301 Moved Permanently"
+		resp.Header.Set("Location", strings.TrimPrefix(body, "redirect:"))
+	default:
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+		resp.Header.Set("Content-Type", "text/html")
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return resp, nil
+}
+
+func TestChecker_CanonicalAndHreflang(t *testing.T) {
+	client := siteClient{
+		"https://site.test": `<html><head>
+<link rel="canonical" href="https://www.site.test/">
+<link rel="alternate" hreflang="en" href="https://site.test">
+<link rel="alternate" hreflang="de" href="/de/">
+<link rel="alternate" hreflang="fr" href="/fr/">
+<link rel="alternate" hreflang="EN" href="/en-old/">
+<link rel="alternate" hreflang="english" href="/en/">
+<link rel="alternate" hreflang="x-default" href="https://site.test">
+</head></html>`,
+		"https://www.site.test/": "redirect:https://www.site.test/home",
+		"https://site.test/de/":  "<html></html>",
+		"https://site.test/en/":  "<html></html>",
+	}
+	c := New(Options{Client: client, Resolver: publicResolver})
+
+	r := c.CheckLink(WithContent(context.Background()), "site.test")
+	if r.Status != StatusAvailable {
+		t.Fatalf("got %+v", r)
+	}
+	want := map[string]Finding{
+		"https://www.site.test/": {Kind: FindingCanonical, URL: "https://www.site.test/", Detail: "redirects to https://www.site.test/home"},
+		"/fr/":                   {Kind: FindingHreflang, URL: "/fr/", Detail: "fr: status 404 Not Found"},
+		"/en-old/":               {Kind: FindingHreflang, URL: "/en-old/", Detail: "EN also points to https://site.test"},
+		"/en/":                   {Kind: FindingHreflang, URL: "/en/", Detail: `invalid language code "english"`},
+	}
+	if len(r.Findings) != len(want) {
+		t.Fatalf("findings = %+v", r.Findings)
+	}
+	for _, f := range r.Findings {
+		if want[f.URL] != f {
+			t.Fatalf("unexpected finding %+v", f)
+		}
+	}
+}
+
+func TestValidHreflang(t *testing.T) {
+	for lang, want := range map[string]bool{"en": true, "pt-BR": true, "zh-Hant-TW": true, "x-default": true, "e": false, "english": false, "en_US": false, "en-": false} {
+		if got := validHreflang(lang); got != want {
+			t.Errorf("validHreflang(%q) = %v, want %v", lang, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	urlpkg "net/url"
+	"sync"
+)
+
+const (
+	// maxPageResources bounds how many subresources of one page are
+	// requested to estimate its weight.
+	maxPageResources = 50
+	// weightWorkers is how many subresources of a page are requested at once.
+	weightWorkers = 4
+)
+
+// PageWeight estimates how much a browser transfers to show a page: the
+// HTML itself and the subresources it loads, whose sizes are taken from the
+// Content-Length of HEAD requests.
+type PageWeight struct {
+	// HTMLBytes is the size of the page.
+	HTMLBytes int64
+	// Resources is the number of distinct subresources the page loads.
+	Resources int
+	// ResourceBytes is the total size of the subresources whose size is
+	// known; UnknownSize counts those that did not answer with a
+	// Content-Length, failed or were beyond maxPageResources.
+	ResourceBytes int64
+	UnknownSize   int
+	// TotalBytes is HTMLBytes plus ResourceBytes.
+	TotalBytes int64
+}
+
+// pageWeight estimates the weight of pg by sending HEAD requests to its
+// distinct http and https subresources.
+func (c *Checker) pageWeight(ctx context.Context, pg *page) *PageWeight {
+	w := &PageWeight{HTMLBytes: pg.size}
+
+	seen := make(map[string]bool, len(pg.resources))
+	var refs []*urlpkg.URL
+	for _, href := range pg.resources {
+		ref, err := pg.url.Parse(href)
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || ref.Host == "" {
+			// data: и прочие встроенные ресурсы не загружаются отдельно
+			continue
+		}
+		ref.Fragment = ""
+		if seen[ref.String()] {
+			continue
+		}
+		seen[ref.String()] = true
+		refs = append(refs, ref)
+	}
+	w.Resources = len(refs)
+	if len(refs) > maxPageResources {
+		w.UnknownSize = len(refs) - maxPageResources
+		refs = refs[:maxPageResources]
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, weightWorkers)
+	)
+	for _, ref := range refs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			size := c.resourceSize(ctx, pg.url, ref)
+			mu.Lock()
+			defer mu.Unlock()
+			if size < 0 {
+				w.UnknownSize++
+			} else {
+				w.ResourceBytes += size
+			}
+		}()
+	}
+	wg.Wait()
+	w.TotalBytes = w.HTMLBytes + w.ResourceBytes
+	return w
+}
+
+// resourceSize returns the Content-Length of ref, a subresource of the page
+// at base, or -1 if it is unknown.
+func (c *Checker) resourceSize(ctx context.Context, base, ref *urlpkg.URL) int64 {
+	if !c.allowPrivate {
+		if private, err := c.isPrivateHost(ref.Hostname()); err != nil || private {
+			return -1
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, ref.String(), nil)
+	if err != nil {
+		return -1
+	}
+	if ref.Host == base.Host {
+		authorize(ctx, req)
+	}
+	resp, err := c.clientFor(ctx).Do(req)
+	if err != nil {
+		return -1
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
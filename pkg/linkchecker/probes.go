@@ -0,0 +1,146 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"net"
+	urlpkg "net/url"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// defaultPingWait bounds a ping when ctx has no deadline.
+const defaultPingWait = 3 * time.Second
+
+// probeTCP checks a tcp://host:port link: the link is available if a TCP
+// connection can be established.
+func probeTCP(ctx context.Context, u *urlpkg.URL) Result {
+	if u.Port() == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return failed(ErrorInvalidLink, "tcp link must be tcp://host:port")
+	}
+	conn, err := dial(ctx, "tcp", u.Host)
+	if err != nil {
+		return failed(classify(err), err.Error())
+	}
+	_ = conn.Close()
+	return Result{Status: StatusAvailable, Protocol: "TCP"}
+}
+
+// probePing checks a ping://host link with an ICMP echo request. Where the
+// process may not open ICMP sockets it falls back to a UDP datagram to a
+// port that is normally closed: a "port unreachable" answer also proves that
+// the host is up.
+func probePing(ctx context.Context, u *urlpkg.URL) Result {
+	if u.Port() != "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return failed(ErrorInvalidLink, "ping link must be ping://host")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultPingWait)
+		defer cancel()
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return failed(classify(err), err.Error())
+	}
+	if len(addrs) == 0 {
+		return failed(ErrorDNS, "no addresses for "+u.Hostname())
+	}
+	ip := addrs[0].IP
+	for _, a := range addrs {
+		// IPv4 предпочтительнее: ICMPv6 часто фильтруется
+		if a.IP.To4() != nil {
+			ip = a.IP
+			break
+		}
+	}
+
+	res, err := pingICMP(ctx, ip)
+	if err != nil {
+		res = pingUDP(ctx, ip)
+	}
+	return res
+}
+
+// pingICMP sends one echo request over an unprivileged ICMP socket. It
+// returns an error if such a socket cannot be opened.
+func pingICMP(ctx context.Context, ip net.IP) (Result, error) {
+	network, proto := "udp4", 1
+	var echo icmp.Type = ipv4.ICMPTypeEcho
+	var reply icmp.Type = ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, proto = "udp6", 58
+		echo, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	local := ""
+	if src := sourceIP(ctx); src != nil {
+		local = src.String()
+	}
+	conn, err := icmp.ListenPacket(network, local)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	msg := icmp.Message{Type: echo, Body: &icmp.Echo{ID: 1, Seq: 1, Data: []byte("linkchecker")}}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return Result{}, err
+	}
+	if _, err := conn.WriteTo(b, &net.UDPAddr{IP: ip}); err != nil {
+		return failed(classify(err), err.Error()), nil
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return failed(ErrorTimeout, "no echo reply from "+ip.String()), nil
+			}
+			return failed(classify(err), err.Error()), nil
+		}
+		// на unprivileged-сокете ядро подменяет ID, поэтому сверяем только адрес
+		if addr, ok := peer.(*net.UDPAddr); !ok || !addr.IP.Equal(ip) {
+			continue
+		}
+		if m, err := icmp.ParseMessage(proto, buf[:n]); err == nil && m.Type == reply {
+			return Result{Status: StatusAvailable, Protocol: "ICMP"}, nil
+		}
+	}
+}
+
+// pingUDP sends a datagram to the traceroute port of ip. A reply or an ICMP
+// "port unreachable", reported by the kernel as a refused connection, means
+// the host is up; silence until ctx is done means it is not reachable.
+func pingUDP(ctx context.Context, ip net.IP) Result {
+	conn, err := dial(ctx, "udp", net.JoinHostPort(ip.String(), "33434"))
+	if err != nil {
+		return failed(classify(err), err.Error())
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write([]byte("linkchecker")); err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return Result{Status: StatusAvailable, Protocol: "UDP"}
+		}
+		return failed(classify(err), err.Error())
+	}
+	_, err = conn.Read(make([]byte, 1))
+	switch {
+	case err == nil, errors.Is(err, syscall.ECONNREFUSED):
+		return Result{Status: StatusAvailable, Protocol: "UDP"}
+	case ctx.Err() != nil:
+		return failed(ErrorTimeout, "no answer from "+ip.String())
+	default:
+		return failed(classify(err), err.Error())
+	}
+}
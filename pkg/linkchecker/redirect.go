@@ -0,0 +1,75 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects matches the limit of the default http.Client policy.
+const maxRedirects = 10
+
+// errRedirectLoop is returned by the redirect policy for a chain that comes
+// back to a URL it already visited or never ends. With a cookie jar a URL may
+// be visited twice, as sites often set a cookie and redirect back.
+var errRedirectLoop = errors.New("redirect loop")
+
+type noRedirectsKey struct{}
+
+// WithoutRedirects returns a context under which checks do not follow
+// redirects: a 3xx response is reported as not available, with its status
+// code and Location, for callers that treat non-canonical links as broken.
+// It works with the default client or any *http.Client without its own
+// CheckRedirect; other clients must handle redirects themselves.
+func WithoutRedirects(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRedirectsKey{}, true)
+}
+
+func followRedirects(ctx context.Context) bool {
+	no, _ := ctx.Value(noRedirectsKey{}).(bool)
+	return !no
+}
+
+// checkRedirect is the CheckRedirect policy of the checker's *http.Client.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if !followRedirects(req.Context()) {
+		return http.ErrUseLastResponse
+	}
+	// учётные данные не отправляем открытым текстом и на другие хосты
+	if req.URL.Scheme != "https" || !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+		req.Header.Del("Authorization")
+	}
+	next := req.URL.String()
+	allowed := 0
+	if cookieJar(req.Context()) != nil {
+		allowed = 1
+	}
+	visits := 0
+	for _, prev := range via {
+		if prev.URL.String() == next {
+			visits++
+		}
+	}
+	if visits > allowed {
+		return fmt.Errorf("%w: %s visited %d times", errRedirectLoop, next, visits+1)
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", errRedirectLoop, maxRedirects)
+	}
+	return nil
+}
+
+// withRedirectPolicy returns client with checkRedirect installed if it is an
+// *http.Client without a policy of its own. The client is copied, so the
+// caller's value is left unchanged.
+func withRedirectPolicy(client HTTPClient) HTTPClient {
+	hc, ok := client.(*http.Client)
+	if !ok || hc.CheckRedirect != nil {
+		return client
+	}
+	cp := *hc
+	cp.CheckRedirect = checkRedirect
+	return &cp
+}
@@ -0,0 +1,108 @@
+package linkchecker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// redirectTransport answers every request for a host in to with a 301 to
+// the host it maps to, and with 200 for other hosts.
+func redirectTransport(to map[string]string) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Proto: "HTTP/1.1", Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+		if next, ok := to[req.URL.Host]; ok {
+			resp.StatusCode, resp.Status = http.StatusMovedPermanently, "301 Moved Permanently"
+			resp.Header.Set("Location", "https://"+next)
+		}
+		return resp, nil
+	})
+}
+
+func TestChecker_Redirects(t *testing.T) {
+	transport := redirectTransport(map[string]string{"old.test": "new.test", "ping.test": "pong.test", "pong.test": "ping.test"})
+	c := New(Options{
+		Client:   &http.Client{Transport: transport},
+		Resolver: publicResolver,
+		Retry:    &RetryPolicy{Retries: 2},
+	})
+
+	if r := c.CheckLink(context.Background(), "old.test"); r.Status != StatusAvailable || r.StatusCode != http.StatusOK {
+		t.Fatalf("followed redirect: got %+v", r)
+	}
+
+	r := c.CheckLink(WithoutRedirects(context.Background()), "old.test")
+	if r.Status != StatusNotAvailable || r.ErrorKind != ErrorRedirect {
+		t.Fatalf("unfollowed redirect: got %+v", r)
+	}
+	if r.StatusCode != http.StatusMovedPermanently || r.Location != "https://new.test" {
+		t.Fatalf("expected 301 to https://new.test, got %d %q", r.StatusCode, r.Location)
+	}
+
+	if r := c.CheckLink(context.Background(), "ping.test"); r.Status != StatusNotAvailable || r.ErrorKind != ErrorRedirectLoop {
+		t.Fatalf("redirect loop: got %+v", r)
+	}
+}
+
+func TestChecker_KeepsCustomRedirectPolicy(t *testing.T) {
+	called := false
+	client := &http.Client{
+		Transport: redirectTransport(map[string]string{"old.test": "new.test"}),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			called = true
+			return nil
+		},
+	}
+	c := New(Options{Client: client, Resolver: publicResolver})
+	c.CheckLink(context.Background(), "old.test")
+	if !called {
+		t.Fatal("expected the client's own CheckRedirect to be used")
+	}
+}
+
+func TestChecker_CredentialsStayOnHTTPS(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		seen[req.URL.String()] = req.Header.Get("Authorization")
+		mu.Unlock()
+		resp := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: http.Header{}, Body: http.NoBody, Request: req}
+		switch {
+		case req.URL.String() == "https://staging.test/away":
+			resp.StatusCode = http.StatusFound
+			resp.Header.Set("Location", "https://other.test/landing")
+		case req.URL.Scheme == "https" && req.URL.Host == "staging.test":
+			resp.StatusCode = http.StatusFound
+			resp.Header.Set("Location", "http://"+req.URL.Host+"/login")
+		}
+		return resp, nil
+	})
+	c := New(Options{Client: &http.Client{Transport: transport}, Resolver: publicResolver})
+
+	ctx := WithCredentials(context.Background(), Credentials{Username: "qa", Password: "pw", Hosts: []string{"Staging.test"}})
+	for _, link := range []string{"staging.test", "https://staging.test/away", "https://other.test", "http://staging.test/plain"} {
+		if r := c.CheckLink(ctx, link); r.Status != StatusAvailable {
+			t.Fatalf("%s: got %+v", link, r)
+		}
+	}
+	if seen["https://staging.test"] == "" || seen["https://staging.test/away"] == "" {
+		t.Fatal("expected credentials on the HTTPS requests to the named host")
+	}
+	for _, url := range []string{"http://staging.test/login", "http://staging.test/plain", "https://other.test/landing", "https://other.test"} {
+		if got := seen[url]; got != "" {
+			t.Fatalf("credentials sent to %s: %q", url, got)
+		}
+	}
+	if s := fmt.Sprint(Credentials{Password: "pw"}); strings.Contains(s, "pw") {
+		t.Fatalf("credentials not redacted: %s", s)
+	}
+}
@@ -0,0 +1,76 @@
+package linkchecker
+
+import (
+	"context"
+	urlpkg "net/url"
+	"time"
+)
+
+// Prober checks links of a scheme other than HTTP, written as
+// scheme://target, e.g. tcp://db.example.com:5432. CheckLink takes care of
+// the SSRF check, the breaker and timing; Probe only reports whether the
+// target answered.
+type Prober interface {
+	Probe(ctx context.Context, u *urlpkg.URL) Result
+}
+
+// ProberFunc adapts a function to the Prober interface.
+type ProberFunc func(ctx context.Context, u *urlpkg.URL) Result
+
+// Probe calls f(ctx, u).
+func (f ProberFunc) Probe(ctx context.Context, u *urlpkg.URL) Result {
+	return f(ctx, u)
+}
+
+// defaultProbers are registered in every checker; Options.Probers may
+// replace them.
+func defaultProbers() map[string]Prober {
+	return map[string]Prober{
+		"tcp":  ProberFunc(probeTCP),
+		"ping": ProberFunc(probePing),
+		"ftp":  ProberFunc(probeFTP),
+		"sftp": NewSFTPProber(nil),
+	}
+}
+
+// checkScheme checks a scheme://target link with the prober registered for
+// its scheme.
+func (c *Checker) checkScheme(ctx context.Context, link string) Result {
+	u, err := urlpkg.Parse(link)
+	if err != nil {
+		return failed(ErrorInvalidLink, err.Error())
+	}
+	prober, ok := c.probers[u.Scheme]
+	if !ok {
+		return failed(ErrorInvalidLink, "unsupported scheme "+u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return failed(ErrorInvalidLink, "link has no host")
+	}
+	if c.inMaintenance(host) {
+		return maintenanceSkip
+	}
+	if !c.allowPrivate {
+		if private, err := c.isPrivateHost(host); err != nil {
+			return failed(classify(err), err.Error())
+		} else if private {
+			return failed(ErrorPrivateAddress, "host resolves to a private address")
+		}
+	}
+	if c.breaker != nil && !c.breaker.Allow(host) {
+		return failed(ErrorCircuitOpen, "host skipped after repeated failures")
+	}
+
+	start := time.Now()
+	res := prober.Probe(c.probeContext(ctx), u)
+	res.CheckedAt, res.Duration = start, time.Since(start)
+	if c.breaker != nil {
+		if res.Status == StatusAvailable {
+			c.breaker.Success(host)
+		} else if res.ErrorKind != ErrorInvalidLink {
+			c.breaker.Failure(host)
+		}
+	}
+	return res
+}
@@ -0,0 +1,113 @@
+package linkchecker
+
+import (
+	"context"
+	"net"
+	urlpkg "net/url"
+	"testing"
+	"time"
+)
+
+func TestChecker_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	c := New(Options{Timeout: time.Second, AllowPrivate: true})
+	ctx := context.Background()
+
+	res := c.CheckLink(ctx, "tcp://"+ln.Addr().String())
+	if res.Status != StatusAvailable || res.Protocol != "TCP" || res.CheckedAt.IsZero() {
+		t.Fatalf("open port: %+v", res)
+	}
+	res = c.CheckLink(ctx, "tcp://"+closedAddr)
+	if res.Status != StatusNotAvailable || res.ErrorKind != ErrorConnection {
+		t.Fatalf("closed port: %+v", res)
+	}
+	for _, link := range []string{"tcp://127.0.0.1", "tcp://127.0.0.1:80/path"} {
+		if res := c.CheckLink(ctx, link); res.ErrorKind != ErrorInvalidLink {
+			t.Fatalf("%s: %+v", link, res)
+		}
+	}
+}
+
+func TestChecker_SchemeBlocksPrivate(t *testing.T) {
+	c := New(Options{Timeout: time.Second})
+	for _, link := range []string{"tcp://127.0.0.1:22", "ping://10.0.0.1"} {
+		if res := c.CheckLink(context.Background(), link); res.ErrorKind != ErrorPrivateAddress {
+			t.Fatalf("%s: %+v", link, res)
+		}
+	}
+}
+
+func TestChecker_Probers(t *testing.T) {
+	var got string
+	c := New(Options{
+		Timeout:  time.Second,
+		Resolver: publicResolver,
+		Probers: map[string]Prober{"dns": ProberFunc(func(_ context.Context, u *urlpkg.URL) Result {
+			got = u.Host
+			return Result{Status: StatusAvailable, Protocol: "DNS"}
+		})},
+	})
+
+	res := c.CheckLink(context.Background(), "dns://example.com")
+	if res.Status != StatusAvailable || got != "example.com" {
+		t.Fatalf("custom prober: %+v, host %q", res, got)
+	}
+	res = c.CheckLink(context.Background(), "gopher://example.com")
+	if res.ErrorKind != ErrorInvalidLink {
+		t.Fatalf("unsupported scheme: %+v", res)
+	}
+}
+
+func TestChecker_Ping(t *testing.T) {
+	c := New(Options{Timeout: 2 * time.Second, AllowPrivate: true})
+	res := c.CheckLink(context.Background(), "ping://127.0.0.1")
+	if res.Status != StatusAvailable {
+		t.Fatalf("ping loopback: %+v", res)
+	}
+	if res.Protocol != "ICMP" && res.Protocol != "UDP" {
+		t.Fatalf("unexpected protocol %q", res.Protocol)
+	}
+	if res := c.CheckLink(context.Background(), "ping://127.0.0.1:7"); res.ErrorKind != ErrorInvalidLink {
+		t.Fatalf("ping with port: %+v", res)
+	}
+}
+
+func TestChecker_SourceIP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	remote := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		remote <- host
+		_ = conn.Close()
+	}()
+
+	// весь 127.0.0.0/8 локальный только в Linux
+	source := net.ParseIP("127.0.0.2")
+	c := New(Options{Timeout: time.Second, AllowPrivate: true, SourceIP: source})
+	res := c.CheckLink(context.Background(), "tcp://"+ln.Addr().String())
+	if res.Status != StatusAvailable {
+		t.Skipf("cannot connect from %s: %+v", source, res)
+	}
+	if got := <-remote; got != source.String() {
+		t.Fatalf("connected from %s, want %s", got, source)
+	}
+}
@@ -0,0 +1,55 @@
+package linkchecker
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how a failed request is retried. Network errors and
+// 5xx responses are retried; 4xx responses are final, as repeating the same
+// request would get the same answer.
+type RetryPolicy struct {
+	// Retries is the number of attempts after the first one; zero disables
+	// retries.
+	Retries int
+	// BackoffBase is the wait before the first retry; it doubles with every
+	// following retry up to BackoffMax. Zero BackoffMax means no cap.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Jitter randomizes every wait by up to this fraction in either
+	// direction, so hosts that failed together are not retried in lockstep.
+	// It must be between 0 and 1.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is used when Options.Retry is nil.
+var DefaultRetryPolicy = RetryPolicy{
+	Retries:     2,
+	BackoffBase: 100 * time.Millisecond,
+	BackoffMax:  time.Second,
+	Jitter:      0.2,
+}
+
+// backoff returns the wait before retry n, counting from zero.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BackoffBase
+	for range n {
+		if p.BackoffMax > 0 && d >= p.BackoffMax {
+			break
+		}
+		d *= 2
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	if p.BackoffMax > 0 && d > p.BackoffMax {
+		d = p.BackoffMax
+	}
+	return max(d, 0)
+}
+
+// retryableStatus reports whether a response with code is worth retrying.
+func retryableStatus(code int) bool {
+	return code >= http.StatusInternalServerError
+}
@@ -0,0 +1,88 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sequenceClient answers with codes in order, 0 meaning a network error, and
+// repeats the last one.
+type sequenceClient struct {
+	mu    sync.Mutex
+	codes []int
+	calls int
+}
+
+func (c *sequenceClient) Do(*http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	code := c.codes[min(c.calls, len(c.codes)-1)]
+	c.calls++
+	if code == 0 {
+		return nil, errors.New("connection reset")
+	}
+	return &http.Response{StatusCode: code, Proto: "HTTP/1.1", Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestChecker_Retries(t *testing.T) {
+	policy := RetryPolicy{Retries: 2, BackoffBase: time.Millisecond}
+	tests := []struct {
+		name      string
+		codes     []int
+		want      Status
+		wantCalls int
+	}{
+		{"network error then ok", []int{0, http.StatusOK}, StatusAvailable, 2},
+		{"5xx retried", []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, StatusAvailable, 3},
+		{"5xx exhausts retries", []int{http.StatusInternalServerError}, StatusNotAvailable, 3},
+		{"4xx not retried", []int{http.StatusNotFound, http.StatusOK}, StatusNotAvailable, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &sequenceClient{codes: tc.codes}
+			c := New(Options{Client: client, Resolver: publicResolver, Retry: &policy})
+			if got := c.CheckLink(context.Background(), "retry.test").Status; got != tc.want {
+				t.Fatalf("status = %q, want %q", got, tc.want)
+			}
+			if client.calls != tc.wantCalls {
+				t.Fatalf("calls = %d, want %d", client.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestChecker_SetRetryPolicy(t *testing.T) {
+	client := &sequenceClient{codes: []int{0}}
+	c := New(Options{Client: client, Resolver: publicResolver})
+	if c.RetryPolicy() != DefaultRetryPolicy {
+		t.Fatalf("expected default policy, got %+v", c.RetryPolicy())
+	}
+
+	c.SetRetryPolicy(RetryPolicy{})
+	c.CheckLink(context.Background(), "retry.test")
+	if client.calls != 1 {
+		t.Fatalf("expected a single attempt without retries, got %d", client.calls)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BackoffBase: 100 * time.Millisecond, BackoffMax: time.Second}
+	for n, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if got := p.backoff(n); got != want {
+			t.Fatalf("backoff(%d) = %s, want %s", n, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for range 100 {
+		if d := p.backoff(1); d < 100*time.Millisecond || d > 300*time.Millisecond {
+			t.Fatalf("jittered backoff(1) = %s, want within 100ms..300ms", d)
+		}
+	}
+}
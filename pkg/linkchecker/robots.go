@@ -0,0 +1,225 @@
+package linkchecker
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRobotsSize is how much of a robots.txt file is parsed; RFC 9309 asks
+// crawlers to read at least 500 KiB.
+const maxRobotsSize = 500 << 10
+
+// Robots fetches, caches and applies robots.txt rules so that checks skip
+// paths the site owner disallowed. A Robots is safe for concurrent use and
+// may be shared between checkers.
+//
+// A missing robots.txt (4xx) allows everything. If the file cannot be
+// fetched because of a network error or a 5xx response, the link is checked
+// anyway and the file is requested again on the next check of that host.
+type Robots struct {
+	agent string
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+	now   func() time.Time
+}
+
+type robotsEntry struct {
+	ready   chan struct{}
+	rules   []robotsRule
+	expires time.Time
+}
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// NewRobots returns a robots.txt cache that follows the rules for agent,
+// falling back to the "*" group, and keeps each host's rules for ttl. Zero
+// values default to "linkchecker" and one hour.
+func NewRobots(agent string, ttl time.Duration) *Robots {
+	if agent == "" {
+		agent = "linkchecker"
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &Robots{
+		agent: strings.ToLower(agent),
+		ttl:   ttl,
+		hosts: make(map[string]*robotsEntry),
+		now:   time.Now,
+	}
+}
+
+// allowed reports whether path on host may be requested, fetching the
+// host's robots.txt through client unless it is cached. Concurrent checks of
+// the same host wait for a single fetch.
+func (r *Robots) allowed(ctx context.Context, client HTTPClient, scheme, host, path string) bool {
+	r.mu.Lock()
+	e := r.hosts[host]
+	if e != nil && e.done() && !r.now().Before(e.expires) {
+		e = nil
+	}
+	if e == nil {
+		e = &robotsEntry{ready: make(chan struct{})}
+		r.hosts[host] = e
+		r.pruneLocked()
+		r.mu.Unlock()
+
+		rules, ok := r.fetch(ctx, client, scheme+"://"+host+"/robots.txt")
+		e.rules = rules
+		if ok {
+			e.expires = r.now().Add(r.ttl)
+		} else {
+			e.expires = r.now()
+		}
+		close(e.ready)
+	} else {
+		r.mu.Unlock()
+		select {
+		case <-e.ready:
+		case <-ctx.Done():
+			return true
+		}
+	}
+	return robotsAllow(e.rules, path)
+}
+
+func (e *robotsEntry) done() bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// pruneLocked drops expired entries once the cache grows, so that checks of
+// many different hosts do not keep their rules forever.
+func (r *Robots) pruneLocked() {
+	if len(r.hosts) < 1024 {
+		return
+	}
+	now := r.now()
+	for host, e := range r.hosts {
+		if e.done() && !now.Before(e.expires) {
+			delete(r.hosts, host)
+		}
+	}
+}
+
+// fetch downloads and parses robots.txt. ok is false when the result must
+// not be cached.
+func (r *Robots) fetch(ctx context.Context, client HTTPClient, url string) ([]robotsRule, bool) {
+	// robots.txt часто редиректит на канонический хост — идём за редиректом
+	ctx = context.WithValue(ctx, noRedirectsKey{}, false)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", r.agent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer drainAndClose(resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(io.LimitReader(resp.Body, maxRobotsSize), r.agent), true
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, true
+	default:
+		return nil, false
+	}
+}
+
+// parseRobots returns the rules of the groups naming agent or, if there are
+// none, of the "*" groups.
+func parseRobots(body io.Reader, agent string) []robotsRule {
+	var own, star []robotsRule
+	var matchOwn, matchStar, inRules, seenOwn bool
+	sc := bufio.NewScanner(body)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// новая группа начинается с user-agent после правил
+			if inRules {
+				matchOwn, matchStar, inRules = false, false, false
+			}
+			ua := strings.ToLower(value)
+			matchOwn = matchOwn || ua == agent
+			seenOwn = seenOwn || ua == agent
+			matchStar = matchStar || ua == "*"
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow"}
+			if matchOwn {
+				own = append(own, rule)
+			}
+			if matchStar {
+				star = append(star, rule)
+			}
+		}
+	}
+	if seenOwn {
+		return own
+	}
+	return star
+}
+
+// robotsAllow applies the most specific matching rule; on a tie allow wins.
+func robotsAllow(rules []robotsRule, path string) bool {
+	allow, best := true, -1
+	for _, rule := range rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			allow, best = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches path against a robots.txt pattern, where * stands for
+// any sequence of characters and a trailing $ anchors the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
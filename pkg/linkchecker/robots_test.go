@@ -0,0 +1,96 @@
+package linkchecker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	body := `# comments are ignored
+User-agent: *
+Disallow: /private
+Allow: /private/open
+
+User-agent: LinkChecker
+User-agent: other
+Disallow: /*.pdf$
+Disallow: /tmp/
+`
+	own := parseRobots(strings.NewReader(body), "linkchecker")
+	star := parseRobots(strings.NewReader(body), "crawler")
+
+	tests := []struct {
+		rules []robotsRule
+		path  string
+		want  bool
+	}{
+		{star, "/", true},
+		{star, "/private/x", false},
+		{star, "/private/open/x", true},
+		{own, "/private/x", true},
+		{own, "/docs/a.pdf", false},
+		{own, "/docs/a.pdf?v=1", true},
+		{own, "/tmp/", false},
+	}
+	for _, tc := range tests {
+		if got := robotsAllow(tc.rules, tc.path); got != tc.want {
+			t.Errorf("robotsAllow(%v, %q) = %v, want %v", tc.rules, tc.path, got, tc.want)
+		}
+	}
+}
+
+// robotsClient serves robots.txt bodies by host and 200 for other paths.
+type robotsClient struct {
+	mu      sync.Mutex
+	files   map[string]string
+	fetches int
+}
+
+func (c *robotsClient) Do(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Body: io.NopCloser(strings.NewReader(""))}
+	if req.URL.Path == "/robots.txt" {
+		c.mu.Lock()
+		c.fetches++
+		c.mu.Unlock()
+		body, ok := c.files[req.URL.Host]
+		if !ok {
+			resp.StatusCode = http.StatusNotFound
+		}
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return resp, nil
+}
+
+func TestChecker_Robots(t *testing.T) {
+	client := &robotsClient{files: map[string]string{
+		"closed.test": "User-agent: *\nDisallow: /\n",
+		"open.test":   "User-agent: *\nDisallow: /admin\n",
+	}}
+	robots := NewRobots("", time.Hour)
+	c := New(Options{Timeout: time.Second, Client: client, Resolver: publicResolver, Robots: robots})
+
+	got := c.Check(context.Background(), []string{"closed.test", "closed.test", "open.test", "none.test"}, nil)
+	if r := got["closed.test"]; r.Status != StatusNotAvailable || !r.Skipped || r.ErrorKind != ErrorSkippedRobots {
+		t.Fatalf("closed.test: got %+v", r)
+	}
+	for _, host := range []string{"open.test", "none.test"} {
+		if r := got[host]; r.Status != StatusAvailable {
+			t.Fatalf("%s: got %+v", host, r)
+		}
+	}
+	if client.fetches != 3 {
+		t.Fatalf("expected one robots.txt fetch per host, got %d", client.fetches)
+	}
+
+	// кэш истёк — файл запрашивается снова
+	robots.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	c.CheckLink(context.Background(), "open.test")
+	if client.fetches != 4 {
+		t.Fatalf("expected robots.txt to be refetched after ttl, got %d fetches", client.fetches)
+	}
+}
@@ -0,0 +1,137 @@
+package linkchecker
+
+import (
+	"context"
+	"fmt"
+	urlpkg "net/url"
+	"strings"
+)
+
+// HTTPSPolicy decides whether web links are checked over HTTPS or plain
+// HTTP.
+type HTTPSPolicy int
+
+const (
+	// HTTPSAsWritten checks http:// and https:// links with their own
+	// scheme and bare host names over HTTPS.
+	HTTPSAsWritten HTTPSPolicy = iota
+	// HTTPSUpgrade checks http:// links on the default port over HTTPS
+	// first and reports Result.Upgraded when the host answers. Such links
+	// and bare host names fall back to plain HTTP with Result.Downgraded set
+	// when HTTPS gets no response because of a TLS or connection error.
+	HTTPSUpgrade
+	// HTTPSStrict rejects http:// links and checks bare host names over
+	// HTTPS only.
+	HTTPSStrict
+)
+
+var httpsPolicyNames = map[HTTPSPolicy]string{
+	HTTPSAsWritten: "as-written",
+	HTTPSUpgrade:   "upgrade",
+	HTTPSStrict:    "strict",
+}
+
+// String returns the name ParseHTTPSPolicy accepts for p.
+func (p HTTPSPolicy) String() string {
+	if name, ok := httpsPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("HTTPSPolicy(%d)", int(p))
+}
+
+// ParseHTTPSPolicy parses "as-written", "upgrade" or "strict"; an empty
+// string is HTTPSAsWritten.
+func ParseHTTPSPolicy(s string) (HTTPSPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return HTTPSAsWritten, nil
+	}
+	for p, name := range httpsPolicyNames {
+		if name == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown HTTPS policy %q, want as-written, upgrade or strict", s)
+}
+
+// defaultPorts are the ports of the web schemes when a link names none.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+func isWebScheme(scheme string) bool {
+	_, ok := defaultPorts[scheme]
+	return ok
+}
+
+// linkScheme returns the scheme link is checked with: the one it is written
+// with, mailto for mailto: links and https for bare host names.
+func linkScheme(link string) string {
+	if isMailto(link) {
+		return "mailto"
+	}
+	if i := strings.Index(link, "://"); i >= 0 {
+		return strings.ToLower(link[:i])
+	}
+	return "https"
+}
+
+// schemeAllowed reports whether links of scheme may be checked under
+// Options.Schemes and Options.HTTPS.
+func (c *Checker) schemeAllowed(scheme string) bool {
+	if scheme == "http" && c.httpsPolicy == HTTPSStrict {
+		return false
+	}
+	return c.schemes == nil || c.schemes[scheme]
+}
+
+// checkWeb checks an http:// or https:// URL under the HTTPS policy; bare
+// is set for links written as a bare host name.
+func (c *Checker) checkWeb(ctx context.Context, u *urlpkg.URL, bare bool) Result {
+	if c.httpsPolicy != HTTPSUpgrade {
+		return c.checkHTTP(ctx, u)
+	}
+	if u.Scheme == "http" {
+		// порт, отличный от 80, на HTTPS не переносится
+		if port := u.Port(); port != "" && port != defaultPorts["http"] {
+			return c.checkHTTP(ctx, u)
+		}
+		secure := *u
+		secure.Scheme, secure.Host = "https", strings.TrimSuffix(u.Host, ":"+defaultPorts["http"])
+		res := c.checkHTTP(withoutRetries(ctx), &secure)
+		if !fallBack(res) {
+			res.Upgraded = res.StatusCode != 0
+			return res
+		}
+	} else if bare {
+		res := c.checkHTTP(withoutRetries(ctx), u)
+		if !fallBack(res) {
+			return res
+		}
+		plain := *u
+		plain.Scheme = "http"
+		u = &plain
+	} else {
+		return c.checkHTTP(ctx, u)
+	}
+	res := c.checkHTTP(ctx, u)
+	res.Downgraded = true
+	return res
+}
+
+// fallBack reports whether an HTTPS check failed in a way plain HTTP might
+// not: no response because of TLS or the connection.
+func fallBack(res Result) bool {
+	return res.StatusCode == 0 && (res.ErrorKind == ErrorTLS || res.ErrorKind == ErrorConnection)
+}
+
+type noRetriesKey struct{}
+
+// withoutRetries makes the HTTP check under ctx give up after its first
+// request, leaving the retries to the plain HTTP fallback.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetriesKey{}, true)
+}
+
+func retrying(ctx context.Context) bool {
+	no, _ := ctx.Value(noRetriesKey{}).(bool)
+	return !no
+}
@@ -0,0 +1,125 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// schemeClient answers "scheme://host" keys with their status code and
+// refuses the connection for others, recording the URLs requested.
+type schemeClient struct {
+	codes map[string]int
+	mu    sync.Mutex
+	urls  []string
+}
+
+func (c *schemeClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.urls = append(c.urls, req.URL.String())
+	c.mu.Unlock()
+	code, ok := c.codes[req.URL.Scheme+"://"+req.URL.Host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: code, Proto: "HTTP/1.1", Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestParseHTTPSPolicy(t *testing.T) {
+	for in, want := range map[string]HTTPSPolicy{"": HTTPSAsWritten, "as-written": HTTPSAsWritten, "Upgrade": HTTPSUpgrade, " strict ": HTTPSStrict} {
+		if got, err := ParseHTTPSPolicy(in); err != nil || got != want {
+			t.Errorf("ParseHTTPSPolicy(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseHTTPSPolicy("always"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
+
+func TestChecker_AllowedSchemes(t *testing.T) {
+	c := New(Options{
+		Client:   &schemeClient{codes: map[string]int{"https://ok.test": 200, "http://plain.test": 200}},
+		Resolver: publicResolver,
+		Schemes:  []string{"HTTPS", "mailto"},
+	})
+	if res := c.CheckLink(context.Background(), "ok.test"); res.Status != StatusAvailable {
+		t.Fatalf("bare host: %+v", res)
+	}
+	if res := c.CheckLink(context.Background(), "https://ok.test"); res.Status != StatusAvailable {
+		t.Fatalf("https link: %+v", res)
+	}
+	for _, link := range []string{"http://plain.test", "tcp://db.test:5432"} {
+		if res := c.CheckLink(context.Background(), link); res.ErrorKind != ErrorInvalidLink || !strings.Contains(res.Error, "not allowed") {
+			t.Errorf("%s: %+v", link, res)
+		}
+		if err := c.ValidateLink(link); err == nil {
+			t.Errorf("ValidateLink(%s): expected an error", link)
+		}
+	}
+	if err := c.ValidateLink("https://"); err == nil || !strings.Contains(err.Error(), "no host") {
+		t.Fatalf("ValidateLink without host: %v", err)
+	}
+}
+
+func TestChecker_HTTPSPolicy(t *testing.T) {
+	client := &schemeClient{codes: map[string]int{
+		"https://secure.test":    200,
+		"http://secure.test":     200,
+		"http://plain.test":      200,
+		"http://legacy.test":     200,
+		"https://alt.test:8080":  200,
+		"https://missing.test":   404,
+		"http://missing.test":    200,
+		"http://plain.test:8080": 200,
+	}}
+
+	asWritten := New(Options{Client: client, Resolver: publicResolver})
+	if res := asWritten.CheckLink(context.Background(), "http://secure.test"); res.Status != StatusAvailable || res.Upgraded || res.Downgraded {
+		t.Fatalf("as written: %+v", res)
+	}
+	if res := asWritten.CheckLink(context.Background(), "legacy.test"); res.Status != StatusNotAvailable || res.Downgraded {
+		t.Fatalf("bare host as written: %+v", res)
+	}
+
+	strict := New(Options{Client: client, Resolver: publicResolver, HTTPS: HTTPSStrict})
+	if res := strict.CheckLink(context.Background(), "http://secure.test"); res.ErrorKind != ErrorInvalidLink {
+		t.Fatalf("strict http link: %+v", res)
+	}
+	if res := strict.CheckLink(context.Background(), "https://secure.test"); res.Status != StatusAvailable {
+		t.Fatalf("strict https link: %+v", res)
+	}
+
+	upgrade := New(Options{Client: client, Resolver: publicResolver, HTTPS: HTTPSUpgrade})
+	tests := []struct {
+		link                 string
+		status               Status
+		upgraded, downgraded bool
+		requested            []string
+	}{
+		{"http://secure.test", StatusAvailable, true, false, []string{"https://secure.test"}},
+		{"http://secure.test:80", StatusAvailable, true, false, []string{"https://secure.test"}},
+		{"http://plain.test", StatusAvailable, false, true, []string{"https://plain.test", "http://plain.test"}},
+		{"legacy.test", StatusAvailable, false, true, []string{"https://legacy.test", "http://legacy.test"}},
+		// HTTPS ответил — откат на HTTP не нужен
+		{"http://missing.test", StatusNotAvailable, true, false, []string{"https://missing.test"}},
+		// нестандартный порт проверяется как записан
+		{"http://plain.test:8080", StatusAvailable, false, false, []string{"http://plain.test:8080"}},
+		{"https://alt.test:8080", StatusAvailable, false, false, []string{"https://alt.test:8080"}},
+	}
+	for _, tt := range tests {
+		client.mu.Lock()
+		client.urls = nil
+		client.mu.Unlock()
+		res := upgrade.CheckLink(context.Background(), tt.link)
+		if res.Status != tt.status || res.Upgraded != tt.upgraded || res.Downgraded != tt.downgraded {
+			t.Errorf("%s: %+v", tt.link, res)
+		}
+		if strings.Join(client.urls, " ") != strings.Join(tt.requested, " ") {
+			t.Errorf("%s: requested %v, want %v", tt.link, client.urls, tt.requested)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package linkchecker
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// minHSTSMaxAge is the shortest Strict-Transport-Security max-age that
+// counts as sound: 180 days.
+const minHSTSMaxAge = 180 * 24 * 60 * 60
+
+// SecurityAudit rates the security headers of an HTTPS response.
+// Strict-Transport-Security, Content-Security-Policy, X-Content-Type-Options
+// and X-Frame-Options add 25 points each to Score when present and sound;
+// Issues says what is wrong with the others.
+type SecurityAudit struct {
+	Score  int
+	Issues []string
+}
+
+type securityKey struct{}
+
+// WithSecurityHeaders returns a context under which checks of available
+// HTTPS pages also rate their security headers as Result.Security.
+func WithSecurityHeaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, securityKey{}, true)
+}
+
+func securityMode(ctx context.Context) bool {
+	on, _ := ctx.Value(securityKey{}).(bool)
+	return on
+}
+
+// auditSecurityHeaders rates the headers of resp, or returns nil when it
+// was not served over HTTPS.
+func auditSecurityHeaders(resp *http.Response) *SecurityAudit {
+	if resp.Request == nil || resp.Request.URL.Scheme != "https" {
+		return nil
+	}
+	h := resp.Header
+	a := &SecurityAudit{}
+	check := func(issue string) {
+		if issue == "" {
+			a.Score += 25
+			return
+		}
+		a.Issues = append(a.Issues, issue)
+	}
+	csp := h.Get("Content-Security-Policy")
+	check(hstsIssue(h.Get("Strict-Transport-Security")))
+	check(cspIssue(csp))
+	check(nosniffIssue(h.Get("X-Content-Type-Options")))
+	// frame-ancestors в CSP заменяет X-Frame-Options
+	if cspDirective(csp, "frame-ancestors") != "" {
+		check("")
+	} else {
+		check(frameOptionsIssue(h.Get("X-Frame-Options")))
+	}
+	return a
+}
+
+func hstsIssue(v string) string {
+	if v == "" {
+		return "missing Strict-Transport-Security"
+	}
+	for _, part := range strings.Split(v, ";") {
+		name, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		age, err := strconv.Atoi(strings.Trim(val, `"`))
+		if err != nil || age < minHSTSMaxAge {
+			return "Strict-Transport-Security max-age is shorter than 180 days"
+		}
+		return ""
+	}
+	return "Strict-Transport-Security has no max-age"
+}
+
+func cspIssue(csp string) string {
+	if csp == "" {
+		return "missing Content-Security-Policy"
+	}
+	scripts := cspDirective(csp, "script-src")
+	if scripts == "" {
+		scripts = cspDirective(csp, "default-src")
+	}
+	if scripts == "" {
+		return "Content-Security-Policy does not restrict scripts"
+	}
+	if strings.Contains(scripts, "'unsafe-inline'") || strings.Contains(scripts, "'unsafe-eval'") {
+		return "Content-Security-Policy allows unsafe scripts"
+	}
+	return ""
+}
+
+// cspDirective returns the sources of directive name in csp, or "" if it
+// is absent.
+func cspDirective(csp, name string) string {
+	for _, d := range strings.Split(csp, ";") {
+		fields := strings.Fields(d)
+		if len(fields) > 0 && strings.EqualFold(fields[0], name) {
+			if len(fields) == 1 {
+				// директива без источников запрещает всё
+				return "'none'"
+			}
+			return strings.ToLower(strings.Join(fields[1:], " "))
+		}
+	}
+	return ""
+}
+
+func nosniffIssue(v string) string {
+	switch v = strings.TrimSpace(v); {
+	case v == "":
+		return "missing X-Content-Type-Options"
+	case !strings.EqualFold(v, "nosniff"):
+		return "X-Content-Type-Options is not nosniff"
+	}
+	return ""
+}
+
+func frameOptionsIssue(v string) string {
+	switch v = strings.ToUpper(strings.TrimSpace(v)); v {
+	case "DENY", "SAMEORIGIN":
+		return ""
+	case "":
+		return "missing X-Frame-Options"
+	}
+	return "X-Frame-Options is neither DENY nor SAMEORIGIN"
+}
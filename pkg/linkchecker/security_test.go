@@ -0,0 +1,73 @@
+package linkchecker
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// headerClient answers every request with 200 and header.
+type headerClient http.Header
+
+func (c headerClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: http.Header(c), Body: http.NoBody, Request: req}, nil
+}
+
+func TestChecker_SecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		score  int
+		issues []string
+	}{
+		{
+			name: "sound",
+			header: http.Header{
+				"Strict-Transport-Security": {"max-age=31536000; includeSubDomains"},
+				"Content-Security-Policy":   {"default-src 'self'; frame-ancestors 'none'"},
+				"X-Content-Type-Options":    {"nosniff"},
+			},
+			score: 100,
+		},
+		{
+			name: "weak",
+			header: http.Header{
+				"Strict-Transport-Security": {"max-age=300"},
+				"Content-Security-Policy":   {"script-src 'self' 'unsafe-inline'"},
+				"X-Content-Type-Options":    {"sniff"},
+				"X-Frame-Options":           {"SAMEORIGIN"},
+			},
+			score: 25,
+			issues: []string{
+				"Strict-Transport-Security max-age is shorter than 180 days",
+				"Content-Security-Policy allows unsafe scripts",
+				"X-Content-Type-Options is not nosniff",
+			},
+		},
+		{
+			name:   "missing",
+			header: http.Header{},
+			issues: []string{
+				"missing Strict-Transport-Security",
+				"missing Content-Security-Policy",
+				"missing X-Content-Type-Options",
+				"missing X-Frame-Options",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Options{Client: headerClient(tt.header), Resolver: publicResolver})
+			r := c.CheckLink(WithSecurityHeaders(context.Background()), "site.test")
+			if r.Security == nil || r.Security.Score != tt.score || !slices.Equal(r.Security.Issues, tt.issues) {
+				t.Fatalf("security = %+v, want score %d and issues %q", r.Security, tt.score, tt.issues)
+			}
+		})
+	}
+
+	c := New(Options{Client: headerClient{}, Resolver: publicResolver})
+	if r := c.CheckLink(context.Background(), "site.test"); r.Security != nil {
+		t.Fatalf("expected no audit without security mode, got %+v", r.Security)
+	}
+}
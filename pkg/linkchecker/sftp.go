@@ -0,0 +1,206 @@
+package linkchecker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	urlpkg "net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP packet types and status codes, draft-ietf-secsh-filexfer-02 (v3).
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpStat    = 17
+	sftpStatus  = 101
+	sftpAttrs   = 105
+
+	sftpNoSuchFile       = 2
+	sftpPermissionDenied = 3
+)
+
+// SFTPProber checks sftp://user@host/path links: it logs in over SSH and
+// stats path. The password comes from the link or WithCredentials; the
+// prober's keys are offered as well.
+type SFTPProber struct {
+	hostKey  ssh.HostKeyCallback
+	verified bool
+	signers  []ssh.Signer
+}
+
+// NewSFTPProber returns a prober that verifies servers with hostKey, e.g.
+// from golang.org/x/crypto/ssh/knownhosts, and authenticates with signers
+// in addition to passwords. A nil hostKey accepts any server key, which is
+// enough to tell whether a path exists with signers, but then passwords are
+// never sent: links with one are reported as invalid, since a spoofed server
+// would see it.
+func NewSFTPProber(hostKey ssh.HostKeyCallback, signers ...ssh.Signer) *SFTPProber {
+	p := &SFTPProber{hostKey: hostKey, verified: hostKey != nil, signers: signers}
+	if hostKey == nil {
+		p.hostKey = ssh.InsecureIgnoreHostKey()
+	}
+	return p
+}
+
+// Probe implements Prober.
+func (p *SFTPProber) Probe(ctx context.Context, u *urlpkg.URL) Result {
+	user, password, ok := login(ctx, u)
+	if !ok {
+		return failed(ErrorInvalidLink, "sftp link needs a user: sftp://user@host/path")
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+	var auth []ssh.AuthMethod
+	if len(p.signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(p.signers...))
+	}
+	if password != "" {
+		if !p.verified {
+			return failed(ErrorInvalidLink, "sftp password login needs known host keys")
+		}
+		auth = append(auth, ssh.Password(password))
+	}
+
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return failed(classify(err), err.Error())
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{User: user, Auth: auth, HostKeyCallback: p.hostKey})
+	if err != nil {
+		return sftpFailed(ctx, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return sftpFailed(ctx, err)
+	}
+	defer session.Close()
+	w, err := session.StdinPipe()
+	if err != nil {
+		return sftpFailed(ctx, err)
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return sftpFailed(ctx, err)
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return sftpFailed(ctx, err)
+	}
+	if err := sftpStatPath(w, r, sftpPath(u.Path)); err != nil {
+		return sftpFailed(ctx, err)
+	}
+	return Result{Status: StatusAvailable, Protocol: "SFTP"}
+}
+
+// sftpPath maps the path of a link to a server path: "" and "/~/" stand for
+// the home directory.
+func sftpPath(p string) string {
+	switch {
+	case p == "" || p == "/" || p == "/~" || p == "/~/":
+		return "."
+	case strings.HasPrefix(p, "/~/"):
+		return p[3:]
+	default:
+		return p
+	}
+}
+
+// errSFTP is an SFTP status reply other than OK.
+type errSFTP struct {
+	code uint32
+	msg  string
+}
+
+func (e *errSFTP) Error() string { return fmt.Sprintf("sftp status %d: %s", e.code, e.msg) }
+
+// sftpStatPath negotiates version 3 and stats path.
+func sftpStatPath(w io.Writer, r io.Reader, path string) error {
+	if err := sftpSend(w, sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return err
+	}
+	if typ, _, err := sftpRecv(r); err != nil {
+		return err
+	} else if typ != sftpVersion {
+		return fmt.Errorf("unexpected sftp packet %d", typ)
+	}
+
+	const id = 1
+	payload := binary.BigEndian.AppendUint32(nil, id)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(path)))
+	payload = append(payload, path...)
+	if err := sftpSend(w, sftpStat, payload); err != nil {
+		return err
+	}
+	typ, body, err := sftpRecv(r)
+	if err != nil {
+		return err
+	}
+	switch {
+	case typ == sftpAttrs:
+		return nil
+	case typ == sftpStatus && len(body) >= 8:
+		e := &errSFTP{code: binary.BigEndian.Uint32(body[4:8])}
+		if len(body) >= 12 {
+			if n := int(binary.BigEndian.Uint32(body[8:12])); n <= len(body)-12 {
+				e.msg = string(body[12 : 12+n])
+			}
+		}
+		return e
+	default:
+		return fmt.Errorf("unexpected sftp packet %d", typ)
+	}
+}
+
+func sftpSend(w io.Writer, typ byte, payload []byte) error {
+	pkt := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	pkt = append(pkt, typ)
+	_, err := w.Write(append(pkt, payload...))
+	return err
+}
+
+// sftpRecv reads one packet; packets over 64 KiB are refused, as a stat
+// reply is far smaller.
+func sftpRecv(r io.Reader) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:4])
+	if n == 0 || n > 1<<16 {
+		return 0, nil, fmt.Errorf("sftp packet of %d bytes", n)
+	}
+	body := make([]byte, n-1)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return hdr[4], body, nil
+}
+
+// sftpFailed reports a refused login or a missing path as ErrorRejected and
+// anything else by its network class.
+func sftpFailed(ctx context.Context, err error) Result {
+	var statusErr *errSFTP
+	switch {
+	case ctx.Err() != nil:
+		return failed(classify(ctx.Err()), err.Error())
+	case errors.As(err, &statusErr) && (statusErr.code == sftpNoSuchFile || statusErr.code == sftpPermissionDenied),
+		strings.Contains(err.Error(), "unable to authenticate"):
+		return failed(ErrorRejected, err.Error())
+	default:
+		return failed(classify(err), err.Error())
+	}
+}
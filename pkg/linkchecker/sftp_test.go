@@ -0,0 +1,141 @@
+package linkchecker
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpServer lets "qa" in with password "secret" and answers STAT for the
+// paths in files.
+func sftpServer(t *testing.T, files map[string]bool) (addr string, hostKey ssh.PublicKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{PasswordCallback: func(m ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) {
+		if m.User() == "qa" && string(pw) == "secret" {
+			return nil, nil
+		}
+		return nil, ssh.ErrNoAuth
+	}}
+	cfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nc := range chans {
+					ch, chReqs, err := nc.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						for req := range chReqs {
+							_ = req.Reply(req.Type == "subsystem", nil)
+						}
+					}()
+					serveSFTP(ch, files)
+					ch.Close()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), signer.PublicKey()
+}
+
+func serveSFTP(ch ssh.Channel, files map[string]bool) {
+	for {
+		typ, body, err := sftpRecv(ch)
+		if err != nil {
+			return
+		}
+		switch typ {
+		case sftpInit:
+			_ = sftpSend(ch, sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+		case sftpStat:
+			id := body[:4]
+			path := string(body[8:])
+			if files[path] {
+				_ = sftpSend(ch, sftpAttrs, append(id, 0, 0, 0, 0))
+				continue
+			}
+			reply := append(id, 0, 0, 0, sftpNoSuchFile)
+			reply = binary.BigEndian.AppendUint32(reply, 7)
+			_ = sftpSend(ch, sftpStatus, append(reply, "no file"...))
+		}
+	}
+}
+
+func TestChecker_SFTP(t *testing.T) {
+	addr, hostKey := sftpServer(t, map[string]bool{".": true, "/srv/a.gz": true, "docs": true})
+	ctx := context.Background()
+	c := New(Options{Timeout: 2 * time.Second, AllowPrivate: true, Probers: map[string]Prober{
+		"sftp": NewSFTPProber(ssh.FixedHostKey(hostKey)),
+	}})
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		link  string
+		kind  ErrorKind
+		avail bool
+	}{
+		{"home", ctx, "sftp://qa:secret@" + addr, "", true},
+		{"absolute", ctx, "sftp://qa:secret@" + addr + "/srv/a.gz", "", true},
+		{"in home", ctx, "sftp://qa:secret@" + addr + "/~/docs", "", true},
+		{"missing", ctx, "sftp://qa:secret@" + addr + "/srv/b.gz", ErrorRejected, false},
+		{"wrong password", ctx, "sftp://qa:nope@" + addr, ErrorRejected, false},
+		{"credentials", WithCredentials(ctx, Credentials{Username: "qa", Password: "secret", Hosts: []string{"127.0.0.1"}}), "sftp://" + addr + "/srv/a.gz", "", true},
+		{"credentials for another host", WithCredentials(ctx, Credentials{Username: "qa", Password: "secret", Hosts: []string{"staging.test"}}), "sftp://" + addr + "/srv/a.gz", ErrorInvalidLink, false},
+		{"no user", ctx, "sftp://" + addr, ErrorInvalidLink, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := c.CheckLink(tc.ctx, tc.link)
+			if (res.Status == StatusAvailable) != tc.avail || res.ErrorKind != tc.kind {
+				t.Fatalf("got %+v", res)
+			}
+		})
+	}
+
+	// без known_hosts пароль никуда не отправляется
+	unverified := New(Options{Timeout: 2 * time.Second, AllowPrivate: true})
+	if res := unverified.CheckLink(ctx, "sftp://qa:secret@"+addr); res.ErrorKind != ErrorInvalidLink || !strings.Contains(res.Error, "host keys") {
+		t.Fatalf("password without host keys: %+v", res)
+	}
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(other)
+	wrong := New(Options{Timeout: 2 * time.Second, AllowPrivate: true, Probers: map[string]Prober{
+		"sftp": NewSFTPProber(ssh.FixedHostKey(otherSigner.PublicKey())),
+	}})
+	if res := wrong.CheckLink(ctx, "sftp://qa:secret@"+addr); res.Status != StatusNotAvailable {
+		t.Fatalf("unknown host key accepted: %+v", res)
+	}
+}
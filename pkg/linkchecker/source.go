@@ -0,0 +1,54 @@
+package linkchecker
+
+import (
+	"context"
+	"net"
+)
+
+type sourceIPKey struct{}
+
+// withSourceIP makes the probes under ctx connect from ip; nil leaves the
+// choice to the system.
+func withSourceIP(ctx context.Context, ip net.IP) context.Context {
+	if ip == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sourceIPKey{}, ip)
+}
+
+// probeContext returns ctx with the source address and bandwidth cap of c
+// for the connections of probes.
+func (c *Checker) probeContext(ctx context.Context) context.Context {
+	return withBandwidth(withSourceIP(ctx, c.sourceIP), c.bandwidth)
+}
+
+func sourceIP(ctx context.Context) net.IP {
+	ip, _ := ctx.Value(sourceIPKey{}).(net.IP)
+	return ip
+}
+
+// dialer returns a dialer for network, "tcp" or "udp", bound to the source
+// address of ctx. With a source address only destinations of its family are
+// dialed. Private addresses are refused as RefusePrivate does.
+func dialer(ctx context.Context, network string) *net.Dialer {
+	d := &net.Dialer{ControlContext: RefusePrivate}
+	ip := sourceIP(ctx)
+	switch {
+	case ip == nil:
+	case network == "udp":
+		d.LocalAddr = &net.UDPAddr{IP: ip}
+	default:
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return d
+}
+
+// dial connects to addr as dialer(ctx, network) does, with the reads of the
+// connection throttled by the Bandwidth of ctx.
+func dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := dialer(ctx, network).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return throttleConn(ctx, conn), nil
+}
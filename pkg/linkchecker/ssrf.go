@@ -0,0 +1,126 @@
+package linkchecker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// nat64Prefix is the well-known NAT64 prefix 64:ff9b::/96, whose addresses
+// embed an IPv4 address in their last four bytes.
+var nat64Prefix = net.IPNet{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}
+
+// isPrivateIP reports whether host is an IP address that must not be
+// checked: IPv4 private, loopback, link-local, "this network" (0.0.0.0/8)
+// and carrier-grade NAT (100.64.0.0/10) ranges and, for IPv6, the
+// loopback and unspecified addresses, unique local (fc00::/7), link-local
+// (fe80::/10) and the deprecated site-local (fec0::/10) ranges, and NAT64
+// or 6to4 addresses embedding a private IPv4 address. IPv4-mapped IPv6
+// addresses are checked as IPv4.
+func isPrivateIP(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		switch {
+		case ip4[0] == 10:
+			return true
+		case ip4[0] == 172 && ip4[1] >= 16 && ip4[1] <= 31:
+			return true
+		case ip4[0] == 192 && ip4[1] == 168:
+			return true
+		case ip4[0] == 127:
+			return true
+		case ip4[0] == 169 && ip4[1] == 254:
+			return true
+		case ip4[0] == 0:
+			return true
+		case ip4[0] == 100 && ip4[1]&0xc0 == 64: // 100.64.0.0/10
+			return true
+		}
+		return false
+	}
+	switch {
+	case ip.IsLoopback(), ip.IsUnspecified(), ip.IsPrivate(),
+		ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast(), ip.IsInterfaceLocalMulticast():
+		return true
+	case ip[0] == 0xfe && ip[1]&0xc0 == 0xc0: // fec0::/10
+		return true
+	case nat64Prefix.Contains(ip):
+		return isPrivateIP(net.IP(ip[12:16]).String())
+	case ip[0] == 0x20 && ip[1] == 0x02: // 6to4, 2002::/16
+		return isPrivateIP(net.IP(ip[2:6]).String())
+	}
+	return false
+}
+
+// AddressFamily returns "ipv4" or "ipv6" for an IP address such as
+// Result.RemoteIP and "" for anything else.
+func AddressFamily(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return ""
+	case parsed.To4() != nil:
+		return "ipv4"
+	default:
+		return "ipv6"
+	}
+}
+
+// isPrivateHost reports whether all addresses of host are private. A host
+// that cannot be resolved is treated as private and the error is returned.
+func (c *Checker) isPrivateHost(host string) (bool, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return isPrivateIP(host), nil
+	}
+
+	ips, err := c.resolve(host)
+	if err != nil {
+		return true, err // fail-safe
+	}
+	if len(ips) == 0 {
+		return true, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	// Проверяем, что ВСЕ адреса приватные
+	for _, ip := range ips {
+		if !isPrivateIP(ip.String()) {
+			return false, nil // публичный IP найден
+		}
+	}
+	return true, nil // все приватные
+}
+
+// errPrivateDial is returned by RefusePrivate for a connection to a private
+// address.
+var errPrivateDial = errors.New("connection to a private address refused")
+
+type refusePrivateKey struct{}
+
+// refusePrivate marks ctx so that RefusePrivate refuses the connections
+// dialed under it to private addresses.
+func refusePrivate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refusePrivateKey{}, true)
+}
+
+// RefusePrivate is a net.Dialer ControlContext hook that refuses to connect
+// to a private address on behalf of a link check of a Checker without
+// AllowPrivate; other connections are let through. It checks the address
+// actually dialed, so a host name that resolved to a public address when
+// the link was checked and to a private one when connecting is refused too.
+// Set it on the dialer of the Options.Client transport.
+func RefusePrivate(ctx context.Context, network, address string, _ syscall.RawConn) error {
+	if refuse, _ := ctx.Value(refusePrivateKey{}).(bool); !refuse {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if isPrivateIP(host) {
+		return &net.OpError{Op: "dial", Net: network, Err: errPrivateDial}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package linkchecker
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatusRule decides whether responses with a status count as available,
+// overriding the default rule under which 2xx and 3xx responses do.
+type StatusRule struct {
+	// Status is the status code the rule applies to. When it is zero the
+	// rule applies to every status of Class, 4 standing for 4xx.
+	Status int
+	Class  int
+	// OtherHost limits a rule for redirects to those leading to another
+	// host than the one of the checked link.
+	OtherHost bool
+	Available bool
+}
+
+// StatusRules classify responses in order; the first matching rule wins.
+// Rules for redirects also apply to the redirects followed on the way to
+// the final response, so that a link can be reported as not available for
+// redirecting to another host. A rule making a status available ends
+// retries of it.
+type StatusRules []StatusRule
+
+// ParseStatusRules parses rules written as "403=available,429=available,
+// 301:other-host=broken,5xx=broken". Codes are three digits or a class
+// such as 4xx; ":other-host" applies to redirect statuses only.
+func ParseStatusRules(spec string) (StatusRules, error) {
+	var rules StatusRules
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		status, class, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q, want status=available or status=broken", item)
+		}
+		var r StatusRule
+		switch strings.ToLower(strings.TrimSpace(class)) {
+		case "available":
+			r.Available = true
+		case "broken":
+		default:
+			return nil, fmt.Errorf("rule %q: unknown class %q, want available or broken", item, class)
+		}
+		status, scope, scoped := strings.Cut(strings.ToLower(strings.TrimSpace(status)), ":")
+		if scoped {
+			if scope != "other-host" {
+				return nil, fmt.Errorf("rule %q: unknown scope %q, want other-host", item, scope)
+			}
+			r.OtherHost = true
+		}
+		if len(status) == 3 && strings.HasSuffix(status, "xx") && status[0] >= '1' && status[0] <= '5' {
+			r.Class = int(status[0] - '0')
+		} else if n, err := strconv.Atoi(status); err == nil && n >= 100 && n <= 599 {
+			r.Status = n
+		} else {
+			return nil, fmt.Errorf("rule %q: invalid status %q", item, status)
+		}
+		if r.OtherHost && !r.redirect() {
+			return nil, fmt.Errorf("rule %q: other-host applies to 3xx statuses only", item)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r StatusRule) redirect() bool {
+	return r.Class == 3 || r.Status/100 == 3
+}
+
+func (r StatusRule) matches(code int, otherHost bool) bool {
+	if r.OtherHost && !otherHost {
+		return false
+	}
+	if r.Status != 0 {
+		return code == r.Status
+	}
+	return code/100 == r.Class
+}
+
+// String returns r in the syntax of ParseStatusRules.
+func (r StatusRule) String() string {
+	s := strconv.Itoa(r.Status)
+	if r.Status == 0 {
+		s = strconv.Itoa(r.Class) + "xx"
+	}
+	if r.OtherHost {
+		s += ":other-host"
+	}
+	if r.Available {
+		return s + "=available"
+	}
+	return s + "=broken"
+}
+
+// classify reports whether a response with code counts as available and
+// whether a rule decided it.
+func (rs StatusRules) classify(code int, otherHost bool) (available, ruled bool) {
+	for _, r := range rs {
+		if r.matches(code, otherHost) {
+			return r.Available, true
+		}
+	}
+	return false, false
+}
+
+// brokenRedirect returns the first redirect followed to resp that a rule
+// classifies as not available, or nil.
+func (rs StatusRules) brokenRedirect(host string, resp *http.Response) *http.Response {
+	if len(rs) == 0 || resp.Request == nil {
+		return nil
+	}
+	var hops []*http.Response
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hops = append(hops, req.Response)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if available, ruled := rs.classify(hop.StatusCode, otherHost(host, hop)); ruled && !available {
+			return hop
+		}
+	}
+	return nil
+}
+
+// otherHost reports whether the redirect resp leads away from host.
+func otherHost(host string, resp *http.Response) bool {
+	loc, err := resp.Location()
+	return err == nil && loc.Hostname() != "" && !strings.EqualFold(loc.Hostname(), host)
+}
+
+// Legend describes how statuses are classified, rules first, for reports.
+func (rs StatusRules) Legend() []string {
+	lines := make([]string, 0, len(rs)+2)
+	for _, r := range rs {
+		status := strconv.Itoa(r.Status)
+		if r.Status == 0 {
+			status = strconv.Itoa(r.Class) + "xx"
+		}
+		if r.OtherHost {
+			status += " redirect to another host"
+		}
+		class := StatusNotAvailable
+		if r.Available {
+			class = StatusAvailable
+		}
+		lines = append(lines, fmt.Sprintf("HTTP %s - %s", status, class))
+	}
+	return append(lines,
+		fmt.Sprintf("HTTP 2xx-3xx - %s", StatusAvailable),
+		fmt.Sprintf("any other status or a request error - %s", StatusNotAvailable))
+}
@@ -0,0 +1,78 @@
+package linkchecker
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseStatusRules(t *testing.T) {
+	rules, err := ParseStatusRules("403=available, 429=Available,301:other-host=broken,5xx=broken,")
+	if err != nil {
+		t.Fatalf("ParseStatusRules: %v", err)
+	}
+	want := StatusRules{
+		{Status: 403, Available: true},
+		{Status: 429, Available: true},
+		{Status: 301, OtherHost: true},
+		{Class: 5},
+	}
+	if !slices.Equal(rules, want) {
+		t.Fatalf("rules = %+v, want %+v", rules, want)
+	}
+	if got := rules[2].String(); got != "301:other-host=broken" {
+		t.Fatalf("String() = %q", got)
+	}
+
+	for _, spec := range []string{"403", "403=ok", "99=available", "6xx=broken", "404:other-host=broken", "301:same-host=broken"} {
+		if _, err := ParseStatusRules(spec); err == nil {
+			t.Errorf("ParseStatusRules(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestChecker_StatusRules(t *testing.T) {
+	rules, _ := ParseStatusRules("403=available,429=available,301:other-host=broken,204=broken")
+	c := New(Options{
+		Client:      statusClient{"forbidden.test": 403, "busy.test": 429, "empty.test": 204, "gone.test": 404},
+		Resolver:    publicResolver,
+		Breaker:     NewBreaker(1, 0),
+		StatusRules: rules,
+	})
+	for link, want := range map[string]Status{
+		"forbidden.test": StatusAvailable,
+		"busy.test":      StatusAvailable,
+		"empty.test":     StatusNotAvailable,
+		"gone.test":      StatusNotAvailable,
+	} {
+		if r := c.CheckLink(context.Background(), link); r.Status != want {
+			t.Errorf("%s: got %+v, want %s", link, r, want)
+		}
+	}
+	// статус, признанный правилом недоступным, не считается отказом хоста
+	if !c.breaker.Allow("empty.test") {
+		t.Fatal("a response classified as broken tripped the breaker")
+	}
+
+	redirects := New(Options{
+		Client:      &http.Client{Transport: redirectTransport(map[string]string{"old.test": "new.test"})},
+		Resolver:    publicResolver,
+		StatusRules: rules,
+	})
+	r := redirects.CheckLink(context.Background(), "old.test")
+	if r.Status != StatusNotAvailable || r.ErrorKind != ErrorHTTPStatus || r.Location != "https://new.test" {
+		t.Fatalf("redirect to another host: got %+v", r)
+	}
+	if r := redirects.CheckLink(WithoutRedirects(context.Background()), "old.test"); r.Status != StatusNotAvailable || r.Location != "https://new.test" {
+		t.Fatalf("unfollowed redirect to another host: got %+v", r)
+	}
+	if r := redirects.CheckLink(context.Background(), "new.test"); r.Status != StatusAvailable {
+		t.Fatalf("no redirect: got %+v", r)
+	}
+
+	legend := rules.Legend()
+	if len(legend) != len(rules)+2 || legend[2] != "HTTP 301 redirect to another host - not available" {
+		t.Fatalf("legend = %q", legend)
+	}
+}